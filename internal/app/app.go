@@ -1,19 +1,51 @@
 package app
 
 import (
+	"calendar-server/internal/auth"
 	"calendar-server/internal/config"
+	adminHandler "calendar-server/internal/delivery/http-server/handler/admin_handler"
+	apiKeyHandler "calendar-server/internal/delivery/http-server/handler/apikey_handler"
+	calDAVHandler "calendar-server/internal/delivery/http-server/handler/caldav_handler"
+	digestHandler "calendar-server/internal/delivery/http-server/handler/digest_handler"
 	handler "calendar-server/internal/delivery/http-server/handler/event_handler"
+	healthHandler "calendar-server/internal/delivery/http-server/handler/health_handler"
+	streamHandler "calendar-server/internal/delivery/http-server/handler/stream_handler"
+	webhookHandler "calendar-server/internal/delivery/http-server/handler/webhook_handler"
+	webSocketHandler "calendar-server/internal/delivery/http-server/handler/websocket_handler"
+	"calendar-server/internal/delivery/http-server/middleware"
+	"calendar-server/internal/delivery/http-server/response"
 	"calendar-server/internal/delivery/http-server/router"
+	"calendar-server/internal/digest"
+	"calendar-server/internal/domain"
+	"calendar-server/internal/outbox"
+	"calendar-server/internal/pubsub"
+	"calendar-server/internal/reminder"
+	apiKeyRepository "calendar-server/internal/repository/apikey_repository/inmemory"
+	digestRepository "calendar-server/internal/repository/digest_repository/inmemory"
 	repository "calendar-server/internal/repository/event_repository/inmemory"
+	"calendar-server/internal/repository/event_repository/instrumented"
+	reminderRepository "calendar-server/internal/repository/reminder_repository/inmemory"
+	webhookRepository "calendar-server/internal/repository/webhook_repository/inmemory"
+	"calendar-server/internal/tenant"
+	adminUseCase "calendar-server/internal/usecase/admin_usecase"
+	apiKeyUseCase "calendar-server/internal/usecase/apikey_usecase"
+	digestUseCase "calendar-server/internal/usecase/digest_usecase"
 	usecase "calendar-server/internal/usecase/event_usecase"
+	webhookUseCase "calendar-server/internal/usecase/webhook_usecase"
+	"calendar-server/internal/webhook"
 	"context"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"calendar-server/pkg/i18n"
 	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/metrics"
+	"calendar-server/pkg/tracing"
 
 	"go.uber.org/zap"
 )
@@ -23,33 +55,210 @@ type App struct {
 	config *config.Config
 	server *http.Server
 	logger *zap.Logger
+	// changes - шина изменений; relay - отправка изменений из outbox в шину; dispatcher - доставка изменений вебхукам;
+	// reminders - планировщик напоминаний о событиях; digests - отправка сводок по подпискам
+	changes    *pubsub.Hub
+	relay      *outbox.Relay
+	dispatcher *webhook.Dispatcher
+	reminders  *reminder.Scheduler
+	digests    *digest.Scheduler
+	// draining - идет остановка: /readyz сообщает о неготовности
+	draining atomic.Bool
+	// closers - ресурсы, освобождаемые после остановки сервера
+	closers []io.Closer
 }
 
 // New создает новый экземпляр App
 func New(logger *zap.Logger) *App {
 	cfg := config.MustLoad()
 
-	eventRepo := repository.NewEventRepository(logger)
+	var metricsRegistry *metrics.Registry
+	if cfg.Metrics.Enabled {
+		metricsRegistry = metrics.NewRegistry()
+		metrics.RegisterRuntime(metricsRegistry)
+	}
+
+	// Хранилище записывает изменения в outbox вместе с событиями, relay публикует их в шину
+	eventStore := repository.NewEventRepository(logger, repository.WithOutbox())
+	// Декоратор нужен и без метрик: он открывает спаны трассировки операций хранилища
+	eventRepo := instrumented.NewEventRepository(eventStore, metricsRegistry)
+
+	tenants := tenant.NewRegistry(tenant.Settings{
+		Quota:      cfg.Tenancy.DefaultQuota,
+		Validation: cfg.Tenancy.DefaultValidation,
+	}, cfg.Tenancy.Tenants)
+
+	changes := pubsub.NewHub(cfg.Stream.Buffer, cfg.Stream.History)
+	if metricsRegistry != nil {
+		changes.RegisterMetrics(metricsRegistry)
+	}
+
+	relay := outbox.NewRelay(eventStore, changes, logger, outbox.WithSettings(newOutboxSettings(cfg.Outbox)))
+	if metricsRegistry != nil {
+		relay.RegisterMetrics(metricsRegistry)
+	}
+
+	eventUseCase := usecase.NewEventUseCase(eventRepo, logger,
+		usecase.WithTenants(tenants),
+		usecase.WithMaxRangeDays(cfg.Calendar.MaxRangeDays),
+		usecase.WithMaxImportEvents(cfg.Calendar.ImportMaxEvents),
+		usecase.WithChanges(changes),
+		usecase.WithOutbox(relay),
+		usecase.WithDefaultReminderMethod(cfg.Reminder.DefaultMethod),
+	)
+
+	errorRenderer := response.ErrorRenderer{
+		Format:  cfg.Errors.Format,
+		DocsURL: cfg.Errors.DocsURL,
+		Catalog: newCatalog(cfg.Errors, logger),
+	}
+
+	eventHandler := handler.NewEventHandler(eventUseCase, logger,
+		handler.WithErrorRenderer(errorRenderer),
+		handler.WithCalendarExport(handler.CalendarExport{
+			PastDays:   cfg.Calendar.PastDays,
+			FutureDays: cfg.Calendar.FutureDays,
+		}),
+		handler.WithCalendarImport(newCalendarImport(cfg.Calendar)),
+	)
+
+	davHandler := calDAVHandler.NewCalDAVHandler(eventUseCase, logger,
+		calDAVHandler.WithErrorRenderer(errorRenderer),
+		calDAVHandler.WithSettings(newCalDAVSettings(cfg.Calendar)),
+	)
 
-	eventUseCase := usecase.NewEventUseCase(eventRepo, logger)
+	changesHandler := streamHandler.NewStreamHandler(eventUseCase, logger,
+		streamHandler.WithErrorRenderer(errorRenderer),
+		streamHandler.WithSettings(newStreamSettings(cfg.Stream)),
+	)
 
-	eventHandler := handler.NewEventHandler(eventUseCase, logger)
+	// Один ограничитель на HTTP и WebSocket: команды расходуют тот же бюджет изменений
+	rateLimiter := newRateLimiter(cfg.RateLimit)
+
+	wsOptions := []webSocketHandler.Option{
+		webSocketHandler.WithErrorRenderer(errorRenderer),
+		webSocketHandler.WithSettings(newWebSocketSettings(cfg.WebSocket)),
+		webSocketHandler.WithOriginCheck(middleware.CORSPolicy(cfg.CORS).AllowsOrigin),
+	}
+	if rateLimiter != nil {
+		wsOptions = append(wsOptions, webSocketHandler.WithCommandLimiter(rateLimiter))
+	}
+	wsHandler := webSocketHandler.NewWebSocketHandler(eventUseCase, logger, wsOptions...)
+	if metricsRegistry != nil {
+		wsHandler.RegisterMetrics(metricsRegistry)
+	}
+
+	apiKeyRepo := apiKeyRepository.NewAPIKeyRepository(logger)
+
+	keyUseCase := apiKeyUseCase.NewAPIKeyUseCase(apiKeyRepo, logger)
+
+	keyHandler := apiKeyHandler.NewAPIKeyHandler(keyUseCase, logger, apiKeyHandler.WithErrorRenderer(errorRenderer))
+
+	webhookRepo := webhookRepository.NewWebhookRepository(logger)
+
+	webhookAddresses := webhook.AddressPolicy{Allowed: cfg.Webhook.AllowedNetworks}
+
+	dispatcher := webhook.NewDispatcher(webhookRepo, logger,
+		webhook.WithSettings(newWebhookSettings(cfg.Webhook)),
+		webhook.WithAddressPolicy(webhookAddresses),
+	)
+	if metricsRegistry != nil {
+		dispatcher.RegisterMetrics(metricsRegistry)
+	}
+
+	reminderRepo := reminderRepository.NewReminderRepository(logger)
+
+	reminders := reminder.NewScheduler(eventStore, reminderRepo, logger, newReminderOptions(cfg.Reminder, logger)...)
+	if metricsRegistry != nil {
+		reminders.RegisterMetrics(metricsRegistry)
+	}
 
-	r := router.NewRouter(eventHandler, logger)
+	digestRepo := digestRepository.NewDigestRepository(logger)
 
-	server := &http.Server{
+	// Сводки читают события через use case, чтобы предпросмотр проверял доступ к календарю
+	digestGenerator := digest.NewGenerator(eventUseCase)
+
+	digests := digest.NewScheduler(digestRepo, digestGenerator, digest.NewLogSender(logger), logger,
+		digest.WithSettings(newDigestSettings(cfg.Digest)),
+	)
+	if metricsRegistry != nil {
+		digests.RegisterMetrics(metricsRegistry)
+	}
+
+	summaryUseCase := digestUseCase.NewDigestUseCase(digestRepo, digestGenerator, logger,
+		digestUseCase.WithDefaults(cfg.Digest.TimeZone, cfg.Digest.SendAt),
+	)
+
+	summaryHandler := digestHandler.NewDigestHandler(summaryUseCase, logger, digestHandler.WithErrorRenderer(errorRenderer))
+
+	hookUseCase := webhookUseCase.NewWebhookUseCase(webhookRepo, dispatcher, logger, webhookUseCase.WithAddressPolicy(webhookAddresses))
+
+	hookHandler := webhookHandler.NewWebhookHandler(hookUseCase, logger, webhookHandler.WithErrorRenderer(errorRenderer))
+
+	opsUseCase := adminUseCase.NewAdminUseCase(eventRepo, logger,
+		adminUseCase.WithTenants(tenants),
+		adminUseCase.WithOutbox(relay),
+	)
+
+	opsHandler := adminHandler.NewAdminHandler(opsUseCase, logger, adminHandler.WithErrorRenderer(errorRenderer))
+
+	authenticator := newAuthenticator(cfg.Auth, keyUseCase, logger)
+
+	a := &App{
+		config:     cfg,
+		logger:     logger,
+		changes:    changes,
+		relay:      relay,
+		dispatcher: dispatcher,
+		reminders:  reminders,
+		digests:    digests,
+	}
+	// Relay и отправители останавливаются после сервера: неотправленные сообщения, доставки,
+	// напоминания и сводки остаются ожидающими до следующего запуска
+	a.closers = append(a.closers, relay, dispatcher, reminders, digests)
+
+	checks := []healthHandler.Check{{Name: "repository", Check: eventRepo.Ping}}
+
+	handlers := router.Handlers{
+		Event:     eventHandler,
+		APIKey:    keyHandler,
+		Admin:     opsHandler,
+		CalDAV:    davHandler,
+		Stream:    changesHandler,
+		WebSocket: wsHandler,
+		Webhook:   hookHandler,
+		Digest:    summaryHandler,
+		Health:    healthHandler.NewHealthHandler(checks, a.draining.Load, logger),
+	}
+	mw := router.Middleware{
+		Authenticator: authenticator,
+		Tenants:       tenants,
+		TenantHeader:  cfg.Tenancy.Header,
+		RateLimiter:   rateLimiter,
+		CORS:          middleware.CORSPolicy(cfg.CORS),
+		Tracer:        a.newTracer(cfg.Tracing),
+		Errors:        errorRenderer,
+	}
+	if metricsRegistry != nil {
+		handlers.Metrics = metricsRegistry.Handler()
+		mw.Metrics = middleware.NewHTTPMetrics(metricsRegistry)
+	}
+
+	r := router.NewRouter(handlers, mw, logger)
+
+	a.server = &http.Server{
 		Addr:         ":" + cfg.Port,
 		Handler:      r,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// Потоки изменений не завершаются сами: закрываем подписки, чтобы Shutdown не ждал их до таймаута
+	a.server.RegisterOnShutdown(changes.Close)
+	// Соединения WebSocket после рукопожатия сервер не отслеживает
+	a.server.RegisterOnShutdown(wsHandler.Shutdown)
 
-	return &App{
-		config: cfg,
-		server: server,
-		logger: logger,
-	}
+	return a
 }
 
 // Run запускает сервер
@@ -64,6 +273,28 @@ func (a *App) Run() error {
 
 	go a.handleSignals(cancel)
 
+	if err := a.dispatcher.Start(ctx); err != nil {
+		a.logger.Error("Failed to start webhook dispatcher", zappretty.Field("error", err))
+		return err
+	}
+	// Вебхуки получают изменения и во время остановки; потребитель завершается при закрытии шины.
+	// Подписка создается до запуска relay, чтобы вебхуки получили и сообщения, оставшиеся в outbox.
+	if err := a.dispatcher.Consume(context.Background(), a.changes); err != nil {
+		a.logger.Error("Failed to subscribe webhook dispatcher to changes", zappretty.Field("error", err))
+		return err
+	}
+	// Планировщик подписывается до пересчета по хранилищу, чтобы не пропустить изменения между ними
+	if err := a.reminders.Consume(context.Background(), a.changes); err != nil {
+		a.logger.Error("Failed to subscribe reminder scheduler to changes", zappretty.Field("error", err))
+		return err
+	}
+	if err := a.reminders.Start(ctx); err != nil {
+		a.logger.Error("Failed to start reminder scheduler", zappretty.Field("error", err))
+		return err
+	}
+	a.relay.Start()
+	a.digests.Start()
+
 	serverErr := make(chan error, 1)
 	go func() {
 		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -78,7 +309,14 @@ func (a *App) Run() error {
 	case <-ctx.Done():
 		a.logger.Info("Shutdown signal received, stopping server...")
 
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		// Даем балансировщику время заметить неготовность до закрытия соединений
+		a.draining.Store(true)
+		if delay := a.config.Shutdown.DrainDelay; delay > 0 {
+			a.logger.Info("Draining before shutdown", zappretty.Field("delay", delay))
+			time.Sleep(delay)
+		}
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), a.config.Shutdown.Timeout)
 		defer shutdownCancel()
 
 		if err := a.server.Shutdown(shutdownCtx); err != nil {
@@ -86,11 +324,198 @@ func (a *App) Run() error {
 			return err
 		}
 
+		a.close()
+
 		a.logger.Info("Server stopped gracefully")
 		return nil
 	}
 }
 
+// newAuthenticator собирает цепочку аутентификаторов из конфигурации
+func newAuthenticator(cfg config.AuthConfig, apiKeys auth.Authenticator, logger *zap.Logger) auth.Authenticator {
+	chain := auth.Chain{apiKeys}
+
+	if cfg.JWTSecret != "" {
+		chain = append(chain, auth.NewJWTAuthenticator([]byte(cfg.JWTSecret), cfg.JWTIssuer))
+	}
+
+	if cfg.TokensFile != "" {
+		tokens, err := auth.LoadStaticTokens(cfg.TokensFile)
+		if err != nil {
+			logger.Fatal("Failed to load auth tokens",
+				zappretty.Field("path", cfg.TokensFile),
+				zappretty.Field("error", err),
+			)
+		}
+		logger.Info("Loaded auth tokens", zappretty.Field("count", tokens.Len()))
+		chain = append(chain, tokens)
+	}
+
+	if len(chain) == 1 {
+		logger.Warn("No JWT secret or tokens file configured, only existing API keys will be accepted")
+	}
+
+	return chain
+}
+
+// newRateLimiter создает ограничитель частоты запросов или nil, если он отключен
+func newRateLimiter(cfg config.RateLimitConfig) *middleware.RateLimiter {
+	if !cfg.Enabled {
+		return nil
+	}
+	return middleware.NewRateLimiter(
+		middleware.Budget{Rate: cfg.ReadRate, Burst: cfg.ReadBurst},
+		middleware.Budget{Rate: cfg.WriteRate, Burst: cfg.WriteBurst},
+		cfg.IdleTTL,
+		middleware.WithAuthFailureBudget(middleware.Budget{Rate: cfg.AuthFailureRate, Burst: cfg.AuthFailureBurst}),
+	)
+}
+
+// newCatalog загружает встроенные сообщения об ошибках и дополнительные файлы из каталога
+func newCatalog(cfg config.ErrorsConfig, logger *zap.Logger) *i18n.Catalog {
+	catalog := i18n.NewBuiltinCatalog(cfg.Language)
+
+	if cfg.CatalogDir != "" {
+		if err := catalog.Load(os.DirFS(cfg.CatalogDir), "."); err != nil {
+			logger.Fatal("Failed to load error message catalogue",
+				zappretty.Field("path", cfg.CatalogDir),
+				zappretty.Field("error", err),
+			)
+		}
+	}
+
+	if !catalog.Has(cfg.Language) {
+		logger.Fatal("No error messages for default language", zappretty.Field("language", cfg.Language))
+	}
+	logger.Info("Loaded error message catalogue", zappretty.Field("languages", catalog.Languages()))
+
+	return catalog
+}
+
+// newCalendarImport - параметры импорта iCalendar; часовой пояс проверен при загрузке конфигурации
+func newCalendarImport(cfg config.CalendarConfig) handler.CalendarImport {
+	loc, _ := time.LoadLocation(cfg.ImportTimeZone)
+	return handler.CalendarImport{
+		MaxBytes:       int64(cfg.ImportMaxBytes),
+		MaxOccurrences: cfg.ImportMaxOccurrences,
+		Location:       loc,
+	}
+}
+
+// newCalDAVSettings - период и часовой пояс CalDAV совпадают с выгрузкой и импортом iCalendar
+func newCalDAVSettings(cfg config.CalendarConfig) calDAVHandler.Settings {
+	loc, _ := time.LoadLocation(cfg.ImportTimeZone)
+	return calDAVHandler.Settings{
+		PastDays:   cfg.PastDays,
+		FutureDays: cfg.FutureDays,
+		MaxBytes:   int64(cfg.ImportMaxBytes),
+		Location:   loc,
+	}
+}
+
+// newStreamSettings - параметры ленты изменений; остальные значения по умолчанию
+func newStreamSettings(cfg config.StreamConfig) streamHandler.Settings {
+	settings := streamHandler.DefaultSettings()
+	settings.Heartbeat = cfg.Heartbeat
+	return settings
+}
+
+// newWebSocketSettings - параметры соединений WebSocket; остальные значения по умолчанию
+func newWebSocketSettings(cfg config.WebSocketConfig) webSocketHandler.Settings {
+	settings := webSocketHandler.DefaultSettings()
+	settings.PingInterval = cfg.PingInterval
+	settings.SendBuffer = cfg.SendBuffer
+	settings.MaxSubscriptions = cfg.MaxSubscriptions
+	return settings
+}
+
+// newWebhookSettings - параметры доставки вебхуков
+func newWebhookSettings(cfg config.WebhookConfig) webhook.Settings {
+	return webhook.Settings{
+		Workers:     cfg.Workers,
+		MaxAttempts: cfg.MaxAttempts,
+		Backoff:     cfg.Backoff,
+		MaxBackoff:  cfg.MaxBackoff,
+		Timeout:     cfg.Timeout,
+	}
+}
+
+// newOutboxSettings - параметры отправки outbox; остальные значения по умолчанию
+func newOutboxSettings(cfg config.OutboxConfig) outbox.Settings {
+	settings := outbox.DefaultSettings()
+	settings.BatchSize = cfg.BatchSize
+	settings.PollInterval = cfg.PollInterval
+	return settings
+}
+
+// newReminderOptions - параметры планировщика напоминаний и настроенные способы доставки;
+// часовой пояс и способ по умолчанию проверены при загрузке конфигурации
+func newReminderOptions(cfg config.ReminderConfig, logger *zap.Logger) []reminder.Option {
+	settings := reminder.DefaultSettings()
+	settings.Location, _ = time.LoadLocation(cfg.TimeZone)
+	settings.DefaultMethod = cfg.DefaultMethod
+	settings.ResyncInterval = cfg.ResyncInterval
+	settings.CatchUp = cfg.CatchUp
+	settings.MaxAttempts = cfg.MaxAttempts
+	settings.RetryDelay = cfg.RetryDelay
+
+	opts := []reminder.Option{
+		reminder.WithSettings(settings),
+		reminder.WithNotifier(domain.ReminderLog, reminder.NewLogNotifier(logger)),
+	}
+	if cfg.WebhookURL != "" {
+		opts = append(opts, reminder.WithNotifier(domain.ReminderWebhook,
+			reminder.NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookSecret, http.DefaultClient)))
+	}
+	if cfg.SMTPAddr != "" {
+		opts = append(opts, reminder.WithNotifier(domain.ReminderEmail, reminder.NewSMTPNotifier(reminder.SMTPSettings{
+			Addr:     cfg.SMTPAddr,
+			From:     cfg.SMTPFrom,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			Domain:   cfg.SMTPDomain,
+		})))
+	}
+
+	if (cfg.DefaultMethod == domain.ReminderWebhook && cfg.WebhookURL == "") ||
+		(cfg.DefaultMethod == domain.ReminderEmail && cfg.SMTPAddr == "") {
+		logger.Warn("Default reminder method is not configured, such reminders will fail",
+			zappretty.Field("method", cfg.DefaultMethod),
+		)
+	}
+	return opts
+}
+
+// newTracer создает трассировщик с выбранным экспортером или nil, если трассировка отключена
+func (a *App) newTracer(cfg config.TracingConfig) *tracing.Tracer {
+	switch cfg.Exporter {
+	case config.TracingExporterNone:
+		return nil
+	case config.TracingExporterStdout:
+		return tracing.NewTracer(cfg.ServiceName, tracing.NewJSONExporter(os.Stdout))
+	case config.TracingExporterFile:
+		exporter, err := tracing.NewFileExporter(cfg.File)
+		if err != nil {
+			a.logger.Fatal("Failed to open trace file",
+				zappretty.Field("path", cfg.File),
+				zappretty.Field("error", err),
+			)
+		}
+		a.closers = append(a.closers, exporter)
+		return tracing.NewTracer(cfg.ServiceName, exporter)
+	}
+	return nil
+}
+
+// close освобождает ресурсы приложения
+func (a *App) close() {
+	for _, c := range a.closers {
+		if err := c.Close(); err != nil {
+			a.logger.Warn("Failed to close resource", zappretty.Field("error", err))
+		}
+	}
+}
+
 // handleSignals обрабатывает сигналы OS для graceful shutdown
 func (a *App) handleSignals(cancel context.CancelFunc) {
 	sigChan := make(chan os.Signal, 1)
@@ -100,3 +525,11 @@ func (a *App) handleSignals(cancel context.CancelFunc) {
 	a.logger.Info("Received signal", zappretty.Field("signal", sig))
 	cancel()
 }
+
+// newDigestSettings - параметры планировщика сводок; остальные значения по умолчанию
+func newDigestSettings(cfg config.DigestConfig) digest.Settings {
+	settings := digest.DefaultSettings()
+	settings.CheckInterval = cfg.CheckInterval
+	settings.CatchUp = cfg.CatchUp
+	return settings
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"calendar-server/pkg/errors"
+)
+
+// Области доступа токенов
+const (
+	// ScopeEventsRead - чтение собственного календаря
+	ScopeEventsRead = "events:read"
+	// ScopeEventsWrite - изменение собственного календаря
+	ScopeEventsWrite = "events:write"
+	// ScopeKeysManage - управление API-ключами
+	ScopeKeysManage = "keys:manage"
+	// ScopeWebhooksManage - управление подписками вебхуков
+	ScopeWebhooksManage = "webhooks:manage"
+)
+
+// RoleAdmin - роль оператора с доступом к /admin
+const RoleAdmin = "admin"
+
+// AnyTenant - значение арендатора токена (claim tenant в JWT, tenant= в файле токенов),
+// разрешающее выбирать арендатора заголовком
+const AnyTenant = "*"
+
+// KnownScopes - все поддерживаемые области доступа
+var KnownScopes = []string{ScopeEventsRead, ScopeEventsWrite, ScopeKeysManage, ScopeWebhooksManage}
+
+// Identity - аутентифицированный вызывающий
+type Identity struct {
+	UserID string
+	// Method - способ аутентификации (jwt, token, api_key)
+	Method string
+	// KeyID - ID API-ключа, если вызов выполнен по ключу
+	KeyID string
+	// Scopes - разрешенные области; nil означает полный доступ владельца
+	Scopes []string
+	// Role - роль пользователя (пусто для обычных пользователей)
+	Role string
+	// TenantID - арендатор, к которому привязан токен
+	TenantID string
+	// MultiTenant - токен действует во всех арендаторах, арендатор выбирается заголовком
+	MultiTenant bool
+}
+
+// bindTenant - привязка личности к арендатору из токена; AnyTenant делает ее многоарендной
+func (i *Identity) bindTenant(tenantID string) {
+	if tenantID == AnyTenant {
+		i.MultiTenant = true
+		return
+	}
+	i.TenantID = tenantID
+}
+
+// IsAdmin - имеет ли личность роль администратора
+func (i Identity) IsAdmin() bool {
+	return i.Role == RoleAdmin
+}
+
+// HasScope - разрешена ли личности указанная область доступа
+func (i Identity) HasScope(scope string) bool {
+	if i.Scopes == nil {
+		return true
+	}
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKnownScope - поддерживается ли область доступа
+func IsKnownScope(scope string) bool {
+	for _, s := range KnownScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator - контракт проверки предъявленного токена
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (Identity, error)
+}
+
+type identityKey struct{}
+
+// WithIdentity - кладет личность вызывающего в контекст
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext - достает личность вызывающего из контекста
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// Chain - перебирает аутентификаторы по порядку до первого успешного
+type Chain []Authenticator
+
+// Authenticate - реализация Authenticator
+func (c Chain) Authenticate(ctx context.Context, token string) (Identity, error) {
+	for _, a := range c {
+		identity, err := a.Authenticate(ctx, token)
+		if err == nil {
+			return identity, nil
+		}
+	}
+	return Identity{}, errors.ErrInvalidToken
+}
+
+// BearerToken - извлекает токен из заголовка Authorization
+func BearerToken(header string) (string, bool) {
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(header[len(prefix):])
+	return token, token != ""
+}
+
+// BasicToken - извлекает токен из пароля заголовка Authorization: Basic.
+// Имя пользователя не учитывается: так токен можно передать клиентам, поддерживающим только Basic,
+// например при подписке на календарь.
+func BasicToken(header string) (string, bool) {
+	const prefix = "Basic "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(header[len(prefix):]))
+	if err != nil {
+		return "", false
+	}
+	_, token, ok := strings.Cut(string(decoded), ":")
+	return token, ok && token != ""
+}
+
+// ProtocolTokenPrefix - префикс подпротокола WebSocket, в котором передается токен
+const ProtocolTokenPrefix = "bearer."
+
+// ProtocolToken - извлекает токен из подпротокола "bearer.<token>" в значениях заголовка
+// Sec-WebSocket-Protocol. Браузерный WebSocket не позволяет задать Authorization, но передает
+// список подпротоколов; сервер не возвращает этот подпротокол в ответе.
+func ProtocolToken(values []string) (string, bool) {
+	for _, value := range values {
+		for _, protocol := range strings.Split(value, ",") {
+			token, ok := strings.CutPrefix(strings.TrimSpace(protocol), ProtocolTokenPrefix)
+			if ok && token != "" {
+				return token, true
+			}
+		}
+	}
+	return "", false
+}
+
+// UserIDOr - ID пользователя из личности; requested используется, только если явно указан
+func (i Identity) UserIDOr(requested string) string {
+	if requested != "" {
+		return requested
+	}
+	return i.UserID
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"calendar-server/pkg/errors"
+	"context"
+	"encoding/base64"
+	stdErrors "errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWTAuthenticator(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	a := NewJWTAuthenticator([]byte("secret"), "calendar")
+	a.now = func() time.Time { return now }
+
+	valid, err := a.Sign(Claims{Subject: "user-1", Issuer: "calendar", ExpiresAt: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	expired, _ := a.Sign(Claims{Subject: "user-1", Issuer: "calendar", ExpiresAt: now.Add(-time.Hour).Unix()})
+	wrongIssuer, _ := a.Sign(Claims{Subject: "user-1", Issuer: "other"})
+	noSubject, _ := a.Sign(Claims{Issuer: "calendar"})
+	foreign, _ := NewJWTAuthenticator([]byte("other-secret"), "calendar").Sign(Claims{Subject: "user-1", Issuer: "calendar"})
+
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + strings.TrimRight(parts[1], "=") + "x." + parts[2]
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid token", token: valid},
+		{name: "expired token", token: expired, wantErr: true},
+		{name: "wrong issuer", token: wrongIssuer, wantErr: true},
+		{name: "missing subject", token: noSubject, wantErr: true},
+		{name: "foreign signature", token: foreign, wantErr: true},
+		{name: "tampered payload", token: tampered, wantErr: true},
+		{name: "garbage", token: "not-a-jwt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := a.Authenticate(context.Background(), tt.token)
+			if tt.wantErr {
+				if !stdErrors.Is(err, errors.ErrInvalidToken) {
+					t.Errorf("Expected ErrInvalidToken, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if identity.UserID != "user-1" {
+				t.Errorf("Expected user-1, got %q", identity.UserID)
+			}
+		})
+	}
+}
+
+func TestStaticTokenAuthenticator(t *testing.T) {
+	a, err := ParseStaticTokens(strings.NewReader("# ci bots\n\ntok-1 user-1\ntok-2 user-2\ntok-3 ops admin\ntok-4 user-1 tenant=dept-a\ntok-6 ops admin tenant=*\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse tokens: %v", err)
+	}
+	if a.Len() != 5 {
+		t.Errorf("Expected 5 tokens, got %d", a.Len())
+	}
+
+	identity, err := a.Authenticate(context.Background(), "tok-2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if identity.UserID != "user-2" || identity.Method != "token" {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+
+	admin, err := a.Authenticate(context.Background(), "tok-3")
+	if err != nil || !admin.IsAdmin() {
+		t.Errorf("Expected admin identity, got %+v, %v", admin, err)
+	}
+
+	bound, err := a.Authenticate(context.Background(), "tok-4")
+	if err != nil || bound.TenantID != "dept-a" || bound.Role != "" {
+		t.Errorf("Expected identity bound to dept-a, got %+v, %v", bound, err)
+	}
+
+	multi, err := a.Authenticate(context.Background(), "tok-6")
+	if err != nil || !multi.MultiTenant || multi.TenantID != "" || !multi.IsAdmin() {
+		t.Errorf("Expected multi-tenant admin identity, got %+v, %v", multi, err)
+	}
+
+	if _, err := a.Authenticate(context.Background(), "tok-5"); !stdErrors.Is(err, errors.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken, got %v", err)
+	}
+
+	if _, err := ParseStaticTokens(strings.NewReader("lonely-token\n")); err == nil {
+		t.Error("Expected error for line without user_id")
+	}
+	if _, err := ParseStaticTokens(strings.NewReader("tok user-1 admin color=red\n")); err == nil {
+		t.Error("Expected error for unknown attribute")
+	}
+}
+
+func TestChain(t *testing.T) {
+	tokens := NewStaticTokenAuthenticator()
+	tokens.Add("tok-1", Identity{UserID: "user-1"})
+	chain := Chain{NewJWTAuthenticator([]byte("secret"), ""), tokens}
+
+	identity, err := chain.Authenticate(context.Background(), "tok-1")
+	if err != nil || identity.UserID != "user-1" {
+		t.Errorf("Expected user-1 via token fallback, got %+v, %v", identity, err)
+	}
+
+	if _, err := (Chain{}).Authenticate(context.Background(), "tok-1"); !stdErrors.Is(err, errors.ErrInvalidToken) {
+		t.Errorf("Expected empty chain to reject, got %v", err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	if token, ok := BearerToken("bearer abc"); !ok || token != "abc" {
+		t.Errorf("Expected abc, got %q", token)
+	}
+	if _, ok := BearerToken("Basic abc"); ok {
+		t.Error("Expected Basic scheme to be rejected")
+	}
+	if _, ok := BearerToken("Bearer "); ok {
+		t.Error("Expected empty token to be rejected")
+	}
+}
+
+func TestProtocolToken(t *testing.T) {
+	if token, ok := ProtocolToken([]string{"calendar.v1, bearer.abc.def"}); !ok || token != "abc.def" {
+		t.Errorf("Expected abc.def, got %q", token)
+	}
+	if token, ok := ProtocolToken([]string{"calendar.v1", "bearer.xyz"}); !ok || token != "xyz" {
+		t.Errorf("Expected xyz from second header value, got %q", token)
+	}
+	if _, ok := ProtocolToken([]string{"calendar.v1, bearer."}); ok {
+		t.Error("Expected empty token to be rejected")
+	}
+	if _, ok := ProtocolToken(nil); ok {
+		t.Error("Expected missing header to be rejected")
+	}
+}
+
+func TestBasicToken(t *testing.T) {
+	header := "Basic " + base64.StdEncoding.EncodeToString([]byte("anyone:secret-token"))
+	if token, ok := BasicToken(header); !ok || token != "secret-token" {
+		t.Errorf("Expected secret-token, got %q", token)
+	}
+	if _, ok := BasicToken("Bearer abc"); ok {
+		t.Error("Expected Bearer scheme to be rejected")
+	}
+	if _, ok := BasicToken("Basic " + base64.StdEncoding.EncodeToString([]byte("user:"))); ok {
+		t.Error("Expected empty password to be rejected")
+	}
+	if _, ok := BasicToken("Basic !!!"); ok {
+		t.Error("Expected malformed base64 to be rejected")
+	}
+}
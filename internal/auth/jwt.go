@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"calendar-server/pkg/errors"
+)
+
+// Claims - поддерживаемые поля полезной нагрузки JWT
+type Claims struct {
+	Subject   string `json:"sub"`
+	Issuer    string `json:"iss,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Tenant    string `json:"tenant,omitempty"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// JWTAuthenticator - проверка JWT, подписанных HMAC-SHA256
+type JWTAuthenticator struct {
+	secret []byte
+	issuer string
+	leeway time.Duration
+	now    func() time.Time
+}
+
+// NewJWTAuthenticator - конструктор JWTAuthenticator; пустой issuer отключает проверку iss
+func NewJWTAuthenticator(secret []byte, issuer string) *JWTAuthenticator {
+	return &JWTAuthenticator{
+		secret: secret,
+		issuer: issuer,
+		leeway: 30 * time.Second,
+		now:    time.Now,
+	}
+}
+
+// Authenticate - реализация Authenticator
+func (a *JWTAuthenticator) Authenticate(_ context.Context, token string) (Identity, error) {
+	claims, err := a.Parse(token)
+	if err != nil {
+		return Identity{}, err
+	}
+	identity := Identity{
+		UserID: claims.Subject,
+		Method: "jwt",
+		Role:   claims.Role,
+	}
+	identity.bindTenant(claims.Tenant)
+	return identity, nil
+}
+
+// Parse - проверяет подпись и сроки действия токена и возвращает его claims
+func (a *JWTAuthenticator) Parse(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, errors.ErrInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return Claims{}, errors.ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, a.sign(parts[0]+"."+parts[1])) {
+		return Claims{}, errors.ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, errors.ErrInvalidToken
+	}
+
+	now := a.now()
+	if claims.ExpiresAt != 0 && now.After(time.Unix(claims.ExpiresAt, 0).Add(a.leeway)) {
+		return Claims{}, errors.ErrInvalidToken
+	}
+	if claims.NotBefore != 0 && now.Add(a.leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return Claims{}, errors.ErrInvalidToken
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return Claims{}, errors.ErrInvalidToken
+	}
+	if claims.Subject == "" {
+		return Claims{}, errors.ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// Sign - выпускает токен с указанными claims (используется в тестах и утилитах)
+func (a *JWTAuthenticator) Sign(claims Claims) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(a.sign(unsigned)), nil
+}
+
+// sign - вычисляет HMAC-SHA256 подпись
+func (a *JWTAuthenticator) sign(data string) []byte {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// decodeSegment - декодирует base64url JSON сегмент токена
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"calendar-server/pkg/errors"
+)
+
+// StaticTokenAuthenticator - проверка непрозрачных токенов из локального файла
+type StaticTokenAuthenticator struct {
+	// tokens - sha256(token) -> личность; сами токены в памяти не храним
+	tokens map[[sha256.Size]byte]Identity
+}
+
+// NewStaticTokenAuthenticator - конструктор StaticTokenAuthenticator
+func NewStaticTokenAuthenticator() *StaticTokenAuthenticator {
+	return &StaticTokenAuthenticator{
+		tokens: make(map[[sha256.Size]byte]Identity),
+	}
+}
+
+// LoadStaticTokens - загружает токены из файла формата "<token> <user_id> [role] [key=value...]"
+// по одному на строку. Поддерживаемые ключи: role, tenant.
+func LoadStaticTokens(path string) (*StaticTokenAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseStaticTokens(f)
+}
+
+// ParseStaticTokens - разбирает список токенов; пустые строки и строки с # пропускаются
+func ParseStaticTokens(r io.Reader) (*StaticTokenAuthenticator, error) {
+	a := NewStaticTokenAuthenticator()
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("tokens file line %d: expected \"<token> <user_id> [role] [key=value...]\"", lineNum)
+		}
+
+		identity := Identity{UserID: fields[1]}
+		for i, field := range fields[2:] {
+			key, value, isPair := strings.Cut(field, "=")
+			switch {
+			case !isPair && i == 0:
+				identity.Role = field
+			case isPair && key == "role":
+				identity.Role = value
+			case isPair && key == "tenant":
+				identity.bindTenant(value)
+			default:
+				return nil, fmt.Errorf("tokens file line %d: unknown attribute %q", lineNum, field)
+			}
+		}
+		a.Add(fields[0], identity)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Add - регистрирует токен
+func (a *StaticTokenAuthenticator) Add(token string, identity Identity) {
+	identity.Method = "token"
+	a.tokens[sha256.Sum256([]byte(token))] = identity
+}
+
+// Len - количество зарегистрированных токенов
+func (a *StaticTokenAuthenticator) Len() int {
+	return len(a.tokens)
+}
+
+// Authenticate - реализация Authenticator
+func (a *StaticTokenAuthenticator) Authenticate(_ context.Context, token string) (Identity, error) {
+	// Поиск идет по хешу, поэтому время ответа не зависит от совпадения префикса токена
+	identity, ok := a.tokens[sha256.Sum256([]byte(token))]
+	if !ok {
+		return Identity{}, errors.ErrInvalidToken
+	}
+	return identity, nil
+}
@@ -1,14 +1,235 @@
 package config
 
 import (
+	"encoding/json"
 	"flag"
+	"fmt"
+	"net/netip"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
 )
 
 // Config представляет конфигурацию приложения
 type Config struct {
 	Port        string
 	Environment string
+	ConfigFile  string
+	Auth        AuthConfig
+	Tenancy     TenancyConfig
+	RateLimit   RateLimitConfig
+	CORS        CORSConfig
+	Metrics     MetricsConfig
+	Shutdown    ShutdownConfig
+	Tracing     TracingConfig
+	Errors      ErrorsConfig
+	Calendar    CalendarConfig
+	Stream      StreamConfig
+	WebSocket   WebSocketConfig
+	Webhook     WebhookConfig
+	Outbox      OutboxConfig
+	Reminder    ReminderConfig
+	Digest      DigestConfig
+}
+
+// AuthConfig - настройки аутентификации
+type AuthConfig struct {
+	// JWTSecret - ключ HMAC для проверки JWT (HS256); пустой отключает JWT
+	JWTSecret string
+	// JWTIssuer - ожидаемый iss; пустой отключает проверку
+	JWTIssuer string
+	// TokensFile - файл с непрозрачными токенами "<token> <user_id> [role] [tenant=<id|*>]"
+	TokensFile string
+}
+
+// TenancyConfig - настройки изоляции арендаторов
+type TenancyConfig struct {
+	// Header - заголовок с арендатором для токенов, не привязанных к арендатору
+	Header string
+	// Tenants - настройки и квоты арендаторов; пустой список разрешает любого арендатора
+	Tenants map[string]tenant.Settings
+	// DefaultQuota - квота для всех арендаторов; лимиты из настроек арендатора имеют приоритет
+	DefaultQuota tenant.Quota
+	// DefaultValidation - правила проверки событий для всех арендаторов; заданные у арендатора имеют приоритет
+	DefaultValidation tenant.ValidationRules
+}
+
+// RateLimitConfig - настройки ограничения частоты запросов
+type RateLimitConfig struct {
+	Enabled bool
+	// ReadRate, ReadBurst - бюджет GET-запросов: запросов в секунду и размер всплеска
+	ReadRate  float64
+	ReadBurst int
+	// WriteRate, WriteBurst - бюджет изменяющих запросов
+	WriteRate  float64
+	WriteBurst int
+	// AuthFailureRate, AuthFailureBurst - бюджет запросов без токена или с неверным токеном с одного адреса
+	AuthFailureRate  float64
+	AuthFailureBurst int
+	// IdleTTL - время простоя, после которого корзина клиента удаляется
+	IdleTTL time.Duration
+}
+
+// CORSConfig - политика CORS
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// MetricsConfig - настройки метрик Prometheus
+type MetricsConfig struct {
+	// Enabled - сбор метрик и эндпоинт /metrics
+	Enabled bool
+}
+
+// ShutdownConfig - настройки остановки сервера
+type ShutdownConfig struct {
+	// DrainDelay - сколько /readyz сообщает о завершении перед закрытием соединений
+	DrainDelay time.Duration
+	// Timeout - время на завершение активных запросов
+	Timeout time.Duration
+}
+
+// Экспортеры трассировки
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterFile   = "file"
+)
+
+// TracingConfig - настройки трассировки
+type TracingConfig struct {
+	// Exporter - none (трассировка отключена), stdout или file
+	Exporter string
+	// File - файл JSON Lines для экспортера file
+	File string
+	// ServiceName - имя сервиса в спанах
+	ServiceName string
+}
+
+// ErrorsConfig - формат ответов с ошибками
+type ErrorsConfig struct {
+	// Format - problem (RFC 7807) или legacy ({"error": "..."})
+	Format string
+	// DocsURL - страница с описанием кодов ошибок для поля type
+	DocsURL string
+	// Language - язык сообщений, если клиент не запросил поддерживаемый
+	Language string
+	// CatalogDir - каталог с дополнительными файлами сообщений <язык>.json
+	CatalogDir string
+}
+
+// CalendarConfig - выгрузка событий за период
+type CalendarConfig struct {
+	// PastDays, FutureDays - период выгрузки iCalendar по умолчанию относительно текущей даты
+	PastDays   int
+	FutureDays int
+	// MaxRangeDays - максимальная длина запрашиваемого периода
+	MaxRangeDays int
+	// ImportMaxBytes - максимальный размер импортируемого файла
+	ImportMaxBytes int
+	// ImportMaxEvents - максимальное количество событий в одном импорте
+	ImportMaxEvents int
+	// ImportMaxOccurrences - максимальное количество повторений одного события при импорте
+	ImportMaxOccurrences int
+	// ImportTimeZone - часовой пояс, в который переводится время импортируемых событий
+	ImportTimeZone string
+}
+
+// StreamConfig - лента изменений событий (Server-Sent Events)
+type StreamConfig struct {
+	// Buffer - изменения в очереди одного подписчика; переполнивший очередь подписчик отключается
+	Buffer int
+	// History - последние изменения, доступные для продолжения по Last-Event-ID
+	History int
+	// Heartbeat - интервал пингов, поддерживающих соединение через прокси
+	Heartbeat time.Duration
+}
+
+// WebSocketConfig - API WebSocket
+type WebSocketConfig struct {
+	// PingInterval - интервал ping; соединение без кадров от клиента в течение двух интервалов закрывается
+	PingInterval time.Duration
+	// SendBuffer - сообщения в очереди отправки одного соединения; переполнивший очередь клиент отключается
+	SendBuffer int
+	// MaxSubscriptions - максимальное количество пользователей, на изменения которых подписано соединение
+	MaxSubscriptions int
+}
+
+// WebhookConfig - доставка вебхуков
+type WebhookConfig struct {
+	// Workers - количество одновременных запросов доставки
+	Workers int
+	// MaxAttempts - попытки до переноса доставки в список недоставленных
+	MaxAttempts int
+	// Backoff - задержка перед первым повтором; каждая следующая вдвое больше
+	Backoff time.Duration
+	// MaxBackoff - верхняя граница задержки между попытками
+	MaxBackoff time.Duration
+	// Timeout - время на один запрос доставки
+	Timeout time.Duration
+	// AllowedNetworks - сети, доставка в которые разрешена, несмотря на запрет локальных и частных адресов
+	AllowedNetworks []netip.Prefix
+}
+
+// OutboxConfig - отправка доменных событий из outbox хранилища
+type OutboxConfig struct {
+	// BatchSize - сообщения, читаемые и подтверждаемые за одну операцию хранилища
+	BatchSize int
+	// PollInterval - интервал проверки outbox и задержка повтора после ошибок хранилища
+	PollInterval time.Duration
+}
+
+// ReminderConfig - планировщик напоминаний и способы их доставки
+type ReminderConfig struct {
+	// TimeZone - часовой пояс, в котором задано время событий
+	TimeZone string
+	// DefaultMethod - способ доставки напоминаний, у которых он не указан
+	DefaultMethod string
+	// ResyncInterval - интервал полного пересчета напоминаний по хранилищу
+	ResyncInterval time.Duration
+	// CatchUp - напоминания, опоздавшие не больше этого (перезапуск, сдвиг часов), отправляются; остальные пропускаются
+	CatchUp time.Duration
+	// MaxAttempts, RetryDelay - попытки доставки одного напоминания и задержка между ними
+	MaxAttempts int
+	RetryDelay  time.Duration
+	// WebhookURL, WebhookSecret - адрес и ключ подписи HMAC для способа webhook; пустой адрес отключает способ
+	WebhookURL    string
+	WebhookSecret string
+	// SMTPAddr - сервер SMTP для способа email; пустой адрес отключает способ
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
+	// SMTPDomain - домен адреса получателя, если ID пользователя не является адресом
+	SMTPDomain string
+}
+
+// DigestConfig - сводки событий на день и неделю
+type DigestConfig struct {
+	// TimeZone, SendAt - часовой пояс и местное время отправки (HH:MM) для подписок, в которых они не указаны
+	TimeZone string
+	SendAt   string
+	// CheckInterval - интервал проверки подписок
+	CheckInterval time.Duration
+	// CatchUp - сводки, опоздавшие больше этого (например, из-за остановки сервера), пропускаются
+	CatchUp time.Duration
+}
+
+// fileConfig - структурированные настройки, которые задаются файлом конфигурации
+type fileConfig struct {
+	Quota      tenant.Quota               `json:"quota"`
+	Validation tenant.ValidationRules     `json:"validation"`
+	Tenants    map[string]tenant.Settings `json:"tenants"`
 }
 
 // MustLoad загружает конфигурацию из переменных окружения и флагов
@@ -17,6 +238,80 @@ func MustLoad() *Config {
 
 	flag.StringVar(&cfg.Port, "port", "8888", "Port to run the server on")
 	flag.StringVar(&cfg.Environment, "env", "development", "Application environment (development/production)")
+	flag.StringVar(&cfg.ConfigFile, "config", "", "Path to JSON config file with tenant settings")
+	flag.StringVar(&cfg.Auth.JWTSecret, "jwt-secret", "", "HMAC secret for HS256 bearer tokens")
+	flag.StringVar(&cfg.Auth.JWTIssuer, "jwt-issuer", "", "Expected JWT issuer (iss claim)")
+	flag.StringVar(&cfg.Auth.TokensFile, "auth-tokens-file", "", "Path to file with opaque API tokens")
+	flag.StringVar(&cfg.Tenancy.Header, "tenant-header", "X-Tenant-ID", "Header with tenant ID for multi-tenant tokens")
+	flag.BoolVar(&cfg.RateLimit.Enabled, "rate-limit", true, "Enable per-client rate limiting")
+	flag.Float64Var(&cfg.RateLimit.ReadRate, "rate-read-rps", 10, "Read requests per second per client")
+	flag.IntVar(&cfg.RateLimit.ReadBurst, "rate-read-burst", 30, "Read request burst per client")
+	flag.Float64Var(&cfg.RateLimit.WriteRate, "rate-write-rps", 2, "Write requests per second per client")
+	flag.IntVar(&cfg.RateLimit.WriteBurst, "rate-write-burst", 10, "Write request burst per client")
+	flag.Float64Var(&cfg.RateLimit.AuthFailureRate, "rate-auth-failure-rps", 0.1, "Failed authentications per second per remote address")
+	flag.IntVar(&cfg.RateLimit.AuthFailureBurst, "rate-auth-failure-burst", 10, "Failed authentication burst per remote address")
+	flag.DurationVar(&cfg.RateLimit.IdleTTL, "rate-idle-ttl", 10*time.Minute, "Evict rate limit buckets idle for this long")
+	corsOrigins := flag.String("cors-allowed-origins", "*", "Comma-separated allowed origins, supports https://*.example.com")
+	corsMethods := flag.String("cors-allowed-methods", "GET,POST,PUT,DELETE,OPTIONS,PROPFIND,REPORT", "Comma-separated allowed methods")
+	corsHeaders := flag.String("cors-allowed-headers", "Content-Type,Authorization,X-Tenant-ID,X-Request-ID,traceparent,If-Match,If-None-Match,Depth,Last-Event-ID", "Comma-separated allowed request headers")
+	corsExposed := flag.String("cors-exposed-headers", "ETag,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After,X-Request-ID", "Comma-separated headers exposed to browsers")
+	flag.BoolVar(&cfg.CORS.AllowCredentials, "cors-allow-credentials", false, "Allow credentials in cross-origin requests")
+	flag.DurationVar(&cfg.CORS.MaxAge, "cors-max-age", 10*time.Minute, "How long browsers may cache preflight responses")
+	flag.BoolVar(&cfg.Metrics.Enabled, "metrics", true, "Expose Prometheus metrics on /metrics")
+	flag.DurationVar(&cfg.Shutdown.DrainDelay, "shutdown-drain-delay", 5*time.Second, "Report not-ready for this long before closing connections")
+	flag.DurationVar(&cfg.Shutdown.Timeout, "shutdown-timeout", 10*time.Second, "Time allowed for in-flight requests on shutdown")
+	flag.StringVar(&cfg.Tracing.Exporter, "tracing-exporter", TracingExporterNone, "Trace exporter: none, stdout or file")
+	flag.StringVar(&cfg.Tracing.File, "tracing-file", "traces.jsonl", "File for the file trace exporter")
+	flag.StringVar(&cfg.Tracing.ServiceName, "tracing-service-name", "calendar-server", "Service name recorded in spans")
+	flag.StringVar(&cfg.Errors.Format, "error-format", "problem", "Error response format: problem (RFC 7807) or legacy")
+	flag.StringVar(&cfg.Errors.DocsURL, "errors-docs-url", "https://github.com/sj-shoff/calendar-server/blob/main/docs/errors.md", "Documentation page for error codes")
+	flag.StringVar(&cfg.Errors.Language, "errors-language", "en", "Default language of error messages")
+	flag.StringVar(&cfg.Errors.CatalogDir, "errors-catalog-dir", "", "Directory with additional <lang>.json error message catalogues")
+	flag.IntVar(&cfg.Calendar.PastDays, "ical-past-days", 90, "Days before today included in calendar.ics by default")
+	flag.IntVar(&cfg.Calendar.FutureDays, "ical-future-days", 365, "Days after today included in calendar.ics by default")
+	flag.IntVar(&cfg.Calendar.MaxRangeDays, "max-range-days", 731, "Maximum length of a requested date range in days")
+	flag.IntVar(&cfg.Calendar.ImportMaxBytes, "ical-import-max-bytes", 5<<20, "Maximum size of an imported iCalendar file in bytes")
+	flag.IntVar(&cfg.Calendar.ImportMaxEvents, "ical-import-max-events", 10000, "Maximum events in one import (0 - unlimited)")
+	flag.IntVar(&cfg.Calendar.ImportMaxOccurrences, "ical-import-max-occurrences", 500, "Maximum imported occurrences of one recurring event")
+	flag.StringVar(&cfg.Calendar.ImportTimeZone, "ical-import-timezone", "UTC", "Time zone for imported events with TZID or UTC times")
+	flag.IntVar(&cfg.Tenancy.DefaultQuota.MaxEvents, "max-events-per-tenant", 0, "Maximum events per tenant (0 - unlimited)")
+	flag.IntVar(&cfg.Tenancy.DefaultQuota.MaxEventsPerUser, "max-events-per-user", 0, "Maximum events per user (0 - unlimited)")
+	flag.IntVar(&cfg.Tenancy.DefaultQuota.MaxEventsPerDay, "max-events-per-day", 0, "Maximum events per user on one date (0 - unlimited)")
+	flag.IntVar(&cfg.Tenancy.DefaultQuota.MaxTitleLength, "max-title-length", 0, "Maximum event title length in characters (0 - unlimited)")
+	flag.IntVar(&cfg.Tenancy.DefaultValidation.MinYear, "validation-min-year", 0, "Earliest allowed event year (0 - default 1900)")
+	flag.IntVar(&cfg.Tenancy.DefaultValidation.MaxYear, "validation-max-year", 0, "Latest allowed event year (0 - default 2100)")
+	flag.IntVar(&cfg.Stream.Buffer, "stream-buffer", 64, "Changes queued per change feed subscriber")
+	flag.IntVar(&cfg.Stream.History, "stream-history", 1024, "Recent changes kept for Last-Event-ID resume")
+	flag.DurationVar(&cfg.Stream.Heartbeat, "stream-heartbeat", 15*time.Second, "Interval of keep-alive comments in the change feed")
+	flag.DurationVar(&cfg.WebSocket.PingInterval, "websocket-ping-interval", 30*time.Second, "Interval of WebSocket pings")
+	flag.IntVar(&cfg.WebSocket.SendBuffer, "websocket-send-buffer", 256, "Messages queued per WebSocket connection")
+	flag.IntVar(&cfg.WebSocket.MaxSubscriptions, "websocket-max-subscriptions", 50, "Maximum users one WebSocket connection can subscribe to")
+	flag.IntVar(&cfg.Webhook.Workers, "webhook-workers", 4, "Concurrent webhook delivery requests")
+	flag.IntVar(&cfg.Webhook.MaxAttempts, "webhook-max-attempts", 8, "Webhook delivery attempts before dead-lettering")
+	flag.DurationVar(&cfg.Webhook.Backoff, "webhook-backoff", time.Second, "Delay before the first webhook retry, doubled on each attempt")
+	flag.DurationVar(&cfg.Webhook.MaxBackoff, "webhook-max-backoff", time.Hour, "Maximum delay between webhook attempts")
+	flag.DurationVar(&cfg.Webhook.Timeout, "webhook-timeout", 10*time.Second, "Timeout of one webhook delivery request")
+	webhookNetworks := flag.String("webhook-allowed-networks", "", "Comma-separated CIDRs or IPs webhooks may target despite the private address ban")
+	flag.IntVar(&cfg.Outbox.BatchSize, "outbox-batch-size", 100, "Outbox messages relayed per repository operation")
+	flag.DurationVar(&cfg.Outbox.PollInterval, "outbox-poll-interval", time.Second, "Interval of outbox checks and retries after repository errors")
+	flag.StringVar(&cfg.Reminder.TimeZone, "reminder-timezone", "UTC", "Time zone of event times used to schedule reminders")
+	flag.StringVar(&cfg.Reminder.DefaultMethod, "reminder-default-method", domain.ReminderLog, "Reminder method when an event reminder has none: log, webhook or email")
+	flag.DurationVar(&cfg.Reminder.ResyncInterval, "reminder-resync-interval", 10*time.Minute, "Interval of full reminder rescans of the event store")
+	flag.DurationVar(&cfg.Reminder.CatchUp, "reminder-catch-up", time.Hour, "Send reminders late by at most this after restarts or clock jumps")
+	flag.IntVar(&cfg.Reminder.MaxAttempts, "reminder-max-attempts", 3, "Delivery attempts of one reminder")
+	flag.DurationVar(&cfg.Reminder.RetryDelay, "reminder-retry-delay", time.Minute, "Delay between reminder delivery attempts")
+	flag.StringVar(&cfg.Reminder.WebhookURL, "reminder-webhook-url", "", "URL receiving signed reminder notifications")
+	flag.StringVar(&cfg.Reminder.WebhookSecret, "reminder-webhook-secret", "", "HMAC secret signing reminder notifications")
+	flag.StringVar(&cfg.Reminder.SMTPAddr, "reminder-smtp-addr", "", "SMTP server host:port for email reminders")
+	flag.StringVar(&cfg.Reminder.SMTPFrom, "reminder-smtp-from", "calendar@localhost", "Sender address of email reminders")
+	flag.StringVar(&cfg.Reminder.SMTPUsername, "reminder-smtp-username", "", "SMTP username; empty disables authentication")
+	flag.StringVar(&cfg.Reminder.SMTPPassword, "reminder-smtp-password", "", "SMTP password")
+	flag.StringVar(&cfg.Reminder.SMTPDomain, "reminder-smtp-domain", "", "Recipient domain for user IDs that are not email addresses")
+	flag.StringVar(&cfg.Digest.TimeZone, "digest-timezone", "UTC", "Time zone of digest subscriptions that do not set one")
+	flag.StringVar(&cfg.Digest.SendAt, "digest-send-at", "07:00", "Local send time (HH:MM) of digest subscriptions that do not set one")
+	flag.DurationVar(&cfg.Digest.CheckInterval, "digest-check-interval", time.Minute, "Interval of digest subscription checks")
+	flag.DurationVar(&cfg.Digest.CatchUp, "digest-catch-up", 3*time.Hour, "Send digests late by at most this after restarts or clock jumps")
+	requiredFields := flag.String("validation-required-fields", "", "Comma-separated event fields required in addition to id, user_id, date and title")
 
 	if port := os.Getenv("PORT"); port != "" {
 		cfg.Port = port
@@ -24,7 +319,306 @@ func MustLoad() *Config {
 	if env := os.Getenv("ENVIRONMENT"); env != "" {
 		cfg.Environment = env
 	}
+	if configFile := os.Getenv("CONFIG_FILE"); configFile != "" {
+		cfg.ConfigFile = configFile
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		cfg.Auth.JWTSecret = secret
+	}
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		cfg.Auth.JWTIssuer = issuer
+	}
+	if tokensFile := os.Getenv("AUTH_TOKENS_FILE"); tokensFile != "" {
+		cfg.Auth.TokensFile = tokensFile
+	}
+	if header := os.Getenv("TENANT_HEADER"); header != "" {
+		cfg.Tenancy.Header = header
+	}
+	boolFromEnv("RATE_LIMIT_ENABLED", &cfg.RateLimit.Enabled)
+	floatFromEnv("RATE_LIMIT_READ_RPS", &cfg.RateLimit.ReadRate)
+	intFromEnv("RATE_LIMIT_READ_BURST", &cfg.RateLimit.ReadBurst)
+	floatFromEnv("RATE_LIMIT_WRITE_RPS", &cfg.RateLimit.WriteRate)
+	intFromEnv("RATE_LIMIT_WRITE_BURST", &cfg.RateLimit.WriteBurst)
+	floatFromEnv("RATE_LIMIT_AUTH_FAILURE_RPS", &cfg.RateLimit.AuthFailureRate)
+	intFromEnv("RATE_LIMIT_AUTH_FAILURE_BURST", &cfg.RateLimit.AuthFailureBurst)
+	durationFromEnv("RATE_LIMIT_IDLE_TTL", &cfg.RateLimit.IdleTTL)
+	stringFromEnv("CORS_ALLOWED_ORIGINS", corsOrigins)
+	stringFromEnv("CORS_ALLOWED_METHODS", corsMethods)
+	stringFromEnv("CORS_ALLOWED_HEADERS", corsHeaders)
+	stringFromEnv("CORS_EXPOSED_HEADERS", corsExposed)
+	boolFromEnv("CORS_ALLOW_CREDENTIALS", &cfg.CORS.AllowCredentials)
+	durationFromEnv("CORS_MAX_AGE", &cfg.CORS.MaxAge)
+	boolFromEnv("METRICS_ENABLED", &cfg.Metrics.Enabled)
+	durationFromEnv("SHUTDOWN_DRAIN_DELAY", &cfg.Shutdown.DrainDelay)
+	durationFromEnv("SHUTDOWN_TIMEOUT", &cfg.Shutdown.Timeout)
+	stringFromEnv("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	stringFromEnv("TRACING_FILE", &cfg.Tracing.File)
+	stringFromEnv("TRACING_SERVICE_NAME", &cfg.Tracing.ServiceName)
+	stringFromEnv("ERROR_FORMAT", &cfg.Errors.Format)
+	stringFromEnv("ERRORS_DOCS_URL", &cfg.Errors.DocsURL)
+	stringFromEnv("ERRORS_LANGUAGE", &cfg.Errors.Language)
+	stringFromEnv("ERRORS_CATALOG_DIR", &cfg.Errors.CatalogDir)
+	intFromEnv("ICAL_PAST_DAYS", &cfg.Calendar.PastDays)
+	intFromEnv("ICAL_FUTURE_DAYS", &cfg.Calendar.FutureDays)
+	intFromEnv("MAX_RANGE_DAYS", &cfg.Calendar.MaxRangeDays)
+	intFromEnv("ICAL_IMPORT_MAX_BYTES", &cfg.Calendar.ImportMaxBytes)
+	intFromEnv("ICAL_IMPORT_MAX_EVENTS", &cfg.Calendar.ImportMaxEvents)
+	intFromEnv("ICAL_IMPORT_MAX_OCCURRENCES", &cfg.Calendar.ImportMaxOccurrences)
+	stringFromEnv("ICAL_IMPORT_TIMEZONE", &cfg.Calendar.ImportTimeZone)
+	intFromEnv("QUOTA_MAX_EVENTS_PER_TENANT", &cfg.Tenancy.DefaultQuota.MaxEvents)
+	intFromEnv("QUOTA_MAX_EVENTS_PER_USER", &cfg.Tenancy.DefaultQuota.MaxEventsPerUser)
+	intFromEnv("QUOTA_MAX_EVENTS_PER_DAY", &cfg.Tenancy.DefaultQuota.MaxEventsPerDay)
+	intFromEnv("QUOTA_MAX_TITLE_LENGTH", &cfg.Tenancy.DefaultQuota.MaxTitleLength)
+	intFromEnv("VALIDATION_MIN_YEAR", &cfg.Tenancy.DefaultValidation.MinYear)
+	intFromEnv("VALIDATION_MAX_YEAR", &cfg.Tenancy.DefaultValidation.MaxYear)
+	stringFromEnv("VALIDATION_REQUIRED_FIELDS", requiredFields)
+	intFromEnv("STREAM_BUFFER", &cfg.Stream.Buffer)
+	intFromEnv("STREAM_HISTORY", &cfg.Stream.History)
+	durationFromEnv("STREAM_HEARTBEAT", &cfg.Stream.Heartbeat)
+	durationFromEnv("WEBSOCKET_PING_INTERVAL", &cfg.WebSocket.PingInterval)
+	intFromEnv("WEBSOCKET_SEND_BUFFER", &cfg.WebSocket.SendBuffer)
+	intFromEnv("WEBSOCKET_MAX_SUBSCRIPTIONS", &cfg.WebSocket.MaxSubscriptions)
+	intFromEnv("WEBHOOK_WORKERS", &cfg.Webhook.Workers)
+	intFromEnv("WEBHOOK_MAX_ATTEMPTS", &cfg.Webhook.MaxAttempts)
+	durationFromEnv("WEBHOOK_BACKOFF", &cfg.Webhook.Backoff)
+	durationFromEnv("WEBHOOK_MAX_BACKOFF", &cfg.Webhook.MaxBackoff)
+	durationFromEnv("WEBHOOK_TIMEOUT", &cfg.Webhook.Timeout)
+	stringFromEnv("WEBHOOK_ALLOWED_NETWORKS", webhookNetworks)
+	intFromEnv("OUTBOX_BATCH_SIZE", &cfg.Outbox.BatchSize)
+	durationFromEnv("OUTBOX_POLL_INTERVAL", &cfg.Outbox.PollInterval)
+	stringFromEnv("REMINDER_TIMEZONE", &cfg.Reminder.TimeZone)
+	stringFromEnv("REMINDER_DEFAULT_METHOD", &cfg.Reminder.DefaultMethod)
+	durationFromEnv("REMINDER_RESYNC_INTERVAL", &cfg.Reminder.ResyncInterval)
+	durationFromEnv("REMINDER_CATCH_UP", &cfg.Reminder.CatchUp)
+	intFromEnv("REMINDER_MAX_ATTEMPTS", &cfg.Reminder.MaxAttempts)
+	durationFromEnv("REMINDER_RETRY_DELAY", &cfg.Reminder.RetryDelay)
+	stringFromEnv("REMINDER_WEBHOOK_URL", &cfg.Reminder.WebhookURL)
+	stringFromEnv("REMINDER_WEBHOOK_SECRET", &cfg.Reminder.WebhookSecret)
+	stringFromEnv("REMINDER_SMTP_ADDR", &cfg.Reminder.SMTPAddr)
+	stringFromEnv("REMINDER_SMTP_FROM", &cfg.Reminder.SMTPFrom)
+	stringFromEnv("REMINDER_SMTP_USERNAME", &cfg.Reminder.SMTPUsername)
+	stringFromEnv("REMINDER_SMTP_PASSWORD", &cfg.Reminder.SMTPPassword)
+	stringFromEnv("REMINDER_SMTP_DOMAIN", &cfg.Reminder.SMTPDomain)
+	stringFromEnv("DIGEST_TIMEZONE", &cfg.Digest.TimeZone)
+	stringFromEnv("DIGEST_SEND_AT", &cfg.Digest.SendAt)
+	durationFromEnv("DIGEST_CHECK_INTERVAL", &cfg.Digest.CheckInterval)
+	durationFromEnv("DIGEST_CATCH_UP", &cfg.Digest.CatchUp)
 
 	flag.Parse()
+
+	cfg.CORS.AllowedOrigins = splitList(*corsOrigins)
+	cfg.CORS.AllowedMethods = splitList(*corsMethods)
+	cfg.CORS.AllowedHeaders = splitList(*corsHeaders)
+	cfg.CORS.ExposedHeaders = splitList(*corsExposed)
+	cfg.Webhook.AllowedNetworks = parseNetworks(splitList(*webhookNetworks))
+	cfg.Tenancy.DefaultValidation.RequiredFields = splitList(*requiredFields)
+	if cfg.CORS.AllowCredentials {
+		for _, origin := range cfg.CORS.AllowedOrigins {
+			// Любой сайт смог бы выполнять запросы с учетными данными пользователя
+			if origin == "*" {
+				panic("CORS credentials cannot be allowed for wildcard origin \"*\"")
+			}
+		}
+	}
+
+	if cfg.RateLimit.Enabled && (cfg.RateLimit.ReadRate <= 0 || cfg.RateLimit.WriteRate <= 0 || cfg.RateLimit.AuthFailureRate <= 0 ||
+		cfg.RateLimit.ReadBurst < 1 || cfg.RateLimit.WriteBurst < 1 || cfg.RateLimit.AuthFailureBurst < 1) {
+		panic("rate limit rates must be positive and bursts at least 1")
+	}
+
+	switch cfg.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout, TracingExporterFile:
+	default:
+		panic(fmt.Sprintf("unknown tracing exporter %q", cfg.Tracing.Exporter))
+	}
+
+	if cfg.Errors.Format != "problem" && cfg.Errors.Format != "legacy" {
+		panic(fmt.Sprintf("unknown error format %q", cfg.Errors.Format))
+	}
+
+	if cfg.Calendar.PastDays < 0 || cfg.Calendar.FutureDays < 0 ||
+		cfg.Calendar.PastDays+cfg.Calendar.FutureDays+1 > cfg.Calendar.MaxRangeDays {
+		panic("calendar export period must be non-negative and fit into the maximum range")
+	}
+
+	if cfg.Calendar.ImportMaxBytes < 1 || cfg.Calendar.ImportMaxEvents < 0 || cfg.Calendar.ImportMaxOccurrences < 1 {
+		panic("calendar import limits must be positive")
+	}
+	if _, err := time.LoadLocation(cfg.Calendar.ImportTimeZone); err != nil {
+		panic(fmt.Sprintf("unknown calendar import time zone %q", cfg.Calendar.ImportTimeZone))
+	}
+
+	if cfg.Stream.Buffer < 1 || cfg.Stream.History < 0 || cfg.Stream.Heartbeat <= 0 {
+		panic("stream buffer and heartbeat must be positive, history non-negative")
+	}
+
+	if cfg.WebSocket.PingInterval <= 0 || cfg.WebSocket.SendBuffer < 1 || cfg.WebSocket.MaxSubscriptions < 1 {
+		panic("websocket ping interval, send buffer and subscription limit must be positive")
+	}
+
+	if cfg.Webhook.Workers < 1 || cfg.Webhook.MaxAttempts < 1 || cfg.Webhook.Backoff <= 0 ||
+		cfg.Webhook.MaxBackoff < cfg.Webhook.Backoff || cfg.Webhook.Timeout <= 0 {
+		panic("webhook workers, attempts, backoff and timeout must be positive, max backoff not below backoff")
+	}
+
+	if cfg.Outbox.BatchSize < 1 || cfg.Outbox.PollInterval <= 0 {
+		panic("outbox batch size and poll interval must be positive")
+	}
+
+	if _, err := time.LoadLocation(cfg.Reminder.TimeZone); err != nil {
+		panic(fmt.Sprintf("unknown reminder time zone %q", cfg.Reminder.TimeZone))
+	}
+	if !slices.Contains(domain.ReminderMethods, cfg.Reminder.DefaultMethod) {
+		panic(fmt.Sprintf("unknown reminder method %q, expected log, webhook or email", cfg.Reminder.DefaultMethod))
+	}
+	// Пересчет должен успевать раньше, чем истечет горизонт планирования напоминаний (сутки)
+	if cfg.Reminder.ResyncInterval <= 0 || cfg.Reminder.ResyncInterval >= 24*time.Hour || cfg.Reminder.CatchUp < 0 ||
+		cfg.Reminder.MaxAttempts < 1 || cfg.Reminder.RetryDelay <= 0 {
+		panic("reminder resync interval must be positive and below 24h, catch-up non-negative, attempts and retry delay positive")
+	}
+	if cfg.Reminder.WebhookURL != "" && cfg.Reminder.WebhookSecret == "" {
+		panic("reminder webhook secret is required with a reminder webhook URL")
+	}
+
+	if _, err := time.LoadLocation(cfg.Digest.TimeZone); err != nil {
+		panic(fmt.Sprintf("unknown digest time zone %q", cfg.Digest.TimeZone))
+	}
+	if _, err := time.Parse("15:04", cfg.Digest.SendAt); err != nil || len(cfg.Digest.SendAt) != len("15:04") {
+		panic(fmt.Sprintf("invalid digest send time %q, expected HH:MM", cfg.Digest.SendAt))
+	}
+	if cfg.Digest.CheckInterval <= 0 || cfg.Digest.CatchUp < 0 {
+		panic("digest check interval must be positive and catch-up non-negative")
+	}
+
+	if cfg.ConfigFile != "" {
+		if err := cfg.loadFile(cfg.ConfigFile); err != nil {
+			panic(fmt.Sprintf("failed to load config file %s: %v", cfg.ConfigFile, err))
+		}
+	}
+
+	if err := cfg.Tenancy.validateRules(); err != nil {
+		panic(err.Error())
+	}
+
 	return cfg
 }
+
+// loadFile дополняет конфигурацию настройками из JSON файла
+func (cfg *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var fc fileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return err
+	}
+
+	cfg.Tenancy.Tenants = fc.Tenants
+	// Флаги и переменные окружения приоритетнее файла
+	cfg.Tenancy.DefaultQuota = fc.Quota.Merge(cfg.Tenancy.DefaultQuota)
+	cfg.Tenancy.DefaultValidation = fc.Validation.Merge(cfg.Tenancy.DefaultValidation)
+	return nil
+}
+
+// validateRules проверяет действующие правила проверки событий по умолчанию и каждого арендатора
+func (t TenancyConfig) validateRules() error {
+	defaults := tenant.DefaultValidationRules().Merge(t.DefaultValidation)
+	if err := defaults.Validate(); err != nil {
+		return fmt.Errorf("invalid validation rules: %w", err)
+	}
+	for id, settings := range t.Tenants {
+		if err := defaults.Merge(settings.Validation).Validate(); err != nil {
+			return fmt.Errorf("invalid validation rules for tenant %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// stringFromEnv записывает в dst значение переменной окружения, если она задана
+func stringFromEnv(key string, dst *string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+// splitList разбирает список значений, разделенных запятыми
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseNetworks - разбор сетей в нотации CIDR или отдельных адресов
+func parseNetworks(values []string) []netip.Prefix {
+	networks := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if addr, err := netip.ParseAddr(value); err == nil {
+			networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			panic(fmt.Sprintf("invalid network %q, expected CIDR or IP address", value))
+		}
+		networks = append(networks, prefix.Masked())
+	}
+	return networks
+}
+
+// intFromEnv записывает в dst целое значение переменной окружения, если она задана
+func intFromEnv(key string, dst *int) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid integer in %s: %q", key, value))
+	}
+	*dst = parsed
+}
+
+// floatFromEnv записывает в dst дробное значение переменной окружения, если она задана
+func floatFromEnv(key string, dst *float64) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		panic(fmt.Sprintf("invalid number in %s: %q", key, value))
+	}
+	*dst = parsed
+}
+
+// boolFromEnv записывает в dst логическое значение переменной окружения, если она задана
+func boolFromEnv(key string, dst *bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid boolean in %s: %q", key, value))
+	}
+	*dst = parsed
+}
+
+// durationFromEnv записывает в dst длительность из переменной окружения, если она задана
+func durationFromEnv(key string, dst *time.Duration) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid duration in %s: %q", key, value))
+	}
+	*dst = parsed
+}
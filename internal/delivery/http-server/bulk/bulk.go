@@ -0,0 +1,325 @@
+// Package bulk - массовая выгрузка и загрузка событий в форматах CSV и JSON Lines.
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"calendar-server/internal/domain"
+	"calendar-server/pkg/errors"
+)
+
+// Format - формат файла с событиями
+type Format string
+
+const (
+	// CSV - таблица с заголовком, одна строка на событие
+	CSV Format = "csv"
+	// JSONL - JSON Lines, один объект события на строку
+	JSONL Format = "jsonl"
+)
+
+// Columns - поля события в порядке колонок выгрузки
+var Columns = []string{"id", "user_id", "date", "title", "start_time", "end_time"}
+
+// maxLineBytes - максимальная длина строки JSON Lines
+const maxLineBytes = 1 << 20
+
+// FormatOf - формат по расширению пути: .csv или .jsonl
+func FormatOf(p string) (Format, bool) {
+	switch format := Format(strings.TrimPrefix(path.Ext(p), ".")); format {
+	case CSV, JSONL:
+		return format, true
+	}
+	return "", false
+}
+
+// ContentType - тип содержимого файла формата
+func (f Format) ContentType() string {
+	if f == JSONL {
+		return "application/jsonl; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Mapping - имена колонок CSV или ключей JSON Lines для полей события.
+// Поле без сопоставления читается из одноименной колонки.
+type Mapping map[string]string
+
+// ParseMapping - разбор параметра вида "title=Subject,date=Day"
+func ParseMapping(value string) (Mapping, error) {
+	mapping := Mapping{}
+	if value == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if _, known := (domain.Event{}).Field(field); !ok || !known || column == "" {
+			return nil, errors.WithParams(
+				fmt.Errorf("%w: map must list field=column pairs for event fields, got %s", errors.ErrInvalidImportOption, value),
+				errors.Params{errors.ParamRule: "map", "map": value},
+			)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// column - имя колонки поля события
+func (m Mapping) column(field string) string {
+	if column, ok := m[field]; ok {
+		return column
+	}
+	return field
+}
+
+// Writer - потоковая запись событий
+type Writer interface {
+	Write(event domain.Event) error
+	// Flush - запись буферизованных данных в исходный writer
+	Flush() error
+}
+
+// NewWriter - запись событий в формате f
+func (f Format) NewWriter(w io.Writer) Writer {
+	if f == JSONL {
+		buf := bufio.NewWriter(w)
+		return &jsonlWriter{buf: buf, encoder: json.NewEncoder(buf)}
+	}
+	return &csvWriter{writer: csv.NewWriter(w), row: make([]string, len(Columns))}
+}
+
+// Read - разбор файла в события для импорта. Ошибки отдельных строк попадают в ImportCandidate.Err,
+// ошибка разбора всего файла возвращается; ошибки чтения возвращаются без изменений.
+func (f Format) Read(r io.Reader, mapping Mapping) ([]domain.ImportCandidate, error) {
+	if f == JSONL {
+		return readJSONL(r, mapping)
+	}
+	return readCSV(r, mapping)
+}
+
+// csvWriter - запись CSV; заголовок пишется перед первой строкой или при первом Flush
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+	row    []string
+}
+
+// Write - запись строки события
+func (w *csvWriter) Write(event domain.Event) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	for i, column := range Columns {
+		value, _ := event.Field(column)
+		w.row[i] = escapeFormula(value)
+	}
+	return w.writer.Write(w.row)
+}
+
+// Flush - запись буфера
+func (w *csvWriter) Flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// writeHeader - запись заголовка, если он еще не записан
+func (w *csvWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	return w.writer.Write(Columns)
+}
+
+// jsonlWriter - запись JSON Lines
+type jsonlWriter struct {
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+// Write - запись объекта события
+func (w *jsonlWriter) Write(event domain.Event) error {
+	return w.encoder.Encode(event)
+}
+
+// Flush - запись буфера
+func (w *jsonlWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+// escapeFormula защищает значение от исполнения как формулы при открытии CSV в табличном редакторе
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeFormula - обратное escapeFormula преобразование при импорте
+func unescapeFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// readCSV - разбор CSV с заголовком
+func readCSV(r io.Reader, mapping Mapping) ([]domain.ImportCandidate, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if stdErrors.Is(err, io.EOF) {
+		return nil, errors.WithParams(
+			fmt.Errorf("%w: CSV header is missing", errors.ErrInvalidRecord),
+			errors.Params{errors.ParamRule: "header"},
+		)
+	}
+	if err != nil {
+		return nil, syntaxError(err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// Табличные редакторы сохраняют CSV с BOM
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		index[strings.TrimSpace(name)] = i
+	}
+
+	positions := make(map[string]int, len(Columns))
+	for _, field := range Columns {
+		column := mapping.column(field)
+		position, ok := index[column]
+		if !ok {
+			if _, explicit := mapping[field]; explicit {
+				return nil, errors.WithParams(
+					fmt.Errorf("%w: column %s is missing in the header", errors.ErrInvalidImportOption, column),
+					errors.Params{errors.ParamRule: "column", "column": column},
+				)
+			}
+			continue
+		}
+		positions[field] = position
+	}
+
+	var candidates []domain.ImportCandidate
+	for {
+		record, err := reader.Read()
+		if stdErrors.Is(err, io.EOF) {
+			return candidates, nil
+		}
+
+		switch {
+		case stdErrors.Is(err, csv.ErrFieldCount):
+			line, _ := reader.FieldPos(0)
+			candidates = append(candidates, domain.ImportCandidate{
+				Source: source(line),
+				Err: errors.WithParams(
+					fmt.Errorf("%w: line %d has %d columns, expected %d", errors.ErrInvalidRecord, line, len(record), len(header)),
+					errors.Params{errors.ParamRule: "columns", "line": line, "count": len(record), "expected": len(header)},
+				),
+			})
+			continue
+		case err != nil:
+			return nil, syntaxError(err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		var event domain.Event
+		for field, position := range positions {
+			event.SetField(field, unescapeFormula(record[position]))
+		}
+		candidates = append(candidates, domain.ImportCandidate{Source: source(line), Event: event})
+	}
+}
+
+// readJSONL - разбор JSON Lines; пустые строки пропускаются
+func readJSONL(r io.Reader, mapping Mapping) ([]domain.ImportCandidate, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+
+	var candidates []domain.ImportCandidate
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		candidate := domain.ImportCandidate{Source: source(line)}
+		candidate.Event, candidate.Err = decodeLine(data, line, mapping)
+		candidates = append(candidates, candidate)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if stdErrors.Is(err, bufio.ErrTooLong) {
+			return nil, errors.WithParams(
+				fmt.Errorf("%w: malformed line %d: longer than %d bytes", errors.ErrInvalidRecord, line+1, maxLineBytes),
+				errors.Params{errors.ParamRule: "syntax", "line": line + 1},
+			)
+		}
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// decodeLine - событие из объекта JSON; отсутствующие ключи и null оставляют поле пустым
+func decodeLine(data []byte, line int, mapping Mapping) (domain.Event, error) {
+	var record map[string]any
+	if err := json.Unmarshal(data, &record); err != nil {
+		return domain.Event{}, errors.WithParams(
+			fmt.Errorf("%w: malformed line %d: %v", errors.ErrInvalidRecord, line, err),
+			errors.Params{errors.ParamRule: "syntax", "line": line},
+		)
+	}
+
+	var event domain.Event
+	for _, field := range Columns {
+		key := mapping.column(field)
+		value, ok := record[key]
+		if !ok || value == nil {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			return domain.Event{}, errors.WithParams(
+				fmt.Errorf("%w: %s on line %d must be a string", errors.ErrInvalidRecord, key, line),
+				errors.Params{errors.ParamRule: "value", "key": key, "line": line},
+			)
+		}
+		event.SetField(field, text)
+	}
+	return event, nil
+}
+
+// syntaxError - ошибка разбора всего файла; ошибки чтения, например превышение размера, не оборачиваются
+func syntaxError(err error) error {
+	var parseErr *csv.ParseError
+	if !stdErrors.As(err, &parseErr) {
+		return err
+	}
+	return errors.WithParams(
+		fmt.Errorf("%w: malformed line %d: %v", errors.ErrInvalidRecord, parseErr.StartLine, parseErr.Err),
+		errors.Params{errors.ParamRule: "syntax", "line": parseErr.StartLine},
+	)
+}
+
+// source - положение записи в отчете импорта
+func source(line int) string {
+	return fmt.Sprintf("line %d", line)
+}
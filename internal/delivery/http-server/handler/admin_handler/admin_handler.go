@@ -0,0 +1,122 @@
+package admin_handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"calendar-server/internal/delivery/http-server/response"
+	uc "calendar-server/internal/usecase/admin_usecase"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// AdminHandler - обработчик административных запросов
+type AdminHandler struct {
+	adminUseCase uc.AdminUseCaseContract
+	logger       *zap.Logger
+	errors       response.ErrorRenderer
+}
+
+// Option - функциональная опция AdminHandler
+type Option func(*AdminHandler)
+
+// WithErrorRenderer - формат ответов с ошибками
+func WithErrorRenderer(renderer response.ErrorRenderer) Option {
+	return func(h *AdminHandler) {
+		h.errors = renderer
+	}
+}
+
+// NewAdminHandler - конструктор обработчика административных запросов
+func NewAdminHandler(adminUseCase uc.AdminUseCaseContract, logger *zap.Logger, opts ...Option) *AdminHandler {
+	h := &AdminHandler{
+		adminUseCase: adminUseCase,
+		logger:       logger,
+		errors:       response.DefaultErrorRenderer(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// ListUsers - метод получения пользователей с количеством событий
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.adminUseCase.ListUsers(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list users", zappretty.Field("error", err))
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: users})
+}
+
+// DeleteUserEvents - метод принудительного удаления событий пользователя
+func (h *AdminHandler) DeleteUserEvents(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		UserID string `json:"user_id"`
+	}
+	if !h.decode(w, r, &request) {
+		return
+	}
+
+	deleted, err := h.adminUseCase.DeleteUserEvents(r.Context(), request.UserID)
+	if err != nil {
+		h.logger.Error("Failed to delete user events",
+			zappretty.Field("error", err),
+			zappretty.Field("user_id", request.UserID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: map[string]int{"deleted": deleted}})
+}
+
+// ReassignEvents - метод передачи событий другому пользователю
+func (h *AdminHandler) ReassignEvents(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		FromUserID string   `json:"from_user_id"`
+		ToUserID   string   `json:"to_user_id"`
+		EventIDs   []string `json:"event_ids"`
+	}
+	if !h.decode(w, r, &request) {
+		return
+	}
+
+	reassigned, err := h.adminUseCase.ReassignEvents(r.Context(), request.FromUserID, request.ToUserID, request.EventIDs)
+	if err != nil {
+		h.logger.Error("Failed to reassign events",
+			zappretty.Field("error", err),
+			zappretty.Field("from_user_id", request.FromUserID),
+			zappretty.Field("to_user_id", request.ToUserID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: map[string]int{"reassigned": reassigned}})
+}
+
+// decode - проверка Content-Type и разбор JSON тела запроса
+func (h *AdminHandler) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Header.Get("Content-Type") != "application/json" {
+		h.logger.Warn("Unsupported media type")
+		h.handleError(w, r, errors.ErrUnsupportedMedia)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		h.logger.Warn("Invalid JSON format", zappretty.Field("error", err))
+		h.handleError(w, r, errors.ErrInvalidJSON)
+		return false
+	}
+	return true
+}
+
+// handleError - обработчик ошибок административных запросов
+func (h *AdminHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	h.errors.Render(w, r, h.logger, errors.Describe(err))
+}
@@ -0,0 +1,148 @@
+package apikey_handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/internal/domain"
+	uc "calendar-server/internal/usecase/apikey_usecase"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// IssuedKey - ключ вместе с секретом, который показывается только при выпуске и ротации
+type IssuedKey struct {
+	domain.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyHandler - обработчик управления API-ключами
+type APIKeyHandler struct {
+	apiKeyUseCase uc.APIKeyUseCaseContract
+	logger        *zap.Logger
+	errors        response.ErrorRenderer
+}
+
+// Option - функциональная опция APIKeyHandler
+type Option func(*APIKeyHandler)
+
+// WithErrorRenderer - формат ответов с ошибками
+func WithErrorRenderer(renderer response.ErrorRenderer) Option {
+	return func(h *APIKeyHandler) {
+		h.errors = renderer
+	}
+}
+
+// NewAPIKeyHandler - конструктор обработчика API-ключей
+func NewAPIKeyHandler(apiKeyUseCase uc.APIKeyUseCaseContract, logger *zap.Logger, opts ...Option) *APIKeyHandler {
+	h := &APIKeyHandler{
+		apiKeyUseCase: apiKeyUseCase,
+		logger:        logger,
+		errors:        response.DefaultErrorRenderer(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// CreateAPIKey - метод выпуска ключа
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if !h.decode(w, r, &request) {
+		return
+	}
+
+	key, secret, err := h.apiKeyUseCase.IssueKey(r.Context(), request.Name, request.Scopes)
+	if err != nil {
+		h.logger.Error("Failed to issue API key", zappretty.Field("error", err))
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: IssuedKey{APIKey: key, Key: secret}})
+}
+
+// ListAPIKeys - метод получения списка ключей
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeyUseCase.ListKeys(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list API keys", zappretty.Field("error", err))
+		h.handleError(w, r, err)
+		return
+	}
+
+	if keys == nil {
+		keys = []domain.APIKey{}
+	}
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: keys})
+}
+
+// RotateAPIKey - метод ротации секрета ключа
+func (h *APIKeyHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ID string `json:"id"`
+	}
+	if !h.decode(w, r, &request) {
+		return
+	}
+
+	key, secret, err := h.apiKeyUseCase.RotateKey(r.Context(), request.ID)
+	if err != nil {
+		h.logger.Error("Failed to rotate API key",
+			zappretty.Field("error", err),
+			zappretty.Field("key_id", request.ID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: IssuedKey{APIKey: key, Key: secret}})
+}
+
+// RevokeAPIKey - метод отзыва ключа
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ID string `json:"id"`
+	}
+	if !h.decode(w, r, &request) {
+		return
+	}
+
+	if err := h.apiKeyUseCase.RevokeKey(r.Context(), request.ID); err != nil {
+		h.logger.Error("Failed to revoke API key",
+			zappretty.Field("error", err),
+			zappretty.Field("key_id", request.ID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: "api key revoked"})
+}
+
+// decode - проверка Content-Type и разбор JSON тела запроса
+func (h *APIKeyHandler) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Header.Get("Content-Type") != "application/json" {
+		h.logger.Warn("Unsupported media type")
+		h.handleError(w, r, errors.ErrUnsupportedMedia)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		h.logger.Warn("Invalid JSON format", zappretty.Field("error", err))
+		h.handleError(w, r, errors.ErrInvalidJSON)
+		return false
+	}
+	return true
+}
+
+// handleError - обработчик ошибок API-ключей
+func (h *APIKeyHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	h.errors.Render(w, r, h.logger, errors.Describe(err))
+}
@@ -0,0 +1,666 @@
+package caldav_handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	stdErrors "errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/delivery/http-server/icalendar"
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/internal/domain"
+	uc "calendar-server/internal/usecase/event_usecase"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/ical"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// Prefix - корень CalDAV
+const Prefix = "/caldav/"
+
+// Settings - параметры CalDAV
+type Settings struct {
+	// PastDays, FutureDays - период относительно текущей даты, события которого видны в календаре
+	PastDays   int
+	FutureDays int
+	// MaxBytes - максимальный размер загружаемого события
+	MaxBytes int64
+	// Location - часовой пояс, в который переводится время событий с TZID и UTC
+	Location *time.Location
+}
+
+// DefaultSettings - квартал назад и год вперед, события до 1 МиБ, время в UTC
+func DefaultSettings() Settings {
+	return Settings{PastDays: 90, FutureDays: 365, MaxBytes: 1 << 20, Location: time.UTC}
+}
+
+// CalDAVHandler - подмножество CalDAV (RFC 4791) поверх EventUseCaseContract.
+// У пользователя один календарь /caldav/users/{id}/calendar/, события - ресурсы <id>.ics в нем.
+type CalDAVHandler struct {
+	eventUseCase uc.EventUseCaseContract
+	logger       *zap.Logger
+	errors       response.ErrorRenderer
+	settings     Settings
+	now          func() time.Time
+}
+
+// Option - функциональная опция CalDAVHandler
+type Option func(*CalDAVHandler)
+
+// WithErrorRenderer - формат ответов с ошибками
+func WithErrorRenderer(renderer response.ErrorRenderer) Option {
+	return func(h *CalDAVHandler) {
+		h.errors = renderer
+	}
+}
+
+// WithSettings - период календаря, лимит размера и часовой пояс событий
+func WithSettings(settings Settings) Option {
+	return func(h *CalDAVHandler) {
+		h.settings = settings
+	}
+}
+
+// NewCalDAVHandler - конструктор обработчика CalDAV
+func NewCalDAVHandler(eventUseCase uc.EventUseCaseContract, logger *zap.Logger, opts ...Option) *CalDAVHandler {
+	h := &CalDAVHandler{
+		eventUseCase: eventUseCase,
+		logger:       logger,
+		errors:       response.DefaultErrorRenderer(),
+		settings:     DefaultSettings(),
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// WellKnown - перенаправление /.well-known/caldav на корень CalDAV (RFC 6764)
+func (h *CalDAVHandler) WellKnown(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, Prefix, http.StatusMovedPermanently)
+}
+
+// Options - возможности сервера
+func (h *CalDAVHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+	w.WriteHeader(http.StatusOK)
+}
+
+// Root - PROPFIND корня: клиент узнает адрес своего принципала
+func (h *CalDAVHandler) Root(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CalDAVHandler.Root")
+	defer span.End()
+
+	identity, req, ok := h.propfind(w, r)
+	if !ok {
+		return
+	}
+	if identity.UserID == "" {
+		h.handleError(w, r, errors.ErrEmptyUserID)
+		return
+	}
+
+	props := map[xml.Name]string{
+		propResourceType:         `<collection xmlns="DAV:"/>`,
+		propCurrentUserPrincipal: hrefXML(principalPath(identity.UserID)),
+	}
+	writeMultistatus(w, h.log(ctx), []davResponse{req.response(resource{href: Prefix, props: props})})
+}
+
+// Principal - PROPFIND принципала, он же домашняя коллекция календарей
+func (h *CalDAVHandler) Principal(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CalDAVHandler.Principal")
+	defer span.End()
+
+	identity, req, ok := h.propfind(w, r)
+	if !ok {
+		return
+	}
+	userID := r.PathValue("id")
+
+	responses := []davResponse{req.response(resource{
+		href: principalPath(userID),
+		props: map[xml.Name]string{
+			propResourceType:         `<collection xmlns="DAV:"/><principal xmlns="DAV:"/>`,
+			propDisplayName:          escape(userID),
+			propCurrentUserPrincipal: hrefXML(principalPath(identity.UserID)),
+			propPrincipalURL:         hrefXML(principalPath(userID)),
+			propCalendarHomeSet:      hrefXML(principalPath(userID)),
+		},
+	})}
+
+	if depth(r) > 0 {
+		events, err := h.events(ctx, userID, time.Time{}, time.Time{})
+		if err != nil {
+			h.handleError(w, r, err)
+			return
+		}
+		responses = append(responses, req.response(h.collection(identity, userID, events)))
+	}
+
+	writeMultistatus(w, h.log(ctx), responses)
+}
+
+// Calendar - PROPFIND календаря; с Depth: 1 перечисляет события и их ETag
+func (h *CalDAVHandler) Calendar(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CalDAVHandler.Calendar")
+	defer span.End()
+
+	identity, req, ok := h.propfind(w, r)
+	if !ok {
+		return
+	}
+	userID := r.PathValue("id")
+
+	events, err := h.events(ctx, userID, time.Time{}, time.Time{})
+	if err != nil {
+		h.log(ctx).Error("Failed to list CalDAV calendar",
+			zappretty.Field("error", err),
+			zappretty.Field("user_id", userID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	responses := []davResponse{req.response(h.collection(identity, userID, events))}
+	if depth(r) > 0 {
+		for _, event := range events {
+			responses = append(responses, req.response(h.object(userID, event, false)))
+		}
+	}
+
+	writeMultistatus(w, h.log(ctx), responses)
+}
+
+// Report - REPORT calendar-query (с фильтром time-range) и calendar-multiget
+func (h *CalDAVHandler) Report(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CalDAVHandler.Report")
+	defer span.End()
+
+	if _, ok := h.identity(w, r); !ok {
+		return
+	}
+	userID := r.PathValue("id")
+
+	req, err := parseDAVRequest(http.MaxBytesReader(w, r.Body, h.settings.MaxBytes))
+	if err != nil {
+		h.log(ctx).Warn("Invalid CalDAV request body", zappretty.Field("error", err))
+		h.handleError(w, r, errors.ErrInvalidXML)
+		return
+	}
+
+	switch req.root {
+	case reportQuery:
+		events, err := h.events(ctx, userID, req.start, req.end)
+		if err != nil {
+			h.handleError(w, r, err)
+			return
+		}
+		responses := make([]davResponse, 0, len(events))
+		for _, event := range events {
+			responses = append(responses, req.response(h.object(userID, event, true)))
+		}
+		writeMultistatus(w, h.log(ctx), responses)
+	case reportMultiget:
+		events, err := h.events(ctx, userID, time.Time{}, time.Time{})
+		if err != nil {
+			h.handleError(w, r, err)
+			return
+		}
+		byID := make(map[string]domain.Event, len(events))
+		for _, event := range events {
+			byID[event.ID] = event
+		}
+
+		responses := make([]davResponse, 0, len(req.hrefs))
+		for _, href := range req.hrefs {
+			id := objectID(userID, href)
+			event, ok := byID[id]
+			if !ok && id != "" {
+				// Объекты за пределами периода календаря ищутся по ID, как в GET
+				event, err = h.find(ctx, userID, id+".ics")
+				ok = err == nil
+			}
+			if !ok {
+				responses = append(responses, notFound(href))
+				continue
+			}
+			responses = append(responses, req.response(h.object(userID, event, true)))
+		}
+		writeMultistatus(w, h.log(ctx), responses)
+	default:
+		h.log(ctx).Warn("Unsupported CalDAV report", zappretty.Field("report", req.root.Local))
+		h.handleError(w, r, errors.ErrUnsupportedReport)
+	}
+}
+
+// GetObject - событие в формате iCalendar
+func (h *CalDAVHandler) GetObject(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CalDAVHandler.GetObject")
+	defer span.End()
+
+	if _, ok := h.identity(w, r); !ok {
+		return
+	}
+	userID := r.PathValue("id")
+
+	event, err := h.find(ctx, userID, r.PathValue("name"))
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	data, err := h.calendarData(event)
+	if err != nil {
+		h.log(ctx).Error("Failed to encode CalDAV object", zappretty.Field("error", err))
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("ETag", etag(event))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		h.log(ctx).Error("Failed to write CalDAV object", zappretty.Field("error", err))
+	}
+}
+
+// PutObject - создание или изменение события из iCalendar.
+// If-None-Match: * разрешает только создание, If-Match - только изменение версии с этим ETag.
+func (h *CalDAVHandler) PutObject(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CalDAVHandler.PutObject")
+	defer span.End()
+
+	if _, ok := h.identity(w, r); !ok {
+		return
+	}
+	userID := r.PathValue("id")
+	eventID, ok := strings.CutSuffix(r.PathValue("name"), ".ics")
+	if !ok || eventID == "" {
+		h.handleError(w, r, errors.ErrEventNotFound)
+		return
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "text/calendar" {
+		h.log(ctx).Warn("Unsupported media type",
+			zappretty.Field("content_type", r.Header.Get("Content-Type")),
+		)
+		h.handleError(w, r, errors.ErrUnsupportedMedia)
+		return
+	}
+
+	event, err := h.decodeObject(w, r, eventID)
+	if err != nil {
+		h.log(ctx).Warn("Invalid CalDAV object",
+			zappretty.Field("error", err),
+			zappretty.Field("event_id", eventID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+	event.UserID = userID
+
+	existing, exists, err := h.stored(ctx, userID, eventID)
+	if err != nil {
+		h.log(ctx).Warn("CalDAV object rejected",
+			zappretty.Field("error", err),
+			zappretty.Field("event_id", eventID),
+			zappretty.Field("user_id", userID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+	if err := checkPreconditions(r, existing, exists); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	status := http.StatusNoContent
+	if exists {
+		// Напоминания задаются через API событий, объект iCalendar их не меняет
+		event.Reminders = existing.Reminders
+		err = h.eventUseCase.UpdateEvent(ctx, event)
+	} else {
+		status = http.StatusCreated
+		err = h.eventUseCase.CreateEvent(ctx, event)
+	}
+	if err != nil {
+		h.log(ctx).Error("Failed to store CalDAV object",
+			zappretty.Field("error", err),
+			zappretty.Field("event_id", eventID),
+			zappretty.Field("user_id", userID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.log(ctx).Info("CalDAV object stored",
+		zappretty.Field("event_id", eventID),
+		zappretty.Field("user_id", userID),
+		zappretty.Field("created", status == http.StatusCreated),
+	)
+	// ETag - версия сохраненного события: объект из запроса при разборе теряет часть данных,
+	// и клиент должен получить ту же версию, что вернет GET. Без нее клиент перечитает объект.
+	if stored, exists, err := h.stored(ctx, userID, eventID); err == nil && exists {
+		w.Header().Set("ETag", etag(stored))
+	}
+	w.WriteHeader(status)
+}
+
+// DeleteObject - удаление события; If-Match проверяет версию
+func (h *CalDAVHandler) DeleteObject(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "CalDAVHandler.DeleteObject")
+	defer span.End()
+
+	if _, ok := h.identity(w, r); !ok {
+		return
+	}
+	userID := r.PathValue("id")
+
+	event, err := h.find(ctx, userID, r.PathValue("name"))
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	if err := checkPreconditions(r, event, true); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	if err := h.eventUseCase.DeleteEvent(ctx, event.ID); err != nil {
+		h.log(ctx).Error("Failed to delete CalDAV object",
+			zappretty.Field("error", err),
+			zappretty.Field("event_id", event.ID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.log(ctx).Info("CalDAV object deleted",
+		zappretty.Field("event_id", event.ID),
+		zappretty.Field("user_id", userID),
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// propfind - проверка личности и разбор тела PROPFIND
+func (h *CalDAVHandler) propfind(w http.ResponseWriter, r *http.Request) (auth.Identity, davRequest, bool) {
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return auth.Identity{}, davRequest{}, false
+	}
+
+	req, err := parseDAVRequest(http.MaxBytesReader(w, r.Body, h.settings.MaxBytes))
+	if err != nil {
+		h.log(r.Context()).Warn("Invalid CalDAV request body", zappretty.Field("error", err))
+		h.handleError(w, r, errors.ErrInvalidXML)
+		return auth.Identity{}, davRequest{}, false
+	}
+	return identity, req, true
+}
+
+// events - события пользователя в периоде календаря, ограниченном from и to, если они заданы
+func (h *CalDAVHandler) events(ctx context.Context, userID string, from, to time.Time) ([]domain.Event, error) {
+	now := h.now()
+	start := now.AddDate(0, 0, -h.settings.PastDays)
+	end := now.AddDate(0, 0, h.settings.FutureDays)
+	if !from.IsZero() && from.After(start) {
+		start = from
+	}
+	// Граница time-range не включается, события в день окончания фильтра не нужны
+	if !to.IsZero() && to.Add(-time.Nanosecond).Before(end) {
+		end = to.Add(-time.Nanosecond)
+	}
+	if end.Before(start) {
+		return nil, nil
+	}
+
+	return h.eventUseCase.GetEventsForRange(ctx, userID,
+		start.Format(icalendar.DateLayout), end.Format(icalendar.DateLayout))
+}
+
+// find - событие пользователя по имени ресурса <id>.ics, в том числе за пределами периода календаря:
+// объект, записанный PUT, доступен по тому же адресу. Событие другого пользователя не находится.
+func (h *CalDAVHandler) find(ctx context.Context, userID, name string) (domain.Event, error) {
+	eventID, ok := strings.CutSuffix(name, ".ics")
+	if !ok || eventID == "" {
+		return domain.Event{}, errors.ErrEventNotFound
+	}
+
+	event, exists, err := h.stored(ctx, userID, eventID)
+	switch {
+	case stdErrors.Is(err, errors.ErrEventConflict):
+		return domain.Event{}, errors.ErrEventNotFound
+	case err != nil:
+		return domain.Event{}, err
+	case !exists:
+		return domain.Event{}, errors.ErrEventNotFound
+	}
+	return event, nil
+}
+
+// stored - сохраненное событие с этим ID в календаре пользователя, в том числе за пределами
+// периода календаря. Событие другого пользователя с тем же ID - конфликт: PUT не переносит
+// события между календарями.
+func (h *CalDAVHandler) stored(ctx context.Context, userID, eventID string) (domain.Event, bool, error) {
+	event, err := h.eventUseCase.GetEvent(ctx, eventID)
+	switch {
+	case stdErrors.Is(err, errors.ErrEventNotFound):
+		return domain.Event{}, false, nil
+	case stdErrors.Is(err, errors.ErrForbidden):
+		return domain.Event{}, false, fmt.Errorf("%w: ID is used in another calendar", errors.ErrEventConflict)
+	case err != nil:
+		return domain.Event{}, false, err
+	case event.UserID != userID:
+		return domain.Event{}, false, fmt.Errorf("%w: ID is used in another calendar", errors.ErrEventConflict)
+	}
+	return event, true, nil
+}
+
+// decodeObject - событие из тела PUT: ровно один VEVENT без повторений
+func (h *CalDAVHandler) decodeObject(w http.ResponseWriter, r *http.Request, eventID string) (domain.Event, error) {
+	parsed, err := ical.Parse(http.MaxBytesReader(w, r.Body, h.settings.MaxBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if stdErrors.As(err, &tooLarge) {
+			return domain.Event{}, errors.WithParams(
+				fmt.Errorf("%w: at most %d bytes", errors.ErrImportTooLarge, tooLarge.Limit),
+				errors.Params{errors.ParamRule: "bytes", "max": tooLarge.Limit},
+			)
+		}
+		return domain.Event{}, fmt.Errorf("%w: %v", errors.ErrInvalidCalendar, err)
+	}
+
+	switch {
+	case len(parsed) != 1:
+		return domain.Event{}, fmt.Errorf("%w: expected exactly one VEVENT, got %d", errors.ErrInvalidCalendar, len(parsed))
+	case parsed[0].Err != nil:
+		return domain.Event{}, fmt.Errorf("%w: %v", errors.ErrInvalidCalendar, parsed[0].Err)
+	case parsed[0].RRule != nil || !parsed[0].RecurrenceID.IsZero():
+		return domain.Event{}, fmt.Errorf("%w: recurring events are not supported", errors.ErrInvalidCalendar)
+	}
+
+	converter := icalendar.Converter{Location: h.settings.Location}
+	return converter.Event(eventID, parsed[0].Event, parsed[0].Start), nil
+}
+
+// collection - свойства календаря пользователя
+func (h *CalDAVHandler) collection(identity auth.Identity, userID string, events []domain.Event) resource {
+	privileges := `<privilege xmlns="DAV:"><read/></privilege>`
+	if identity.HasScope(auth.ScopeEventsWrite) {
+		privileges += `<privilege xmlns="DAV:"><write/></privilege>`
+	}
+
+	return resource{
+		href: calendarPath(userID),
+		props: map[xml.Name]string{
+			propResourceType:         `<collection xmlns="DAV:"/><calendar xmlns="urn:ietf:params:xml:ns:caldav"/>`,
+			propDisplayName:          escape(userID),
+			propOwner:                hrefXML(principalPath(userID)),
+			propCurrentUserPrincipal: hrefXML(principalPath(identity.UserID)),
+			propPrivilegeSet:         privileges,
+			propComponentSet:         `<comp xmlns="urn:ietf:params:xml:ns:caldav" name="VEVENT"/>`,
+			propGetCTag:              escape(ctag(events)),
+		},
+	}
+}
+
+// object - свойства события; calendar-data только для REPORT
+func (h *CalDAVHandler) object(userID string, event domain.Event, withData bool) resource {
+	props := map[xml.Name]string{
+		propResourceType:   "",
+		propGetETag:        escape(etag(event)),
+		propGetContentType: escape(ical.ContentType),
+	}
+	if withData {
+		if data, err := h.calendarData(event); err == nil {
+			props[propCalendarData] = escape(string(data))
+		} else {
+			h.logger.Warn("Skipping calendar data of malformed event",
+				zappretty.Field("event_id", event.ID),
+				zappretty.Field("error", err),
+			)
+		}
+	}
+	return resource{href: objectPath(userID, event.ID), props: props}
+}
+
+// calendarData - VCALENDAR с одним событием
+func (h *CalDAVHandler) calendarData(event domain.Event) ([]byte, error) {
+	item, ok := icalendar.ToICal(event, h.now())
+	if !ok {
+		return nil, fmt.Errorf("event %s has malformed date or time", event.ID)
+	}
+
+	var buf bytes.Buffer
+	cal := ical.Calendar{ProdID: icalendar.ProdID, Events: []ical.Event{item}}
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkPreconditions - проверка If-Match и If-None-Match
+func checkPreconditions(r *http.Request, existing domain.Event, exists bool) error {
+	if match := r.Header.Get("If-Match"); match != "" {
+		if !exists || (match != "*" && !containsETag(match, etag(existing))) {
+			return errors.ErrPreconditionFailed
+		}
+	}
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && exists {
+		if noneMatch == "*" || containsETag(noneMatch, etag(existing)) {
+			return errors.ErrPreconditionFailed
+		}
+	}
+	return nil
+}
+
+// containsETag - есть ли tag в списке заголовка If-Match или If-None-Match
+func containsETag(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// etag - сильный ETag события по его содержимому
+func etag(event domain.Event) string {
+	data, _ := json.Marshal(event)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// ctag - версия календаря: меняется при любом изменении набора событий
+func ctag(events []domain.Event) string {
+	tags := make([]string, 0, len(events))
+	for _, event := range events {
+		tags = append(tags, event.ID+"="+etag(event))
+	}
+	sort.Strings(tags)
+
+	sum := sha256.Sum256([]byte(strings.Join(tags, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// depth - значение заголовка Depth; infinity обрабатывается как 1
+func depth(r *http.Request) int {
+	if r.Header.Get("Depth") == "0" {
+		return 0
+	}
+	return 1
+}
+
+// principalPath - путь принципала пользователя
+func principalPath(userID string) string {
+	return Prefix + "users/" + url.PathEscape(userID) + "/"
+}
+
+// calendarPath - путь календаря пользователя
+func calendarPath(userID string) string {
+	return principalPath(userID) + "calendar/"
+}
+
+// objectPath - путь события
+func objectPath(userID, eventID string) string {
+	return calendarPath(userID) + url.PathEscape(eventID) + ".ics"
+}
+
+// objectID - идентификатор события по href из calendar-multiget или пустая строка
+func objectID(userID, href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	name, ok := strings.CutPrefix(u.EscapedPath(), calendarPath(userID))
+	if !ok || strings.Contains(name, "/") {
+		return ""
+	}
+	name, err = url.PathUnescape(name)
+	if err != nil {
+		return ""
+	}
+	id, ok := strings.CutSuffix(name, ".ics")
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+// identity - получение личности вызывающего, установленной middleware аутентификации
+func (h *CalDAVHandler) identity(w http.ResponseWriter, r *http.Request) (auth.Identity, bool) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		h.handleError(w, r, errors.ErrUnauthorized)
+		return auth.Identity{}, false
+	}
+	return identity, true
+}
+
+// log - логгер запроса с request_id и пользователем, либо логгер обработчика
+func (h *CalDAVHandler) log(ctx context.Context) *zap.Logger {
+	return ctxlog.FromContext(ctx, h.logger)
+}
+
+// handleError - обработчик ошибок CalDAV
+func (h *CalDAVHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	h.errors.Render(w, r, h.log(r.Context()), errors.Describe(err))
+}
@@ -0,0 +1,337 @@
+package caldav_handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/internal/domain"
+	"calendar-server/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+type mockEventUseCase struct {
+	events map[string]domain.Event
+}
+
+func (m *mockEventUseCase) CreateEvent(ctx context.Context, event domain.Event) error {
+	if _, exists := m.events[event.ID]; exists {
+		return errors.ErrEventConflict
+	}
+	m.events[event.ID] = event
+	return nil
+}
+
+func (m *mockEventUseCase) UpdateEvent(ctx context.Context, event domain.Event) error {
+	if _, exists := m.events[event.ID]; !exists {
+		return errors.ErrEventNotFound
+	}
+	m.events[event.ID] = event
+	return nil
+}
+
+func (m *mockEventUseCase) DeleteEvent(ctx context.Context, eventID string) error {
+	if _, exists := m.events[eventID]; !exists {
+		return errors.ErrEventNotFound
+	}
+	delete(m.events, eventID)
+	return nil
+}
+
+func (m *mockEventUseCase) GetEvent(ctx context.Context, eventID string) (domain.Event, error) {
+	event, exists := m.events[eventID]
+	if !exists {
+		return domain.Event{}, errors.ErrEventNotFound
+	}
+	return event, nil
+}
+
+func (m *mockEventUseCase) GetEventsForDay(ctx context.Context, userID, date string) ([]domain.Event, error) {
+	return m.GetEventsForRange(ctx, userID, date, date)
+}
+
+func (m *mockEventUseCase) GetEventsForWeek(ctx context.Context, userID, date string) ([]domain.Event, error) {
+	return nil, nil
+}
+
+func (m *mockEventUseCase) GetEventsForMonth(ctx context.Context, userID, date string) ([]domain.Event, error) {
+	return nil, nil
+}
+
+func (m *mockEventUseCase) GetEventsForRange(ctx context.Context, userID, from, to string) ([]domain.Event, error) {
+	var result []domain.Event
+	for _, event := range m.events {
+		if event.UserID == userID && event.Date >= from && event.Date <= to {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockEventUseCase) GetUsage(ctx context.Context, userID, date string) (domain.Usage, error) {
+	return domain.Usage{}, nil
+}
+
+func (m *mockEventUseCase) ImportEvents(ctx context.Context, userID string, candidates []domain.ImportCandidate, opts domain.ImportOptions) (domain.ImportReport, error) {
+	return domain.ImportReport{}, nil
+}
+
+func (m *mockEventUseCase) ExportEvents(ctx context.Context, filter domain.ExportFilter, fn func(domain.Event) error) error {
+	return nil
+}
+
+// setupTestHandler создает обработчик с календарем user-1 на 10 января 2025 года
+func setupTestHandler() (*CalDAVHandler, *mockEventUseCase) {
+	logger, _ := zap.NewDevelopment()
+	mock := &mockEventUseCase{events: map[string]domain.Event{
+		"standup": {ID: "standup", UserID: "user-1", Date: "2025-01-15", Title: "Standup", StartTime: "09:30", EndTime: "09:45"},
+		"holiday": {ID: "holiday", UserID: "user-1", Date: "2025-02-20", Title: "Holiday"},
+		"old":     {ID: "old", UserID: "user-1", Date: "2023-01-01", Title: "Outside the calendar period"},
+	}}
+	h := NewCalDAVHandler(mock, logger, WithErrorRenderer(response.ErrorRenderer{Format: response.FormatLegacy}))
+	h.now = func() time.Time { return time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC) }
+	return h, mock
+}
+
+// newDAVRequest создает запрос от имени user-1 к ресурсу его календаря
+func newDAVRequest(method, target, body string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.SetPathValue("id", "user-1")
+	if name, ok := strings.CutPrefix(target, calendarPath("user-1")); ok && name != "" {
+		req.SetPathValue("name", name)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{UserID: "user-1"}))
+}
+
+const meetingICS = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:meeting\r\nSUMMARY:Meeting\r\n" +
+	"DTSTART;TZID=Europe/Moscow:20250120T130000\r\nDURATION:PT30M\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+func TestCalDAVHandler_Objects(t *testing.T) {
+	handler, mock := setupTestHandler()
+	target := calendarPath("user-1") + "meeting.ics"
+
+	rr := httptest.NewRecorder()
+	handler.PutObject(rr, newDAVRequest("PUT", target, meetingICS, map[string]string{
+		"Content-Type":  "text/calendar",
+		"If-None-Match": "*",
+	}))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	created := rr.Header().Get("ETag")
+
+	event := mock.events["meeting"]
+	if event.UserID != "user-1" || event.Date != "2025-01-20" || event.StartTime != "10:00" || event.EndTime != "10:30" {
+		t.Errorf("Expected meeting on 2025-01-20 10:00-10:30 UTC, got %+v", event)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.GetObject(rr, newDAVRequest("GET", target, "", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != created {
+		t.Errorf("Expected status 200 with ETag %s, got %d with %s", created, rr.Code, rr.Header().Get("ETag"))
+	}
+	if !strings.Contains(rr.Body.String(), "SUMMARY:Meeting\r\n") || strings.Contains(rr.Body.String(), "METHOD:") {
+		t.Errorf("Expected calendar object without METHOD, got:\n%s", rr.Body.String())
+	}
+
+	testCases := []struct {
+		name           string
+		method         string
+		body           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{name: "create existing", method: "PUT", body: meetingICS, headers: map[string]string{"If-None-Match": "*"}, expectedStatus: http.StatusPreconditionFailed},
+		{name: "update stale version", method: "PUT", body: meetingICS, headers: map[string]string{"If-Match": `"stale"`}, expectedStatus: http.StatusPreconditionFailed},
+		{name: "recurring event", method: "PUT", body: strings.Replace(meetingICS, "END:VEVENT", "RRULE:FREQ=DAILY\r\nEND:VEVENT", 1), expectedStatus: http.StatusBadRequest},
+		{name: "update current version", method: "PUT", body: strings.Replace(meetingICS, "Meeting", "Moved", 1), headers: map[string]string{"If-Match": created}, expectedStatus: http.StatusNoContent},
+		{name: "delete stale version", method: "DELETE", headers: map[string]string{"If-Match": created}, expectedStatus: http.StatusPreconditionFailed},
+		{name: "delete", method: "DELETE", expectedStatus: http.StatusNoContent},
+		{name: "get deleted", method: "GET", expectedStatus: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tc.method == "PUT" {
+				headers["Content-Type"] = "text/calendar; charset=utf-8"
+			}
+			for key, value := range tc.headers {
+				headers[key] = value
+			}
+
+			rr := httptest.NewRecorder()
+			req := newDAVRequest(tc.method, target, tc.body, headers)
+			switch tc.method {
+			case "PUT":
+				handler.PutObject(rr, req)
+			case "DELETE":
+				handler.DeleteObject(rr, req)
+			default:
+				handler.GetObject(rr, req)
+			}
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCalDAVHandler_PutOutsidePeriod(t *testing.T) {
+	handler, mock := setupTestHandler()
+	old := mock.events["old"]
+	old.Reminders = []domain.Reminder{{MinutesBefore: 15}}
+	mock.events["old"] = old
+
+	rr := httptest.NewRecorder()
+	body := strings.Replace(meetingICS, "UID:meeting", "UID:old", 1)
+	handler.PutObject(rr, newDAVRequest("PUT", calendarPath("user-1")+"old.ics", body, map[string]string{
+		"Content-Type": "text/calendar",
+	}))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusNoContent, rr.Code, rr.Body.String())
+	}
+	event := mock.events["old"]
+	if event.Title != "Meeting" || len(event.Reminders) != 1 || event.Reminders[0].MinutesBefore != 15 {
+		t.Errorf("Expected updated event to keep its reminders, got %+v", event)
+	}
+	stored := rr.Header().Get("ETag")
+
+	// Записанный объект доступен по тому же адресу, хотя и не виден в календаре
+	rr = httptest.NewRecorder()
+	handler.GetObject(rr, newDAVRequest("GET", calendarPath("user-1")+"old.ics", "", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != stored {
+		t.Errorf("Expected status 200 with ETag %s, got %d with %s", stored, rr.Code, rr.Header().Get("ETag"))
+	}
+
+	rr = httptest.NewRecorder()
+	handler.DeleteObject(rr, newDAVRequest("DELETE", calendarPath("user-1")+"old.ics", "", map[string]string{"If-Match": stored}))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusNoContent, rr.Code, rr.Body.String())
+	}
+	if _, exists := mock.events["old"]; exists {
+		t.Error("Expected event outside the calendar period to be deleted")
+	}
+}
+
+func TestCalDAVHandler_PutForeignEvent(t *testing.T) {
+	handler, mock := setupTestHandler()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", calendarPath("user-2")+"old.ics",
+		strings.NewReader(strings.Replace(meetingICS, "UID:meeting", "UID:old", 1)))
+	req.SetPathValue("id", "user-2")
+	req.SetPathValue("name", "old.ics")
+	req.Header.Set("Content-Type", "text/calendar")
+	req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{UserID: "admin", Role: auth.RoleAdmin}))
+
+	handler.PutObject(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusConflict, rr.Code, rr.Body.String())
+	}
+	if event := mock.events["old"]; event.UserID != "user-1" || event.Title != "Outside the calendar period" {
+		t.Errorf("Expected event of user-1 to stay unchanged, got %+v", event)
+	}
+
+	rr = httptest.NewRecorder()
+	get := httptest.NewRequest("GET", calendarPath("user-2")+"old.ics", nil)
+	get.SetPathValue("id", "user-2")
+	get.SetPathValue("name", "old.ics")
+	handler.GetObject(rr, get.WithContext(req.Context()))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected event of user-1 to be hidden in the calendar of user-2, got %d", rr.Code)
+	}
+}
+
+func TestCalDAVHandler_Calendar(t *testing.T) {
+	handler, mock := setupTestHandler()
+
+	propfind := func() string {
+		rr := httptest.NewRecorder()
+		handler.Calendar(rr, newDAVRequest("PROPFIND", calendarPath("user-1"), "", map[string]string{"Depth": "1"}))
+		if rr.Code != http.StatusMultiStatus {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusMultiStatus, rr.Code, rr.Body.String())
+		}
+		return rr.Body.String()
+	}
+
+	body := propfind()
+	for _, want := range []string{
+		`<calendar xmlns="urn:ietf:params:xml:ns:caldav"/>`,
+		"<href>/caldav/users/user-1/calendar/standup.ics</href>",
+		"<href>/caldav/users/user-1/calendar/holiday.ics</href>",
+		`<getctag xmlns="http://calendarserver.org/ns/">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected response to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "old.ics") {
+		t.Error("Expected event outside the calendar period to be hidden")
+	}
+
+	event := mock.events["holiday"]
+	event.Title = "Vacation"
+	mock.events["holiday"] = event
+	if propfind() == body {
+		t.Error("Expected ctag and ETag to change after an event update")
+	}
+}
+
+func TestCalDAVHandler_Report(t *testing.T) {
+	handler, _ := setupTestHandler()
+
+	report := func(body string) (int, string) {
+		rr := httptest.NewRecorder()
+		handler.Report(rr, newDAVRequest("REPORT", calendarPath("user-1"), body, nil))
+		data, _ := io.ReadAll(rr.Body)
+		return rr.Code, string(data)
+	}
+
+	code, body := report(`<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+		<D:prop><D:getetag/><C:calendar-data/><D:displayname/></D:prop>
+		<D:href>/caldav/users/user-1/calendar/standup.ics</D:href>
+		<D:href>/caldav/users/user-1/calendar/missing.ics</D:href>
+	</C:calendar-multiget>`)
+	if code != http.StatusMultiStatus {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusMultiStatus, code, body)
+	}
+	for _, want := range []string{
+		"UID:standup",
+		"DTSTART:20250115T093000",
+		"<href>/caldav/users/user-1/calendar/missing.ics</href><status>HTTP/1.1 404 Not Found</status>",
+		`<displayname xmlns="DAV:"></displayname></prop><status>HTTP/1.1 404 Not Found</status>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected multiget to contain %q, got:\n%s", want, body)
+		}
+	}
+
+	_, body = report(`<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+		<D:prop><D:getetag/></D:prop>
+		<C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT">
+			<C:time-range start="20250201T000000Z" end="20250301T000000Z"/>
+		</C:comp-filter></C:comp-filter></C:filter>
+	</C:calendar-query>`)
+	if !strings.Contains(body, "holiday.ics") || strings.Contains(body, "standup.ics") {
+		t.Errorf("Expected only events in February, got:\n%s", body)
+	}
+
+	code, _ = report(`<D:sync-collection xmlns:D="DAV:"/>`)
+	if code != http.StatusForbidden {
+		t.Errorf("Expected status %d for unsupported report, got %d", http.StatusForbidden, code)
+	}
+	code, _ = report(`<C:calendar-query`)
+	if code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for invalid XML, got %d", http.StatusBadRequest, code)
+	}
+}
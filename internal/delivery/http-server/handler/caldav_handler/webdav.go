@@ -0,0 +1,242 @@
+package caldav_handler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// Пространства имен XML
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	// nsCS - расширения Apple Calendar Server (getctag)
+	nsCS = "http://calendarserver.org/ns/"
+)
+
+// Свойства ресурсов
+var (
+	propResourceType         = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName          = xml.Name{Space: nsDAV, Local: "displayname"}
+	propCurrentUserPrincipal = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL         = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propOwner                = xml.Name{Space: nsDAV, Local: "owner"}
+	propPrivilegeSet         = xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}
+	propGetETag              = xml.Name{Space: nsDAV, Local: "getetag"}
+	propGetContentType       = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propCalendarHomeSet      = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propComponentSet         = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propCalendarData         = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propGetCTag              = xml.Name{Space: nsCS, Local: "getctag"}
+)
+
+// Элементы тела запросов
+var (
+	elemProp       = xml.Name{Space: nsDAV, Local: "prop"}
+	elemAllProp    = xml.Name{Space: nsDAV, Local: "allprop"}
+	elemPropName   = xml.Name{Space: nsDAV, Local: "propname"}
+	elemHref       = xml.Name{Space: nsDAV, Local: "href"}
+	elemTimeRange  = xml.Name{Space: nsCalDAV, Local: "time-range"}
+	reportQuery    = xml.Name{Space: nsCalDAV, Local: "calendar-query"}
+	reportMultiget = xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}
+)
+
+// timeRangeLayout - формат границ time-range
+const timeRangeLayout = "20060102T150405Z"
+
+// davRequest - тело PROPFIND или REPORT
+type davRequest struct {
+	// root - корневой элемент, для REPORT определяет тип отчета
+	root xml.Name
+	// props - запрошенные свойства; allProps - все свойства
+	props    []xml.Name
+	allProps bool
+	// hrefs - ресурсы calendar-multiget
+	hrefs []string
+	// start, end - фильтр time-range calendar-query
+	start, end time.Time
+}
+
+// parseDAVRequest - разбор тела запроса; пустое тело означает allprop
+func parseDAVRequest(r io.Reader) (davRequest, error) {
+	var (
+		req   davRequest
+		stack []xml.Name
+	)
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return davRequest{}, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			var parent xml.Name
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			} else {
+				req.root = t.Name
+			}
+
+			switch {
+			case parent == elemProp:
+				req.props = append(req.props, t.Name)
+			case t.Name == elemAllProp || t.Name == elemPropName:
+				req.allProps = true
+			case t.Name == elemHref && req.root == reportMultiget:
+				var href string
+				if err := decoder.DecodeElement(&href, &t); err != nil {
+					return davRequest{}, err
+				}
+				req.hrefs = append(req.hrefs, strings.TrimSpace(href))
+				continue
+			case t.Name == elemTimeRange:
+				for _, attr := range t.Attr {
+					value, err := time.Parse(timeRangeLayout, attr.Value)
+					if err != nil {
+						return davRequest{}, err
+					}
+					switch attr.Name.Local {
+					case "start":
+						req.start = value
+					case "end":
+						req.end = value
+					}
+				}
+			}
+			stack = append(stack, t.Name)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if req.root.Local == "" || (len(req.props) == 0 && req.root.Local == "propfind") {
+		req.allProps = true
+	}
+	return req, nil
+}
+
+// resource - ресурс в ответе multistatus: путь и значения свойств в виде XML
+type resource struct {
+	href  string
+	props map[xml.Name]string
+}
+
+// rawProp - свойство с произвольным именем и готовым XML-содержимым
+type rawProp struct {
+	XMLName xml.Name
+	Inner   string `xml:",innerxml"`
+}
+
+// propstat - свойства с общим статусом
+type propstat struct {
+	Props  []rawProp `xml:"prop>x"`
+	Status string    `xml:"status"`
+}
+
+// davResponse - ответ для одного ресурса
+type davResponse struct {
+	Href      string     `xml:"href"`
+	Propstats []propstat `xml:"propstat"`
+	// Status - статус ресурса без свойств
+	Status string `xml:"status,omitempty"`
+}
+
+// multistatus - тело ответа 207 Multi-Status
+type multistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"response"`
+}
+
+// response - ответ по ресурсу: найденные свойства со статусом 200, отсутствующие - 404
+func (req davRequest) response(res resource) davResponse {
+	var found, missing []rawProp
+
+	if req.allProps {
+		for name, value := range res.props {
+			found = append(found, rawProp{XMLName: name, Inner: value})
+		}
+		sort.Slice(found, func(i, j int) bool {
+			if found[i].XMLName.Space != found[j].XMLName.Space {
+				return found[i].XMLName.Space < found[j].XMLName.Space
+			}
+			return found[i].XMLName.Local < found[j].XMLName.Local
+		})
+	} else {
+		for _, name := range req.props {
+			if value, ok := res.props[name]; ok {
+				found = append(found, rawProp{XMLName: name, Inner: value})
+			} else {
+				missing = append(missing, rawProp{XMLName: name})
+			}
+		}
+	}
+
+	resp := davResponse{Href: res.href}
+	if len(found) > 0 {
+		resp.Propstats = append(resp.Propstats, propstat{Props: found, Status: statusLine(http.StatusOK)})
+	}
+	if len(missing) > 0 {
+		resp.Propstats = append(resp.Propstats, propstat{Props: missing, Status: statusLine(http.StatusNotFound)})
+	}
+	return resp
+}
+
+// notFound - ответ по ресурсу, которого нет
+func notFound(href string) davResponse {
+	return davResponse{Href: href, Status: statusLine(http.StatusNotFound)}
+}
+
+// statusLine - строка статуса HTTP для propstat
+func statusLine(code int) string {
+	return "HTTP/1.1 " + strconv.Itoa(code) + " " + http.StatusText(code)
+}
+
+// writeMultistatus - запись ответа 207 Multi-Status
+func writeMultistatus(w http.ResponseWriter, log *zap.Logger, responses []davResponse) {
+	body, err := xml.Marshal(multistatus{Responses: responses})
+	if err != nil {
+		log.Error("Failed to encode multistatus response", zappretty.Field("error", err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		log.Error("Failed to write multistatus response", zappretty.Field("error", err))
+		return
+	}
+	if _, err := w.Write(body); err != nil {
+		log.Error("Failed to write multistatus response", zappretty.Field("error", err))
+	}
+}
+
+// escape - экранирование текста для вставки в XML
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// hrefXML - элемент DAV:href с путем
+func hrefXML(path string) string {
+	return `<href xmlns="DAV:">` + escape(path) + `</href>`
+}
@@ -0,0 +1,172 @@
+package digest_handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/internal/digest"
+	"calendar-server/internal/domain"
+	uc "calendar-server/internal/usecase/digest_usecase"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// Форматы предпросмотра сводки
+const (
+	FormatText = "text"
+	FormatHTML = "html"
+	FormatJSON = "json"
+)
+
+// DigestHandler - обработчик подписок на сводки и их предпросмотра
+type DigestHandler struct {
+	digestUseCase uc.DigestUseCaseContract
+	logger        *zap.Logger
+	errors        response.ErrorRenderer
+}
+
+// Option - функциональная опция DigestHandler
+type Option func(*DigestHandler)
+
+// WithErrorRenderer - формат ответов с ошибками
+func WithErrorRenderer(renderer response.ErrorRenderer) Option {
+	return func(h *DigestHandler) {
+		h.errors = renderer
+	}
+}
+
+// NewDigestHandler - конструктор обработчика сводок
+func NewDigestHandler(digestUseCase uc.DigestUseCaseContract, logger *zap.Logger, opts ...Option) *DigestHandler {
+	h := &DigestHandler{
+		digestUseCase: digestUseCase,
+		logger:        logger,
+		errors:        response.DefaultErrorRenderer(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// SaveDigest - метод создания или изменения подписки на сводку периода {period}
+func (h *DigestHandler) SaveDigest(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		UserID   string `json:"user_id"`
+		TimeZone string `json:"time_zone"`
+		SendAt   string `json:"send_at"`
+		Weekday  string `json:"weekday"`
+	}
+	if !h.decode(w, r, &request) {
+		return
+	}
+
+	subscription, err := h.digestUseCase.SaveSubscription(r.Context(), domain.DigestSubscription{
+		UserID:   request.UserID,
+		Period:   r.PathValue("period"),
+		TimeZone: request.TimeZone,
+		SendAt:   request.SendAt,
+		Weekday:  request.Weekday,
+	})
+	if err != nil {
+		h.logger.Error("Failed to save digest subscription", zappretty.Field("error", err))
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: subscription})
+}
+
+// ListDigests - метод получения подписок на сводки (?user_id=)
+func (h *DigestHandler) ListDigests(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.digestUseCase.ListSubscriptions(r.Context(), r.URL.Query().Get("user_id"))
+	if err != nil {
+		h.logger.Error("Failed to list digest subscriptions", zappretty.Field("error", err))
+		h.handleError(w, r, err)
+		return
+	}
+
+	if subscriptions == nil {
+		subscriptions = []domain.DigestSubscription{}
+	}
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: subscriptions})
+}
+
+// DeleteDigest - метод удаления подписки на сводку периода {period} (?user_id=)
+func (h *DigestHandler) DeleteDigest(w http.ResponseWriter, r *http.Request) {
+	period := r.PathValue("period")
+	if err := h.digestUseCase.DeleteSubscription(r.Context(), r.URL.Query().Get("user_id"), period); err != nil {
+		h.logger.Error("Failed to delete digest subscription",
+			zappretty.Field("error", err),
+			zappretty.Field("period", period),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: "digest deleted"})
+}
+
+// PreviewDigest - метод предпросмотра сводки периода {period} (?user_id=&date=&format=text|html|json)
+func (h *DigestHandler) PreviewDigest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = FormatText
+	}
+	if format != FormatText && format != FormatHTML && format != FormatJSON {
+		h.logger.Warn("Unknown digest preview format", zappretty.Field("format", format))
+		h.handleError(w, r, digest.InvalidSetting("format", format))
+		return
+	}
+
+	period := r.PathValue("period")
+	msg, err := h.digestUseCase.Preview(r.Context(), query.Get("user_id"), period, query.Get("date"))
+	if err != nil {
+		h.logger.Error("Failed to preview digest",
+			zappretty.Field("error", err),
+			zappretty.Field("period", period),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	var body string
+	switch format {
+	case FormatJSON:
+		response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: msg})
+		return
+	case FormatHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		body = msg.HTML
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		body = msg.Text
+	}
+	if _, err := io.WriteString(w, body); err != nil {
+		h.logger.Error("Failed to write digest preview", zappretty.Field("error", err))
+	}
+}
+
+// decode - проверка Content-Type и разбор JSON тела запроса
+func (h *DigestHandler) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Header.Get("Content-Type") != "application/json" {
+		h.logger.Warn("Unsupported media type")
+		h.handleError(w, r, errors.ErrUnsupportedMedia)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		h.logger.Warn("Invalid JSON format", zappretty.Field("error", err))
+		h.handleError(w, r, errors.ErrInvalidJSON)
+		return false
+	}
+	return true
+}
+
+// handleError - обработчик ошибок сводок
+func (h *DigestHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	h.errors.Render(w, r, h.logger, errors.Describe(err))
+}
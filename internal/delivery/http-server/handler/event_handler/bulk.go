@@ -0,0 +1,182 @@
+package event_handler
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"calendar-server/internal/delivery/http-server/bulk"
+	"calendar-server/internal/domain"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+)
+
+const (
+	// exportFlushEvents - количество событий между отправками данных клиенту при выгрузке
+	exportFlushEvents = 500
+	// exportWriteTimeout - время на отправку очередной порции выгрузки; продлевается после каждой порции,
+	// поэтому длинная выгрузка не обрывается общим WriteTimeout сервера
+	exportWriteTimeout = 30 * time.Second
+)
+
+// ExportEvents - потоковая выгрузка событий в CSV (*.csv) или JSON Lines (*.jsonl).
+// Путь с {id} выгружает события пользователя, без него - всех пользователей арендатора.
+// Необязательные параметры from и to (YYYY-MM-DD) ограничивают период.
+func (h *EventHandler) ExportEvents(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "EventHandler.ExportEvents")
+	defer span.End()
+
+	if _, ok := h.identity(w, r); !ok {
+		return
+	}
+
+	format, ok := bulk.FormatOf(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	filter := domain.ExportFilter{UserID: r.PathValue("id"), From: query.Get("from"), To: query.Get("to")}
+
+	h.log(ctx).Debug("Exporting events",
+		zappretty.Field("user_id", filter.UserID),
+		zappretty.Field("format", format),
+		zappretty.Field("from", filter.From),
+		zappretty.Field("to", filter.To),
+	)
+
+	controller := http.NewResponseController(w)
+	writer := format.NewWriter(w)
+	// Заголовки отправляются с первым событием, чтобы ошибки проверки и доступа вернулись обычным ответом
+	started := false
+	start := func() {
+		started = true
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="events.%s"`, format))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	count := 0
+	err := h.eventUseCase.ExportEvents(ctx, filter, func(event domain.Event) error {
+		if !started {
+			start()
+		}
+		if err := writer.Write(event); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvents == 0 {
+			return flushExport(writer, controller)
+		}
+		return nil
+	})
+	if err == nil {
+		if !started {
+			start()
+		}
+		err = flushExport(writer, controller)
+	}
+
+	if err != nil && !started {
+		h.log(ctx).Error("Failed to export events",
+			zappretty.Field("error", err),
+			zappretty.Field("user_id", filter.UserID),
+		)
+		h.handleCalendarError(w, r, err)
+		return
+	}
+	if err != nil {
+		h.log(ctx).Error("Export interrupted",
+			zappretty.Field("error", err),
+			zappretty.Field("user_id", filter.UserID),
+			zappretty.Field("exported", count),
+		)
+		// Статус уже отправлен: обрываем соединение, чтобы клиент не принял неполный файл за целый
+		panic(http.ErrAbortHandler)
+	}
+
+	h.log(ctx).Info("Events exported",
+		zappretty.Field("user_id", filter.UserID),
+		zappretty.Field("format", format),
+		zappretty.Field("count", count),
+	)
+}
+
+// flushExport отправляет накопленную часть выгрузки клиенту и продлевает срок записи
+func flushExport(writer bulk.Writer, controller *http.ResponseController) error {
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil && !stdErrors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if err := controller.Flush(); err != nil && !stdErrors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// ImportEvents - импорт событий из CSV (*.csv) или JSON Lines (*.jsonl).
+// Путь с {id} импортирует в календарь пользователя, без него - в календари всех пользователей
+// арендатора по колонке user_id. Параметры: mode (merge или replace), dry_run (проверка без записи),
+// map - имена колонок для полей события, например "title=Subject,date=Day".
+// Каждая строка проверяется use case; ошибки строк возвращаются в отчете и не прерывают импорт.
+func (h *EventHandler) ImportEvents(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "EventHandler.ImportEvents")
+	defer span.End()
+
+	if _, ok := h.identity(w, r); !ok {
+		return
+	}
+
+	format, ok := bulk.FormatOf(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	userID := r.PathValue("id")
+	query := r.URL.Query()
+
+	opts, err := importOptions(query)
+	if err != nil {
+		h.handleCalendarError(w, r, err)
+		return
+	}
+	mapping, err := bulk.ParseMapping(query.Get("map"))
+	if err != nil {
+		h.handleCalendarError(w, r, err)
+		return
+	}
+
+	candidates, err := format.Read(http.MaxBytesReader(w, r.Body, h.imports.MaxBytes), mapping)
+	if err != nil {
+		h.log(ctx).Warn("Failed to parse import file",
+			zappretty.Field("error", err),
+			zappretty.Field("format", format),
+		)
+		if tooLarge := importTooLarge(err); tooLarge != nil {
+			err = tooLarge
+		}
+		h.handleCalendarError(w, r, err)
+		return
+	}
+
+	h.log(ctx).Debug("Importing events",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("format", format),
+		zappretty.Field("rows", len(candidates)),
+	)
+
+	report, err := h.eventUseCase.ImportEvents(ctx, userID, candidates, opts)
+	if err != nil {
+		h.log(ctx).Error("Failed to import events",
+			zappretty.Field("error", err),
+			zappretty.Field("user_id", userID),
+		)
+		h.handleCalendarError(w, r, err)
+		return
+	}
+
+	h.writeResponse(w, Response{Result: report})
+}
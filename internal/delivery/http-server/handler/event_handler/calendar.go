@@ -0,0 +1,93 @@
+package event_handler
+
+import (
+	"net/http"
+	"time"
+
+	"calendar-server/internal/delivery/http-server/icalendar"
+	"calendar-server/pkg/ical"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+)
+
+// dateLayout - формат даты события
+const dateLayout = icalendar.DateLayout
+
+// CalendarExport - параметры выгрузки iCalendar
+type CalendarExport struct {
+	// PastDays, FutureDays - период по умолчанию относительно текущей даты, если from и to не заданы
+	PastDays   int
+	FutureDays int
+}
+
+// DefaultCalendarExport - квартал назад и год вперед
+func DefaultCalendarExport() CalendarExport {
+	return CalendarExport{PastDays: 90, FutureDays: 365}
+}
+
+// WithCalendarExport - период выгрузки iCalendar по умолчанию
+func WithCalendarExport(export CalendarExport) Option {
+	return func(h *EventHandler) {
+		h.export = export
+	}
+}
+
+// CalendarICS - выгрузка событий пользователя в формате iCalendar для подписки из календарных клиентов.
+// Период задается параметрами from и to (YYYY-MM-DD), его длину ограничивает use case.
+func (h *EventHandler) CalendarICS(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "EventHandler.CalendarICS")
+	defer span.End()
+
+	if _, ok := h.identity(w, r); !ok {
+		return
+	}
+
+	userID := r.PathValue("id")
+	now := time.Now()
+	from := r.URL.Query().Get("from")
+	if from == "" {
+		from = now.AddDate(0, 0, -h.export.PastDays).Format(dateLayout)
+	}
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = now.AddDate(0, 0, h.export.FutureDays).Format(dateLayout)
+	}
+
+	h.log(ctx).Debug("Exporting calendar",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("from", from),
+		zappretty.Field("to", to),
+	)
+
+	events, err := h.eventUseCase.GetEventsForRange(ctx, userID, from, to)
+	if err != nil {
+		h.log(ctx).Error("Failed to export calendar",
+			zappretty.Field("error", err),
+			zappretty.Field("user_id", userID),
+		)
+		h.handleCalendarError(w, r, err)
+		return
+	}
+
+	cal := ical.Calendar{ProdID: icalendar.ProdID, Name: userID, Method: "PUBLISH", Events: make([]ical.Event, 0, len(events))}
+	for _, event := range events {
+		item, ok := icalendar.ToICal(event, now)
+		if !ok {
+			h.log(ctx).Warn("Skipping event with malformed date or time", zappretty.Field("event_id", event.ID))
+			continue
+		}
+		cal.Events = append(cal.Events, item)
+	}
+
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Content-Disposition", `inline; filename="calendar.ics"`)
+	if err := ical.NewEncoder(w).Encode(cal); err != nil {
+		h.log(ctx).Error("Failed to write calendar", zappretty.Field("error", err))
+		return
+	}
+
+	h.log(ctx).Debug("Calendar exported",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("count", len(cal.Events)),
+	)
+}
@@ -1,75 +1,103 @@
 package event_handler
 
 import (
+	"calendar-server/internal/auth"
+	"calendar-server/internal/delivery/http-server/response"
 	"calendar-server/internal/domain"
 	"calendar-server/pkg/errors"
+	"context"
 	"encoding/json"
-	stdErrors "errors"
 	"net/http"
 
 	uc "calendar-server/internal/usecase/event_usecase"
+	"calendar-server/pkg/logger/ctxlog"
 	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
 
 	"go.uber.org/zap"
 )
 
 // Response - структура ответа
-type Response struct {
-	Result interface{} `json:"result,omitempty"`
-	Error  string      `json:"error,omitempty"`
-}
+type Response = response.Response
 
 // EventHandler - обработчик событий
 type EventHandler struct {
 	eventUseCase uc.EventUseCaseContract
 	logger       *zap.Logger
+	errors       response.ErrorRenderer
+	export       CalendarExport
+	imports      CalendarImport
+}
+
+// Option - функциональная опция EventHandler
+type Option func(*EventHandler)
+
+// WithErrorRenderer - формат ответов с ошибками
+func WithErrorRenderer(renderer response.ErrorRenderer) Option {
+	return func(h *EventHandler) {
+		h.errors = renderer
+	}
 }
 
 // NewEventHandler - конструктор обработчика событий
-func NewEventHandler(eventUseCase uc.EventUseCaseContract, logger *zap.Logger) *EventHandler {
-	return &EventHandler{
+func NewEventHandler(eventUseCase uc.EventUseCaseContract, logger *zap.Logger, opts ...Option) *EventHandler {
+	h := &EventHandler{
 		eventUseCase: eventUseCase,
 		logger:       logger,
+		errors:       response.DefaultErrorRenderer(),
+		export:       DefaultCalendarExport(),
+		imports:      DefaultCalendarImport(),
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 // CreateEvent - метод создания события
 func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracing.Start(r.Context(), "EventHandler.CreateEvent")
+	defer span.End()
 
-	h.logger.Debug("Creating event",
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	h.log(ctx).Debug("Creating event",
 		zappretty.Field("method", r.Method),
 		zappretty.Field("path", r.URL.Path),
 	)
 
 	if r.Header.Get("Content-Type") != "application/json" {
-		h.logger.Warn("Unsupported media type",
+		h.log(ctx).Warn("Unsupported media type",
 			zappretty.Field("content_type", r.Header.Get("Content-Type")),
 		)
-		h.writeError(w, errors.ErrUnsupportedMedia.Error(), http.StatusBadRequest)
+		h.handleCalendarError(w, r, errors.ErrUnsupportedMedia)
 		return
 	}
 
 	var event domain.Event
 	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
-		h.logger.Warn("Invalid JSON format",
+		h.log(ctx).Warn("Invalid JSON format",
 			zappretty.Field("error", err),
 		)
-		h.writeError(w, errors.ErrInvalidJSON.Error(), http.StatusBadRequest)
+		h.handleCalendarError(w, r, errors.ErrInvalidJSON)
 		return
 	}
+	event.UserID = identity.UserIDOr(event.UserID)
 
 	if err := h.eventUseCase.CreateEvent(ctx, event); err != nil {
-		h.logger.Error("Failed to create event",
+		h.log(ctx).Error("Failed to create event",
 			zappretty.Field("error", err),
 			zappretty.Field("event_id", event.ID),
 			zappretty.Field("user_id", event.UserID),
 		)
-		h.handleCalendarError(w, err)
+		h.handleCalendarError(w, r, err)
 		return
 	}
 
-	h.logger.Info("Event created successfully",
+	h.log(ctx).Info("Event created successfully",
 		zappretty.Field("event_id", event.ID),
 		zappretty.Field("user_id", event.UserID),
 	)
@@ -78,36 +106,43 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 
 // UpdateEvent - метод обновления события
 func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracing.Start(r.Context(), "EventHandler.UpdateEvent")
+	defer span.End()
 
-	h.logger.Debug("Updating event",
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	h.log(ctx).Debug("Updating event",
 		zappretty.Field("method", r.Method),
 		zappretty.Field("path", r.URL.Path),
 	)
 
 	if r.Header.Get("Content-Type") != "application/json" {
-		h.logger.Warn("Unsupported media type")
-		h.writeError(w, errors.ErrUnsupportedMedia.Error(), http.StatusBadRequest)
+		h.log(ctx).Warn("Unsupported media type")
+		h.handleCalendarError(w, r, errors.ErrUnsupportedMedia)
 		return
 	}
 
 	var event domain.Event
 	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
-		h.logger.Warn("Invalid JSON format", zappretty.Field("error", err))
-		h.writeError(w, errors.ErrInvalidJSON.Error(), http.StatusBadRequest)
+		h.log(ctx).Warn("Invalid JSON format", zappretty.Field("error", err))
+		h.handleCalendarError(w, r, errors.ErrInvalidJSON)
 		return
 	}
+	event.UserID = identity.UserIDOr(event.UserID)
 
 	if err := h.eventUseCase.UpdateEvent(ctx, event); err != nil {
-		h.logger.Error("Failed to update event",
+		h.log(ctx).Error("Failed to update event",
 			zappretty.Field("error", err),
 			zappretty.Field("event_id", event.ID),
 		)
-		h.handleCalendarError(w, err)
+		h.handleCalendarError(w, r, err)
 		return
 	}
 
-	h.logger.Info("Event updated successfully",
+	h.log(ctx).Info("Event updated successfully",
 		zappretty.Field("event_id", event.ID),
 	)
 	h.writeResponse(w, Response{Result: "event updated"})
@@ -115,16 +150,21 @@ func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 
 // DeleteEvent - метод удаления события
 func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracing.Start(r.Context(), "EventHandler.DeleteEvent")
+	defer span.End()
 
-	h.logger.Debug("Deleting event",
+	if _, ok := h.identity(w, r); !ok {
+		return
+	}
+
+	h.log(ctx).Debug("Deleting event",
 		zappretty.Field("method", r.Method),
 		zappretty.Field("path", r.URL.Path),
 	)
 
 	if r.Header.Get("Content-Type") != "application/json" {
-		h.logger.Warn("Unsupported media type")
-		h.writeError(w, errors.ErrUnsupportedMedia.Error(), http.StatusBadRequest)
+		h.log(ctx).Warn("Unsupported media type")
+		h.handleCalendarError(w, r, errors.ErrUnsupportedMedia)
 		return
 	}
 
@@ -132,21 +172,21 @@ func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 		ID string `json:"id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		h.logger.Warn("Invalid JSON format", zappretty.Field("error", err))
-		h.writeError(w, errors.ErrInvalidJSON.Error(), http.StatusBadRequest)
+		h.log(ctx).Warn("Invalid JSON format", zappretty.Field("error", err))
+		h.handleCalendarError(w, r, errors.ErrInvalidJSON)
 		return
 	}
 
 	if err := h.eventUseCase.DeleteEvent(ctx, request.ID); err != nil {
-		h.logger.Error("Failed to delete event",
+		h.log(ctx).Error("Failed to delete event",
 			zappretty.Field("error", err),
 			zappretty.Field("event_id", request.ID),
 		)
-		h.handleCalendarError(w, err)
+		h.handleCalendarError(w, r, err)
 		return
 	}
 
-	h.logger.Info("Event deleted successfully",
+	h.log(ctx).Info("Event deleted successfully",
 		zappretty.Field("event_id", request.ID),
 	)
 	h.writeResponse(w, Response{Result: "event deleted"})
@@ -154,12 +194,18 @@ func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 
 // EventsForDay - метод получения событий за день
 func (h *EventHandler) EventsForDay(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracing.Start(r.Context(), "EventHandler.EventsForDay")
+	defer span.End()
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
 
-	userID := r.URL.Query().Get("user_id")
+	userID := identity.UserIDOr(r.URL.Query().Get("user_id"))
 	date := r.URL.Query().Get("date")
 
-	h.logger.Debug("Getting events for day",
+	h.log(ctx).Debug("Getting events for day",
 		zappretty.Field("method", r.Method),
 		zappretty.Field("path", r.URL.Path),
 		zappretty.Field("user_id", userID),
@@ -167,23 +213,23 @@ func (h *EventHandler) EventsForDay(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if userID == "" || date == "" {
-		h.logger.Warn("Missing parameters for events for day")
-		h.writeError(w, errors.ErrMissingParameters.Error(), http.StatusBadRequest)
+		h.log(ctx).Warn("Missing parameters for events for day")
+		h.handleCalendarError(w, r, errors.ErrMissingParameters)
 		return
 	}
 
 	events, err := h.eventUseCase.GetEventsForDay(ctx, userID, date)
 	if err != nil {
-		h.logger.Error("Failed to get events for day",
+		h.log(ctx).Error("Failed to get events for day",
 			zappretty.Field("error", err),
 			zappretty.Field("user_id", userID),
 			zappretty.Field("date", date),
 		)
-		h.handleCalendarError(w, err)
+		h.handleCalendarError(w, r, err)
 		return
 	}
 
-	h.logger.Debug("Retrieved events for day",
+	h.log(ctx).Debug("Retrieved events for day",
 		zappretty.Field("user_id", userID),
 		zappretty.Field("date", date),
 		zappretty.Field("count", len(events)),
@@ -193,12 +239,18 @@ func (h *EventHandler) EventsForDay(w http.ResponseWriter, r *http.Request) {
 
 // EventsForWeek - метод получения событий за неделю
 func (h *EventHandler) EventsForWeek(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracing.Start(r.Context(), "EventHandler.EventsForWeek")
+	defer span.End()
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
 
-	userID := r.URL.Query().Get("user_id")
+	userID := identity.UserIDOr(r.URL.Query().Get("user_id"))
 	date := r.URL.Query().Get("date")
 
-	h.logger.Debug("Getting events for week",
+	h.log(ctx).Debug("Getting events for week",
 		zappretty.Field("method", r.Method),
 		zappretty.Field("path", r.URL.Path),
 		zappretty.Field("user_id", userID),
@@ -206,23 +258,23 @@ func (h *EventHandler) EventsForWeek(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if userID == "" || date == "" {
-		h.logger.Warn("Missing parameters for events for week")
-		h.writeError(w, errors.ErrMissingParameters.Error(), http.StatusBadRequest)
+		h.log(ctx).Warn("Missing parameters for events for week")
+		h.handleCalendarError(w, r, errors.ErrMissingParameters)
 		return
 	}
 
 	events, err := h.eventUseCase.GetEventsForWeek(ctx, userID, date)
 	if err != nil {
-		h.logger.Error("Failed to get events for week",
+		h.log(ctx).Error("Failed to get events for week",
 			zappretty.Field("error", err),
 			zappretty.Field("user_id", userID),
 			zappretty.Field("date", date),
 		)
-		h.handleCalendarError(w, err)
+		h.handleCalendarError(w, r, err)
 		return
 	}
 
-	h.logger.Debug("Retrieved events for week",
+	h.log(ctx).Debug("Retrieved events for week",
 		zappretty.Field("user_id", userID),
 		zappretty.Field("date", date),
 		zappretty.Field("count", len(events)),
@@ -232,12 +284,18 @@ func (h *EventHandler) EventsForWeek(w http.ResponseWriter, r *http.Request) {
 
 // EventsForMonth - метод получения событий за месяц
 func (h *EventHandler) EventsForMonth(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracing.Start(r.Context(), "EventHandler.EventsForMonth")
+	defer span.End()
 
-	userID := r.URL.Query().Get("user_id")
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	userID := identity.UserIDOr(r.URL.Query().Get("user_id"))
 	date := r.URL.Query().Get("date")
 
-	h.logger.Debug("Getting events for month",
+	h.log(ctx).Debug("Getting events for month",
 		zappretty.Field("method", r.Method),
 		zappretty.Field("path", r.URL.Path),
 		zappretty.Field("user_id", userID),
@@ -245,23 +303,23 @@ func (h *EventHandler) EventsForMonth(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if userID == "" || date == "" {
-		h.logger.Warn("Missing parameters for events for month")
-		h.writeError(w, errors.ErrMissingParameters.Error(), http.StatusBadRequest)
+		h.log(ctx).Warn("Missing parameters for events for month")
+		h.handleCalendarError(w, r, errors.ErrMissingParameters)
 		return
 	}
 
 	events, err := h.eventUseCase.GetEventsForMonth(ctx, userID, date)
 	if err != nil {
-		h.logger.Error("Failed to get events for month",
+		h.log(ctx).Error("Failed to get events for month",
 			zappretty.Field("error", err),
 			zappretty.Field("user_id", userID),
 			zappretty.Field("date", date),
 		)
-		h.handleCalendarError(w, err)
+		h.handleCalendarError(w, r, err)
 		return
 	}
 
-	h.logger.Debug("Retrieved events for month",
+	h.log(ctx).Debug("Retrieved events for month",
 		zappretty.Field("user_id", userID),
 		zappretty.Field("date", date),
 		zappretty.Field("count", len(events)),
@@ -269,42 +327,66 @@ func (h *EventHandler) EventsForMonth(w http.ResponseWriter, r *http.Request) {
 	h.writeResponse(w, Response{Result: events})
 }
 
-// handleCalendarError - обработчик ошибок календаря
-func (h *EventHandler) handleCalendarError(w http.ResponseWriter, err error) {
-	switch {
-	case stdErrors.Is(err, errors.ErrEventNotFound):
-		h.writeError(w, err.Error(), http.StatusServiceUnavailable)
+// Usage - метод получения текущего использования квот
+func (h *EventHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "EventHandler.Usage")
+	defer span.End()
 
-	case stdErrors.Is(err, errors.ErrInvalidDate),
-		stdErrors.Is(err, errors.ErrEmptyEventID),
-		stdErrors.Is(err, errors.ErrEmptyUserID),
-		stdErrors.Is(err, errors.ErrEmptyTitle):
-		h.writeError(w, err.Error(), http.StatusBadRequest)
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
 
-	case stdErrors.Is(err, errors.ErrEventConflict):
-		h.writeError(w, err.Error(), http.StatusConflict)
+	userID := identity.UserIDOr(r.URL.Query().Get("user_id"))
+	date := r.URL.Query().Get("date")
 
-	default:
-		h.writeError(w, "internal server error", http.StatusInternalServerError)
+	usage, err := h.eventUseCase.GetUsage(ctx, userID, date)
+	if err != nil {
+		h.log(ctx).Error("Failed to get quota usage",
+			zappretty.Field("error", err),
+			zappretty.Field("user_id", userID),
+		)
+		h.handleCalendarError(w, r, err)
+		return
 	}
+
+	h.writeResponse(w, Response{Result: usage})
 }
 
-// writeResponse - функция для записи ответа
-func (h *EventHandler) writeResponse(w http.ResponseWriter, response Response) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.logger.Error("Failed to encode response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// identity - получение личности вызывающего, установленной middleware аутентификации
+func (h *EventHandler) identity(w http.ResponseWriter, r *http.Request) (auth.Identity, bool) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		h.log(r.Context()).Warn("Request without authenticated identity",
+			zappretty.Field("path", r.URL.Path),
+		)
+		h.handleCalendarError(w, r, errors.ErrUnauthorized)
+		return auth.Identity{}, false
 	}
+	return identity, true
+}
+
+// log - логгер запроса с request_id и пользователем, либо логгер обработчика
+func (h *EventHandler) log(ctx context.Context) *zap.Logger {
+	return ctxlog.FromContext(ctx, h.logger)
 }
 
-// writeError - функция для записи ошибки
-func (h *EventHandler) writeError(w http.ResponseWriter, errorMsg string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	if err := json.NewEncoder(w).Encode(Response{Error: errorMsg}); err != nil {
-		h.logger.Error("Failed to encode error response", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// handleCalendarError - обработчик ошибок календаря.
+// Ошибка описывается кодом и статусом из pkg/errors и записывается как application/problem+json
+// либо в прежнем формате, где отсутствующее событие по-прежнему возвращает 503.
+func (h *EventHandler) handleCalendarError(w http.ResponseWriter, r *http.Request, err error) {
+	e := errors.Describe(err)
+
+	if h.errors.Legacy(r) && e.Code == errors.CodeEventNotFound {
+		legacy := *e
+		legacy.Status = http.StatusServiceUnavailable
+		e = &legacy
 	}
+
+	h.errors.Render(w, r, h.log(r.Context()), e)
+}
+
+// writeResponse - функция для записи ответа
+func (h *EventHandler) writeResponse(w http.ResponseWriter, resp Response) {
+	response.WriteJSON(w, h.logger, http.StatusOK, resp)
 }
@@ -2,13 +2,19 @@ package event_handler
 
 import (
 	"bytes"
+	"calendar-server/internal/auth"
+	"calendar-server/internal/delivery/http-server/response"
 	"calendar-server/internal/domain"
 	"calendar-server/pkg/errors"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	uc "calendar-server/internal/usecase/event_usecase"
 
@@ -77,6 +83,18 @@ func (m *mockEventUseCase) DeleteEvent(ctx context.Context, eventID string) erro
 	return nil
 }
 
+func (m *mockEventUseCase) GetEvent(ctx context.Context, eventID string) (domain.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Event{}, err
+	}
+
+	event, exists := m.events[eventID]
+	if !exists {
+		return domain.Event{}, errors.ErrEventNotFound
+	}
+	return event, nil
+}
+
 func (m *mockEventUseCase) GetEventsForDay(ctx context.Context, userID, date string) ([]domain.Event, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
@@ -119,10 +137,100 @@ func (m *mockEventUseCase) GetEventsForMonth(ctx context.Context, userID, date s
 	return result, nil
 }
 
+func (m *mockEventUseCase) GetEventsForRange(ctx context.Context, userID, from, to string) ([]domain.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []domain.Event
+	for _, event := range m.events {
+		if event.UserID == userID && event.Date >= from && event.Date <= to {
+			result = append(result, event)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+	return result, nil
+}
+
+func (m *mockEventUseCase) GetUsage(ctx context.Context, userID, date string) (domain.Usage, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Usage{}, err
+	}
+
+	usage := domain.Usage{UserID: userID, UserEvents: domain.UsageMeter{Limit: 2}}
+	for _, event := range m.events {
+		if event.UserID == userID {
+			usage.UserEvents.Used++
+		}
+	}
+	return usage, nil
+}
+
+func (m *mockEventUseCase) ImportEvents(ctx context.Context, userID string, candidates []domain.ImportCandidate, opts domain.ImportOptions) (domain.ImportReport, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ImportReport{}, err
+	}
+	if opts.Mode == "" {
+		opts.Mode = domain.ImportMerge
+	}
+	if opts.Mode != domain.ImportMerge && opts.Mode != domain.ImportReplace {
+		return domain.ImportReport{}, errors.ErrInvalidImportOption
+	}
+
+	report := domain.ImportReport{Mode: opts.Mode, DryRun: opts.DryRun}
+	for _, candidate := range candidates {
+		event := candidate.Event
+		if userID != "" {
+			event.UserID = userID
+		}
+		item := domain.ImportItem{Source: candidate.Source, EventID: event.ID, Status: domain.ImportCreated}
+		if candidate.Err != nil {
+			item.Status, item.Error = domain.ImportFailed, candidate.Err.Error()
+		} else if _, exists := m.events[event.ID]; exists {
+			item.Status = domain.ImportUpdated
+		}
+		if item.Status != domain.ImportFailed && !opts.DryRun {
+			m.events[event.ID] = event
+		}
+		report.Add(item)
+	}
+	return report, nil
+}
+
+func (m *mockEventUseCase) ExportEvents(ctx context.Context, filter domain.ExportFilter, fn func(domain.Event) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if filter.From != "" && filter.To != "" && filter.To < filter.From {
+		return errors.ErrInvalidRange
+	}
+
+	ids := make([]string, 0, len(m.events))
+	for id, event := range m.events {
+		if (filter.UserID == "" || event.UserID == filter.UserID) && filter.Match(event) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := fn(m.events[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupTestHandler создает обработчик в прежнем формате ошибок, на котором написана большая часть тестов
 func setupTestHandler() *EventHandler {
 	logger, _ := zap.NewDevelopment()
 	eventUseCase := newMockEventUseCase()
-	return NewEventHandler(eventUseCase, logger)
+	return NewEventHandler(eventUseCase, logger, WithErrorRenderer(response.ErrorRenderer{Format: response.FormatLegacy}))
+}
+
+// newAuthRequest создает запрос от имени аутентифицированного user-1
+func newAuthRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	return req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{UserID: "user-1"}))
 }
 
 func TestEventHandler_CreateEvent(t *testing.T) {
@@ -185,7 +293,7 @@ func TestEventHandler_CreateEvent(t *testing.T) {
 				body, _ = json.Marshal(v)
 			}
 
-			req := httptest.NewRequest("POST", "/create_event", bytes.NewBuffer(body))
+			req := newAuthRequest("POST", "/create_event", bytes.NewBuffer(body))
 			req.Header.Set("Content-Type", tt.contentType)
 			rr := httptest.NewRecorder()
 
@@ -202,7 +310,7 @@ func TestEventHandler_CreateEvent_ErrorCases(t *testing.T) {
 	handler := setupTestHandler()
 
 	// Test with wrong content type
-	req := httptest.NewRequest("POST", "/create_event", bytes.NewBufferString(`{}`))
+	req := newAuthRequest("POST", "/create_event", bytes.NewBufferString(`{}`))
 	req.Header.Set("Content-Type", "text/plain")
 	rr := httptest.NewRecorder()
 	handler.CreateEvent(rr, req)
@@ -211,7 +319,7 @@ func TestEventHandler_CreateEvent_ErrorCases(t *testing.T) {
 	}
 
 	// Test with invalid JSON
-	req = httptest.NewRequest("POST", "/create_event", bytes.NewBufferString(`invalid json`))
+	req = newAuthRequest("POST", "/create_event", bytes.NewBufferString(`invalid json`))
 	req.Header.Set("Content-Type", "application/json")
 	rr = httptest.NewRecorder()
 	handler.CreateEvent(rr, req)
@@ -231,7 +339,7 @@ func TestEventHandler_UpdateEvent(t *testing.T) {
 	}
 
 	body, _ := json.Marshal(createPayload)
-	req := httptest.NewRequest("POST", "/create_event", bytes.NewBuffer(body))
+	req := newAuthRequest("POST", "/create_event", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 	handler.CreateEvent(rr, req)
@@ -244,7 +352,7 @@ func TestEventHandler_UpdateEvent(t *testing.T) {
 	}
 
 	body, _ = json.Marshal(updatePayload)
-	req = httptest.NewRequest("POST", "/update_event", bytes.NewBuffer(body))
+	req = newAuthRequest("POST", "/update_event", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	rr = httptest.NewRecorder()
 
@@ -275,7 +383,7 @@ func TestEventHandler_UpdateEvent_NotFound(t *testing.T) {
 	}
 
 	body, _ := json.Marshal(payload)
-	req := httptest.NewRequest("POST", "/update_event", bytes.NewBuffer(body))
+	req := newAuthRequest("POST", "/update_event", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -297,7 +405,7 @@ func TestEventHandler_DeleteEvent(t *testing.T) {
 	}
 
 	body, _ := json.Marshal(createPayload)
-	req := httptest.NewRequest("POST", "/create_event", bytes.NewBuffer(body))
+	req := newAuthRequest("POST", "/create_event", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 	handler.CreateEvent(rr, req)
@@ -307,7 +415,7 @@ func TestEventHandler_DeleteEvent(t *testing.T) {
 	}
 
 	body, _ = json.Marshal(deletePayload)
-	req = httptest.NewRequest("POST", "/delete_event", bytes.NewBuffer(body))
+	req = newAuthRequest("POST", "/delete_event", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	rr = httptest.NewRecorder()
 
@@ -333,7 +441,7 @@ func TestEventHandler_DeleteEvent_NotFound(t *testing.T) {
 	payload := map[string]string{"id": "non-existent"}
 
 	body, _ := json.Marshal(payload)
-	req := httptest.NewRequest("POST", "/delete_event", bytes.NewBuffer(body))
+	req := newAuthRequest("POST", "/delete_event", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -354,13 +462,13 @@ func TestEventHandler_GetEvents(t *testing.T) {
 
 	for _, event := range events {
 		body, _ := json.Marshal(event)
-		req := httptest.NewRequest("POST", "/create_event", bytes.NewBuffer(body))
+		req := newAuthRequest("POST", "/create_event", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
 		rr := httptest.NewRecorder()
 		handler.CreateEvent(rr, req)
 	}
 
-	req := httptest.NewRequest("GET", "/events_for_day?user_id=user-1&date=2025-01-15", nil)
+	req := newAuthRequest("GET", "/events_for_day?user_id=user-1&date=2025-01-15", nil)
 	rr := httptest.NewRecorder()
 
 	handler.EventsForDay(rr, req)
@@ -393,9 +501,9 @@ func TestEventHandler_GetEvents_MissingParameters(t *testing.T) {
 		expectedStatus int
 	}{
 		{
-			name:           "missing user_id",
+			name:           "missing user_id falls back to caller",
 			url:            "/events_for_day?date=2025-01-15",
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusOK,
 		},
 		{
 			name:           "missing date",
@@ -411,7 +519,7 @@ func TestEventHandler_GetEvents_MissingParameters(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("GET", tt.url, nil)
+			req := newAuthRequest("GET", tt.url, nil)
 			rr := httptest.NewRecorder()
 
 			handler.EventsForDay(rr, req)
@@ -434,7 +542,7 @@ func TestEventHandler_ErrorHandling(t *testing.T) {
 	}
 
 	body, _ := json.Marshal(payload)
-	req := httptest.NewRequest("POST", "/update_event", bytes.NewBuffer(body))
+	req := newAuthRequest("POST", "/update_event", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	rr := httptest.NewRecorder()
 
@@ -448,7 +556,7 @@ func TestEventHandler_ErrorHandling(t *testing.T) {
 func TestEventHandler_GetEventsForWeek(t *testing.T) {
 	handler := setupTestHandler()
 
-	req := httptest.NewRequest("GET", "/events_for_week?user_id=user-1&date=2025-01-15", nil)
+	req := newAuthRequest("GET", "/events_for_week?user_id=user-1&date=2025-01-15", nil)
 	rr := httptest.NewRecorder()
 
 	handler.EventsForWeek(rr, req)
@@ -461,7 +569,7 @@ func TestEventHandler_GetEventsForWeek(t *testing.T) {
 func TestEventHandler_GetEventsForMonth(t *testing.T) {
 	handler := setupTestHandler()
 
-	req := httptest.NewRequest("GET", "/events_for_month?user_id=user-1&date=2025-01-15", nil)
+	req := newAuthRequest("GET", "/events_for_month?user_id=user-1&date=2025-01-15", nil)
 	rr := httptest.NewRecorder()
 
 	handler.EventsForMonth(rr, req)
@@ -470,3 +578,598 @@ func TestEventHandler_GetEventsForMonth(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", rr.Code)
 	}
 }
+
+func TestEventHandler_Unauthenticated(t *testing.T) {
+	handler := setupTestHandler()
+
+	req := httptest.NewRequest("GET", "/events_for_day?user_id=user-1&date=2025-01-15", nil)
+	rr := httptest.NewRecorder()
+
+	handler.EventsForDay(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without identity, got %d", rr.Code)
+	}
+}
+
+func TestEventHandler_CreateEvent_UserFromContext(t *testing.T) {
+	handler := setupTestHandler()
+
+	body := []byte(`{"id":"test-1","date":"2025-01-15","title":"Test Event"}`)
+	req := newAuthRequest("POST", "/create_event", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	handler.CreateEvent(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	created := handler.eventUseCase.(*mockEventUseCase).events["test-1"]
+	if created.UserID != "user-1" {
+		t.Errorf("Expected user_id taken from identity, got %q", created.UserID)
+	}
+}
+
+func TestEventHandler_Usage(t *testing.T) {
+	handler := setupTestHandler()
+
+	req := newAuthRequest("GET", "/usage", nil)
+	rr := httptest.NewRecorder()
+	handler.Usage(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var response struct {
+		Result domain.Usage `json:"result"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Result.UserID != "user-1" || response.Result.UserEvents.Limit != 2 {
+		t.Errorf("Unexpected usage %+v", response.Result)
+	}
+}
+
+func TestEventHandler_QuotaErrors(t *testing.T) {
+	handler := setupTestHandler()
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{
+			name:           "events limit",
+			err:            &errors.QuotaError{Limit: errors.LimitUserEvents, Max: 10, Current: 10},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "title length",
+			err:            &errors.QuotaError{Limit: errors.LimitTitleLength, Max: 10, Current: 11},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.handleCalendarError(rr, newAuthRequest("POST", "/create_event", nil), tt.err)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestEventHandler_ProblemDetails(t *testing.T) {
+	logger := zap.NewNop()
+	handler := NewEventHandler(newMockEventUseCase(), logger)
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		accept         string
+		legacy         bool
+		expectedStatus int
+		expectedCode   errors.Code
+		expectedField  string
+	}{
+		{
+			name:           "validation error with field",
+			method:         "POST",
+			target:         "/create_event",
+			body:           `{"id":"e1","date":"","title":"T"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   errors.CodeInvalidDate,
+			expectedField:  "date",
+		},
+		{
+			name:           "not found uses 404",
+			method:         "POST",
+			target:         "/update_event",
+			body:           `{"id":"missing","date":"2025-01-15","title":"T"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   errors.CodeEventNotFound,
+		},
+		{
+			name:           "legacy mode honours Accept",
+			method:         "POST",
+			target:         "/update_event",
+			body:           `{"id":"missing","date":"2025-01-15","title":"T"}`,
+			accept:         response.ProblemContentType,
+			legacy:         true,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   errors.CodeEventNotFound,
+		},
+		{
+			name:           "missing parameters",
+			method:         "GET",
+			target:         "/events_for_day",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   errors.CodeMissingParameters,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler
+			if tt.legacy {
+				h = NewEventHandler(newMockEventUseCase(), logger, WithErrorRenderer(response.ErrorRenderer{
+					Format:  response.FormatLegacy,
+					DocsURL: response.DefaultDocsURL,
+				}))
+			}
+
+			req := newAuthRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			switch tt.target {
+			case "/create_event":
+				h.CreateEvent(rr, req)
+			case "/update_event":
+				h.UpdateEvent(rr, req)
+			default:
+				h.EventsForDay(rr, req)
+			}
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != response.ProblemContentType {
+				t.Errorf("Expected %s, got %q", response.ProblemContentType, ct)
+			}
+
+			var problem response.Problem
+			if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to unmarshal problem: %v", err)
+			}
+			if problem.Code != tt.expectedCode || problem.Status != tt.expectedStatus {
+				t.Errorf("Unexpected problem %+v", problem)
+			}
+			if problem.Type != response.DefaultDocsURL+"#"+string(tt.expectedCode) {
+				t.Errorf("Unexpected problem type %q", problem.Type)
+			}
+			if problem.Instance != tt.target {
+				t.Errorf("Expected instance %q, got %q", tt.target, problem.Instance)
+			}
+			if tt.expectedField != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != tt.expectedField) {
+				t.Errorf("Expected field error for %q, got %+v", tt.expectedField, problem.Errors)
+			}
+		})
+	}
+}
+
+func TestEventHandler_LegacyErrorShape(t *testing.T) {
+	handler := setupTestHandler()
+
+	req := newAuthRequest("POST", "/create_event", bytes.NewBufferString(`{"id":"","date":"2025-01-15","title":"T"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.CreateEvent(rr, req)
+
+	var resp Response
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if resp.Error != errors.ErrEmptyEventID.Error() {
+		t.Errorf("Expected legacy error message, got %q", resp.Error)
+	}
+}
+
+func TestEventHandler_LocalizedErrors(t *testing.T) {
+	handler := NewEventHandler(newMockEventUseCase(), zap.NewNop())
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		legacy         bool
+		expectedLang   string
+		expectedTitle  string
+		expectedDetail string
+	}{
+		{
+			name:           "russian",
+			acceptLanguage: "ru-RU,ru;q=0.9,en;q=0.8",
+			expectedLang:   "ru",
+			expectedTitle:  "Событие не найдено",
+			expectedDetail: "событие не найдено",
+		},
+		{
+			name:           "default english",
+			expectedLang:   "en",
+			expectedTitle:  "Event not found",
+			expectedDetail: "event not found",
+		},
+		{
+			name:           "legacy shape is localized too",
+			acceptLanguage: "ru",
+			legacy:         true,
+			expectedLang:   "ru",
+			expectedDetail: "событие не найдено",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handler
+			if tt.legacy {
+				renderer := response.DefaultErrorRenderer()
+				renderer.Format = response.FormatLegacy
+				h = NewEventHandler(newMockEventUseCase(), zap.NewNop(), WithErrorRenderer(renderer))
+			}
+
+			req := newAuthRequest("POST", "/update_event", bytes.NewBufferString(`{"id":"missing","date":"2025-01-15","title":"T"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rr := httptest.NewRecorder()
+			h.UpdateEvent(rr, req)
+
+			if lang := rr.Header().Get("Content-Language"); lang != tt.expectedLang {
+				t.Errorf("Expected Content-Language %q, got %q", tt.expectedLang, lang)
+			}
+
+			if tt.legacy {
+				var resp Response
+				if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if resp.Error != tt.expectedDetail {
+					t.Errorf("Expected error %q, got %q", tt.expectedDetail, resp.Error)
+				}
+				return
+			}
+
+			var problem response.Problem
+			if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to unmarshal problem: %v", err)
+			}
+			if problem.Title != tt.expectedTitle || problem.Detail != tt.expectedDetail {
+				t.Errorf("Expected %q / %q, got %q / %q", tt.expectedTitle, tt.expectedDetail, problem.Title, problem.Detail)
+			}
+			if problem.Code != errors.CodeEventNotFound {
+				t.Errorf("Expected code to stay %s, got %s", errors.CodeEventNotFound, problem.Code)
+			}
+		})
+	}
+}
+
+func TestEventHandler_CalendarICS(t *testing.T) {
+	handler := setupTestHandler()
+
+	for _, body := range []string{
+		`{"id":"e1","date":"2025-01-15","title":"Holiday"}`,
+		`{"id":"e2","date":"2025-01-16","title":"Standup, daily","start_time":"09:30","end_time":"09:45"}`,
+		`{"id":"e3","date":"2025-03-01","title":"Outside range"}`,
+	} {
+		req := newAuthRequest("POST", "/create_event", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.CreateEvent(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Failed to create event: %s", rr.Body.String())
+		}
+	}
+
+	req := newAuthRequest("GET", "/users/user-1/calendar.ics?from=2025-01-01&to=2025-01-31", nil)
+	req.SetPathValue("id", "user-1")
+	rr := httptest.NewRecorder()
+	handler.CalendarICS(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/calendar; charset=utf-8" {
+		t.Errorf("Expected text/calendar, got %q", ct)
+	}
+
+	body := rr.Body.String()
+	for _, want := range []string{
+		"UID:e1\r\n",
+		"DTSTART;VALUE=DATE:20250115\r\nDTEND;VALUE=DATE:20250116\r\n",
+		"UID:e2\r\n",
+		"DTSTART:20250116T093000\r\nDTEND:20250116T094500\r\n",
+		`SUMMARY:Standup\, daily` + "\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected calendar to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "UID:e3") {
+		t.Error("Expected event outside the range to be excluded")
+	}
+}
+
+func TestEventHandler_ImportICS(t *testing.T) {
+	handler := setupTestHandler()
+	start := time.Now().AddDate(0, 0, 1)
+
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:weekly",
+		"SUMMARY:Planning",
+		"DTSTART:" + start.Format("20060102") + "T100000",
+		"DURATION:PT1H",
+		"RRULE:FREQ=WEEKLY;COUNT=3",
+		"EXDATE:" + start.AddDate(0, 0, 7).Format("20060102") + "T100000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:moscow",
+		"SUMMARY:Call",
+		"DTSTART;TZID=Europe/Moscow:20250115T120000",
+		"DTEND;TZID=Europe/Moscow:20250115T123000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:broken",
+		"SUMMARY:No start",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+
+	req := newAuthRequest("POST", "/users/user-1/calendar.ics?tz=UTC", strings.NewReader(calendar))
+	req.SetPathValue("id", "user-1")
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	rr := httptest.NewRecorder()
+	handler.ImportICS(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		Result domain.ImportReport `json:"result"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Result.Created != 3 || resp.Result.Failed != 1 {
+		t.Errorf("Expected 3 created and 1 failed, got %+v", resp.Result)
+	}
+
+	events := handler.eventUseCase.(*mockEventUseCase).events
+	expected := map[string]domain.Event{
+		"weekly-" + start.Format("20060102"):                   {Date: start.Format(dateLayout), StartTime: "10:00", EndTime: "11:00"},
+		"weekly-" + start.AddDate(0, 0, 14).Format("20060102"): {Date: start.AddDate(0, 0, 14).Format(dateLayout), StartTime: "10:00", EndTime: "11:00"},
+		"moscow": {Date: "2025-01-15", StartTime: "09:00", EndTime: "09:30"},
+	}
+	for id, want := range expected {
+		got, ok := events[id]
+		if !ok {
+			t.Errorf("Expected event %s to be imported", id)
+			continue
+		}
+		if got.Date != want.Date || got.StartTime != want.StartTime || got.EndTime != want.EndTime {
+			t.Errorf("Expected %s on %s %s-%s, got %s %s-%s", id, want.Date, want.StartTime, want.EndTime, got.Date, got.StartTime, got.EndTime)
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		target         string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		{name: "unsupported media type", target: "/users/user-1/calendar.ics", contentType: "application/json", body: calendar, expectedStatus: http.StatusBadRequest},
+		{name: "invalid dry_run", target: "/users/user-1/calendar.ics?dry_run=maybe", contentType: "text/calendar", body: calendar, expectedStatus: http.StatusBadRequest},
+		{name: "unknown time zone", target: "/users/user-1/calendar.ics?tz=Mars/Olympus", contentType: "text/calendar", body: calendar, expectedStatus: http.StatusBadRequest},
+		{name: "not a calendar", target: "/users/user-1/calendar.ics", contentType: "text/calendar", body: "hello", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newAuthRequest("POST", tc.target, strings.NewReader(tc.body))
+			req.SetPathValue("id", "user-1")
+			req.Header.Set("Content-Type", tc.contentType)
+			rr := httptest.NewRecorder()
+			handler.ImportICS(rr, req)
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestEventHandler_ExportEvents(t *testing.T) {
+	handler := setupTestHandler()
+	events := handler.eventUseCase.(*mockEventUseCase).events
+	events["b"] = domain.Event{ID: "b", UserID: "user-1", Date: "2025-01-02", Title: "=SUM(A1:A2)", StartTime: "10:00", EndTime: "11:00"}
+	events["a"] = domain.Event{ID: "a", UserID: "user-1", Date: "2025-01-01", Title: "Holiday, all day"}
+	events["c"] = domain.Event{ID: "c", UserID: "user-2", Date: "2025-01-03", Title: "Other user"}
+
+	testCases := []struct {
+		name           string
+		target         string
+		userID         string
+		expectedStatus int
+		expectedType   string
+		expectedBody   string
+	}{
+		{
+			name:           "user csv",
+			target:         "/users/user-1/events.csv",
+			userID:         "user-1",
+			expectedStatus: http.StatusOK,
+			expectedType:   "text/csv; charset=utf-8",
+			expectedBody: "id,user_id,date,title,start_time,end_time\n" +
+				"a,user-1,2025-01-01,\"Holiday, all day\",,\n" +
+				"b,user-1,2025-01-02,'=SUM(A1:A2),10:00,11:00\n",
+		},
+		{
+			name:           "tenant jsonl for period",
+			target:         "/admin/events.jsonl?from=2025-01-02",
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/jsonl; charset=utf-8",
+			expectedBody: `{"id":"b","user_id":"user-1","date":"2025-01-02","title":"=SUM(A1:A2)","start_time":"10:00","end_time":"11:00"}` + "\n" +
+				`{"id":"c","user_id":"user-2","date":"2025-01-03","title":"Other user"}` + "\n",
+		},
+		{
+			name:           "empty csv has header",
+			target:         "/users/user-3/events.csv",
+			userID:         "user-3",
+			expectedStatus: http.StatusOK,
+			expectedType:   "text/csv; charset=utf-8",
+			expectedBody:   "id,user_id,date,title,start_time,end_time\n",
+		},
+		{
+			name:           "invalid period",
+			target:         "/users/user-1/events.csv?from=2025-02-01&to=2025-01-01",
+			userID:         "user-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newAuthRequest("GET", tc.target, nil)
+			req.SetPathValue("id", tc.userID)
+			rr := httptest.NewRecorder()
+			handler.ExportEvents(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != tc.expectedType {
+				t.Errorf("Expected Content-Type %q, got %q", tc.expectedType, contentType)
+			}
+			if rr.Body.String() != tc.expectedBody {
+				t.Errorf("Expected body:\n%s\ngot:\n%s", tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestEventHandler_ImportEvents(t *testing.T) {
+	decodeReport := func(t *testing.T, rr *httptest.ResponseRecorder) domain.ImportReport {
+		t.Helper()
+		var resp struct {
+			Result domain.ImportReport `json:"result"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp.Result
+	}
+
+	t.Run("csv with column mapping", func(t *testing.T) {
+		handler := setupTestHandler()
+		body := "\ufeffKey,Day,Subject,Start,Notes\n" +
+			"standup,2025-01-15,Standup,09:30,ignored\n" +
+			"formula,2025-01-16,'=1+1,,\n" +
+			"short,2025-01-17\n"
+
+		req := newAuthRequest("POST", "/users/user-1/events.csv?map=id=Key,date=Day,title=Subject,start_time=Start", strings.NewReader(body))
+		req.SetPathValue("id", "user-1")
+		rr := httptest.NewRecorder()
+		handler.ImportEvents(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		report := decodeReport(t, rr)
+		if report.Created != 2 || report.Failed != 1 {
+			t.Errorf("Expected 2 created and 1 failed, got %+v", report)
+		}
+		if item := report.Items[2]; item.Source != "line 4" || item.Status != domain.ImportFailed {
+			t.Errorf("Expected failed row on line 4, got %+v", item)
+		}
+
+		events := handler.eventUseCase.(*mockEventUseCase).events
+		if event := events["standup"]; event.UserID != "user-1" || event.Title != "Standup" || event.StartTime != "09:30" {
+			t.Errorf("Expected mapped standup event, got %+v", event)
+		}
+		if title := events["formula"].Title; title != "=1+1" {
+			t.Errorf("Expected escaped formula to be restored, got %q", title)
+		}
+	})
+
+	t.Run("tenant jsonl", func(t *testing.T) {
+		handler := setupTestHandler()
+		body := `{"id":"a","user_id":"user-2","date":"2025-01-15","summary":"Review"}` + "\n\n" +
+			`{"id":"b","user_id":"user-3","date":20250115,"summary":"Bad date"}` + "\n" +
+			`not json` + "\n"
+
+		req := newAuthRequest("POST", "/admin/events.jsonl?map=title=summary", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.ImportEvents(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		report := decodeReport(t, rr)
+		if report.Created != 1 || report.Failed != 2 {
+			t.Errorf("Expected 1 created and 2 failed, got %+v", report)
+		}
+		if item := report.Items[2]; item.Source != "line 4" {
+			t.Errorf("Expected malformed line 4 in report, got %+v", item)
+		}
+		if event := handler.eventUseCase.(*mockEventUseCase).events["a"]; event.UserID != "user-2" || event.Title != "Review" {
+			t.Errorf("Expected event of user-2 from the file, got %+v", event)
+		}
+	})
+
+	testCases := []struct {
+		name           string
+		target         string
+		body           string
+		expectedStatus int
+		expectedCode   errors.Code
+	}{
+		{name: "unknown mapped field", target: "/users/user-1/events.csv?map=owner=Owner", body: "id\n", expectedStatus: http.StatusBadRequest, expectedCode: errors.CodeInvalidImportOption},
+		{name: "mapped column missing", target: "/users/user-1/events.csv?map=title=Subject", body: "id,title\n", expectedStatus: http.StatusBadRequest, expectedCode: errors.CodeInvalidImportOption},
+		{name: "missing header", target: "/users/user-1/events.csv", body: "", expectedStatus: http.StatusBadRequest, expectedCode: errors.CodeInvalidRecord},
+		{name: "malformed csv", target: "/users/user-1/events.csv", body: "id,title\n\"a,b\n", expectedStatus: http.StatusBadRequest, expectedCode: errors.CodeInvalidRecord},
+		{name: "too large", target: "/users/user-1/events.jsonl", body: strings.Repeat("\n", 6<<20), expectedStatus: http.StatusRequestEntityTooLarge, expectedCode: errors.CodeImportTooLarge},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewEventHandler(newMockEventUseCase(), zap.NewNop())
+			req := newAuthRequest("POST", tc.target, strings.NewReader(tc.body))
+			req.SetPathValue("id", "user-1")
+			rr := httptest.NewRecorder()
+			handler.ImportEvents(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), string(tc.expectedCode)) {
+				t.Errorf("Expected error code %s, got %s", tc.expectedCode, rr.Body.String())
+			}
+		})
+	}
+}
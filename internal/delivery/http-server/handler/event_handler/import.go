@@ -0,0 +1,148 @@
+package event_handler
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"calendar-server/internal/delivery/http-server/icalendar"
+	"calendar-server/internal/domain"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/ical"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+)
+
+// CalendarImport - параметры импорта iCalendar
+type CalendarImport struct {
+	// MaxBytes - максимальный размер файла
+	MaxBytes int64
+	// MaxOccurrences - максимальное количество повторений одного события в периоде импорта
+	MaxOccurrences int
+	// Location - часовой пояс, в который переводится время событий с TZID и UTC
+	Location *time.Location
+}
+
+// DefaultCalendarImport - файлы до 5 МиБ, до 500 повторений события, время в UTC
+func DefaultCalendarImport() CalendarImport {
+	return CalendarImport{MaxBytes: 5 << 20, MaxOccurrences: 500, Location: time.UTC}
+}
+
+// WithCalendarImport - параметры импорта iCalendar
+func WithCalendarImport(imports CalendarImport) Option {
+	return func(h *EventHandler) {
+		h.imports = imports
+	}
+}
+
+// calendarMediaType - тип содержимого импортируемого файла
+const calendarMediaType = "text/calendar"
+
+// ImportICS - импорт событий из файла iCalendar в календарь пользователя.
+// Параметры: mode (merge или replace), dry_run (проверка без записи), tz (часовой пояс событий).
+// Повторяющиеся события разворачиваются в период выгрузки по умолчанию.
+func (h *EventHandler) ImportICS(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "EventHandler.ImportICS")
+	defer span.End()
+
+	if _, ok := h.identity(w, r); !ok {
+		return
+	}
+
+	userID := r.PathValue("id")
+	query := r.URL.Query()
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != calendarMediaType {
+		h.log(ctx).Warn("Unsupported media type",
+			zappretty.Field("content_type", r.Header.Get("Content-Type")),
+		)
+		h.handleCalendarError(w, r, errors.ErrUnsupportedMedia)
+		return
+	}
+
+	opts, err := importOptions(query)
+	if err != nil {
+		h.handleCalendarError(w, r, err)
+		return
+	}
+
+	loc := h.imports.Location
+	if tz := query.Get("tz"); tz != "" {
+		var err error
+		if loc, err = ical.LoadLocation(tz); err != nil {
+			h.handleCalendarError(w, r, errors.ErrInvalidTimeZone)
+			return
+		}
+	}
+
+	parsed, err := ical.Parse(http.MaxBytesReader(w, r.Body, h.imports.MaxBytes))
+	if err != nil {
+		h.log(ctx).Warn("Failed to parse calendar", zappretty.Field("error", err))
+
+		if tooLarge := importTooLarge(err); tooLarge != nil {
+			h.handleCalendarError(w, r, tooLarge)
+			return
+		}
+		h.handleCalendarError(w, r, fmt.Errorf("%w: %v", errors.ErrInvalidCalendar, err))
+		return
+	}
+
+	now := time.Now()
+	converter := icalendar.Converter{
+		From:           now.AddDate(0, 0, -h.export.PastDays),
+		Until:          now.AddDate(0, 0, h.export.FutureDays),
+		MaxOccurrences: h.imports.MaxOccurrences,
+		Location:       loc,
+	}
+	candidates := converter.Candidates(parsed)
+
+	h.log(ctx).Debug("Importing calendar",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("components", len(parsed)),
+		zappretty.Field("events", len(candidates)),
+	)
+
+	report, err := h.eventUseCase.ImportEvents(ctx, userID, candidates, opts)
+	if err != nil {
+		h.log(ctx).Error("Failed to import calendar",
+			zappretty.Field("error", err),
+			zappretty.Field("user_id", userID),
+		)
+		h.handleCalendarError(w, r, err)
+		return
+	}
+
+	h.writeResponse(w, Response{Result: report})
+}
+
+// importOptions - параметры импорта из запроса: mode и dry_run
+func importOptions(query url.Values) (domain.ImportOptions, error) {
+	opts := domain.ImportOptions{Mode: query.Get("mode")}
+	if value := query.Get("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			return domain.ImportOptions{}, errors.WithParams(
+				fmt.Errorf("%w: dry_run must be true or false, got %s", errors.ErrInvalidImportOption, value),
+				errors.Params{errors.ParamRule: "dry_run", "dry_run": value},
+			)
+		}
+		opts.DryRun = dryRun
+	}
+	return opts, nil
+}
+
+// importTooLarge - ErrImportTooLarge, если чтение тела прервано ограничением размера; иначе nil
+func importTooLarge(err error) error {
+	var tooLarge *http.MaxBytesError
+	if !stdErrors.As(err, &tooLarge) {
+		return nil
+	}
+	return errors.WithParams(
+		fmt.Errorf("%w: at most %d bytes", errors.ErrImportTooLarge, tooLarge.Limit),
+		errors.Params{errors.ParamRule: "bytes", "max": tooLarge.Limit},
+	)
+}
@@ -0,0 +1,165 @@
+package health_handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// Статусы проверок
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// defaultTimeout - время на выполнение всех проверок готовности
+const defaultTimeout = 2 * time.Second
+
+// Check - проверка одного компонента
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// ComponentStatus - подробный результат проверки компонента
+type ComponentStatus struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Report - ответ эндпоинтов здоровья.
+// В кратком режиме Checks содержит статусы компонентов, в подробном - ComponentStatus.
+type Report struct {
+	Status string      `json:"status"`
+	Checks interface{} `json:"checks,omitempty"`
+}
+
+// HealthHandler - обработчик проверок живости и готовности
+type HealthHandler struct {
+	checks   []Check
+	draining func() bool
+	timeout  time.Duration
+	logger   *zap.Logger
+}
+
+// NewHealthHandler - конструктор обработчика; draining сообщает о начавшейся остановке сервера
+func NewHealthHandler(checks []Check, draining func() bool, logger *zap.Logger) *HealthHandler {
+	if draining == nil {
+		draining = func() bool { return false }
+	}
+	return &HealthHandler{
+		checks:   checks,
+		draining: draining,
+		timeout:  defaultTimeout,
+		logger:   logger,
+	}
+}
+
+// Liveness - процесс жив и обслуживает запросы
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	h.write(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+// Readiness - сервер готов принимать трафик: все компоненты доступны и остановка не начата.
+// Параметр verbose=1 включает ошибки и длительность каждой проверки.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	verbose := isVerbose(r)
+
+	results := h.run(r.Context())
+
+	status := StatusOK
+	if h.draining() {
+		status = StatusDraining
+	}
+	for name, result := range results {
+		if result.Status != StatusOK {
+			if status == StatusOK {
+				status = StatusFail
+			}
+			h.logger.Warn("Readiness check failed",
+				zappretty.Field("component", name),
+				zappretty.Field("error", result.Error),
+			)
+		}
+	}
+
+	report := Report{Status: status}
+	if verbose {
+		report.Checks = results
+	} else if len(results) > 0 {
+		short := make(map[string]string, len(results))
+		for name, result := range results {
+			short[name] = result.Status
+		}
+		report.Checks = short
+	}
+
+	code := http.StatusOK
+	if status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	h.write(w, code, report)
+}
+
+// run - параллельное выполнение проверок с общим таймаутом
+func (h *HealthHandler) run(ctx context.Context) map[string]ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]ComponentStatus, len(h.checks))
+	)
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			result := ComponentStatus{
+				Status:     StatusOK,
+				DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// write - запись отчета; кеширование запрещено, чтобы прокси не скрывали смену статуса
+func (h *HealthHandler) write(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.logger.Error("Failed to encode health report", zappretty.Field("error", err))
+	}
+}
+
+// isVerbose - признак подробного режима в параметре verbose
+func isVerbose(r *http.Request) bool {
+	switch r.URL.Query().Get("verbose") {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package health_handler
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestLiveness(t *testing.T) {
+	failing := []Check{{Name: "repository", Check: func(context.Context) error { return stdErrors.New("down") }}}
+	h := NewHealthHandler(failing, func() bool { return true }, zap.NewNop())
+
+	rr := httptest.NewRecorder()
+	h.Liveness(rr, httptest.NewRequest("GET", "/healthz", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected liveness to ignore readiness checks, got %d", rr.Code)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	ok := Check{Name: "repository", Check: func(context.Context) error { return nil }}
+	failing := Check{Name: "cache", Check: func(context.Context) error { return stdErrors.New("connection refused") }}
+
+	tests := []struct {
+		name       string
+		checks     []Check
+		draining   bool
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "ready", checks: []Check{ok}, wantCode: http.StatusOK, wantStatus: StatusOK},
+		{name: "no checks", wantCode: http.StatusOK, wantStatus: StatusOK},
+		{name: "component failed", checks: []Check{ok, failing}, wantCode: http.StatusServiceUnavailable, wantStatus: StatusFail},
+		{name: "draining", checks: []Check{ok}, draining: true, wantCode: http.StatusServiceUnavailable, wantStatus: StatusDraining},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(tt.checks, func() bool { return tt.draining }, zap.NewNop())
+
+			rr := httptest.NewRecorder()
+			h.Readiness(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("Expected status code %d, got %d", tt.wantCode, rr.Code)
+			}
+
+			var report struct {
+				Status string            `json:"status"`
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to unmarshal report: %v", err)
+			}
+			if report.Status != tt.wantStatus {
+				t.Errorf("Expected status %q, got %q", tt.wantStatus, report.Status)
+			}
+			for _, check := range tt.checks {
+				if _, found := report.Checks[check.Name]; !found {
+					t.Errorf("Expected component %q in report", check.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestReadinessVerbose(t *testing.T) {
+	failing := Check{Name: "repository", Check: func(context.Context) error { return stdErrors.New("connection refused") }}
+	h := NewHealthHandler([]Check{failing}, nil, zap.NewNop())
+
+	rr := httptest.NewRecorder()
+	h.Readiness(rr, httptest.NewRequest("GET", "/readyz?verbose=1", nil))
+
+	var report struct {
+		Status string                     `json:"status"`
+		Checks map[string]ComponentStatus `json:"checks"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to unmarshal report: %v", err)
+	}
+
+	component, found := report.Checks["repository"]
+	if !found {
+		t.Fatal("Expected repository component in verbose report")
+	}
+	if component.Status != StatusFail || component.Error != "connection refused" {
+		t.Errorf("Unexpected component status %+v", component)
+	}
+}
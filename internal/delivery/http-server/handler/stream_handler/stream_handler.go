@@ -0,0 +1,244 @@
+package stream_handler
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/internal/domain"
+	"calendar-server/internal/pubsub"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// ChangeFeed - источник изменений событий (реализуется EventUseCase)
+type ChangeFeed interface {
+	SubscribeChanges(ctx context.Context, userID, lastEventID string) (*pubsub.Subscription, error)
+}
+
+// Settings - параметры потока Server-Sent Events
+type Settings struct {
+	// Heartbeat - интервал комментариев, поддерживающих соединение через прокси
+	Heartbeat time.Duration
+	// Retry - пауза перед переподключением, которую сервер сообщает клиенту
+	Retry time.Duration
+	// WriteTimeout - время на отправку одного сообщения; соединение с зависшим клиентом закрывается
+	WriteTimeout time.Duration
+}
+
+// DefaultSettings - пинг раз в 15 секунд, переподключение через 3 секунды
+func DefaultSettings() Settings {
+	return Settings{Heartbeat: 15 * time.Second, Retry: 3 * time.Second, WriteTimeout: 10 * time.Second}
+}
+
+// StreamHandler - лента изменений событий в формате Server-Sent Events
+type StreamHandler struct {
+	feed     ChangeFeed
+	logger   *zap.Logger
+	errors   response.ErrorRenderer
+	settings Settings
+}
+
+// Option - функциональная опция StreamHandler
+type Option func(*StreamHandler)
+
+// WithErrorRenderer - формат ответов с ошибками
+func WithErrorRenderer(renderer response.ErrorRenderer) Option {
+	return func(h *StreamHandler) {
+		h.errors = renderer
+	}
+}
+
+// WithSettings - параметры потока
+func WithSettings(settings Settings) Option {
+	return func(h *StreamHandler) {
+		h.settings = settings
+	}
+}
+
+// NewStreamHandler - конструктор обработчика ленты изменений
+func NewStreamHandler(feed ChangeFeed, logger *zap.Logger, opts ...Option) *StreamHandler {
+	h := &StreamHandler{
+		feed:     feed,
+		logger:   logger,
+		errors:   response.DefaultErrorRenderer(),
+		settings: DefaultSettings(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// UserStream - изменения событий пользователя user_id (по умолчанию - вызывающего)
+func (h *StreamHandler) UserStream(w http.ResponseWriter, r *http.Request) {
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	h.stream(w, r, identity.UserIDOr(r.URL.Query().Get("user_id")))
+}
+
+// TenantStream - изменения событий всех пользователей арендатора
+func (h *StreamHandler) TenantStream(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.identity(w, r); !ok {
+		return
+	}
+	h.stream(w, r, "")
+}
+
+// stream - отправка изменений, пока клиент не отключится или подписка не будет закрыта.
+// Позиция продолжения берется из заголовка Last-Event-ID, который браузер отправляет
+// при переподключении, либо из параметра last_event_id для первого подключения.
+func (h *StreamHandler) stream(w http.ResponseWriter, r *http.Request, userID string) {
+	ctx, span := tracing.Start(r.Context(), "StreamHandler.Stream", tracing.String("user.id", userID))
+	defer span.End()
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	sub, err := h.feed.SubscribeChanges(ctx, userID, lastEventID)
+	if err != nil {
+		h.log(ctx).Warn("Failed to subscribe to changes",
+			zappretty.Field("error", err),
+			zappretty.Field("user_id", userID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	h.log(ctx).Info("Change stream opened",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("last_event_id", lastEventID),
+		zappretty.Field("reset", sub.Reset),
+	)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Отключает буферизацию ответа в nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(w)
+	// ReadTimeout сервера отменил бы контекст долгого запроса: снимаем срок чтения
+	if err := controller.SetReadDeadline(time.Time{}); err != nil && !stdErrors.Is(err, http.ErrNotSupported) {
+		h.log(ctx).Warn("Failed to clear read deadline", zappretty.Field("error", err))
+	}
+
+	out := &sseWriter{w: w, controller: controller, timeout: h.settings.WriteTimeout}
+	out.printf("retry: %d\n\n", h.settings.Retry.Milliseconds())
+	if sub.Reset {
+		// Пропущенные изменения недоступны: клиент должен перечитать события целиком
+		out.printf("event: reset\ndata: {}\n\n")
+	}
+
+	heartbeat := time.NewTicker(h.settings.Heartbeat)
+	defer heartbeat.Stop()
+
+	sent := 0
+	for out.err == nil {
+		out.flush()
+
+		select {
+		case <-ctx.Done():
+			h.log(ctx).Info("Change stream closed by client",
+				zappretty.Field("user_id", userID),
+				zappretty.Field("sent", sent),
+			)
+			return
+		case change, ok := <-sub.C():
+			if !ok {
+				// Клиент переподключится с Last-Event-ID и получит пропущенное из истории
+				h.log(ctx).Warn("Change stream closed by server",
+					zappretty.Field("user_id", userID),
+					zappretty.Field("reason", sub.Err()),
+					zappretty.Field("sent", sent),
+				)
+				return
+			}
+			out.change(change)
+			sent++
+		case <-heartbeat.C:
+			out.printf(": ping\n\n")
+		}
+	}
+
+	h.log(ctx).Info("Change stream write failed",
+		zappretty.Field("error", out.err),
+		zappretty.Field("user_id", userID),
+		zappretty.Field("sent", sent),
+	)
+}
+
+// sseWriter - запись сообщений Server-Sent Events; первая ошибка сохраняется, последующие записи пропускаются
+type sseWriter struct {
+	w          io.Writer
+	controller *http.ResponseController
+	timeout    time.Duration
+	err        error
+}
+
+// change - сообщение об изменении: id для Last-Event-ID, тип и изменение в JSON
+func (s *sseWriter) change(change domain.Change) {
+	data, err := json.Marshal(change)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.printf("id: %s\nevent: %s\ndata: %s\n\n", change.ID, change.Type, data)
+}
+
+// printf - запись фрагмента потока с продлением срока записи
+func (s *sseWriter) printf(format string, args ...any) {
+	if s.err != nil {
+		return
+	}
+	if err := s.controller.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil && !stdErrors.Is(err, http.ErrNotSupported) {
+		s.err = err
+		return
+	}
+	_, s.err = fmt.Fprintf(s.w, format, args...)
+}
+
+// flush - отправка записанного клиенту
+func (s *sseWriter) flush() {
+	if s.err != nil {
+		return
+	}
+	if err := s.controller.Flush(); err != nil && !stdErrors.Is(err, http.ErrNotSupported) {
+		s.err = err
+	}
+}
+
+// identity - получение личности вызывающего, установленной middleware аутентификации
+func (h *StreamHandler) identity(w http.ResponseWriter, r *http.Request) (auth.Identity, bool) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		h.handleError(w, r, errors.ErrUnauthorized)
+		return auth.Identity{}, false
+	}
+	return identity, true
+}
+
+// log - логгер запроса с request_id и пользователем, либо логгер обработчика
+func (h *StreamHandler) log(ctx context.Context) *zap.Logger {
+	return ctxlog.FromContext(ctx, h.logger)
+}
+
+// handleError - обработчик ошибок ленты изменений
+func (h *StreamHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	h.errors.Render(w, r, h.log(r.Context()), errors.Describe(err))
+}
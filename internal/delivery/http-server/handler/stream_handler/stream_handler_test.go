@@ -0,0 +1,191 @@
+package stream_handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/domain"
+	"calendar-server/internal/pubsub"
+	"calendar-server/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// mockChangeFeed - лента изменений без проверки доступа: пользователь user-2 запрещен
+type mockChangeFeed struct {
+	hub *pubsub.Hub
+}
+
+func (m *mockChangeFeed) SubscribeChanges(ctx context.Context, userID, lastEventID string) (*pubsub.Subscription, error) {
+	if userID == "user-2" {
+		return nil, errors.ErrForbidden
+	}
+	return m.hub.Subscribe(pubsub.Filter{UserID: userID}, lastEventID)
+}
+
+// message - сообщение Server-Sent Events
+type message struct {
+	id, event, data string
+}
+
+// setupTestServer - сервер с лентой изменений для пользователя user-1
+func setupTestServer(t *testing.T, hub *pubsub.Hub) *httptest.Server {
+	settings := Settings{Heartbeat: time.Hour, Retry: 2 * time.Second, WriteTimeout: time.Second}
+	h := NewStreamHandler(&mockChangeFeed{hub: hub}, zap.NewNop(), WithSettings(settings))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := auth.WithIdentity(r.Context(), auth.Identity{UserID: "user-1"})
+		h.UserStream(w, r.WithContext(ctx))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// openStream - подключение к ленте; сообщения читаются из возвращаемого канала
+func openStream(t *testing.T, url, lastEventID string) (*http.Response, <-chan message) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	messages := make(chan message, 16)
+	go func() {
+		defer close(messages)
+		scanner := bufio.NewScanner(resp.Body)
+		var msg message
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ": ")
+			switch field {
+			case "id":
+				msg.id = value
+			case "event":
+				msg.event = value
+			case "data":
+				msg.data = value
+			case "retry":
+				msg.event = "retry"
+				msg.data = value
+			case "":
+				if msg != (message{}) {
+					messages <- msg
+				}
+				msg = message{}
+			}
+		}
+	}()
+	return resp, messages
+}
+
+func next(t *testing.T, messages <-chan message) message {
+	select {
+	case msg, ok := <-messages:
+		if !ok {
+			t.Fatal("Expected message, stream closed")
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	return message{}
+}
+
+func TestStreamHandler_Changes(t *testing.T) {
+	hub := pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory)
+	server := setupTestServer(t, hub)
+
+	resp, messages := openStream(t, server.URL+"/events/stream", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", contentType)
+	}
+	if msg := next(t, messages); msg.event != "retry" || msg.data != "2000" {
+		t.Errorf("Expected retry 2000, got %+v", msg)
+	}
+
+	event := domain.Event{ID: "test-1", UserID: "user-1", Date: "2025-01-15", Title: "Title"}
+	created := hub.Publish(domain.Change{Type: domain.ChangeCreated, UserID: "user-1", EventID: "test-1", Event: &event})
+	hub.Publish(domain.Change{Type: domain.ChangeCreated, UserID: "user-3", EventID: "foreign"})
+	deleted := hub.Publish(domain.Change{Type: domain.ChangeDeleted, UserID: "user-1", EventID: "test-1"})
+
+	msg := next(t, messages)
+	if msg.id != created.ID || msg.event != domain.ChangeCreated {
+		t.Errorf("Expected created with id %s, got %+v", created.ID, msg)
+	}
+	var change domain.Change
+	if err := json.Unmarshal([]byte(msg.data), &change); err != nil {
+		t.Fatalf("Failed to unmarshal change: %v", err)
+	}
+	if change.EventID != "test-1" || change.Event == nil || change.Event.Title != "Title" {
+		t.Errorf("Expected created event in data, got %+v", change)
+	}
+
+	if msg := next(t, messages); msg.id != deleted.ID || msg.event != domain.ChangeDeleted {
+		t.Errorf("Expected deleted with id %s, got %+v", deleted.ID, msg)
+	}
+
+	// Переподключение с Last-Event-ID получает пропущенное изменение
+	_, resumed := openStream(t, server.URL+"/events/stream", created.ID)
+	next(t, resumed)
+	if msg := next(t, resumed); msg.id != deleted.ID {
+		t.Errorf("Expected replay of %s, got %+v", deleted.ID, msg)
+	}
+
+	// Позиция из прошлого запуска сервера требует перечитать данные
+	_, reset := openStream(t, server.URL+"/events/stream", "0-1")
+	next(t, reset)
+	if msg := next(t, reset); msg.event != "reset" {
+		t.Errorf("Expected reset event, got %+v", msg)
+	}
+
+	// Остановка шины завершает поток
+	hub.Close()
+	for range messages {
+	}
+}
+
+func TestStreamHandler_Errors(t *testing.T) {
+	hub := pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory)
+	server := setupTestServer(t, hub)
+
+	testCases := []struct {
+		name         string
+		query        string
+		lastEventID  string
+		expectedCode int
+	}{
+		{name: "another user", query: "?user_id=user-2", expectedCode: http.StatusForbidden},
+		{name: "invalid Last-Event-ID", lastEventID: "garbage", expectedCode: http.StatusBadRequest},
+		{name: "invalid last_event_id", query: "?last_event_id=garbage", expectedCode: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, _ := openStream(t, server.URL+"/events/stream"+tc.query, tc.lastEventID)
+			if resp.StatusCode != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	NewStreamHandler(&mockChangeFeed{hub: hub}, zap.NewNop()).UserStream(rr, httptest.NewRequest("GET", "/events/stream", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d without identity, got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
@@ -0,0 +1,203 @@
+package webhook_handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/internal/domain"
+	uc "calendar-server/internal/usecase/webhook_usecase"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// CreatedWebhook - вебхук вместе с ключом подписи, который показывается только при создании
+type CreatedWebhook struct {
+	domain.Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookHandler - обработчик управления вебхуками и журнала доставок
+type WebhookHandler struct {
+	webhookUseCase uc.WebhookUseCaseContract
+	logger         *zap.Logger
+	errors         response.ErrorRenderer
+}
+
+// Option - функциональная опция WebhookHandler
+type Option func(*WebhookHandler)
+
+// WithErrorRenderer - формат ответов с ошибками
+func WithErrorRenderer(renderer response.ErrorRenderer) Option {
+	return func(h *WebhookHandler) {
+		h.errors = renderer
+	}
+}
+
+// NewWebhookHandler - конструктор обработчика вебхуков
+func NewWebhookHandler(webhookUseCase uc.WebhookUseCaseContract, logger *zap.Logger, opts ...Option) *WebhookHandler {
+	h := &WebhookHandler{
+		webhookUseCase: webhookUseCase,
+		logger:         logger,
+		errors:         response.DefaultErrorRenderer(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// CreateWebhook - метод создания вебхука
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		URL          string   `json:"url"`
+		EventTypes   []string `json:"event_types"`
+		FilterUserID string   `json:"filter_user_id"`
+	}
+	if !h.decode(w, r, &request) {
+		return
+	}
+
+	hook, secret, err := h.webhookUseCase.CreateWebhook(r.Context(), domain.Webhook{
+		URL:          request.URL,
+		EventTypes:   request.EventTypes,
+		FilterUserID: request.FilterUserID,
+	})
+	if err != nil {
+		h.logger.Error("Failed to create webhook", zappretty.Field("error", err))
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: CreatedWebhook{Webhook: hook, Secret: secret}})
+}
+
+// ListWebhooks - метод получения списка вебхуков
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.webhookUseCase.ListWebhooks(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list webhooks", zappretty.Field("error", err))
+		h.handleError(w, r, err)
+		return
+	}
+
+	if hooks == nil {
+		hooks = []domain.Webhook{}
+	}
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: hooks})
+}
+
+// DeleteWebhook - метод удаления вебхука
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID := r.PathValue("id")
+	if err := h.webhookUseCase.DeleteWebhook(r.Context(), webhookID); err != nil {
+		h.logger.Error("Failed to delete webhook",
+			zappretty.Field("error", err),
+			zappretty.Field("webhook_id", webhookID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: "webhook deleted"})
+}
+
+// ListDeliveries - метод получения журнала доставок вебхука (?status=&limit=)
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit, ok := h.limit(w, r)
+	if !ok {
+		return
+	}
+
+	webhookID := r.PathValue("id")
+	deliveries, err := h.webhookUseCase.ListDeliveries(r.Context(), webhookID, r.URL.Query().Get("status"), limit)
+	if err != nil {
+		h.logger.Error("Failed to list webhook deliveries",
+			zappretty.Field("error", err),
+			zappretty.Field("webhook_id", webhookID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.writeDeliveries(w, deliveries)
+}
+
+// ListDeadLetters - метод получения недоставленных доставок всех вебхуков вызывающего (?limit=)
+func (h *WebhookHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit, ok := h.limit(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookUseCase.ListDeadLetters(r.Context(), limit)
+	if err != nil {
+		h.logger.Error("Failed to list webhook dead letters", zappretty.Field("error", err))
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.writeDeliveries(w, deliveries)
+}
+
+// RetryDelivery - метод повторной отправки недоставленной доставки
+func (h *WebhookHandler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	deliveryID := r.PathValue("id")
+	delivery, err := h.webhookUseCase.RetryDelivery(r.Context(), deliveryID)
+	if err != nil {
+		h.logger.Error("Failed to retry webhook delivery",
+			zappretty.Field("error", err),
+			zappretty.Field("delivery_id", deliveryID),
+		)
+		h.handleError(w, r, err)
+		return
+	}
+
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: delivery})
+}
+
+// limit - разбор параметра limit; без параметра используется значение по умолчанию
+func (h *WebhookHandler) limit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		h.logger.Warn("Invalid delivery limit", zappretty.Field("limit", raw))
+		h.handleError(w, r, uc.InvalidDeliveryFilter(raw))
+		return 0, false
+	}
+	return limit, true
+}
+
+// writeDeliveries - ответ со списком доставок; пустой список вместо null
+func (h *WebhookHandler) writeDeliveries(w http.ResponseWriter, deliveries []domain.WebhookDelivery) {
+	if deliveries == nil {
+		deliveries = []domain.WebhookDelivery{}
+	}
+	response.WriteJSON(w, h.logger, http.StatusOK, response.Response{Result: deliveries})
+}
+
+// decode - проверка Content-Type и разбор JSON тела запроса
+func (h *WebhookHandler) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Header.Get("Content-Type") != "application/json" {
+		h.logger.Warn("Unsupported media type")
+		h.handleError(w, r, errors.ErrUnsupportedMedia)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		h.logger.Warn("Invalid JSON format", zappretty.Field("error", err))
+		h.handleError(w, r, errors.ErrInvalidJSON)
+		return false
+	}
+	return true
+}
+
+// handleError - обработчик ошибок вебхуков
+func (h *WebhookHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	h.errors.Render(w, r, h.logger, errors.Describe(err))
+}
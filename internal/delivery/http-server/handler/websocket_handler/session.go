@@ -0,0 +1,386 @@
+package websocket_handler
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/internal/domain"
+	"calendar-server/internal/pubsub"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+	"calendar-server/pkg/websocket"
+)
+
+// Команды клиента
+const (
+	CommandSubscribe   = "subscribe"
+	CommandUnsubscribe = "unsubscribe"
+	CommandCreate      = "create"
+	CommandUpdate      = "update"
+	CommandDelete      = "delete"
+)
+
+// Сообщения сервера
+const (
+	MessageSubscribed   = "subscribed"
+	MessageUnsubscribed = "unsubscribed"
+	MessageResult       = "result"
+	MessageChange       = "change"
+	MessageReset        = "reset"
+	MessageError        = "error"
+)
+
+// closeGrace - ожидание подтверждения закрытия от клиента
+const closeGrace = time.Second
+
+// Message - сообщение протокола в обоих направлениях. ID команды клиента
+// повторяется в ответе на нее.
+type Message struct {
+	Type        string            `json:"type"`
+	ID          string            `json:"id,omitempty"`
+	UserIDs     []string          `json:"user_ids,omitempty"`
+	LastEventID string            `json:"last_event_id,omitempty"`
+	Event       *domain.Event     `json:"event,omitempty"`
+	EventID     string            `json:"event_id,omitempty"`
+	Change      *domain.Change    `json:"change,omitempty"`
+	Error       *response.Problem `json:"error,omitempty"`
+}
+
+// session - обслуживание одного соединения: чтение команд, очередь отправки и подписки
+type session struct {
+	h        *WebSocketHandler
+	conn     *websocket.Conn
+	r        *http.Request
+	identity auth.Identity
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	send chan Message
+	done chan struct{}
+
+	closeOnce sync.Once
+	code      int
+	reason    string
+
+	// subs - подписки по ID пользователя; изменяются только горутиной чтения
+	subs       map[string]*pubsub.Subscription
+	forwarders sync.WaitGroup
+	commands   int
+}
+
+// newSession - соединение после рукопожатия r
+func newSession(h *WebSocketHandler, conn *websocket.Conn, r *http.Request, identity auth.Identity) *session {
+	ctx, cancel := context.WithCancel(r.Context())
+	return &session{
+		h:        h,
+		conn:     conn,
+		r:        r,
+		identity: identity,
+		ctx:      ctx,
+		cancel:   cancel,
+		send:     make(chan Message, h.settings.SendBuffer),
+		done:     make(chan struct{}),
+		subs:     make(map[string]*pubsub.Subscription),
+	}
+}
+
+// run - обслуживание соединения до закрытия; возвращает код и причину закрытия
+func (s *session) run() (int, string) {
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		s.writeLoop()
+	}()
+
+	s.readLoop()
+
+	s.close(websocket.CloseNormal, "")
+	<-written
+	s.conn.Close()
+	s.cancel()
+	for _, sub := range s.subs {
+		sub.Close()
+	}
+	s.forwarders.Wait()
+	return s.code, s.reason
+}
+
+// close - начало закрытия соединения; учитывается только первый вызов
+func (s *session) close(code int, reason string) {
+	s.closeOnce.Do(func() {
+		s.code, s.reason = code, reason
+		close(s.done)
+	})
+}
+
+// enqueue - постановка сообщения в очередь отправки без ожидания. Клиент, не успевающий
+// читать, отключается с кодом 1013: так медленное соединение не задерживает рассылку.
+func (s *session) enqueue(msg Message) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+
+	select {
+	case s.send <- msg:
+		return true
+	default:
+		s.h.slow.Add(1)
+		s.close(websocket.CloseTryAgainLater, "client is too slow")
+		return false
+	}
+}
+
+// writeLoop - отправка сообщений из очереди и ping; после close отправляет закрытие
+func (s *session) writeLoop() {
+	ping := time.NewTicker(s.h.settings.PingInterval)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case <-s.done:
+			if err := s.conn.WriteClose(s.code, s.reason); err == nil {
+				// Клиент подтвердит закрытие, горутина чтения получит его или истечет срок
+				_ = s.conn.SetReadDeadline(time.Now().Add(closeGrace))
+			} else if !stdErrors.Is(err, websocket.ErrCloseSent) {
+				s.conn.Close()
+			}
+			return
+		case msg := <-s.send:
+			var data []byte
+			if data, err = json.Marshal(msg); err == nil {
+				err = s.conn.WriteMessage(websocket.TextMessage, data)
+			}
+		case <-ping.C:
+			err = s.conn.Ping(nil)
+		}
+
+		if err != nil {
+			s.h.log(s.ctx).Warn("WebSocket write failed", zappretty.Field("error", err))
+			s.close(websocket.CloseInternalError, "write failed")
+			// Прерывает чтение: соединение больше не пригодно
+			s.conn.Close()
+			return
+		}
+	}
+}
+
+// readLoop - чтение и последовательное выполнение команд. Следующая команда не читается,
+// пока не выполнена предыдущая, поэтому клиент не может обогнать сервер.
+func (s *session) readLoop() {
+	for {
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if stdErrors.As(err, &closeErr) {
+				s.close(closeErr.Code, closeErr.Reason)
+			} else {
+				s.close(websocket.CloseGoingAway, err.Error())
+			}
+			return
+		}
+
+		var msg Message
+		if messageType != websocket.TextMessage || json.Unmarshal(data, &msg) != nil {
+			s.reply(Message{}, errors.ErrInvalidJSON)
+			continue
+		}
+		s.commands++
+		s.dispatch(msg)
+	}
+}
+
+// dispatch - выполнение команды клиента с ответом на нее
+func (s *session) dispatch(msg Message) {
+	ctx, span := tracing.Start(s.ctx, "WebSocketHandler.Command",
+		tracing.String("websocket.command", msg.Type),
+	)
+	var err error
+	defer span.EndErr(&err)
+
+	switch msg.Type {
+	case CommandSubscribe:
+		err = s.subscribe(ctx, msg)
+	case CommandUnsubscribe:
+		s.unsubscribe(msg)
+	case CommandCreate, CommandUpdate, CommandDelete:
+		err = s.modify(ctx, msg)
+	default:
+		err = errors.WithParams(
+			fmt.Errorf("%w: %q", errors.ErrUnknownCommand, msg.Type),
+			errors.Params{"type": msg.Type},
+		)
+	}
+
+	if err != nil {
+		s.h.log(ctx).Warn("WebSocket command failed",
+			zappretty.Field("error", err),
+			zappretty.Field("command", msg.Type),
+		)
+		s.reply(msg, err)
+	}
+}
+
+// subscribe - подписка на изменения пользователей; без user_ids - на изменения вызывающего.
+// Подписка выполняется целиком: при ошибке ни один пользователь не добавляется.
+func (s *session) subscribe(ctx context.Context, msg Message) error {
+	userIDs := msg.UserIDs
+	if len(userIDs) == 0 {
+		userIDs = []string{s.identity.UserID}
+	}
+
+	var added []string
+	for _, userID := range userIDs {
+		if userID == "" {
+			return errors.ErrEmptyUserID
+		}
+		if _, ok := s.subs[userID]; !ok && !slices.Contains(added, userID) {
+			added = append(added, userID)
+		}
+	}
+	if limit := s.h.settings.MaxSubscriptions; len(s.subs)+len(added) > limit {
+		return errors.WithParams(
+			fmt.Errorf("%w: at most %d", errors.ErrTooManySubscriptions, limit),
+			errors.Params{"max": limit},
+		)
+	}
+
+	subs := make([]*pubsub.Subscription, 0, len(added))
+	for _, userID := range added {
+		sub, err := s.h.events.SubscribeChanges(ctx, userID, msg.LastEventID)
+		if err != nil {
+			for _, sub := range subs {
+				sub.Close()
+			}
+			return err
+		}
+		subs = append(subs, sub)
+	}
+
+	var reset []string
+	for i, sub := range subs {
+		s.subs[added[i]] = sub
+		if sub.Reset {
+			reset = append(reset, added[i])
+		}
+	}
+
+	s.enqueue(Message{Type: MessageSubscribed, ID: msg.ID, UserIDs: userIDs})
+	if len(reset) > 0 {
+		// Пропущенные изменения недоступны: клиент должен перечитать события этих пользователей
+		s.enqueue(Message{Type: MessageReset, UserIDs: reset})
+	}
+	// Изменения отправляются после подтверждения подписки
+	for _, sub := range subs {
+		s.forwarders.Add(1)
+		go s.forward(sub)
+	}
+
+	s.h.log(ctx).Info("WebSocket subscribed to changes",
+		zappretty.Field("user_ids", userIDs),
+		zappretty.Field("last_event_id", msg.LastEventID),
+	)
+	return nil
+}
+
+// unsubscribe - отписка от пользователей; без user_ids - от всех
+func (s *session) unsubscribe(msg Message) {
+	userIDs := msg.UserIDs
+	if len(userIDs) == 0 {
+		for userID := range s.subs {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	for _, userID := range userIDs {
+		if sub, ok := s.subs[userID]; ok {
+			sub.Close()
+			delete(s.subs, userID)
+		}
+	}
+	s.enqueue(Message{Type: MessageUnsubscribed, ID: msg.ID, UserIDs: userIDs})
+}
+
+// forward - пересылка изменений подписки в очередь отправки
+func (s *session) forward(sub *pubsub.Subscription) {
+	defer s.forwarders.Done()
+
+	for change := range sub.C() {
+		if !s.enqueue(Message{Type: MessageChange, Change: &change}) {
+			return
+		}
+	}
+
+	switch err := sub.Err(); {
+	case stdErrors.Is(err, pubsub.ErrSlowSubscriber):
+		s.h.slow.Add(1)
+		s.close(websocket.CloseTryAgainLater, "client is too slow")
+	case stdErrors.Is(err, pubsub.ErrClosed):
+		s.close(websocket.CloseGoingAway, "server shutting down")
+	}
+}
+
+// modify - команды create, update и delete; требуют области events:write
+// и расходуют бюджет изменений клиента
+func (s *session) modify(ctx context.Context, msg Message) error {
+	if !s.identity.HasScope(auth.ScopeEventsWrite) {
+		return errors.ErrInsufficientScope
+	}
+	if s.h.limiter != nil {
+		if retryAfter, ok := s.h.limiter.AllowWrite(s.r); !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			return errors.WithParams(
+				fmt.Errorf("%w: retry in %ds", errors.ErrRateLimited, seconds),
+				errors.Params{"retry_after": seconds},
+			)
+		}
+	}
+
+	if msg.Type == CommandDelete {
+		if msg.EventID == "" {
+			return errors.ErrMissingParameters
+		}
+		if err := s.h.events.DeleteEvent(ctx, msg.EventID); err != nil {
+			return err
+		}
+		s.enqueue(Message{Type: MessageResult, ID: msg.ID, EventID: msg.EventID})
+		return nil
+	}
+
+	if msg.Event == nil {
+		return errors.ErrMissingParameters
+	}
+	event := *msg.Event
+	event.UserID = s.identity.UserIDOr(event.UserID)
+
+	var err error
+	if msg.Type == CommandCreate {
+		err = s.h.events.CreateEvent(ctx, event)
+	} else {
+		err = s.h.events.UpdateEvent(ctx, event)
+	}
+	if err != nil {
+		return err
+	}
+	s.enqueue(Message{Type: MessageResult, ID: msg.ID, Event: &event})
+	return nil
+}
+
+// reply - ответ на команду ошибкой в формате RFC 7807 на языке рукопожатия
+func (s *session) reply(msg Message, err error) {
+	problem := s.h.errors.Problem(s.r, errors.Describe(err))
+	s.enqueue(Message{Type: MessageError, ID: msg.ID, Error: &problem})
+}
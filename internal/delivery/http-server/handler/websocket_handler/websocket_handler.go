@@ -0,0 +1,256 @@
+package websocket_handler
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/internal/domain"
+	"calendar-server/internal/pubsub"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/metrics"
+	"calendar-server/pkg/tracing"
+	"calendar-server/pkg/websocket"
+
+	"go.uber.org/zap"
+)
+
+// Protocol - подпротокол API календаря поверх WebSocket
+const Protocol = "calendar.v1"
+
+// EventService - операции с событиями, доступные через WebSocket (реализуется EventUseCase)
+type EventService interface {
+	CreateEvent(ctx context.Context, event domain.Event) error
+	UpdateEvent(ctx context.Context, event domain.Event) error
+	DeleteEvent(ctx context.Context, eventID string) error
+	SubscribeChanges(ctx context.Context, userID, lastEventID string) (*pubsub.Subscription, error)
+}
+
+// CommandLimiter - ограничение частоты команд изменения (реализуется middleware.RateLimiter)
+type CommandLimiter interface {
+	AllowWrite(r *http.Request) (time.Duration, bool)
+}
+
+// Settings - параметры соединений WebSocket
+type Settings struct {
+	// PingInterval - интервал ping; соединение, не приславшее ни одного кадра за два интервала, закрывается
+	PingInterval time.Duration
+	// WriteTimeout - время на отправку одного сообщения
+	WriteTimeout time.Duration
+	// SendBuffer - сообщения в очереди отправки; клиент, переполнивший очередь, отключается
+	SendBuffer int
+	// MaxMessageSize - максимальный размер сообщения клиента в байтах
+	MaxMessageSize int64
+	// MaxSubscriptions - максимальное количество пользователей, на изменения которых подписано соединение
+	MaxSubscriptions int
+}
+
+// DefaultSettings - ping раз в 30 секунд, очередь на 256 сообщений, до 50 подписок
+func DefaultSettings() Settings {
+	return Settings{
+		PingInterval:     30 * time.Second,
+		WriteTimeout:     10 * time.Second,
+		SendBuffer:       256,
+		MaxMessageSize:   websocket.DefaultMaxMessageSize,
+		MaxSubscriptions: 50,
+	}
+}
+
+// WebSocketHandler - подписка на изменения нескольких календарей и команды изменения событий
+// через одно соединение WebSocket
+type WebSocketHandler struct {
+	events   EventService
+	logger   *zap.Logger
+	errors   response.ErrorRenderer
+	settings Settings
+	// allowOrigin - проверка заголовка Origin; nil разрешает любой источник
+	allowOrigin func(origin string) bool
+	limiter     CommandLimiter
+
+	mu       sync.Mutex
+	sessions map[*session]struct{}
+	closed   bool
+
+	slow atomic.Uint64
+}
+
+// Option - функциональная опция WebSocketHandler
+type Option func(*WebSocketHandler)
+
+// WithErrorRenderer - формат ошибок рукопожатия и команд
+func WithErrorRenderer(renderer response.ErrorRenderer) Option {
+	return func(h *WebSocketHandler) {
+		h.errors = renderer
+	}
+}
+
+// WithSettings - параметры соединений
+func WithSettings(settings Settings) Option {
+	return func(h *WebSocketHandler) {
+		h.settings = settings
+	}
+}
+
+// WithOriginCheck - проверка источника рукопожатия: браузер отправляет WebSocket с любого сайта,
+// и политика CORS на него не распространяется
+func WithOriginCheck(allow func(origin string) bool) Option {
+	return func(h *WebSocketHandler) {
+		h.allowOrigin = allow
+	}
+}
+
+// WithCommandLimiter - команды изменения расходуют бюджет изменений клиента
+func WithCommandLimiter(limiter CommandLimiter) Option {
+	return func(h *WebSocketHandler) {
+		h.limiter = limiter
+	}
+}
+
+// NewWebSocketHandler - конструктор обработчика WebSocket
+func NewWebSocketHandler(events EventService, logger *zap.Logger, opts ...Option) *WebSocketHandler {
+	h := &WebSocketHandler{
+		events:   events,
+		logger:   logger,
+		errors:   response.DefaultErrorRenderer(),
+		settings: DefaultSettings(),
+		sessions: make(map[*session]struct{}),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// RegisterMetrics - метрики соединений
+func (h *WebSocketHandler) RegisterMetrics(registry *metrics.Registry) {
+	registry.NewGaugeFunc("calendar_websocket_connections", "Number of open WebSocket connections.", func() float64 {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return float64(len(h.sessions))
+	})
+	registry.NewCounterFunc("calendar_websocket_slow_disconnects_total", "WebSocket clients disconnected because their send queue overflowed.", func() float64 {
+		return float64(h.slow.Load())
+	})
+}
+
+// Serve - рукопожатие и обслуживание соединения до его закрытия.
+// Аутентификация выполняется middleware по заголовку запроса рукопожатия.
+func (h *WebSocketHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "WebSocketHandler.Serve")
+	defer span.End()
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	if origin := r.Header.Get("Origin"); !h.originAllowed(r, origin) {
+		h.log(ctx).Warn("WebSocket origin rejected", zappretty.Field("origin", origin))
+		h.handleError(w, r, errors.WithParams(
+			fmt.Errorf("%w: %s", errors.ErrOriginNotAllowed, origin),
+			errors.Params{"origin": origin},
+		))
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		Protocols: []string{Protocol},
+		Config: websocket.Config{
+			MaxMessageSize: h.settings.MaxMessageSize,
+			ReadTimeout:    2 * h.settings.PingInterval,
+			WriteTimeout:   h.settings.WriteTimeout,
+		},
+	}
+	conn, err := upgrader.Upgrade(w, r)
+	if err != nil {
+		h.log(ctx).Warn("WebSocket handshake failed", zappretty.Field("error", err))
+		if stdErrors.Is(err, websocket.ErrBadHandshake) {
+			err = fmt.Errorf("%w: %v", errors.ErrInvalidHandshake, err)
+		}
+		h.handleError(w, r, err)
+		return
+	}
+
+	s := newSession(h, conn, r.WithContext(ctx), identity)
+	if !h.track(s) {
+		s.close(websocket.CloseGoingAway, "server shutting down")
+	}
+	defer h.untrack(s)
+
+	h.log(ctx).Info("WebSocket connection opened", zappretty.Field("remote_addr", r.RemoteAddr))
+	code, reason := s.run()
+	h.log(ctx).Info("WebSocket connection closed",
+		zappretty.Field("code", code),
+		zappretty.Field("reason", reason),
+		zappretty.Field("commands", s.commands),
+	)
+}
+
+// Shutdown - закрытие всех соединений с кодом 1001; http.Server не отслеживает их при остановке
+func (h *WebSocketHandler) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for s := range h.sessions {
+		s.close(websocket.CloseGoingAway, "server shutting down")
+	}
+}
+
+// track - регистрация соединения; false, если обработчик уже остановлен
+func (h *WebSocketHandler) track(s *session) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sessions[s] = struct{}{}
+	return !h.closed
+}
+
+// untrack - удаление закрытого соединения
+func (h *WebSocketHandler) untrack(s *session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.sessions, s)
+}
+
+// originAllowed - клиенты вне браузера не отправляют Origin; страницы с того же хоста
+// разрешены всегда, остальные источники - по проверке WithOriginCheck
+func (h *WebSocketHandler) originAllowed(r *http.Request, origin string) bool {
+	if origin == "" || h.allowOrigin == nil {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return h.allowOrigin(origin)
+}
+
+// identity - получение личности вызывающего, установленной middleware аутентификации
+func (h *WebSocketHandler) identity(w http.ResponseWriter, r *http.Request) (auth.Identity, bool) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		h.handleError(w, r, errors.ErrUnauthorized)
+		return auth.Identity{}, false
+	}
+	return identity, true
+}
+
+// log - логгер запроса с request_id и пользователем, либо логгер обработчика
+func (h *WebSocketHandler) log(ctx context.Context) *zap.Logger {
+	return ctxlog.FromContext(ctx, h.logger)
+}
+
+// handleError - ответ на ошибку рукопожатия
+func (h *WebSocketHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	h.errors.Render(w, r, h.log(r.Context()), errors.Describe(err))
+}
@@ -0,0 +1,432 @@
+package websocket_handler
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/domain"
+	"calendar-server/internal/pubsub"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/websocket"
+
+	"go.uber.org/zap"
+)
+
+// mockEventService - события публикуются в шину без хранилища; пользователь user-2 запрещен
+type mockEventService struct {
+	hub *pubsub.Hub
+}
+
+func (m *mockEventService) CreateEvent(ctx context.Context, event domain.Event) error {
+	if event.UserID == "user-2" {
+		return errors.ErrForbidden
+	}
+	m.hub.Publish(domain.Change{Type: domain.ChangeCreated, UserID: event.UserID, EventID: event.ID, Event: &event})
+	return nil
+}
+
+func (m *mockEventService) UpdateEvent(ctx context.Context, event domain.Event) error {
+	m.hub.Publish(domain.Change{Type: domain.ChangeUpdated, UserID: event.UserID, EventID: event.ID, Event: &event})
+	return nil
+}
+
+func (m *mockEventService) DeleteEvent(ctx context.Context, eventID string) error {
+	if eventID == "missing" {
+		return errors.ErrEventNotFound
+	}
+	m.hub.Publish(domain.Change{Type: domain.ChangeDeleted, UserID: "user-1", EventID: eventID})
+	return nil
+}
+
+func (m *mockEventService) SubscribeChanges(ctx context.Context, userID, lastEventID string) (*pubsub.Subscription, error) {
+	if userID == "user-2" {
+		return nil, errors.ErrForbidden
+	}
+	return m.hub.Subscribe(pubsub.Filter{UserID: userID}, lastEventID)
+}
+
+// mockLimiter - разрешает allowed команд изменения
+type mockLimiter struct {
+	allowed int
+}
+
+func (m *mockLimiter) AllowWrite(r *http.Request) (time.Duration, bool) {
+	if m.allowed == 0 {
+		return 1500 * time.Millisecond, false
+	}
+	m.allowed--
+	return 0, true
+}
+
+// setupTestServer - сервер WebSocket для пользователя user-1; параметр scopes ограничивает области токена
+func setupTestServer(t *testing.T, hub *pubsub.Hub, opts ...Option) (*WebSocketHandler, string) {
+	settings := DefaultSettings()
+	settings.PingInterval = time.Second
+	opts = append([]Option{WithSettings(settings)}, opts...)
+	h := NewWebSocketHandler(&mockEventService{hub: hub}, zap.NewNop(), opts...)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := auth.Identity{UserID: "user-1"}
+		if scopes := r.URL.Query().Get("scopes"); scopes != "" {
+			identity.Scopes = strings.Split(scopes, ",")
+		}
+		h.Serve(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+	}))
+	t.Cleanup(server.Close)
+	return h, "ws" + strings.TrimPrefix(server.URL, "http") + "/events/ws"
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	header := http.Header{}
+	header.Set("Sec-WebSocket-Protocol", Protocol)
+	conn, _, err := websocket.Dial(ctx, url, header, websocket.Config{ReadTimeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, msg Message) {
+	data, _ := json.Marshal(msg)
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		t.Fatalf("Failed to send %s: %v", msg.Type, err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) Message {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to receive message: %v", err)
+	}
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("Failed to decode message %s: %v", data, err)
+	}
+	return msg
+}
+
+// closeCode - код закрытия, полученный от сервера после пропуска оставшихся сообщений
+func closeCode(t *testing.T, conn *websocket.Conn) int {
+	for {
+		_, _, err := conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		if stdErrors.As(err, &closeErr) {
+			return closeErr.Code
+		}
+		if err != nil {
+			t.Fatalf("Expected close message, got %v", err)
+		}
+	}
+}
+
+func TestWebSocketHandler_Subscribe(t *testing.T) {
+	hub := pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory)
+	_, url := setupTestServer(t, hub)
+	conn := dial(t, url)
+
+	if conn.Subprotocol() != Protocol {
+		t.Errorf("Expected subprotocol %s, got %q", Protocol, conn.Subprotocol())
+	}
+
+	send(t, conn, Message{Type: CommandSubscribe, ID: "1", UserIDs: []string{"user-1", "user-3"}})
+	if msg := receive(t, conn); msg.Type != MessageSubscribed || msg.ID != "1" || len(msg.UserIDs) != 2 {
+		t.Fatalf("Expected subscribed ack for two users, got %+v", msg)
+	}
+
+	hub.Publish(domain.Change{Type: domain.ChangeCreated, UserID: "user-4", EventID: "ignored"})
+	hub.Publish(domain.Change{Type: domain.ChangeCreated, UserID: "user-3", EventID: "1"})
+	msg := receive(t, conn)
+	if msg.Type != MessageChange || msg.Change == nil || msg.Change.UserID != "user-3" || msg.Change.EventID != "1" {
+		t.Fatalf("Expected change of user-3, got %+v", msg)
+	}
+
+	send(t, conn, Message{Type: CommandUnsubscribe, ID: "2", UserIDs: []string{"user-3"}})
+	if msg := receive(t, conn); msg.Type != MessageUnsubscribed || msg.ID != "2" {
+		t.Fatalf("Expected unsubscribed ack, got %+v", msg)
+	}
+	hub.Publish(domain.Change{Type: domain.ChangeCreated, UserID: "user-3", EventID: "2"})
+	hub.Publish(domain.Change{Type: domain.ChangeCreated, UserID: "user-1", EventID: "3"})
+	if msg := receive(t, conn); msg.Change == nil || msg.Change.EventID != "3" {
+		t.Errorf("Expected only changes of user-1 after unsubscribe, got %+v", msg)
+	}
+
+	// Клиент, пропустивший изменения вне истории, получает reset
+	send(t, conn, Message{Type: CommandSubscribe, ID: "3", UserIDs: []string{"user-5"}, LastEventID: "old-1"})
+	if msg := receive(t, conn); msg.Type != MessageSubscribed {
+		t.Fatalf("Expected subscribed ack, got %+v", msg)
+	}
+	if msg := receive(t, conn); msg.Type != MessageReset || len(msg.UserIDs) != 1 || msg.UserIDs[0] != "user-5" {
+		t.Errorf("Expected reset for user-5, got %+v", msg)
+	}
+}
+
+func TestWebSocketHandler_Commands(t *testing.T) {
+	hub := pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory)
+	_, url := setupTestServer(t, hub)
+	conn := dial(t, url)
+
+	send(t, conn, Message{Type: CommandSubscribe, ID: "sub"})
+	receive(t, conn)
+
+	event := &domain.Event{ID: "e1", Date: "2024-01-15", Title: "Standup"}
+	send(t, conn, Message{Type: CommandCreate, ID: "c1", Event: event})
+
+	// Изменение публикуется до ответа на команду, поэтому порядок этих сообщений не гарантирован
+	result, change := receivePair(t, conn)
+	if result.ID != "c1" || result.Event == nil || result.Event.UserID != "user-1" {
+		t.Errorf("Expected create result with caller's user ID, got %+v", result)
+	}
+	if change.Change == nil || change.Change.Type != domain.ChangeCreated {
+		t.Errorf("Expected created change, got %+v", change)
+	}
+
+	send(t, conn, Message{Type: CommandDelete, ID: "d1", EventID: "e1"})
+	result, change = receivePair(t, conn)
+	if result.ID != "d1" || result.EventID != "e1" {
+		t.Errorf("Expected delete result, got %+v", result)
+	}
+	if change.Change == nil || change.Change.Type != domain.ChangeDeleted {
+		t.Errorf("Expected deleted change, got %+v", change)
+	}
+}
+
+// receivePair - результат команды и вызванное ею изменение в любом порядке
+func receivePair(t *testing.T, conn *websocket.Conn) (result, change Message) {
+	for i := 0; i < 2; i++ {
+		switch msg := receive(t, conn); msg.Type {
+		case MessageResult:
+			result = msg
+		case MessageChange:
+			change = msg
+		default:
+			t.Fatalf("Expected result or change, got %+v", msg)
+		}
+	}
+	return result, change
+}
+
+func TestWebSocketHandler_CommandErrors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		query        string
+		limiter      *mockLimiter
+		msg          Message
+		expectedCode errors.Code
+	}{
+		{
+			name:         "forbidden subscription",
+			msg:          Message{Type: CommandSubscribe, UserIDs: []string{"user-1", "user-2"}},
+			expectedCode: errors.CodeForbidden,
+		},
+		{
+			name:         "too many subscriptions",
+			msg:          Message{Type: CommandSubscribe, UserIDs: []string{"a", "b", "c"}},
+			expectedCode: errors.CodeTooManySubscriptions,
+		},
+		{
+			name:         "unknown command",
+			msg:          Message{Type: "rename"},
+			expectedCode: errors.CodeUnknownCommand,
+		},
+		{
+			name:         "missing event",
+			msg:          Message{Type: CommandCreate},
+			expectedCode: errors.CodeMissingParameters,
+		},
+		{
+			name:         "use case error",
+			msg:          Message{Type: CommandDelete, EventID: "missing"},
+			expectedCode: errors.CodeEventNotFound,
+		},
+		{
+			name:         "read-only token",
+			query:        "?scopes=" + auth.ScopeEventsRead,
+			msg:          Message{Type: CommandDelete, EventID: "e1"},
+			expectedCode: errors.CodeInsufficientScope,
+		},
+		{
+			name:         "rate limited",
+			limiter:      &mockLimiter{},
+			msg:          Message{Type: CommandDelete, EventID: "e1"},
+			expectedCode: errors.CodeRateLimited,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hub := pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory)
+			settings := DefaultSettings()
+			settings.PingInterval = time.Second
+			settings.MaxSubscriptions = 2
+			opts := []Option{WithSettings(settings)}
+			if tc.limiter != nil {
+				opts = append(opts, WithCommandLimiter(tc.limiter))
+			}
+			_, url := setupTestServer(t, hub, opts...)
+			conn := dial(t, url+tc.query)
+
+			tc.msg.ID = "cmd"
+			send(t, conn, tc.msg)
+			msg := receive(t, conn)
+			if msg.Type != MessageError || msg.ID != "cmd" || msg.Error == nil {
+				t.Fatalf("Expected error reply, got %+v", msg)
+			}
+			if msg.Error.Code != tc.expectedCode {
+				t.Errorf("Expected code %s, got %s", tc.expectedCode, msg.Error.Code)
+			}
+
+			// Ошибка команды не закрывает соединение
+			send(t, conn, Message{Type: CommandUnsubscribe, ID: "next"})
+			if msg := receive(t, conn); msg.Type != MessageUnsubscribed {
+				t.Errorf("Expected connection to stay open, got %+v", msg)
+			}
+		})
+	}
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		_, url := setupTestServer(t, pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory))
+		conn := dial(t, url)
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("{")); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		if msg := receive(t, conn); msg.Error == nil || msg.Error.Code != errors.CodeInvalidJSON {
+			t.Errorf("Expected invalid_json error, got %+v", msg)
+		}
+	})
+}
+
+func TestWebSocketHandler_Handshake(t *testing.T) {
+	hub := pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory)
+	_, url := setupTestServer(t, hub, WithOriginCheck(func(origin string) bool {
+		return origin == "https://board.example.com"
+	}))
+	httpURL := "http" + strings.TrimPrefix(url, "ws")
+
+	testCases := []struct {
+		name           string
+		origin         string
+		expectedStatus int
+		expectedCode   errors.Code
+	}{
+		{name: "foreign origin", origin: "https://evil.example.com", expectedStatus: http.StatusForbidden, expectedCode: errors.CodeOriginNotAllowed},
+		{name: "plain request", expectedStatus: http.StatusBadRequest, expectedCode: errors.CodeInvalidHandshake},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", httpURL, nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, resp.StatusCode)
+			}
+			var problem struct {
+				Code errors.Code `json:"code"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil || problem.Code != tc.expectedCode {
+				t.Errorf("Expected code %s, got %s (%v)", tc.expectedCode, problem.Code, err)
+			}
+		})
+	}
+
+	// Разрешенный источник и страница с того же хоста
+	for _, origin := range []string{"https://board.example.com", strings.TrimSuffix(httpURL, "/events/ws")} {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		header := http.Header{}
+		header.Set("Origin", origin)
+		conn, _, err := websocket.Dial(ctx, url, header, websocket.Config{})
+		cancel()
+		if err != nil {
+			t.Errorf("Expected origin %s to be allowed, got %v", origin, err)
+			continue
+		}
+		conn.Close()
+	}
+}
+
+func TestWebSocketHandler_Close(t *testing.T) {
+	t.Run("slow client", func(t *testing.T) {
+		hub := pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory)
+		settings := DefaultSettings()
+		settings.PingInterval = time.Second
+		settings.SendBuffer = 2
+		h, url := setupTestServer(t, hub, WithSettings(settings))
+		conn := dial(t, url)
+
+		send(t, conn, Message{Type: CommandSubscribe})
+		receive(t, conn)
+
+		// Клиент не читает, пока сервер не заполнит сокет и очередь отправки
+		title := strings.Repeat("x", 16<<10)
+		for i := 0; i < 1000; i++ {
+			hub.Publish(domain.Change{Type: domain.ChangeCreated, UserID: "user-1", Event: &domain.Event{Title: title}})
+		}
+
+		if code := closeCode(t, conn); code != websocket.CloseTryAgainLater {
+			t.Errorf("Expected close code %d, got %d", websocket.CloseTryAgainLater, code)
+		}
+		if h.slow.Load() == 0 {
+			t.Error("Expected slow disconnect to be counted")
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		h, url := setupTestServer(t, pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory))
+		conn := dial(t, url)
+
+		send(t, conn, Message{Type: CommandSubscribe})
+		receive(t, conn)
+
+		h.Shutdown()
+		if code := closeCode(t, conn); code != websocket.CloseGoingAway {
+			t.Errorf("Expected close code %d, got %d", websocket.CloseGoingAway, code)
+		}
+	})
+
+	t.Run("client close", func(t *testing.T) {
+		h, url := setupTestServer(t, pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory))
+		conn := dial(t, url)
+
+		send(t, conn, Message{Type: CommandSubscribe})
+		receive(t, conn)
+
+		if err := conn.WriteClose(websocket.CloseNormal, ""); err != nil {
+			t.Fatalf("Failed to write close: %v", err)
+		}
+		if code := closeCode(t, conn); code != websocket.CloseNormal {
+			t.Errorf("Expected close confirmation %d, got %d", websocket.CloseNormal, code)
+		}
+
+		// Соединение удаляется из учета после завершения обработчика
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			h.mu.Lock()
+			open := len(h.sessions)
+			h.mu.Unlock()
+			if open == 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected session to be released, %d open", open)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+// Package icalendar - преобразование событий календаря в iCalendar и обратно
+// для выгрузки, импорта и CalDAV.
+package icalendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/ical"
+)
+
+// ProdID - идентификатор сервера в выгружаемых календарях
+const ProdID = "-//calendar-server//calendar-server//EN"
+
+const (
+	// DateLayout - формат даты события
+	DateLayout = "2006-01-02"
+	// TimeLayout - формат времени события
+	TimeLayout = "15:04"
+	// maxUID - UID длиннее этого заменяется хешем в идентификаторе события
+	maxUID = 64
+)
+
+// idPattern - UID, не подходящие под шаблон идентификатора, заменяются хешем
+var idPattern = regexp.MustCompile(tenant.DefaultIDPattern)
+
+// ToICal - событие календаря; время события не привязано к часовому поясу
+func ToICal(event domain.Event, stamp time.Time) (ical.Event, bool) {
+	date, err := time.Parse(DateLayout, event.Date)
+	if err != nil {
+		return ical.Event{}, false
+	}
+
+	item := ical.Event{
+		UID:     event.ID,
+		Summary: event.Title,
+		Start:   date,
+		AllDay:  event.AllDay(),
+		Stamp:   stamp,
+	}
+	if item.AllDay {
+		return item, true
+	}
+
+	item.Floating = true
+	if item.Start, err = time.Parse(DateLayout+" "+TimeLayout, event.Date+" "+event.StartTime); err != nil {
+		return ical.Event{}, false
+	}
+	if event.EndTime != "" {
+		if item.End, err = time.Parse(DateLayout+" "+TimeLayout, event.Date+" "+event.EndTime); err != nil {
+			return ical.Event{}, false
+		}
+	}
+	return item, true
+}
+
+// EventID - идентификатор события по UID
+func EventID(uid string) string {
+	if len(uid) <= maxUID && idPattern.MatchString(uid) {
+		return uid
+	}
+	sum := sha256.Sum256([]byte(uid))
+	return "ics-" + hex.EncodeToString(sum[:])[:32]
+}
+
+// Converter - перевод VEVENT в события календаря
+type Converter struct {
+	// From, Until - период, в который разворачиваются повторения
+	From, Until time.Time
+	// MaxOccurrences - максимальное количество повторений одного события
+	MaxOccurrences int
+	// Location - часовой пояс, в который переводится время с TZID и UTC
+	Location *time.Location
+}
+
+// Candidates - события календаря для импорта; повторения получают идентификаторы с датой.
+// Если повторений больше MaxOccurrences, после импортируемых добавляется кандидат с ошибкой
+// errors.ErrTooManyOccurrences, чтобы отчет импорта показал, что серия обрезана.
+func (c Converter) Candidates(parsed []ical.ParsedEvent) []domain.ImportCandidate {
+	// Измененные повторения (RECURRENCE-ID) исключаются при разворачивании основного события
+	overridden := make(map[string][]time.Time)
+	for _, p := range parsed {
+		if p.Err == nil && !p.RecurrenceID.IsZero() {
+			overridden[p.UID] = append(overridden[p.UID], p.RecurrenceID)
+		}
+	}
+
+	candidates := make([]domain.ImportCandidate, 0, len(parsed))
+	for _, p := range parsed {
+		source := fmt.Sprintf("line %d", p.Line)
+		if p.Err != nil {
+			candidates = append(candidates, domain.ImportCandidate{
+				Source: source,
+				Err:    fmt.Errorf("%w: %v", errors.ErrInvalidCalendar, p.Err),
+			})
+			continue
+		}
+
+		id := EventID(p.UID)
+		switch {
+		case !p.RecurrenceID.IsZero():
+			id += "-" + c.local(p.RecurrenceID, p.Event).Format("20060102")
+			candidates = append(candidates, domain.ImportCandidate{Source: source, Event: c.Event(id, p.Event, p.Start)})
+		case p.RRule != nil:
+			excluded := slices.Concat(p.ExDates, overridden[p.UID])
+			count := 0
+			for _, start := range p.RRule.Occurrences(p.Start, c.Until, math.MaxInt) {
+				if start.Before(c.From) || containsTime(excluded, start) {
+					continue
+				}
+				if count++; count > c.MaxOccurrences {
+					candidates = append(candidates, domain.ImportCandidate{
+						Source: source,
+						Event:  domain.Event{ID: id},
+						Err: errors.WithParams(
+							fmt.Errorf("%w: more than %d", errors.ErrTooManyOccurrences, c.MaxOccurrences),
+							errors.Params{"max": c.MaxOccurrences},
+						),
+					})
+					break
+				}
+				occurrenceID := id + "-" + c.local(start, p.Event).Format("20060102")
+				candidates = append(candidates, domain.ImportCandidate{Source: source, Event: c.Event(occurrenceID, p.Event, start)})
+			}
+		default:
+			candidates = append(candidates, domain.ImportCandidate{Source: source, Event: c.Event(id, p.Event, p.Start)})
+		}
+	}
+	return candidates
+}
+
+// Event - событие календаря с идентификатором id, начинающееся в start
+func (c Converter) Event(id string, ev ical.Event, start time.Time) domain.Event {
+	local := c.local(start, ev)
+	event := domain.Event{
+		ID:    id,
+		Date:  local.Format(DateLayout),
+		Title: strings.Join(strings.Fields(ev.Summary), " "),
+	}
+	if ev.AllDay {
+		return event
+	}
+
+	event.StartTime = local.Format(TimeLayout)
+	// Время окончания сохраняется, только если событие заканчивается в тот же день
+	if !ev.End.IsZero() {
+		end := local.Add(ev.End.Sub(ev.Start))
+		if end.Format(DateLayout) == event.Date && end.Format(TimeLayout) > event.StartTime {
+			event.EndTime = end.Format(TimeLayout)
+		}
+	}
+	return event
+}
+
+// local - время в часовом поясе конвертера; даты и плавающее время не переводятся
+func (c Converter) local(t time.Time, ev ical.Event) time.Time {
+	if ev.AllDay || ev.Floating || c.Location == nil {
+		return t
+	}
+	return t.In(c.Location)
+}
+
+// containsTime - есть ли t среди times
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, item := range times {
+		if item.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package icalendar
+
+import (
+	stdErrors "errors"
+	"strings"
+	"testing"
+	"time"
+
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/ical"
+)
+
+func TestConverter_Candidates(t *testing.T) {
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:daily",
+		"SUMMARY:Standup",
+		"DTSTART:20250101T090000",
+		"RRULE:FREQ=DAILY;COUNT=10",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:weekly",
+		"SUMMARY:Planning",
+		"DTSTART;VALUE=DATE:20250106",
+		"RRULE:FREQ=WEEKLY;COUNT=2",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+
+	parsed, err := ical.Parse(strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("Failed to parse calendar: %v", err)
+	}
+
+	converter := Converter{
+		From:           time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Until:          time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		MaxOccurrences: 3,
+		Location:       time.UTC,
+	}
+	candidates := converter.Candidates(parsed)
+
+	expected := []string{"daily-20250101", "daily-20250102", "daily-20250103", "daily", "weekly-20250106", "weekly-20250113"}
+	if len(candidates) != len(expected) {
+		t.Fatalf("Expected %d candidates, got %+v", len(expected), candidates)
+	}
+	for i, id := range expected {
+		if candidates[i].Event.ID != id {
+			t.Errorf("Expected candidate %s at position %d, got %s", id, i, candidates[i].Event.ID)
+		}
+	}
+
+	// Обрезанная серия попадает в отчет ошибкой с лимитом, остальные кандидаты без ошибок
+	truncated := candidates[3]
+	if !stdErrors.Is(truncated.Err, errors.ErrTooManyOccurrences) || truncated.Source != candidates[0].Source {
+		t.Fatalf("Expected truncation error for the daily series, got %+v", truncated)
+	}
+	described := errors.Describe(truncated.Err)
+	if described.Code != errors.CodeTooManyOccurrences || described.Params["max"] != 3 {
+		t.Errorf("Expected too_many_occurrences with max 3, got %+v", described)
+	}
+	for i, candidate := range candidates {
+		if i != 3 && candidate.Err != nil {
+			t.Errorf("Unexpected error for %s: %v", candidate.Event.ID, candidate.Err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"net/http"
+
+	"calendar-server/internal/auth"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// Auth - middleware аутентификации по заголовку Authorization: Bearer <token>.
+// Токен также принимается паролем в Authorization: Basic для календарных клиентов
+// и подпротоколом bearer.<token> в Sec-WebSocket-Protocol для браузерных WebSocket.
+// С WithAuthFailureLimiter запросы без токена и с неверным токеном расходуют бюджет адреса клиента.
+func Auth(authenticator auth.Authenticator, log *zap.Logger, next http.Handler, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.authLimiter != nil {
+			if retryAfter, blocked := o.authLimiter.AuthBlocked(r); blocked {
+				ctxlog.FromContext(r.Context(), log).Warn("Too many failed authentications",
+					zappretty.Field("path", r.URL.Path),
+					zappretty.Field("remote_addr", r.RemoteAddr),
+				)
+				o.renderRateLimited(w, r, log, retryAfter)
+				return
+			}
+		}
+
+		token, ok := auth.BearerToken(r.Header.Get("Authorization"))
+		if !ok {
+			token, ok = auth.BasicToken(r.Header.Get("Authorization"))
+		}
+		if !ok {
+			token, ok = auth.ProtocolToken(r.Header.Values("Sec-WebSocket-Protocol"))
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="calendar-server"`)
+			w.Header().Add("WWW-Authenticate", `Basic realm="calendar-server", charset="UTF-8"`)
+			o.authFailed(r)
+			o.renderError(w, r, log, errors.ErrUnauthorized)
+			return
+		}
+
+		identity, err := authenticator.Authenticate(r.Context(), token)
+		if err != nil {
+			ctxlog.FromContext(r.Context(), log).Warn("Authentication failed",
+				zappretty.Field("error", err),
+				zappretty.Field("path", r.URL.Path),
+				zappretty.Field("remote_addr", r.RemoteAddr),
+			)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="calendar-server", error="invalid_token"`)
+			o.authFailed(r)
+			o.renderError(w, r, log, errors.ErrInvalidToken)
+			return
+		}
+
+		ctx := auth.WithIdentity(r.Context(), identity)
+		ctx = ctxlog.With(ctx, log, zappretty.Field("auth_user_id", identity.UserID))
+		tracing.SpanFromContext(ctx).SetAttributes(
+			tracing.String("user.id", identity.UserID),
+			tracing.String("auth.method", identity.Method),
+		)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScope - middleware проверки области доступа токена
+func RequireScope(scope string, log *zap.Logger, next http.Handler, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := auth.FromContext(r.Context())
+		if !ok {
+			o.renderError(w, r, log, errors.ErrUnauthorized)
+			return
+		}
+
+		if !identity.HasScope(scope) {
+			ctxlog.FromContext(r.Context(), log).Warn("Insufficient token scope",
+				zappretty.Field("key_id", identity.KeyID),
+				zappretty.Field("required_scope", scope),
+			)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="calendar-server", error="insufficient_scope", scope="`+scope+`"`)
+			o.renderError(w, r, log, errors.ErrInsufficientScope)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole - middleware проверки роли пользователя
+func RequireRole(role string, log *zap.Logger, next http.Handler, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := auth.FromContext(r.Context())
+		if !ok {
+			o.renderError(w, r, log, errors.ErrUnauthorized)
+			return
+		}
+
+		if identity.Role != role {
+			ctxlog.FromContext(r.Context(), log).Warn("Access to role-protected route denied",
+				zappretty.Field("required_role", role),
+				zappretty.Field("path", r.URL.Path),
+			)
+			o.renderError(w, r, log, errors.ErrAdminOnly)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authFailed - списание неудачной аутентификации из бюджета адреса клиента
+func (o options) authFailed(r *http.Request) {
+	if o.authLimiter != nil {
+		o.authLimiter.ChargeAuthFailure(r)
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/i18n"
+
+	"go.uber.org/zap"
+)
+
+// decodeProblem - тело ошибки в формате RFC 7807
+func decodeProblem(t *testing.T, rr *httptest.ResponseRecorder) response.Problem {
+	t.Helper()
+	if ct := rr.Header().Get("Content-Type"); ct != response.ProblemContentType {
+		t.Fatalf("Expected %s, got %q: %s", response.ProblemContentType, ct, rr.Body.String())
+	}
+	var problem response.Problem
+	if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to unmarshal problem: %v", err)
+	}
+	return problem
+}
+
+func TestAuth_Errors(t *testing.T) {
+	authenticator := auth.NewStaticTokenAuthenticator()
+	authenticator.Add("reader", auth.Identity{UserID: "user-1", Scopes: []string{auth.ScopeEventsRead}})
+	authenticator.Add("admin", auth.Identity{UserID: "ops", Role: auth.RoleAdmin})
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /create_event", RequireScope(auth.ScopeEventsWrite, zap.NewNop(),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	mux.Handle("GET /admin/users", RequireRole(auth.RoleAdmin, zap.NewNop(),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	handler := Auth(authenticator, zap.NewNop(), mux)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+		wantCode   errors.Code
+	}{
+		{name: "missing token", method: "GET", path: "/admin/users", wantStatus: http.StatusUnauthorized, wantCode: errors.CodeUnauthorized},
+		{name: "invalid token", method: "GET", path: "/admin/users", token: "guess", wantStatus: http.StatusUnauthorized, wantCode: errors.CodeInvalidToken},
+		{name: "insufficient scope", method: "POST", path: "/create_event", token: "reader", wantStatus: http.StatusForbidden, wantCode: errors.CodeInsufficientScope},
+		{name: "admin role required", method: "GET", path: "/admin/users", token: "reader", wantStatus: http.StatusForbidden, wantCode: errors.CodeAdminOnly},
+		{name: "allowed", method: "GET", path: "/admin/users", token: "admin", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			if problem := decodeProblem(t, rr); problem.Code != tt.wantCode || problem.Status != tt.wantStatus {
+				t.Errorf("Expected %s, got %+v", tt.wantCode, problem)
+			}
+		})
+	}
+}
+
+func TestAuth_ErrorRenderer(t *testing.T) {
+	authenticator := auth.NewStaticTokenAuthenticator()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("localized", func(t *testing.T) {
+		renderer := response.ErrorRenderer{Format: response.FormatProblem, Catalog: i18n.Default()}
+		handler := Auth(authenticator, zap.NewNop(), next, WithErrorRenderer(renderer))
+
+		req := httptest.NewRequest("GET", "/events_for_day", nil)
+		req.Header.Set("Authorization", "Bearer guess")
+		req.Header.Set("Accept-Language", "ru")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		problem := decodeProblem(t, rr)
+		if rr.Header().Get("Content-Language") != "ru" || problem.Code != errors.CodeInvalidToken ||
+			problem.Detail != "токен недействителен или истек" {
+			t.Errorf("Expected Russian invalid_token problem, got %+v", problem)
+		}
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		renderer := response.ErrorRenderer{Format: response.FormatLegacy}
+		handler := Auth(authenticator, zap.NewNop(), next, WithErrorRenderer(renderer))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/events_for_day", nil))
+
+		var resp response.Response
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if rr.Code != http.StatusUnauthorized || resp.Error != errors.ErrUnauthorized.Error() {
+			t.Errorf("Expected legacy 401, got %d %+v", rr.Code, resp)
+		}
+	})
+}
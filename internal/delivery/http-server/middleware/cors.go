@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy - политика CORS
+type CORSPolicy struct {
+	// AllowedOrigins - разрешенные источники: точные ("https://app.example.com"),
+	// поддомены ("https://*.example.com") или "*" для любого источника
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// AllowsOrigin проверяет, разрешен ли источник политикой
+func (p CORSPolicy) AllowsOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		switch {
+		case allowed == "*" || allowed == origin:
+			return true
+		case strings.Contains(allowed, "://*."):
+			scheme, suffix, _ := strings.Cut(allowed, "://*")
+			host, ok := strings.CutPrefix(origin, scheme+"://")
+			// Требуем хотя бы одну метку перед доменом: "https://example.com" не совпадает с "https://*.example.com"
+			if ok && strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowsMethod проверяет, разрешен ли метод политикой
+func (p CORSPolicy) allowsMethod(method string) bool {
+	for _, allowed := range p.AllowedMethods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeaders проверяет, что все запрошенные заголовки разрешены политикой
+func (p CORSPolicy) allowsHeaders(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		allowed := false
+		for _, candidate := range p.AllowedHeaders {
+			if candidate == "*" || strings.EqualFold(candidate, header) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// CORS - middleware, применяющее политику CORS.
+// Разрешенный источник возвращается в Access-Control-Allow-Origin как есть, поэтому ответы
+// всегда помечаются Vary: Origin. Preflight-запросы обрабатываются здесь, остальные OPTIONS
+// передаются дальше.
+func CORS(policy CORSPolicy, next http.Handler) http.Handler {
+	allowMethods := strings.Join(policy.AllowedMethods, ", ")
+	allowHeaders := strings.Join(policy.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(policy.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(policy.MaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		allowed := policy.AllowsOrigin(origin)
+
+		if !preflight {
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if policy.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+				if exposeHeaders != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+		if !allowed ||
+			!policy.allowsMethod(r.Header.Get("Access-Control-Request-Method")) ||
+			!policy.allowsHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+		}
+		if policy.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		if policy.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
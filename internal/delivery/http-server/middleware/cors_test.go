@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testCORSPolicy() CORSPolicy {
+	return CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.corp.example"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+}
+
+func TestCORS_AllowsOrigin(t *testing.T) {
+	policy := testCORSPolicy()
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "https://app.example.com", allowed: true},
+		{origin: "https://APP.example.com", allowed: true},
+		{origin: "https://team.corp.example", allowed: true},
+		{origin: "https://a.b.corp.example", allowed: true},
+		{origin: "https://corp.example", allowed: false},
+		{origin: "http://team.corp.example", allowed: false},
+		{origin: "https://evilcorp.example", allowed: false},
+		{origin: "https://app.example.com.evil.io", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := policy.AllowsOrigin(tt.origin); got != tt.allowed {
+				t.Errorf("Expected allowed=%v for %s, got %v", tt.allowed, tt.origin, got)
+			}
+		})
+	}
+
+	if !(CORSPolicy{AllowedOrigins: []string{"*"}}).AllowsOrigin("https://any.site") {
+		t.Error("Expected wildcard policy to allow any origin")
+	}
+}
+
+func TestCORS_Requests(t *testing.T) {
+	reached := false
+	handler := CORS(testCORSPolicy(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		headers        map[string]string
+		expectedStatus int
+		expectedOrigin string
+		expectNext     bool
+	}{
+		{
+			name:           "simple request from allowed origin",
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://app.example.com"},
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://app.example.com",
+			expectNext:     true,
+		},
+		{
+			name:           "simple request from foreign origin",
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://evil.io"},
+			expectedStatus: http.StatusOK,
+			expectNext:     true,
+		},
+		{
+			name:   "preflight from allowed origin",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://team.corp.example",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "content-type, authorization",
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedOrigin: "https://team.corp.example",
+		},
+		{
+			name:   "preflight with forbidden method",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "preflight with forbidden header",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "X-Debug",
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "plain OPTIONS is not a preflight",
+			method:         "OPTIONS",
+			headers:        map[string]string{"Origin": "https://app.example.com"},
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://app.example.com",
+			expectNext:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+			req := httptest.NewRequest(tt.method, "/events_for_day", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.expectedOrigin {
+				t.Errorf("Expected Allow-Origin %q, got %q", tt.expectedOrigin, got)
+			}
+			if tt.expectedOrigin != "" && rr.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("Expected Allow-Credentials for allowed origin")
+			}
+			if reached != tt.expectNext {
+				t.Errorf("Expected next handler reached=%v, got %v", tt.expectNext, reached)
+			}
+			if rr.Header().Values("Vary")[0] != "Origin" {
+				t.Errorf("Expected Vary: Origin, got %v", rr.Header().Values("Vary"))
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"calendar-server/pkg/metrics"
+)
+
+// unmatchedRoute - метка маршрута для запросов, не совпавших ни с одним шаблоном
+const unmatchedRoute = "unmatched"
+
+// HTTPMetrics - метрики HTTP-запросов
+type HTTPMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	inFlight *metrics.GaugeVec
+}
+
+// NewHTTPMetrics - регистрация метрик HTTP-запросов и перехваченных паник в реестре
+func NewHTTPMetrics(registry *metrics.Registry) *HTTPMetrics {
+	registry.NewCounterFunc("calendar_http_panics_total", "Number of panics recovered in HTTP handlers.",
+		func() float64 { return float64(PanicsTotal()) })
+
+	return &HTTPMetrics{
+		requests: registry.NewCounterVec("calendar_http_requests_total",
+			"Number of HTTP requests by route, method and status.", "route", "method", "status"),
+		duration: registry.NewHistogramVec("calendar_http_request_duration_seconds",
+			"HTTP request latency by route and method.", nil, "route", "method"),
+		inFlight: registry.NewGaugeVec("calendar_http_requests_in_flight",
+			"Number of HTTP requests currently being served."),
+	}
+}
+
+// RouteResolver - источник шаблона маршрута для запроса, например *http.ServeMux
+type RouteResolver interface {
+	Handler(r *http.Request) (http.Handler, string)
+}
+
+// Metrics - middleware учета количества, длительности и статусов запросов.
+// Маршрут берется из шаблона ServeMux, чтобы число меток не зависело от параметров запроса.
+func Metrics(m *HTTPMetrics, routes RouteResolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := routeLabel(routes, r)
+
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+
+		rw := &responseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+
+		next.ServeHTTP(rw, r)
+
+		m.requests.Inc(route, r.Method, strconv.Itoa(rw.statusCode))
+		m.duration.Observe(time.Since(start).Seconds(), route, r.Method)
+	})
+}
+
+// routeLabel - путь шаблона маршрута без метода
+func routeLabel(routes RouteResolver, r *http.Request) string {
+	_, pattern := routes.Handler(r)
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = pattern[i+1:]
+	}
+	return pattern
+}
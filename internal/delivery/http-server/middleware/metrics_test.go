@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"calendar-server/pkg/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	m := NewHTTPMetrics(reg)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /events_for_day", func(w http.ResponseWriter, r *http.Request) {
+		if m.inFlight.Value() != 1 {
+			t.Errorf("Expected 1 request in flight, got %v", m.inFlight.Value())
+		}
+	})
+	mux.HandleFunc("POST /create_event", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+	handler := Metrics(m, mux, mux)
+
+	requests := []struct {
+		method string
+		target string
+	}{
+		{"GET", "/events_for_day?user_id=u1&date=2025-01-15"},
+		{"GET", "/events_for_day?user_id=u2&date=2025-01-16"},
+		{"POST", "/create_event"},
+		{"GET", "/nope"},
+	}
+	for _, req := range requests {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.target, nil))
+	}
+
+	tests := []struct {
+		route  string
+		method string
+		status string
+		want   float64
+	}{
+		{"/events_for_day", "GET", "200", 2},
+		{"/create_event", "POST", "409", 1},
+		{unmatchedRoute, "GET", "404", 1},
+	}
+	for _, tt := range tests {
+		if got := m.requests.Value(tt.route, tt.method, tt.status); got != tt.want {
+			t.Errorf("Expected %v requests for %s %s %s, got %v", tt.want, tt.method, tt.route, tt.status, got)
+		}
+	}
+
+	if got := m.duration.Count("/events_for_day", "GET"); got != 2 {
+		t.Errorf("Expected 2 latency observations, got %d", got)
+	}
+	if m.inFlight.Value() != 0 {
+		t.Errorf("Expected no requests in flight, got %v", m.inFlight.Value())
+	}
+}
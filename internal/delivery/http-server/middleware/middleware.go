@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"calendar-server/pkg/logger/ctxlog"
 	"calendar-server/pkg/logger/zappretty"
 	"net/http"
 	"time"
@@ -20,6 +21,11 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap - доступ к исходному writer для http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // LoggingMiddleware - middleware для логирования запросов
 func LoggingMiddleware(log *zap.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -32,7 +38,7 @@ func LoggingMiddleware(log *zap.Logger, next http.Handler) http.Handler {
 
 		next.ServeHTTP(rw, r)
 
-		log.Info("HTTP request",
+		ctxlog.FromContext(r.Context(), log).Info("HTTP request",
 			zappretty.Field("method", r.Method),
 			zappretty.Field("path", r.URL.Path),
 			zappretty.Field("status", rw.statusCode),
@@ -42,21 +48,3 @@ func LoggingMiddleware(log *zap.Logger, next http.Handler) http.Handler {
 		)
 	})
 }
-
-// CORS middleware добавляет заголовки для CORS
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Устанавливаем заголовки CORS
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
+
+	"go.uber.org/zap"
+)
+
+// options - общие параметры middleware
+type options struct {
+	errors response.ErrorRenderer
+	// authLimiter - ограничитель неудачных аутентификаций в Auth; nil отключает ограничение
+	authLimiter *RateLimiter
+}
+
+// Option - функциональная опция middleware
+type Option func(*options)
+
+// WithErrorRenderer - формат ответов с ошибками
+func WithErrorRenderer(renderer response.ErrorRenderer) Option {
+	return func(o *options) {
+		o.errors = renderer
+	}
+}
+
+// WithAuthFailureLimiter - Auth списывает неудачные аутентификации из бюджета адреса клиента
+// и отклоняет запросы с адреса, исчерпавшего его, не проверяя токен
+func WithAuthFailureLimiter(limiter *RateLimiter) Option {
+	return func(o *options) {
+		o.authLimiter = limiter
+	}
+}
+
+// newOptions - параметры middleware; по умолчанию ошибки записываются в формате RFC 7807
+func newOptions(opts []Option) options {
+	o := options{errors: response.DefaultErrorRenderer()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// renderError - запись типизированной ошибки в настроенном формате
+func (o options) renderError(w http.ResponseWriter, r *http.Request, log *zap.Logger, err error) {
+	o.errors.Render(w, r, ctxlog.FromContext(r.Context(), log), errors.Describe(err))
+}
@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// Budget - параметры корзины токенов: скорость пополнения и емкость
+type Budget struct {
+	// Rate - токенов в секунду
+	Rate float64
+	// Burst - максимальное количество токенов в корзине
+	Burst int
+}
+
+// bucket - корзина токенов одного клиента
+type bucket struct {
+	tokens   float64
+	updated  time.Time
+	lastSeen time.Time
+}
+
+// RateLimiter - ограничитель частоты запросов по алгоритму token bucket.
+// Чтение и запись расходуют разные корзины.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	read    Budget
+	write   Budget
+	// authFailures - бюджет неудачных аутентификаций с одного адреса; нулевой Rate отключает его
+	authFailures Budget
+	idleTTL      time.Duration
+	lastSweep    time.Time
+	now          func() time.Time
+}
+
+// LimiterOption - функциональная опция RateLimiter
+type LimiterOption func(*RateLimiter)
+
+// WithAuthFailureBudget - ограничение неудачных аутентификаций с одного адреса (см. WithAuthFailureLimiter)
+func WithAuthFailureBudget(budget Budget) LimiterOption {
+	return func(l *RateLimiter) {
+		l.authFailures = budget
+	}
+}
+
+// NewRateLimiter - конструктор RateLimiter; корзины, не использовавшиеся idleTTL, удаляются
+func NewRateLimiter(read, write Budget, idleTTL time.Duration, opts ...LimiterOption) *RateLimiter {
+	l := &RateLimiter{
+		buckets: make(map[string]*bucket),
+		read:    read,
+		write:   write,
+		idleTTL: idleTTL,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// decision - результат проверки лимита
+type decision struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// allow - списывает токен из корзины клиента
+func (l *RateLimiter) allow(key string, budget Budget) decision {
+	return l.take(key, budget, true)
+}
+
+// take - проверка корзины клиента; charge списывает токен, если он есть
+func (l *RateLimiter) take(key string, budget Budget, charge bool) decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(budget.Burst), updated: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(budget.Burst), b.tokens+elapsed*budget.Rate)
+		b.updated = now
+	}
+	b.lastSeen = now
+
+	d := decision{limit: budget.Burst}
+	if b.tokens >= 1 {
+		if charge {
+			b.tokens--
+		}
+		d.allowed = true
+	} else {
+		d.retryAfter = secondsToDuration((1 - b.tokens) / budget.Rate)
+	}
+
+	d.remaining = int(math.Floor(b.tokens))
+	d.reset = secondsToDuration((float64(budget.Burst) - b.tokens) / budget.Rate)
+	return d
+}
+
+// sweep - удаление простаивающих корзин; выполняется не чаще раза в idleTTL
+func (l *RateLimiter) sweep(now time.Time) {
+	if l.idleTTL <= 0 || now.Sub(l.lastSweep) < l.idleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// AllowWrite - списание из бюджета изменений клиента запроса r для операций внутри
+// установленного соединения, например команд WebSocket; при отказе возвращает время до повтора
+func (l *RateLimiter) AllowWrite(r *http.Request) (time.Duration, bool) {
+	d := l.allow("write|"+clientKey(r), l.write)
+	return d.retryAfter, d.allowed
+}
+
+// AuthBlocked - исчерпан ли бюджет неудачных аутентификаций адреса запроса r;
+// при исчерпании возвращает время до повтора. Без бюджета всегда ложно.
+func (l *RateLimiter) AuthBlocked(r *http.Request) (time.Duration, bool) {
+	if l.authFailures.Rate <= 0 {
+		return 0, false
+	}
+	d := l.take("auth|"+addrKey(r), l.authFailures, false)
+	return d.retryAfter, !d.allowed
+}
+
+// ChargeAuthFailure - списание неудачной аутентификации из бюджета адреса запроса r
+func (l *RateLimiter) ChargeAuthFailure(r *http.Request) {
+	if l.authFailures.Rate > 0 {
+		l.take("auth|"+addrKey(r), l.authFailures, true)
+	}
+}
+
+// Len - количество активных корзин
+func (l *RateLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// RateLimit - middleware ограничения частоты запросов.
+// Ключ клиента: API-ключ, затем пользователь арендатора, затем удаленный адрес.
+// Должен выполняться после Auth и Tenant; запросы, не прошедшие аутентификацию,
+// ограничивает сам Auth (см. WithAuthFailureLimiter).
+func RateLimit(limiter *RateLimiter, log *zap.Logger, next http.Handler, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class, budget := "write", limiter.write
+		if isReadMethod(r.Method) {
+			class, budget = "read", limiter.read
+		}
+		client := clientKey(r)
+
+		d := limiter.allow(class+"|"+client, budget)
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(d.limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.reset)))
+
+		if !d.allowed {
+			ctxlog.FromContext(r.Context(), log).Warn("Rate limit exceeded",
+				zappretty.Field("client", client),
+				zappretty.Field("class", class),
+				zappretty.Field("path", r.URL.Path),
+			)
+			o.renderRateLimited(w, r, log, d.retryAfter)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// renderRateLimited - ответ 429 с Retry-After
+func (o options) renderRateLimited(w http.ResponseWriter, r *http.Request, log *zap.Logger, retryAfter time.Duration) {
+	seconds := ceilSeconds(retryAfter)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	o.renderError(w, r, log, errors.WithParams(
+		fmt.Errorf("%w: retry in %ds", errors.ErrRateLimited, seconds),
+		errors.Params{"retry_after": seconds},
+	))
+}
+
+// isReadMethod - метод только читает данные; PROPFIND и REPORT используют клиенты CalDAV
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND", "REPORT":
+		return true
+	}
+	return false
+}
+
+// clientKey определяет, чей бюджет расходует запрос
+func clientKey(r *http.Request) string {
+	if identity, ok := auth.FromContext(r.Context()); ok {
+		if identity.KeyID != "" {
+			return "key:" + identity.KeyID
+		}
+		return "user:" + tenant.FromContext(r.Context()) + "/" + identity.UserID
+	}
+	return addrKey(r)
+}
+
+// addrKey - ключ удаленного адреса запроса
+func addrKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// secondsToDuration переводит дробное количество секунд в time.Duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"calendar-server/internal/auth"
+	"calendar-server/pkg/errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func newTestLimiter(now *time.Time) *RateLimiter {
+	limiter := NewRateLimiter(Budget{Rate: 1, Burst: 2}, Budget{Rate: 0.5, Burst: 1}, time.Minute)
+	limiter.now = func() time.Time { return *now }
+	return limiter
+}
+
+func TestRateLimit_Headers(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	handler := RateLimit(newTestLimiter(&now), zap.NewNop(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/events_for_month", nil)
+		req.RemoteAddr = "10.0.0.1:5555"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i, expectedRemaining := range []string{"1", "0"} {
+		rr := do("GET")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, rr.Code)
+		}
+		if rr.Header().Get("RateLimit-Limit") != "2" || rr.Header().Get("RateLimit-Remaining") != expectedRemaining {
+			t.Errorf("Request %d: unexpected headers %v", i, rr.Header())
+		}
+	}
+
+	rr := do("GET")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 after burst, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", rr.Header().Get("Retry-After"))
+	}
+	if problem := decodeProblem(t, rr); problem.Code != errors.CodeRateLimited || problem.Detail != "rate limit exceeded, retry in 1 s" {
+		t.Errorf("Expected rate_limited problem, got %+v", problem)
+	}
+
+	// Бюджет записи не зависит от бюджета чтения
+	if rr := do("POST"); rr.Code != http.StatusOK {
+		t.Errorf("Expected write budget to be separate, got %d", rr.Code)
+	}
+	if rr := do("POST"); rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "2" {
+		t.Errorf("Expected 429 with Retry-After 2 for writes, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+
+	now = now.Add(time.Second)
+	if rr := do("GET"); rr.Code != http.StatusOK {
+		t.Errorf("Expected refill after one second, got %d", rr.Code)
+	}
+}
+
+func TestRateLimit_KeyedByIdentity(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	handler := RateLimit(newTestLimiter(&now), zap.NewNop(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(identity auth.Identity) int {
+		req := httptest.NewRequest("POST", "/create_event", nil)
+		req.RemoteAddr = "10.0.0.1:5555"
+		req = req.WithContext(auth.WithIdentity(req.Context(), identity))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := do(auth.Identity{UserID: "user-1"}); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if code := do(auth.Identity{UserID: "user-2"}); code != http.StatusOK {
+		t.Errorf("Expected other user from same address to have own budget, got %d", code)
+	}
+	if code := do(auth.Identity{UserID: "user-1", KeyID: "key_1"}); code != http.StatusOK {
+		t.Errorf("Expected API key to have own budget, got %d", code)
+	}
+	if code := do(auth.Identity{UserID: "user-1"}); code != http.StatusTooManyRequests {
+		t.Errorf("Expected user-1 to be limited, got %d", code)
+	}
+}
+
+func TestRateLimiter_EvictsIdleBuckets(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+
+	limiter.allow("read|addr:10.0.0.1", limiter.read)
+	limiter.allow("read|addr:10.0.0.2", limiter.read)
+	if limiter.Len() != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", limiter.Len())
+	}
+
+	now = now.Add(2 * time.Minute)
+	limiter.allow("read|addr:10.0.0.3", limiter.read)
+	if limiter.Len() != 1 {
+		t.Errorf("Expected idle buckets to be evicted, got %d", limiter.Len())
+	}
+}
+
+func TestRateLimit_DAVReadMethods(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	handler := RateLimit(newTestLimiter(&now), zap.NewNop(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// Бюджет записи - один запрос, поэтому второй PROPFIND прошел бы только из бюджета чтения
+	for i, method := range []string{"PROPFIND", "REPORT"} {
+		req := httptest.NewRequest(method, "/caldav/users/user-1/calendar/", nil)
+		req.RemoteAddr = "10.0.0.1:5555"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK || rr.Header().Get("RateLimit-Limit") != "2" {
+			t.Errorf("Request %d: expected %s to use the read budget, got %d %v", i, method, rr.Code, rr.Header())
+		}
+	}
+}
+
+func TestRateLimiter_AllowWrite(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+	handler := RateLimit(limiter, zap.NewNop(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/events/ws", nil)
+	req = req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{UserID: "user-1"}))
+
+	if _, ok := limiter.AllowWrite(req); !ok {
+		t.Fatal("Expected first command to be allowed")
+	}
+	retryAfter, ok := limiter.AllowWrite(req)
+	if ok || retryAfter != 2*time.Second {
+		t.Errorf("Expected command to be limited for 2s, got %v %v", ok, retryAfter)
+	}
+
+	// Команды расходуют тот же бюджет, что и HTTP-запросы на изменение
+	post := httptest.NewRequest("POST", "/create_event", nil).WithContext(req.Context())
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, post)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected write budget shared with HTTP requests, got %d", rr.Code)
+	}
+}
+
+func TestAuth_FailureLimit(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+	limiter.authFailures = Budget{Rate: 0.1, Burst: 2}
+
+	authenticator := auth.NewStaticTokenAuthenticator()
+	authenticator.Add("secret", auth.Identity{UserID: "user-1"})
+	handler := Auth(authenticator, zap.NewNop(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithAuthFailureLimiter(limiter))
+
+	do := func(addr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/events_for_day", nil)
+		req.RemoteAddr = addr + ":5555"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Успешная аутентификация бюджет не расходует
+	for range 3 {
+		if rr := do("10.0.0.1", "secret"); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200 for a valid token, got %d", rr.Code)
+		}
+	}
+	for i, token := range []string{"guess-1", ""} {
+		if rr := do("10.0.0.1", token); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: expected 401, got %d", i, rr.Code)
+		}
+	}
+
+	// Бюджет исчерпан: адрес получает 429 даже с верным токеном
+	rr := do("10.0.0.1", "secret")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "10" {
+		t.Fatalf("Expected 429 with Retry-After 10, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if problem := decodeProblem(t, rr); problem.Code != errors.CodeRateLimited {
+		t.Errorf("Expected rate_limited problem, got %+v", problem)
+	}
+	if rr := do("10.0.0.2", "guess-2"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected another address to have its own budget, got %d", rr.Code)
+	}
+
+	now = now.Add(10 * time.Second)
+	if rr := do("10.0.0.1", "secret"); rr.Code != http.StatusOK {
+		t.Errorf("Expected the budget to refill, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	"calendar-server/internal/auth"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// panicsTotal - количество перехваченных паник с момента запуска
+var panicsTotal atomic.Int64
+
+// PanicsTotal - количество перехваченных паник для метрик
+func PanicsTotal() int64 {
+	return panicsTotal.Load()
+}
+
+// recoveryWriter - отслеживает, начата ли уже запись ответа
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader - метод для записи статуса ответа
+func (rw *recoveryWriter) WriteHeader(code int) {
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write - метод для записи тела ответа
+func (rw *recoveryWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap - доступ к исходному writer для http.ResponseController
+func (rw *recoveryWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// errPanic - причина ответа 500 после паники; клиенту текст не раскрывается
+var errPanic = stdErrors.New("panic in HTTP handler")
+
+// Recovery - middleware перехвата паник в обработчиках.
+// Паника логируется со стеком и контекстом запроса, клиент получает internal_error,
+// если ответ еще не начат. Должен выполняться после Auth и Tenant: иначе в логе нет
+// пользователя и арендатора.
+func Recovery(log *zap.Logger, next http.Handler, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoveryWriter{ResponseWriter: w}
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// Штатный способ прервать ответ - пробрасываем его серверу
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			panicsTotal.Add(1)
+
+			identity, _ := auth.FromContext(r.Context())
+			ctxlog.FromContext(r.Context(), log).Error("Panic recovered in HTTP handler",
+				zappretty.Field("panic", rec),
+				zappretty.Field("method", r.Method),
+				zappretty.Field("path", r.URL.Path),
+				zappretty.Field("user_id", identity.UserID),
+				zappretty.Field("remote_addr", r.RemoteAddr),
+				zappretty.Field("stack", string(debug.Stack())),
+			)
+
+			if rw.wroteHeader {
+				return
+			}
+			o.renderError(rw, r, log, errPanic)
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"calendar-server/internal/auth"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRecovery(t *testing.T) {
+	handler := Recovery(zap.NewNop(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	before := PanicsTotal()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/events_for_day", nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", rr.Code)
+	}
+	if problem := decodeProblem(t, rr); problem.Code != errors.CodeInternal || problem.Detail != "internal server error" {
+		t.Errorf("Expected internal_error without panic details, got %+v", problem)
+	}
+
+	if PanicsTotal() != before+1 {
+		t.Errorf("Expected panic counter to grow by 1, got %d -> %d", before, PanicsTotal())
+	}
+}
+
+func TestRecovery_AfterHeadersWritten(t *testing.T) {
+	handler := Recovery(zap.NewNop(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late boom")
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/events_for_day", nil))
+
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("Expected original status to be kept, got %d", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("Expected no error body after headers were sent, got %q", rr.Body.String())
+	}
+}
+
+func TestRecovery_AbortHandler(t *testing.T) {
+	handler := Recovery(zap.NewNop(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("Expected ErrAbortHandler to be re-panicked, got %v", rec)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestRecovery_LogsRequestContext(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	logger := zap.New(core)
+
+	authenticator := auth.NewStaticTokenAuthenticator()
+	authenticator.Add("secret", auth.Identity{UserID: "user-1", TenantID: "acme"})
+	registry := tenant.NewRegistry(tenant.Settings{}, map[string]tenant.Settings{"acme": {}})
+
+	// Порядок как в маршрутизаторе: Recovery выполняется после Auth и Tenant
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	handler := Auth(authenticator, logger, Tenant(registry, "X-Tenant-ID", logger, Recovery(logger, panicking)))
+
+	req := httptest.NewRequest("POST", "/create_event", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d", rr.Code)
+	}
+	entries := logs.FilterMessage("Panic recovered in HTTP handler").All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one panic log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	for key, want := range map[string]string{"user_id": "user-1", "tenant_id": "acme", "path": "/create_event"} {
+		if got := fmt.Sprint(fields[key]); got != want {
+			t.Errorf("Expected %s=%s in panic log, got %q", key, want, got)
+		}
+	}
+	if stack, _ := fields["stack"].(string); !strings.Contains(stack, "TestRecovery_LogsRequestContext") {
+		t.Errorf("Expected stack trace in panic log, got %q", stack)
+	}
+}
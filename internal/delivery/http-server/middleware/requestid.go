@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/requestid"
+
+	"go.uber.org/zap"
+)
+
+// RequestID - middleware, присваивающее запросу идентификатор.
+// Корректный X-Request-ID клиента принимается, иначе генерируется новый. Идентификатор
+// возвращается в заголовке ответа, кладется в контекст вместе с логгером, обогащенным request_id.
+func RequestID(log *zap.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+
+		ctx := requestid.WithID(r.Context(), id)
+		ctx = ctxlog.WithLogger(ctx, log.With(zappretty.Field("request_id", id)))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/requestid"
+
+	"go.uber.org/zap"
+)
+
+func TestRequestID(t *testing.T) {
+	var seenID string
+	var seenLogger *zap.Logger
+	handler := RequestID(zap.NewNop(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenID = requestid.FromContext(r.Context())
+		seenLogger = ctxlog.FromContext(r.Context(), nil)
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "generated", incoming: "", keep: false},
+		{name: "accepted", incoming: "abc-123.def_456", keep: true},
+		{name: "invalid characters", incoming: "bad id\n", keep: false},
+		{name: "too long", incoming: strings.Repeat("a", 129), keep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/events_for_day", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestid.Header, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			echoed := rr.Header().Get(requestid.Header)
+			if echoed == "" {
+				t.Fatal("Expected X-Request-ID in response")
+			}
+			if echoed != seenID {
+				t.Errorf("Expected context ID %q, got %q", echoed, seenID)
+			}
+			if tt.keep && echoed != tt.incoming {
+				t.Errorf("Expected incoming ID %q to be kept, got %q", tt.incoming, echoed)
+			}
+			if !tt.keep && echoed == tt.incoming {
+				t.Errorf("Expected incoming ID %q to be replaced", tt.incoming)
+			}
+			if seenLogger == nil {
+				t.Error("Expected request logger in context")
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// Tenant - middleware определения арендатора запроса. Должен выполняться после Auth.
+// Арендатор берется из токена; заголовок выбирает арендатора только для многоарендных токенов
+// (auth.Identity.MultiTenant). Токен без арендатора работает в арендаторе по умолчанию,
+// а при заданном списке арендаторов отклоняется.
+func Tenant(registry *tenant.Registry, header string, log *zap.Logger, next http.Handler, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested := r.Header.Get(header)
+		identity, _ := auth.FromContext(r.Context())
+
+		var tenantID string
+		switch {
+		case identity.TenantID != "":
+			if requested != "" && requested != identity.TenantID {
+				ctxlog.FromContext(r.Context(), log).Warn("Tenant header does not match token",
+					zappretty.Field("token_tenant", identity.TenantID),
+					zappretty.Field("requested_tenant", requested),
+				)
+				o.renderError(w, r, log, errors.ErrTenantMismatch)
+				return
+			}
+			tenantID = identity.TenantID
+		case identity.MultiTenant:
+			tenantID = requested
+		case registry.Strict():
+			ctxlog.FromContext(r.Context(), log).Warn("Token is not bound to a tenant",
+				zappretty.Field("requested_tenant", requested),
+			)
+			o.renderError(w, r, log, errors.ErrTenantRequired)
+			return
+		case requested != "" && requested != tenant.DefaultID:
+			ctxlog.FromContext(r.Context(), log).Warn("Tenant header is not allowed for token",
+				zappretty.Field("requested_tenant", requested),
+			)
+			o.renderError(w, r, log, errors.ErrTenantMismatch)
+			return
+		}
+		if tenantID == "" {
+			tenantID = tenant.DefaultID
+		}
+
+		settings, known := registry.Lookup(tenantID)
+		if !known {
+			ctxlog.FromContext(r.Context(), log).Warn("Request for unknown tenant", zappretty.Field("tenant_id", tenantID))
+			o.renderError(w, r, log, errors.ErrUnknownTenant)
+			return
+		}
+		if settings.Disabled {
+			o.renderError(w, r, log, errors.ErrTenantDisabled)
+			return
+		}
+
+		ctx := tenant.WithID(r.Context(), tenantID)
+		ctx = ctxlog.With(ctx, log, zappretty.Field("tenant_id", tenantID))
+		tracing.SpanFromContext(ctx).SetAttributes(tracing.String("tenant.id", tenantID))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+func TestTenant(t *testing.T) {
+	authenticator := auth.NewStaticTokenAuthenticator()
+	authenticator.Add("unbound", auth.Identity{UserID: "user-1"})
+	authenticator.Add("acme", auth.Identity{UserID: "user-2", TenantID: "acme"})
+	authenticator.Add("ops", auth.Identity{UserID: "ops", Role: auth.RoleAdmin, MultiTenant: true})
+
+	strict := tenant.NewRegistry(tenant.Settings{}, map[string]tenant.Settings{
+		"default": {},
+		"acme":    {},
+		"globex":  {},
+		"initech": {Disabled: true},
+	})
+
+	tests := []struct {
+		name       string
+		registry   *tenant.Registry
+		token      string
+		header     string
+		wantStatus int
+		wantCode   errors.Code
+		wantTenant string
+	}{
+		{name: "unbound token uses default tenant", token: "unbound", wantStatus: http.StatusOK, wantTenant: tenant.DefaultID},
+		{name: "unbound token with default header", token: "unbound", header: tenant.DefaultID, wantStatus: http.StatusOK, wantTenant: tenant.DefaultID},
+		{name: "unbound token cannot pick tenant", token: "unbound", header: "acme", wantStatus: http.StatusForbidden, wantCode: errors.CodeTenantMismatch},
+		{name: "unbound token in strict registry", registry: strict, token: "unbound", wantStatus: http.StatusForbidden, wantCode: errors.CodeTenantRequired},
+		{name: "unbound token with header in strict registry", registry: strict, token: "unbound", header: "acme", wantStatus: http.StatusForbidden, wantCode: errors.CodeTenantRequired},
+		{name: "bound token", registry: strict, token: "acme", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "bound token with matching header", registry: strict, token: "acme", header: "acme", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "bound token with other header", registry: strict, token: "acme", header: "globex", wantStatus: http.StatusForbidden, wantCode: errors.CodeTenantMismatch},
+		{name: "multi-tenant token picks tenant", registry: strict, token: "ops", header: "globex", wantStatus: http.StatusOK, wantTenant: "globex"},
+		{name: "multi-tenant token without header", registry: strict, token: "ops", wantStatus: http.StatusOK, wantTenant: tenant.DefaultID},
+		{name: "multi-tenant token with unknown tenant", registry: strict, token: "ops", header: "umbrella", wantStatus: http.StatusForbidden, wantCode: errors.CodeUnknownTenant},
+		{name: "multi-tenant token with disabled tenant", registry: strict, token: "ops", header: "initech", wantStatus: http.StatusForbidden, wantCode: errors.CodeTenantDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTenant string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotTenant = tenant.FromContext(r.Context())
+			})
+			handler := Auth(authenticator, zap.NewNop(), Tenant(tt.registry, "X-Tenant-ID", zap.NewNop(), next))
+
+			req := httptest.NewRequest("GET", "/events_for_day", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantCode != "" {
+				if problem := decodeProblem(t, rr); problem.Code != tt.wantCode {
+					t.Errorf("Expected %s, got %+v", tt.wantCode, problem)
+				}
+				return
+			}
+			if gotTenant != tt.wantTenant {
+				t.Errorf("Expected tenant %q, got %q", tt.wantTenant, gotTenant)
+			}
+		})
+	}
+}
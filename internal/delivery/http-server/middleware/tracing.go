@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/requestid"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// Tracing - middleware, открывающее серверный спан запроса.
+// Продолжает трассу из заголовка traceparent, добавляет trace_id в логгер запроса
+// и помечает спан ошибкой при ответе 5xx. Должно выполняться после RequestID.
+func Tracing(tracer *tracing.Tracer, routes RouteResolver, log *zap.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeLabel(routes, r)
+		remote, _ := tracing.Extract(r.Header)
+
+		ctx, span := tracer.StartServer(r.Context(), "HTTP "+r.Method+" "+route, remote,
+			tracing.String("http.method", r.Method),
+			tracing.String("http.route", route),
+			tracing.String("http.target", r.URL.RequestURI()),
+			tracing.String("request.id", requestid.FromContext(r.Context())),
+		)
+		defer span.End()
+
+		ctx = ctxlog.With(ctx, log, zappretty.Field("trace_id", span.SpanContext().TraceID.String()))
+
+		rw := &responseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		span.SetAttributes(tracing.Int("http.status_code", rw.statusCode))
+		if rw.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(tracing.StatusError, http.StatusText(rw.statusCode))
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"calendar-server/internal/auth"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(span tracing.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func TestTracing(t *testing.T) {
+	recorder := &spanRecorder{}
+	tracer := tracing.NewTracer("test", recorder)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /events_for_day", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "EventHandler.EventsForDay")
+		span.End()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	authenticator := auth.NewStaticTokenAuthenticator()
+	authenticator.Add("secret", auth.Identity{UserID: "user-1", Method: "token"})
+
+	handler := Tracing(tracer, mux, zap.NewNop(), Auth(authenticator, zap.NewNop(), mux))
+
+	req := httptest.NewRequest("GET", "/events_for_day?date=2025-01-15", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(recorder.spans) != 2 {
+		t.Fatalf("Expected handler and server spans, got %d", len(recorder.spans))
+	}
+
+	server := recorder.spans[1]
+	if server.Name != "HTTP GET /events_for_day" {
+		t.Errorf("Unexpected server span name %q", server.Name)
+	}
+	if server.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || server.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("Expected span to continue incoming trace, got %+v", server)
+	}
+	if server.Attributes["user.id"] != "user-1" {
+		t.Errorf("Expected user.id attribute from auth, got %v", server.Attributes["user.id"])
+	}
+	if server.Attributes["http.status_code"] != http.StatusServiceUnavailable || server.Status != tracing.StatusError {
+		t.Errorf("Expected 503 to mark span as error, got %+v", server)
+	}
+	if recorder.spans[0].ParentSpanID != server.SpanID {
+		t.Errorf("Expected handler span to be child of server span")
+	}
+}
@@ -0,0 +1,149 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/i18n"
+	"calendar-server/pkg/requestid"
+
+	"go.uber.org/zap"
+)
+
+// ProblemContentType - тип содержимого ошибок по RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// Форматы ошибок
+const (
+	// FormatProblem - RFC 7807 application/problem+json
+	FormatProblem = "problem"
+	// FormatLegacy - прежний конверт {"error": "..."}
+	FormatLegacy = "legacy"
+)
+
+// DefaultDocsURL - страница документации кодов ошибок; код добавляется якорем
+const DefaultDocsURL = "https://github.com/sj-shoff/calendar-server/blob/main/docs/errors.md"
+
+// Problem - тело ошибки по RFC 7807 с расширениями code, request_id и errors
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      errors.Code         `json:"code"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []errors.FieldError `json:"errors,omitempty"`
+}
+
+// ErrorRenderer - запись типизированных ошибок в настроенном формате.
+// В режиме legacy клиент может запросить новый формат заголовком Accept: application/problem+json.
+// С каталогом сообщения переводятся на язык из Accept-Language.
+type ErrorRenderer struct {
+	Format  string
+	DocsURL string
+	Catalog *i18n.Catalog
+}
+
+// DefaultErrorRenderer - RFC 7807 со ссылками на документацию проекта и встроенным каталогом
+func DefaultErrorRenderer() ErrorRenderer {
+	return ErrorRenderer{Format: FormatProblem, DocsURL: DefaultDocsURL, Catalog: i18n.Default()}
+}
+
+// Legacy - будет ли ответ на запрос записан в прежнем формате
+func (er ErrorRenderer) Legacy(r *http.Request) bool {
+	if er.Format != FormatLegacy {
+		return false
+	}
+	return !strings.Contains(r.Header.Get("Accept"), ProblemContentType)
+}
+
+// Render - запись ошибки
+func (er ErrorRenderer) Render(w http.ResponseWriter, r *http.Request, log *zap.Logger, e *errors.Error) {
+	e = er.localize(w, r, e)
+
+	if er.Legacy(r) {
+		WriteError(w, log, e.Detail, e.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(e.Status)
+	if err := json.NewEncoder(w).Encode(er.problem(r, e)); err != nil {
+		log.Error("Failed to encode problem response", zap.Error(err))
+	}
+}
+
+// Problem - тело ошибки на языке запроса r без записи ответа: для протоколов поверх
+// установленного соединения, например сообщений WebSocket
+func (er ErrorRenderer) Problem(r *http.Request, e *errors.Error) Problem {
+	if er.Catalog != nil {
+		e = er.translate(er.Catalog.Negotiate(r.Header.Get("Accept-Language")), e)
+	}
+	return er.problem(r, e)
+}
+
+// problem - тело ошибки по RFC 7807
+func (er ErrorRenderer) problem(r *http.Request, e *errors.Error) Problem {
+	return Problem{
+		Type:      er.typeURL(e.Code),
+		Title:     e.Title,
+		Status:    e.Status,
+		Detail:    e.Detail,
+		Instance:  r.URL.Path,
+		Code:      e.Code,
+		RequestID: requestid.FromContext(r.Context()),
+		Errors:    e.Fields,
+	}
+}
+
+// localize - копия ошибки с заголовком и сообщениями на языке клиента
+func (er ErrorRenderer) localize(w http.ResponseWriter, r *http.Request, e *errors.Error) *errors.Error {
+	if er.Catalog == nil {
+		return e
+	}
+
+	lang := er.Catalog.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+	return er.translate(lang, e)
+}
+
+// translate - копия ошибки с заголовком и сообщениями на языке lang
+func (er ErrorRenderer) translate(lang string, e *errors.Error) *errors.Error {
+	localized := *e
+	code := string(e.Code)
+	if title, ok := er.Catalog.Title(lang, code); ok {
+		localized.Title = title
+	}
+	if detail, ok := er.Catalog.Message(lang, code, e.Params); ok {
+		localized.Detail = detail
+	}
+
+	if len(e.Fields) > 0 {
+		localized.Fields = make([]errors.FieldError, len(e.Fields))
+		details := make([]string, len(e.Fields))
+		for i, field := range e.Fields {
+			if msg, ok := er.Catalog.Message(lang, string(field.Code), field.Params); ok {
+				field.Message = msg
+			}
+			localized.Fields[i] = field
+			details[i] = field.Field + ": " + field.Message
+		}
+		// Сводное сообщение собирается из переведенных нарушений
+		if e.Code == errors.CodeValidationFailed {
+			localized.Detail = strings.Join(details, "; ")
+		}
+	}
+	return &localized
+}
+
+// typeURL - ссылка на описание кода; без базы используется about:blank по RFC 7807
+func (er ErrorRenderer) typeURL(code errors.Code) string {
+	if er.DocsURL == "" {
+		return "about:blank"
+	}
+	return er.DocsURL + "#" + string(code)
+}
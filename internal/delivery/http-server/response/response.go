@@ -0,0 +1,28 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// Response - структура ответа
+type Response struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// WriteJSON - запись ответа в формате JSON с указанным статусом
+func WriteJSON(w http.ResponseWriter, log *zap.Logger, statusCode int, response Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// WriteError - запись ошибки в формате JSON
+func WriteError(w http.ResponseWriter, log *zap.Logger, errorMsg string, statusCode int) {
+	WriteJSON(w, log, statusCode, Response{Error: errorMsg})
+}
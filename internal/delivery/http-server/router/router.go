@@ -2,25 +2,187 @@ package router
 
 import (
 	"net/http"
+	"slices"
 
+	"calendar-server/internal/auth"
+	adh "calendar-server/internal/delivery/http-server/handler/admin_handler"
+	akh "calendar-server/internal/delivery/http-server/handler/apikey_handler"
+	cdh "calendar-server/internal/delivery/http-server/handler/caldav_handler"
+	dgh "calendar-server/internal/delivery/http-server/handler/digest_handler"
 	eh "calendar-server/internal/delivery/http-server/handler/event_handler"
+	hh "calendar-server/internal/delivery/http-server/handler/health_handler"
+	sh "calendar-server/internal/delivery/http-server/handler/stream_handler"
+	whh "calendar-server/internal/delivery/http-server/handler/webhook_handler"
+	wsh "calendar-server/internal/delivery/http-server/handler/websocket_handler"
 	"calendar-server/internal/delivery/http-server/middleware"
+	"calendar-server/internal/delivery/http-server/response"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/tracing"
 
 	"go.uber.org/zap"
 )
 
+// Handlers - обработчики, подключаемые к маршрутизатору
+type Handlers struct {
+	Event  *eh.EventHandler
+	APIKey *akh.APIKeyHandler
+	Admin  *adh.AdminHandler
+	// CalDAV - календарь для нативных клиентов; nil отключает /caldav/
+	CalDAV *cdh.CalDAVHandler
+	// Stream - лента изменений (Server-Sent Events); nil отключает /events/stream
+	Stream *sh.StreamHandler
+	// WebSocket - подписки и команды через WebSocket; nil отключает /events/ws
+	WebSocket *wsh.WebSocketHandler
+	// Webhook - управление вебхуками; nil отключает /webhooks
+	Webhook *whh.WebhookHandler
+	// Digest - подписки на сводки и их предпросмотр; nil отключает /digests
+	Digest *dgh.DigestHandler
+	// Metrics - обработчик /metrics; nil отключает эндпоинт
+	Metrics http.Handler
+	// Health - проверки живости и готовности; nil отключает эндпоинты
+	Health *hh.HealthHandler
+}
+
+// Middleware - зависимости middleware маршрутизатора
+type Middleware struct {
+	Authenticator auth.Authenticator
+	Tenants       *tenant.Registry
+	TenantHeader  string
+	// RateLimiter - ограничитель частоты запросов; nil отключает ограничение
+	RateLimiter *middleware.RateLimiter
+	CORS        middleware.CORSPolicy
+	// Metrics - метрики HTTP-запросов; nil отключает их сбор
+	Metrics *middleware.HTTPMetrics
+	// Tracer - трассировщик запросов; nil отключает трассировку
+	Tracer *tracing.Tracer
+	// Errors - формат ответов с ошибками middleware; нулевое значение - response.DefaultErrorRenderer
+	Errors response.ErrorRenderer
+}
+
 // NewRouter создает новый маршрутизатор
-func NewRouter(eventHandler *eh.EventHandler, logger *zap.Logger) http.Handler {
+func NewRouter(handlers Handlers, mw Middleware, logger *zap.Logger) http.Handler {
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("POST /create_event", eventHandler.CreateEvent)
-	mux.HandleFunc("POST /update_event", eventHandler.UpdateEvent)
-	mux.HandleFunc("POST /delete_event", eventHandler.DeleteEvent)
-	mux.HandleFunc("GET /events_for_day", eventHandler.EventsForDay)
-	mux.HandleFunc("GET /events_for_week", eventHandler.EventsForWeek)
-	mux.HandleFunc("GET /events_for_month", eventHandler.EventsForMonth)
+	var opts []middleware.Option
+	if mw.Errors != (response.ErrorRenderer{}) {
+		opts = append(opts, middleware.WithErrorRenderer(mw.Errors))
+	}
+
+	scoped := func(scope string, h http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(scope, logger, h, opts...)
+	}
+
+	mux.Handle("POST /create_event", scoped(auth.ScopeEventsWrite, handlers.Event.CreateEvent))
+	mux.Handle("POST /update_event", scoped(auth.ScopeEventsWrite, handlers.Event.UpdateEvent))
+	mux.Handle("POST /delete_event", scoped(auth.ScopeEventsWrite, handlers.Event.DeleteEvent))
+	mux.Handle("GET /events_for_day", scoped(auth.ScopeEventsRead, handlers.Event.EventsForDay))
+	mux.Handle("GET /events_for_week", scoped(auth.ScopeEventsRead, handlers.Event.EventsForWeek))
+	mux.Handle("GET /events_for_month", scoped(auth.ScopeEventsRead, handlers.Event.EventsForMonth))
+	mux.Handle("GET /usage", scoped(auth.ScopeEventsRead, handlers.Event.Usage))
+	mux.Handle("GET /users/{id}/calendar.ics", scoped(auth.ScopeEventsRead, handlers.Event.CalendarICS))
+	mux.Handle("POST /users/{id}/calendar.ics", scoped(auth.ScopeEventsWrite, handlers.Event.ImportICS))
+	for _, format := range []string{"csv", "jsonl"} {
+		mux.Handle("GET /users/{id}/events."+format, scoped(auth.ScopeEventsRead, handlers.Event.ExportEvents))
+		mux.Handle("POST /users/{id}/events."+format, scoped(auth.ScopeEventsWrite, handlers.Event.ImportEvents))
+	}
+
+	mux.Handle("POST /create_api_key", scoped(auth.ScopeKeysManage, handlers.APIKey.CreateAPIKey))
+	mux.Handle("GET /api_keys", scoped(auth.ScopeKeysManage, handlers.APIKey.ListAPIKeys))
+	mux.Handle("POST /rotate_api_key", scoped(auth.ScopeKeysManage, handlers.APIKey.RotateAPIKey))
+	mux.Handle("POST /revoke_api_key", scoped(auth.ScopeKeysManage, handlers.APIKey.RevokeAPIKey))
+
+	if hooks := handlers.Webhook; hooks != nil {
+		mux.Handle("POST /webhooks", scoped(auth.ScopeWebhooksManage, hooks.CreateWebhook))
+		mux.Handle("GET /webhooks", scoped(auth.ScopeWebhooksManage, hooks.ListWebhooks))
+		mux.Handle("DELETE /webhooks/{id}", scoped(auth.ScopeWebhooksManage, hooks.DeleteWebhook))
+		mux.Handle("GET /webhooks/{id}/deliveries", scoped(auth.ScopeWebhooksManage, hooks.ListDeliveries))
+		mux.Handle("GET /webhooks/dead_letters", scoped(auth.ScopeWebhooksManage, hooks.ListDeadLetters))
+		mux.Handle("POST /webhooks/deliveries/{id}/retry", scoped(auth.ScopeWebhooksManage, hooks.RetryDelivery))
+	}
+
+	if digests := handlers.Digest; digests != nil {
+		mux.Handle("GET /digests", scoped(auth.ScopeEventsRead, digests.ListDigests))
+		mux.Handle("PUT /digests/{period}", scoped(auth.ScopeEventsWrite, digests.SaveDigest))
+		mux.Handle("DELETE /digests/{period}", scoped(auth.ScopeEventsWrite, digests.DeleteDigest))
+		mux.Handle("GET /digests/{period}/preview", scoped(auth.ScopeEventsRead, digests.PreviewDigest))
+	}
+
+	if stream := handlers.Stream; stream != nil {
+		mux.Handle("GET /events/stream", scoped(auth.ScopeEventsRead, stream.UserStream))
+	}
+	if ws := handlers.WebSocket; ws != nil {
+		// Команды изменения проверяют область events:write сами
+		mux.Handle("GET /events/ws", scoped(auth.ScopeEventsRead, ws.Serve))
+	}
+
+	if dav := handlers.CalDAV; dav != nil {
+		mux.Handle("OPTIONS /caldav/", scoped(auth.ScopeEventsRead, dav.Options))
+		mux.Handle("PROPFIND /caldav/{$}", scoped(auth.ScopeEventsRead, dav.Root))
+		mux.Handle("PROPFIND /caldav/users/{id}/{$}", scoped(auth.ScopeEventsRead, dav.Principal))
+		mux.Handle("PROPFIND /caldav/users/{id}/calendar/{$}", scoped(auth.ScopeEventsRead, dav.Calendar))
+		mux.Handle("REPORT /caldav/users/{id}/calendar/{$}", scoped(auth.ScopeEventsRead, dav.Report))
+		mux.Handle("GET /caldav/users/{id}/calendar/{name}", scoped(auth.ScopeEventsRead, dav.GetObject))
+		mux.Handle("PUT /caldav/users/{id}/calendar/{name}", scoped(auth.ScopeEventsWrite, dav.PutObject))
+		mux.Handle("DELETE /caldav/users/{id}/calendar/{name}", scoped(auth.ScopeEventsWrite, dav.DeleteObject))
+	}
+
+	adminMux := http.NewServeMux()
+	adminMux.HandleFunc("GET /admin/users", handlers.Admin.ListUsers)
+	adminMux.HandleFunc("POST /admin/delete_user_events", handlers.Admin.DeleteUserEvents)
+	adminMux.HandleFunc("POST /admin/reassign_events", handlers.Admin.ReassignEvents)
+	for _, format := range []string{"csv", "jsonl"} {
+		// Выгрузка и импорт событий всех пользователей арендатора
+		adminMux.Handle("GET /admin/events."+format, scoped(auth.ScopeEventsRead, handlers.Event.ExportEvents))
+		adminMux.Handle("POST /admin/events."+format, scoped(auth.ScopeEventsWrite, handlers.Event.ImportEvents))
+	}
+	if stream := handlers.Stream; stream != nil {
+		adminMux.Handle("GET /admin/events/stream", scoped(auth.ScopeEventsRead, stream.TenantStream))
+	}
+	mux.Handle("/admin/", middleware.RequireRole(auth.RoleAdmin, logger, adminMux, opts...))
+
+	// Recovery выполняется после Auth и Tenant, чтобы паника логировалась с пользователем и арендатором
+	var handler http.Handler = middleware.Recovery(logger, mux, opts...)
+	if mw.RateLimiter != nil {
+		handler = middleware.RateLimit(mw.RateLimiter, logger, handler, opts...)
+	}
+
+	handlerWithTenant := middleware.Tenant(mw.Tenants, mw.TenantHeader, logger, handler, opts...)
+
+	authOpts := opts
+	if mw.RateLimiter != nil {
+		// Запросы без токена и с неверным токеном не доходят до RateLimit: их ограничивает Auth по адресу
+		authOpts = append(slices.Clone(opts), middleware.WithAuthFailureLimiter(mw.RateLimiter))
+	}
+	handlerWithAuth := middleware.Auth(mw.Authenticator, logger, handlerWithTenant, authOpts...)
+
+	handlerWithCORS := middleware.CORS(mw.CORS, handlerWithAuth)
+
+	handlerWithMetrics := handlerWithCORS
+	if mw.Metrics != nil {
+		handlerWithMetrics = middleware.Metrics(mw.Metrics, mux, handlerWithCORS)
+	}
+
+	handlerWithLogging := middleware.LoggingMiddleware(logger, handlerWithMetrics)
 
-	handlerWithCORS := middleware.CORS(mux)
+	handlerWithTracing := handlerWithLogging
+	if mw.Tracer != nil {
+		handlerWithTracing = middleware.Tracing(mw.Tracer, mux, logger, handlerWithLogging)
+	}
 
-	return middleware.LoggingMiddleware(logger, handlerWithCORS)
+	// Служебные эндпоинты отдаются без аутентификации и не попадают в журнал запросов
+	root := http.NewServeMux()
+	if handlers.Metrics != nil {
+		root.Handle("GET /metrics", handlers.Metrics)
+	}
+	if handlers.Health != nil {
+		root.HandleFunc("GET /healthz", handlers.Health.Liveness)
+		root.HandleFunc("GET /livez", handlers.Health.Liveness)
+		root.HandleFunc("GET /readyz", handlers.Health.Readiness)
+	}
+	if handlers.CalDAV != nil {
+		// Клиенты обращаются к /.well-known/caldav до аутентификации
+		root.HandleFunc("/.well-known/caldav", handlers.CalDAV.WellKnown)
+	}
+	root.Handle("/", middleware.RequestID(logger, handlerWithTracing))
+	return root
 }
@@ -0,0 +1,202 @@
+// Package digest - сводки событий пользователя на день или неделю: сборка, оформление
+// в текст и HTML и отправка по расписанию в часовом поясе пользователя.
+package digest
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmlTemplate "html/template"
+	"slices"
+	"strings"
+	textTemplate "text/template"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/pkg/errors"
+)
+
+const dateLayout = "2006-01-02"
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// Events - чтение событий пользователя (реализуется event_usecase.EventUseCase)
+type Events interface {
+	GetEventsForDay(ctx context.Context, userID, date string) ([]domain.Event, error)
+	GetEventsForWeek(ctx context.Context, userID, date string) ([]domain.Event, error)
+}
+
+// Message - оформленная сводка
+type Message struct {
+	// ID - ID отправки; одинаков при повторной отправке сводки за тот же период
+	ID       string        `json:"id,omitempty"`
+	TenantID string        `json:"-"`
+	UserID   string        `json:"user_id"`
+	Subject  string        `json:"subject"`
+	Text     string        `json:"text"`
+	HTML     string        `json:"html"`
+	Digest   domain.Digest `json:"digest"`
+}
+
+// Generator - сборка сводок по событиям пользователя и их оформление по шаблонам
+type Generator struct {
+	events Events
+	text   *textTemplate.Template
+	html   *htmlTemplate.Template
+}
+
+// NewGenerator - конструктор Generator со встроенными шаблонами
+func NewGenerator(events Events) *Generator {
+	return &Generator{
+		events: events,
+		text:   textTemplate.Must(textTemplate.ParseFS(templates, "templates/digest.txt.tmpl")),
+		html:   htmlTemplate.Must(htmlTemplate.ParseFS(templates, "templates/digest.html.tmpl")),
+	}
+}
+
+// Generate - сводка пользователя на день date или на неделю (с понедельника), в которую он входит
+func (g *Generator) Generate(ctx context.Context, userID, period, date string) (domain.Digest, error) {
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return domain.Digest{}, errors.ErrInvalidDate
+	}
+
+	digest := domain.Digest{UserID: userID, Period: period}
+	var events []domain.Event
+	switch period {
+	case domain.DigestDaily:
+		if events, err = g.events.GetEventsForDay(ctx, userID, date); err != nil {
+			return domain.Digest{}, err
+		}
+		digest.Days = []domain.DigestDay{{Date: date}}
+	case domain.DigestWeekly:
+		if events, err = g.events.GetEventsForWeek(ctx, userID, date); err != nil {
+			return domain.Digest{}, err
+		}
+		monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		for i := range 7 {
+			digest.Days = append(digest.Days, domain.DigestDay{Date: monday.AddDate(0, 0, i).Format(dateLayout)})
+		}
+	default:
+		return domain.Digest{}, InvalidSetting("period", period)
+	}
+	digest.From, digest.To = digest.Days[0].Date, digest.Days[len(digest.Days)-1].Date
+
+	for _, event := range events {
+		for i := range digest.Days {
+			if digest.Days[i].Date == event.Date {
+				digest.Days[i].Events = append(digest.Days[i].Events, event)
+			}
+		}
+	}
+	for i := range digest.Days {
+		slices.SortFunc(digest.Days[i].Events, compareEvents)
+		if digest.Days[i].Events == nil {
+			digest.Days[i].Events = []domain.Event{}
+		}
+	}
+	return digest, nil
+}
+
+// Render - оформление сводки в текст и HTML
+func (g *Generator) Render(digest domain.Digest) (Message, error) {
+	v := newView(digest)
+
+	var text, html bytes.Buffer
+	if err := g.text.Execute(&text, v); err != nil {
+		return Message{}, fmt.Errorf("render text digest: %w", err)
+	}
+	if err := g.html.Execute(&html, v); err != nil {
+		return Message{}, fmt.Errorf("render HTML digest: %w", err)
+	}
+
+	return Message{
+		UserID:  digest.UserID,
+		Subject: v.Subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+		Digest:  digest,
+	}, nil
+}
+
+// InvalidSetting - ошибка настройки сводки field со значением value
+func InvalidSetting(field, value string) error {
+	return errors.WithParams(
+		fmt.Errorf("%w: %s %q", errors.ErrInvalidDigest, field, value),
+		errors.Params{errors.ParamRule: field, field: value},
+	)
+}
+
+// compareEvents - сначала события на весь день, затем по времени начала, названию и ID
+func compareEvents(a, b domain.Event) int {
+	if c := strings.Compare(a.StartTime, b.StartTime); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Title, b.Title); c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// view - данные шаблонов сводки
+type view struct {
+	Subject string
+	Heading string
+	Days    []dayView
+	Summary string
+}
+
+type dayView struct {
+	Label  string
+	Events []eventView
+}
+
+type eventView struct {
+	Time  string
+	Title string
+}
+
+// newView - подписи дней и времени событий для шаблонов
+func newView(digest domain.Digest) view {
+	var v view
+	for _, day := range digest.Days {
+		date, _ := time.Parse(dateLayout, day.Date)
+		dv := dayView{Label: date.Format("Monday, January 2")}
+		for _, event := range day.Events {
+			dv.Events = append(dv.Events, eventView{Time: eventTime(event), Title: event.Title})
+		}
+		v.Days = append(v.Days, dv)
+	}
+
+	from, _ := time.Parse(dateLayout, digest.From)
+	if digest.Period == domain.DigestWeekly {
+		v.Heading = "Agenda for the week of " + from.Format("January 2, 2006")
+	} else {
+		v.Heading = "Agenda for " + from.Format("Monday, January 2, 2006")
+	}
+	v.Subject = v.Heading
+
+	switch count := digest.EventCount(); count {
+	case 0:
+		v.Summary = "No events scheduled."
+	case 1:
+		v.Summary = "1 event scheduled."
+	default:
+		v.Summary = fmt.Sprintf("%d events scheduled.", count)
+	}
+	return v
+}
+
+// eventTime - время события: "All day", "10:00" или "10:00–11:30"
+func eventTime(event domain.Event) string {
+	switch {
+	case event.AllDay():
+		return "All day"
+	case event.EndTime == "":
+		return event.StartTime
+	default:
+		return event.StartTime + "–" + event.EndTime
+	}
+}
@@ -0,0 +1,221 @@
+package digest
+
+import (
+	"context"
+	stdErrors "errors"
+	"strings"
+	"testing"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/pkg/errors"
+)
+
+// fakeEvents - события пользователей в памяти; неделя - ISO-неделя, как в хранилище событий
+type fakeEvents struct {
+	events []domain.Event
+	err    error
+}
+
+func (f *fakeEvents) GetEventsForDay(_ context.Context, userID, date string) ([]domain.Event, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var events []domain.Event
+	for _, event := range f.events {
+		if event.UserID == userID && event.Date == date {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakeEvents) GetEventsForWeek(_ context.Context, userID, date string) ([]domain.Event, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	day, _ := time.Parse(dateLayout, date)
+	year, week := day.ISOWeek()
+	var events []domain.Event
+	for _, event := range f.events {
+		eventDay, _ := time.Parse(dateLayout, event.Date)
+		if eventYear, eventWeek := eventDay.ISOWeek(); event.UserID == userID && eventYear == year && eventWeek == week {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func testEvents() *fakeEvents {
+	return &fakeEvents{events: []domain.Event{
+		{ID: "review", UserID: "user-1", Date: "2025-01-15", Title: "Code review", StartTime: "14:00", EndTime: "15:00"},
+		{ID: "standup", UserID: "user-1", Date: "2025-01-15", Title: "Standup", StartTime: "10:00"},
+		{ID: "offsite", UserID: "user-1", Date: "2025-01-15", Title: "Offsite <Q1>"},
+		{ID: "demo", UserID: "user-1", Date: "2025-01-17", Title: "Demo", StartTime: "16:00"},
+		{ID: "next-week", UserID: "user-1", Date: "2025-01-20", Title: "Planning", StartTime: "09:00"},
+		{ID: "other", UserID: "user-2", Date: "2025-01-15", Title: "Not mine", StartTime: "09:00"},
+	}}
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	generator := NewGenerator(testEvents())
+
+	tests := []struct {
+		name     string
+		period   string
+		date     string
+		wantFrom string
+		wantTo   string
+		wantDays int
+		// wantIDs - ID событий по дням сводки, в которых они есть
+		wantIDs map[string][]string
+		wantErr error
+	}{
+		{
+			name:     "daily",
+			period:   domain.DigestDaily,
+			date:     "2025-01-15",
+			wantFrom: "2025-01-15",
+			wantTo:   "2025-01-15",
+			wantDays: 1,
+			wantIDs:  map[string][]string{"2025-01-15": {"offsite", "standup", "review"}},
+		},
+		{
+			name:     "weekly from midweek",
+			period:   domain.DigestWeekly,
+			date:     "2025-01-17",
+			wantFrom: "2025-01-13",
+			wantTo:   "2025-01-19",
+			wantDays: 7,
+			wantIDs: map[string][]string{
+				"2025-01-15": {"offsite", "standup", "review"},
+				"2025-01-17": {"demo"},
+			},
+		},
+		{
+			name:     "weekly from sunday",
+			period:   domain.DigestWeekly,
+			date:     "2025-01-19",
+			wantFrom: "2025-01-13",
+			wantTo:   "2025-01-19",
+			wantDays: 7,
+			wantIDs: map[string][]string{
+				"2025-01-15": {"offsite", "standup", "review"},
+				"2025-01-17": {"demo"},
+			},
+		},
+		{name: "unknown period", period: "monthly", date: "2025-01-15", wantErr: errors.ErrInvalidDigest},
+		{name: "invalid date", period: domain.DigestDaily, date: "15.01.2025", wantErr: errors.ErrInvalidDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest, err := generator.Generate(context.Background(), "user-1", tt.period, tt.date)
+			if tt.wantErr != nil {
+				if !stdErrors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to generate digest: %v", err)
+			}
+
+			if digest.From != tt.wantFrom || digest.To != tt.wantTo || len(digest.Days) != tt.wantDays {
+				t.Fatalf("Expected %s..%s with %d days, got %s..%s with %d", tt.wantFrom, tt.wantTo, tt.wantDays,
+					digest.From, digest.To, len(digest.Days))
+			}
+			for _, day := range digest.Days {
+				var ids []string
+				for _, event := range day.Events {
+					ids = append(ids, event.ID)
+				}
+				if strings.Join(ids, ",") != strings.Join(tt.wantIDs[day.Date], ",") {
+					t.Errorf("Expected events %v on %s, got %v", tt.wantIDs[day.Date], day.Date, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerator_Render(t *testing.T) {
+	generator := NewGenerator(testEvents())
+
+	tests := []struct {
+		name        string
+		period      string
+		date        string
+		wantSubject string
+		wantText    []string
+		wantHTML    []string
+	}{
+		{
+			name:        "daily",
+			period:      domain.DigestDaily,
+			date:        "2025-01-15",
+			wantSubject: "Agenda for Wednesday, January 15, 2025",
+			wantText: []string{
+				"Wednesday, January 15\n",
+				"  All day      Offsite <Q1>\n",
+				"  10:00        Standup\n",
+				"  14:00–15:00  Code review\n",
+				"3 events scheduled.",
+			},
+			wantHTML: []string{
+				"<title>Agenda for Wednesday, January 15, 2025</title>",
+				"<td>Offsite &lt;Q1&gt;</td>",
+				"14:00–15:00",
+			},
+		},
+		{
+			name:        "weekly with empty days",
+			period:      domain.DigestWeekly,
+			date:        "2025-01-13",
+			wantSubject: "Agenda for the week of January 13, 2025",
+			wantText: []string{
+				"Monday, January 13\n  No events\n",
+				"Friday, January 17\n  16:00        Demo\n",
+				"4 events scheduled.",
+			},
+			wantHTML: []string{"No events", "<td>Demo</td>"},
+		},
+		{
+			name:        "no events",
+			period:      domain.DigestDaily,
+			date:        "2025-01-16",
+			wantSubject: "Agenda for Thursday, January 16, 2025",
+			wantText:    []string{"  No events\n", "No events scheduled."},
+			wantHTML:    []string{"No events scheduled."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest, err := generator.Generate(context.Background(), "user-1", tt.period, tt.date)
+			if err != nil {
+				t.Fatalf("Failed to generate digest: %v", err)
+			}
+			msg, err := generator.Render(digest)
+			if err != nil {
+				t.Fatalf("Failed to render digest: %v", err)
+			}
+
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("Expected subject %q, got %q", tt.wantSubject, msg.Subject)
+			}
+			if !strings.HasPrefix(msg.Text, tt.wantSubject+"\n") {
+				t.Errorf("Expected text to start with the heading, got:\n%s", msg.Text)
+			}
+			for _, want := range tt.wantText {
+				if !strings.Contains(msg.Text, want) {
+					t.Errorf("Expected text to contain %q, got:\n%s", want, msg.Text)
+				}
+			}
+			for _, want := range tt.wantHTML {
+				if !strings.Contains(msg.HTML, want) {
+					t.Errorf("Expected HTML to contain %q, got:\n%s", want, msg.HTML)
+				}
+			}
+		})
+	}
+}
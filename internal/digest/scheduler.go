@@ -0,0 +1,331 @@
+package digest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stdErrors "errors"
+	"strings"
+	"sync"
+	"time"
+
+	"calendar-server/internal/domain"
+	repo "calendar-server/internal/repository/digest_repository"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/metrics"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// sendAtLayout - формат местного времени отправки
+const sendAtLayout = "15:04"
+
+// Weekdays - дни отправки недельной сводки
+var Weekdays = map[string]time.Weekday{
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+	"sunday":    time.Sunday,
+}
+
+// ParseSendAt - разбор местного времени отправки HH:MM
+func ParseSendAt(sendAt string) (time.Time, error) {
+	at, err := time.Parse(sendAtLayout, sendAt)
+	if err != nil || len(sendAt) != len(sendAtLayout) {
+		return time.Time{}, InvalidSetting("send_at", sendAt)
+	}
+	return at, nil
+}
+
+// Slot - местная дата и время отправки сводки в день, на который в часовом поясе loc приходится now;
+// ok ложно, если в этот день сводка не отправляется
+func Slot(subscription domain.DigestSubscription, loc *time.Location, now time.Time) (date string, at time.Time, ok bool) {
+	local := now.In(loc)
+	if subscription.Period == domain.DigestWeekly && local.Weekday() != Weekdays[subscription.Weekday] {
+		return "", time.Time{}, false
+	}
+	sendAt, err := ParseSendAt(subscription.SendAt)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	at = time.Date(local.Year(), local.Month(), local.Day(), sendAt.Hour(), sendAt.Minute(), 0, 0, loc)
+	// Время, пропущенное при переходе на летнее время, time.Date переносит назад; сводка отправляется позже, а не раньше
+	if want, got := sendAt.Hour()*60+sendAt.Minute(), at.Hour()*60+at.Minute(); got != want {
+		at = at.Add(time.Duration(want-got) * time.Minute)
+	}
+	return local.Format(dateLayout), at, true
+}
+
+// Sender - отправка оформленной сводки получателю
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender - запись сводок в лог вместо отправки
+type LogSender struct {
+	logger *zap.Logger
+}
+
+// NewLogSender - конструктор LogSender
+func NewLogSender(logger *zap.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+// Send - запись сводки в лог
+func (s *LogSender) Send(_ context.Context, msg Message) error {
+	s.logger.Info("Agenda digest",
+		zappretty.Field("digest_id", msg.ID),
+		zappretty.Field("tenant_id", msg.TenantID),
+		zappretty.Field("user_id", msg.UserID),
+		zappretty.Field("period", msg.Digest.Period),
+		zappretty.Field("subject", msg.Subject),
+		zappretty.Field("events", msg.Digest.EventCount()),
+	)
+	return nil
+}
+
+// Settings - параметры планировщика сводок
+type Settings struct {
+	// CheckInterval - интервал проверки подписок; сводка отправляется не позже CheckInterval после своего времени
+	CheckInterval time.Duration
+	// CatchUp - сводка, опоздавшая больше CatchUp (например, из-за остановки сервера), пропускается
+	CatchUp time.Duration
+	// Timeout - время на сборку и отправку одной сводки
+	Timeout time.Duration
+}
+
+// DefaultSettings - проверка раз в минуту, опоздание до 3 часов
+func DefaultSettings() Settings {
+	return Settings{
+		CheckInterval: time.Minute,
+		CatchUp:       3 * time.Hour,
+		Timeout:       30 * time.Second,
+	}
+}
+
+// Option - функциональная опция Scheduler
+type Option func(*Scheduler)
+
+// WithSettings - параметры планировщика
+func WithSettings(settings Settings) Option {
+	return func(s *Scheduler) {
+		s.settings = settings
+	}
+}
+
+// WithClock - источник текущего времени
+func WithClock(now func() time.Time) Option {
+	return func(s *Scheduler) {
+		s.now = now
+	}
+}
+
+// Scheduler - отправка сводок по подпискам в местное время пользователя. Подписки сверяются с
+// часами раз в CheckInterval; дата отправленной сводки записывается в подписку, поэтому после
+// перезапуска или перевода часов назад сводка за тот же день не повторяется. Дата записывается
+// после отправки: сбой между ними приводит к повторной отправке с тем же ID.
+type Scheduler struct {
+	repo      repo.DigestRepository
+	generator *Generator
+	sender    Sender
+	logger    *zap.Logger
+	settings  Settings
+	now       func() time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// locations - загруженные часовые пояса подписок
+	mu        sync.Mutex
+	locations map[string]*time.Location
+
+	outcomes *metrics.CounterVec
+}
+
+// NewScheduler - конструктор Scheduler; проверка подписок запускается методом Start
+func NewScheduler(repo repo.DigestRepository, generator *Generator, sender Sender, logger *zap.Logger, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		repo:      repo,
+		generator: generator,
+		sender:    sender,
+		logger:    logger,
+		settings:  DefaultSettings(),
+		now:       time.Now,
+		locations: make(map[string]*time.Location),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// RegisterMetrics - регистрация метрик сводок
+func (s *Scheduler) RegisterMetrics(registry *metrics.Registry) {
+	s.outcomes = registry.NewCounterVec("calendar_digests_total",
+		"Agenda digest outcomes by period: sent, failed or missed.", "period", "outcome")
+}
+
+// Start - запуск проверки подписок
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx)
+}
+
+// Close - остановка проверки подписок; прерванная сводка будет отправлена при следующем запуске
+func (s *Scheduler) Close() error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// run - проверка подписок сразу после запуска и раз в CheckInterval
+func (s *Scheduler) run(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.settings.CheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.Check(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Warn("Failed to check digest subscriptions, will retry", zappretty.Field("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check - отправка сводок, время которых наступило. Неудачная отправка повторяется при
+// следующей проверке, пока опоздание не превысит CatchUp.
+func (s *Scheduler) Check(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "Digest.Check")
+	defer span.EndErr(&err)
+
+	subscriptions, err := s.repo.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.process(ctx, subscription)
+	}
+	return nil
+}
+
+// process - отправка или пропуск сводки одной подписки
+func (s *Scheduler) process(ctx context.Context, subscription domain.DigestSubscription) {
+	loc, err := s.location(subscription.TimeZone)
+	if err != nil {
+		s.logger.Error("Digest subscription has an unknown time zone",
+			zappretty.Field("user_id", subscription.UserID),
+			zappretty.Field("time_zone", subscription.TimeZone),
+		)
+		return
+	}
+
+	now := s.now()
+	date, at, ok := Slot(subscription, loc, now)
+	if !ok || now.Before(at) || subscription.LastDate >= date {
+		return
+	}
+
+	ctx = tenant.WithID(ctx, subscription.TenantID)
+	log := s.logger.With(
+		zappretty.Field("tenant_id", subscription.TenantID),
+		zappretty.Field("user_id", subscription.UserID),
+		zappretty.Field("period", subscription.Period),
+		zappretty.Field("date", date),
+	)
+
+	if late := now.Sub(at); late > s.settings.CatchUp {
+		log.Warn("Agenda digest missed", zappretty.Field("late", late))
+		s.mark(ctx, log, subscription, date)
+		s.count(subscription.Period, "missed")
+		return
+	}
+
+	if err := s.send(ctx, subscription, date); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn("Failed to send agenda digest, will retry", zappretty.Field("error", err))
+		s.count(subscription.Period, "failed")
+		return
+	}
+	s.mark(ctx, log, subscription, date)
+	s.count(subscription.Period, "sent")
+}
+
+// send - сборка, оформление и отправка сводки за местную дату date
+func (s *Scheduler) send(ctx context.Context, subscription domain.DigestSubscription, date string) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.settings.Timeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "Digest.Send",
+		tracing.String("user.id", subscription.UserID),
+		tracing.String("digest.period", subscription.Period),
+	)
+	defer span.EndErr(&err)
+
+	digest, err := s.generator.Generate(ctx, subscription.UserID, subscription.Period, date)
+	if err != nil {
+		return err
+	}
+	msg, err := s.generator.Render(digest)
+	if err != nil {
+		return err
+	}
+	msg.ID = MessageID(subscription.TenantID, digest)
+	msg.TenantID = subscription.TenantID
+	return s.sender.Send(ctx, msg)
+}
+
+// mark - запись даты обработанной сводки; подписка, удаленная во время отправки, пропускается
+func (s *Scheduler) mark(ctx context.Context, log *zap.Logger, subscription domain.DigestSubscription, date string) {
+	err := s.repo.MarkSent(ctx, subscription.UserID, subscription.Period, date)
+	if err != nil && !stdErrors.Is(err, errors.ErrDigestNotFound) {
+		log.Error("Failed to record agenda digest", zappretty.Field("error", err))
+	}
+}
+
+// location - часовой пояс подписки из кэша
+func (s *Scheduler) location(name string) (*time.Location, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if loc, ok := s.locations[name]; ok {
+		return loc, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	s.locations[name] = loc
+	return loc, nil
+}
+
+// count - учет итога в метриках
+func (s *Scheduler) count(period, outcome string) {
+	if s.outcomes != nil {
+		s.outcomes.Inc(period, outcome)
+	}
+}
+
+// MessageID - ID отправки сводки: зависит от арендатора, пользователя, периода и его первого дня
+func MessageID(tenantID string, digest domain.Digest) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{tenantID, digest.UserID, digest.Period, digest.From}, "\x00")))
+	return "dig_" + hex.EncodeToString(sum[:12])
+}
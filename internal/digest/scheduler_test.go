@@ -0,0 +1,251 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"calendar-server/internal/domain"
+	digestRepository "calendar-server/internal/repository/digest_repository/inmemory"
+	"calendar-server/internal/tenant"
+
+	"go.uber.org/zap"
+)
+
+// recordingSender - запоминает отправленные сводки; первые failures отправок завершаются ошибкой
+type recordingSender struct {
+	mu       sync.Mutex
+	messages []Message
+	failures int
+}
+
+func (s *recordingSender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return fmt.Errorf("mail server unavailable")
+	}
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func (s *recordingSender) sent() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+func utc(value string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04", value)
+	return t
+}
+
+func TestSlot(t *testing.T) {
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	tests := []struct {
+		name         string
+		subscription domain.DigestSubscription
+		loc          *time.Location
+		now          time.Time
+		wantDate     string
+		wantAt       time.Time
+		wantOK       bool
+	}{
+		{
+			name:         "daily in user's time zone",
+			subscription: domain.DigestSubscription{Period: domain.DigestDaily, SendAt: "07:00"},
+			loc:          moscow,
+			now:          utc("2025-01-15 03:30"),
+			wantDate:     "2025-01-15",
+			wantAt:       utc("2025-01-15 04:00"),
+			wantOK:       true,
+		},
+		{
+			name:         "local date differs from UTC",
+			subscription: domain.DigestSubscription{Period: domain.DigestDaily, SendAt: "07:00"},
+			loc:          newYork,
+			now:          utc("2025-01-16 02:00"),
+			wantDate:     "2025-01-15",
+			wantAt:       utc("2025-01-15 12:00"),
+			wantOK:       true,
+		},
+		{
+			name:         "weekly on its weekday",
+			subscription: domain.DigestSubscription{Period: domain.DigestWeekly, SendAt: "08:30", Weekday: "monday"},
+			loc:          time.UTC,
+			now:          utc("2025-01-13 12:00"),
+			wantDate:     "2025-01-13",
+			wantAt:       utc("2025-01-13 08:30"),
+			wantOK:       true,
+		},
+		{
+			name:         "weekly on another weekday",
+			subscription: domain.DigestSubscription{Period: domain.DigestWeekly, SendAt: "08:30", Weekday: "monday"},
+			loc:          time.UTC,
+			now:          utc("2025-01-14 12:00"),
+		},
+		{
+			name:         "send time skipped by daylight saving",
+			subscription: domain.DigestSubscription{Period: domain.DigestDaily, SendAt: "02:30"},
+			loc:          newYork,
+			now:          utc("2025-03-09 12:00"),
+			wantDate:     "2025-03-09",
+			wantAt:       utc("2025-03-09 07:30"),
+			wantOK:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, at, ok := Slot(tt.subscription, tt.loc, tt.now)
+			if ok != tt.wantOK || date != tt.wantDate || (ok && !at.Equal(tt.wantAt)) {
+				t.Errorf("Expected %s at %v (%v), got %s at %v (%v)", tt.wantDate, tt.wantAt, tt.wantOK, date, at.UTC(), ok)
+			}
+		})
+	}
+}
+
+func TestScheduler_Check(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	tests := []struct {
+		name     string
+		lastDate string
+		now      time.Time
+		failures int
+		wantSent int
+		wantLast string
+	}{
+		{name: "before send time", now: utc("2025-01-15 03:59")},
+		{name: "at send time", now: utc("2025-01-15 04:00"), wantSent: 1, wantLast: "2025-01-15"},
+		{name: "already sent", lastDate: "2025-01-15", now: utc("2025-01-15 05:00"), wantLast: "2025-01-15"},
+		{name: "late within catch-up", now: utc("2025-01-15 06:30"), wantSent: 1, wantLast: "2025-01-15"},
+		{name: "missed after catch-up", now: utc("2025-01-15 07:30"), wantLast: "2025-01-15"},
+		{name: "failed send is retried", now: utc("2025-01-15 04:00"), failures: 1, wantLast: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := digestRepository.NewDigestRepository(logger)
+			subscription := domain.DigestSubscription{
+				UserID: "user-1", TenantID: "acme", Period: domain.DigestDaily,
+				TimeZone: "Europe/Moscow", SendAt: "07:00", LastDate: tt.lastDate,
+			}
+			if err := repo.Save(ctx, subscription); err != nil {
+				t.Fatalf("Failed to save subscription: %v", err)
+			}
+
+			sender := &recordingSender{failures: tt.failures}
+			scheduler := NewScheduler(repo, NewGenerator(testEvents()), sender, logger,
+				WithClock(func() time.Time { return tt.now }),
+			)
+			if err := scheduler.Check(context.Background()); err != nil {
+				t.Fatalf("Failed to check subscriptions: %v", err)
+			}
+
+			if got := len(sender.sent()); got != tt.wantSent {
+				t.Errorf("Expected %d digests sent, got %d", tt.wantSent, got)
+			}
+			stored, _ := repo.Get(ctx, "user-1", domain.DigestDaily)
+			if stored.LastDate != tt.wantLast {
+				t.Errorf("Expected last date %q, got %q", tt.wantLast, stored.LastDate)
+			}
+		})
+	}
+}
+
+func TestScheduler_SendsOncePerDay(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := tenant.WithID(context.Background(), "acme")
+	repo := digestRepository.NewDigestRepository(logger)
+	if err := repo.Save(ctx, domain.DigestSubscription{
+		UserID: "user-1", TenantID: "acme", Period: domain.DigestDaily, TimeZone: "UTC", SendAt: "07:00",
+	}); err != nil {
+		t.Fatalf("Failed to save subscription: %v", err)
+	}
+
+	var mu sync.Mutex
+	now := utc("2025-01-15 07:00")
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	sender := &recordingSender{failures: 1}
+	newScheduler := func() *Scheduler {
+		return NewScheduler(repo, NewGenerator(testEvents()), sender, logger, WithClock(clock))
+	}
+
+	scheduler := newScheduler()
+	// Первая отправка не удалась, вторая проверка ее повторяет, третья не повторяет отправленную
+	for range 3 {
+		if err := scheduler.Check(context.Background()); err != nil {
+			t.Fatalf("Failed to check subscriptions: %v", err)
+		}
+	}
+	// После перезапуска и перевода часов назад сводка не повторяется
+	mu.Lock()
+	now = utc("2025-01-15 07:10")
+	mu.Unlock()
+	if err := newScheduler().Check(context.Background()); err != nil {
+		t.Fatalf("Failed to check subscriptions: %v", err)
+	}
+
+	sent := sender.sent()
+	if len(sent) != 1 {
+		t.Fatalf("Expected one digest, got %d", len(sent))
+	}
+	msg := sent[0]
+	if msg.TenantID != "acme" || msg.UserID != "user-1" || msg.Digest.From != "2025-01-15" || msg.Digest.EventCount() != 3 {
+		t.Errorf("Unexpected digest %+v", msg.Digest)
+	}
+	if want := MessageID("acme", msg.Digest); msg.ID != want {
+		t.Errorf("Expected message ID %s, got %s", want, msg.ID)
+	}
+
+	// На следующий день сводка отправляется снова
+	mu.Lock()
+	now = utc("2025-01-16 07:00")
+	mu.Unlock()
+	if err := scheduler.Check(context.Background()); err != nil {
+		t.Fatalf("Failed to check subscriptions: %v", err)
+	}
+	if got := len(sender.sent()); got != 2 {
+		t.Errorf("Expected a digest on the next day, got %d digests", got)
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := tenant.WithID(context.Background(), "acme")
+	repo := digestRepository.NewDigestRepository(logger)
+	if err := repo.Save(ctx, domain.DigestSubscription{
+		UserID: "user-1", TenantID: "acme", Period: domain.DigestDaily, TimeZone: "UTC", SendAt: "07:00",
+	}); err != nil {
+		t.Fatalf("Failed to save subscription: %v", err)
+	}
+
+	settings := DefaultSettings()
+	settings.CheckInterval = 10 * time.Millisecond
+	sender := &recordingSender{failures: 1}
+	scheduler := NewScheduler(repo, NewGenerator(testEvents()), sender, logger,
+		WithSettings(settings),
+		WithClock(func() time.Time { return utc("2025-01-15 07:05") }),
+	)
+	scheduler.Start()
+	defer scheduler.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sender.sent()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := len(sender.sent()); got != 1 {
+		t.Fatalf("Expected the failed digest to be retried once, got %d digests", got)
+	}
+}
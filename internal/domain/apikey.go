@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// APIKey представляет долгоживущий ключ доступа для сервисных интеграций
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	TenantID   string     `json:"tenant_id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Hash       string     `json:"-"` // sha256 от ключа, сам ключ не хранится
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Revoked - отозван ли ключ
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
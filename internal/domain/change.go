@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// Типы изменений событий
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// Change - уведомление об изменении события для ленты изменений
+type Change struct {
+	// ID - позиция в ленте; по ней клиент возобновляет чтение (Last-Event-ID)
+	ID string `json:"id"`
+	// MessageID - ID доменного события в outbox; одинаков при повторной отправке, по нему потребители отбрасывают повторы
+	MessageID string `json:"message_id,omitempty"`
+	Type      string `json:"type"`
+	TenantID  string `json:"-"`
+	UserID    string `json:"user_id"`
+	EventID   string `json:"event_id"`
+	// Event - состояние события после изменения; для удаления не заполняется
+	Event *Event    `json:"event,omitempty"`
+	At    time.Time `json:"at"`
+}
@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// Периоды сводки
+const (
+	// DigestDaily - сводка на день
+	DigestDaily = "daily"
+	// DigestWeekly - сводка на неделю с понедельника по воскресенье
+	DigestWeekly = "weekly"
+)
+
+// DigestPeriods - поддерживаемые периоды сводки
+var DigestPeriods = []string{DigestDaily, DigestWeekly}
+
+// DigestSubscription - подписка пользователя на регулярную сводку событий.
+// У пользователя не больше одной подписки каждого периода.
+type DigestSubscription struct {
+	UserID   string `json:"user_id"`
+	TenantID string `json:"-"`
+	Period   string `json:"period"`
+	// TimeZone - часовой пояс пользователя (IANA), в котором выбирается день сводки и время отправки
+	TimeZone string `json:"time_zone"`
+	// SendAt - местное время отправки HH:MM
+	SendAt string `json:"send_at"`
+	// Weekday - день отправки недельной сводки (monday-sunday); пусто для дневной
+	Weekday string `json:"weekday,omitempty"`
+	// LastDate - местная дата последней отправленной или пропущенной сводки
+	LastDate  string    `json:"last_date,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Digest - события пользователя за день или неделю, собранные для сводки
+type Digest struct {
+	UserID string `json:"user_id"`
+	Period string `json:"period"`
+	// From, To - первый и последний день сводки
+	From string      `json:"from"`
+	To   string      `json:"to"`
+	Days []DigestDay `json:"days"`
+}
+
+// DigestDay - события одного дня сводки: сначала события на весь день, затем по времени начала
+type DigestDay struct {
+	Date   string  `json:"date"`
+	Events []Event `json:"events"`
+}
+
+// EventCount - количество событий в сводке
+func (d Digest) EventCount() int {
+	count := 0
+	for _, day := range d.Days {
+		count += len(day.Events)
+	}
+	return count
+}
@@ -1,9 +1,91 @@
 package domain
 
+import "slices"
+
 // Event представляет событие в календаре
 type Event struct {
 	ID     string `json:"id"`
 	UserID string `json:"user_id"`
 	Date   string `json:"date"` // YYYY-MM-DD
 	Title  string `json:"title"`
+	// StartTime, EndTime - время начала и окончания "HH:MM"; без StartTime событие длится весь день
+	StartTime string `json:"start_time,omitempty"`
+	EndTime   string `json:"end_time,omitempty"`
+	// Reminders - напоминания о начале события
+	Reminders []Reminder `json:"reminders,omitempty"`
+}
+
+// Reminder - напоминание за MinutesBefore минут до начала события (для события на весь день - до полуночи)
+type Reminder struct {
+	MinutesBefore int `json:"minutes_before"`
+	// Method - способ доставки (ReminderLog, ReminderWebhook, ReminderEmail); пусто - способ по умолчанию
+	Method string `json:"method,omitempty"`
+}
+
+// Способы доставки напоминаний
+const (
+	ReminderLog     = "log"
+	ReminderWebhook = "webhook"
+	ReminderEmail   = "email"
+)
+
+// ReminderMethods - известные способы доставки напоминаний
+var ReminderMethods = []string{ReminderLog, ReminderWebhook, ReminderEmail}
+
+const (
+	// MaxReminders - напоминаний у одного события
+	MaxReminders = 5
+	// MaxReminderMinutes - самое раннее напоминание: за 4 недели до начала
+	MaxReminderMinutes = 4 * 7 * 24 * 60
+)
+
+// AllDay - событие на весь день
+func (e Event) AllDay() bool {
+	return e.StartTime == ""
+}
+
+// Equal - совпадают ли все поля событий, включая напоминания
+func (e Event) Equal(other Event) bool {
+	return e.ID == other.ID && e.UserID == other.UserID && e.Date == other.Date && e.Title == other.Title &&
+		e.StartTime == other.StartTime && e.EndTime == other.EndTime && slices.Equal(e.Reminders, other.Reminders)
+}
+
+// Field - значение поля события по его JSON-имени; ok = false для неизвестного поля
+func (e Event) Field(name string) (value string, ok bool) {
+	switch name {
+	case "id":
+		return e.ID, true
+	case "user_id":
+		return e.UserID, true
+	case "date":
+		return e.Date, true
+	case "title":
+		return e.Title, true
+	case "start_time":
+		return e.StartTime, true
+	case "end_time":
+		return e.EndTime, true
+	}
+	return "", false
+}
+
+// SetField - установка поля события по его JSON-имени; false для неизвестного поля
+func (e *Event) SetField(name, value string) bool {
+	switch name {
+	case "id":
+		e.ID = value
+	case "user_id":
+		e.UserID = value
+	case "date":
+		e.Date = value
+	case "title":
+		e.Title = value
+	case "start_time":
+		e.StartTime = value
+	case "end_time":
+		e.EndTime = value
+	default:
+		return false
+	}
+	return true
 }
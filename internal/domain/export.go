@@ -0,0 +1,16 @@
+package domain
+
+// ExportFilter - отбор событий для выгрузки
+type ExportFilter struct {
+	// UserID - владелец событий; пустое значение означает все события арендатора
+	UserID string
+	// From, To - необязательные границы периода (YYYY-MM-DD) включительно
+	From string
+	To   string
+}
+
+// Match - событие попадает в период фильтра
+func (f ExportFilter) Match(event Event) bool {
+	// Даты в формате YYYY-MM-DD сравниваются как строки
+	return (f.From == "" || event.Date >= f.From) && (f.To == "" || event.Date <= f.To)
+}
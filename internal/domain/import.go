@@ -0,0 +1,70 @@
+package domain
+
+// Режимы импорта
+const (
+	// ImportMerge - новые события создаются, существующие обновляются
+	ImportMerge = "merge"
+	// ImportReplace - как merge, а события пользователя, которых нет в импорте, удаляются
+	ImportReplace = "replace"
+)
+
+// Результаты импорта отдельного события
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+	ImportDeleted = "deleted"
+)
+
+// ImportOptions - параметры импорта
+type ImportOptions struct {
+	Mode   string
+	DryRun bool
+}
+
+// ImportCandidate - событие из импортируемого файла; Err - ошибка разбора, событие не импортируется
+type ImportCandidate struct {
+	// Source - положение в исходном файле (UID, номер строки)
+	Source string
+	Event  Event
+	Err    error
+}
+
+// ImportItem - результат импорта одного события
+type ImportItem struct {
+	Source  string `json:"source,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+	Status  string `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ImportReport - итог импорта
+type ImportReport struct {
+	Mode    string       `json:"mode"`
+	DryRun  bool         `json:"dry_run"`
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Skipped int          `json:"skipped"`
+	Failed  int          `json:"failed"`
+	Deleted int          `json:"deleted"`
+	Items   []ImportItem `json:"items"`
+}
+
+// Add - добавляет результат и обновляет счетчики
+func (r *ImportReport) Add(item ImportItem) {
+	switch item.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportUpdated:
+		r.Updated++
+	case ImportSkipped:
+		r.Skipped++
+	case ImportFailed:
+		r.Failed++
+	case ImportDeleted:
+		r.Deleted++
+	}
+	r.Items = append(r.Items, item)
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// OutboxMessage - доменное событие, записанное хранилищем в той же операции, что и изменение события.
+// Сообщение хранится до подтверждения отправки, поэтому сбой после записи не теряет уведомление.
+type OutboxMessage struct {
+	// ID - идентификатор для отбрасывания повторов; не меняется при повторной отправке
+	ID        string
+	Change    Change
+	CreatedAt time.Time
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// Итоги срабатывания напоминания
+const (
+	// ReminderSent - напоминание доставлено
+	ReminderSent = "sent"
+	// ReminderFailed - попытки доставки исчерпаны или способ доставки не настроен
+	ReminderFailed = "failed"
+	// ReminderMissed - сервер был остановлен или часы сдвинулись, и напоминание опоздало больше допустимого
+	ReminderMissed = "missed"
+)
+
+// ReminderFiring - итог срабатывания одного напоминания события. ID выводится из события,
+// напоминания и времени срабатывания, поэтому после перезапуска то же срабатывание не повторяется,
+// а изменение времени события или напоминания дает новое.
+type ReminderFiring struct {
+	ID            string    `json:"id"`
+	TenantID      string    `json:"-"`
+	UserID        string    `json:"user_id"`
+	EventID       string    `json:"event_id"`
+	MinutesBefore int       `json:"minutes_before"`
+	Method        string    `json:"method"`
+	FireAt        time.Time `json:"fire_at"`
+	FiredAt       time.Time `json:"fired_at"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+}
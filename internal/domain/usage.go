@@ -0,0 +1,18 @@
+package domain
+
+// UsageMeter - использование одного лимита; Limit = 0 означает отсутствие ограничения
+type UsageMeter struct {
+	Used  int `json:"used"`
+	Limit int `json:"limit,omitempty"`
+}
+
+// Usage - текущее использование квот пользователем
+type Usage struct {
+	TenantID       string      `json:"tenant_id"`
+	UserID         string      `json:"user_id"`
+	TenantEvents   UsageMeter  `json:"tenant_events"`
+	UserEvents     UsageMeter  `json:"user_events"`
+	Date           string      `json:"date,omitempty"`
+	DayEvents      *UsageMeter `json:"day_events,omitempty"`
+	MaxTitleLength int         `json:"max_title_length,omitempty"`
+}
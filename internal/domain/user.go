@@ -0,0 +1,7 @@
+package domain
+
+// UserSummary представляет пользователя и количество его событий
+type UserSummary struct {
+	UserID     string `json:"user_id"`
+	EventCount int    `json:"event_count"`
+}
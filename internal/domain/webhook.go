@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Webhook - подписка внешней системы на изменения событий
+type Webhook struct {
+	ID       string `json:"id"`
+	UserID   string `json:"user_id"`
+	TenantID string `json:"tenant_id"`
+	URL      string `json:"url"`
+	// EventTypes - типы изменений (ChangeCreated, ChangeUpdated, ChangeDeleted); пусто - все
+	EventTypes []string `json:"event_types,omitempty"`
+	// FilterUserID - владелец календаря, изменения которого отправляются; пусто - все пользователи арендатора
+	FilterUserID string    `json:"filter_user_id,omitempty"`
+	Secret       string    `json:"-"` // ключ подписи HMAC; показывается только при создании
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Matches - отправляется ли изменение этому вебхуку
+func (w Webhook) Matches(change Change) bool {
+	if change.TenantID != w.TenantID {
+		return false
+	}
+	if w.FilterUserID != "" && change.UserID != w.FilterUserID {
+		return false
+	}
+	return len(w.EventTypes) == 0 || slices.Contains(w.EventTypes, change.Type)
+}
+
+// Состояния доставки вебхука
+const (
+	// DeliveryPending - доставка ожидает отправки или повтора
+	DeliveryPending = "pending"
+	// DeliverySucceeded - получатель ответил 2xx
+	DeliverySucceeded = "succeeded"
+	// DeliveryDead - попытки исчерпаны или получатель отклонил запрос; доставка в списке недоставленных
+	DeliveryDead = "dead"
+)
+
+// WebhookDelivery - отправка одного изменения одному вебхуку со всеми попытками.
+// ID доставки не меняется между попытками, по нему получатель отбрасывает повторы.
+type WebhookDelivery struct {
+	ID        string `json:"id"`
+	WebhookID string `json:"webhook_id"`
+	UserID    string `json:"-"`
+	TenantID  string `json:"-"`
+	ChangeID  string `json:"change_id"`
+	EventType string `json:"event_type"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	// ResponseStatus - код ответа на последнюю попытку; 0, если ответа не было
+	ResponseStatus int    `json:"response_status,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	// Payload - тело запроса; одинаково во всех попытках, чтобы подпись совпадала
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	CompletedAt   *time.Time      `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,223 @@
+// Package outbox - отправка доменных событий, записанных хранилищем вместе с изменениями,
+// в шину изменений с гарантией хотя бы одной доставки.
+package outbox
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"calendar-server/internal/domain"
+	repo "calendar-server/internal/repository/event_repository"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/metrics"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// Publisher - получатель изменений (реализуется pubsub.Hub). Изменение без присвоенного ID
+// означает, что получатель остановлен и сообщение нужно оставить в outbox.
+type Publisher interface {
+	Publish(change domain.Change) domain.Change
+}
+
+// Settings - параметры отправки outbox
+type Settings struct {
+	// BatchSize - сообщения, читаемые и подтверждаемые за одну операцию хранилища
+	BatchSize int
+	// PollInterval - интервал проверки outbox, если Wake не вызывался; после ошибок хранилища - задержка повтора
+	PollInterval time.Duration
+	// DedupWindow - последние отправленные ID, повторы которых отбрасываются
+	DedupWindow int
+}
+
+// DefaultSettings - пачки по 100 сообщений, проверка раз в секунду, окно повторов 4096
+func DefaultSettings() Settings {
+	return Settings{
+		BatchSize:    100,
+		PollInterval: time.Second,
+		DedupWindow:  4096,
+	}
+}
+
+// Option - функциональная опция Relay
+type Option func(*Relay)
+
+// WithSettings - параметры отправки
+func WithSettings(settings Settings) Option {
+	return func(r *Relay) {
+		r.settings = settings
+	}
+}
+
+// Relay - перенос сообщений outbox в шину изменений. Сообщение подтверждается после публикации:
+// сбой между ними приводит к повторной отправке с тем же MessageID, поэтому потребители
+// должны отбрасывать повторы. Повторы в пределах одного запуска Relay отбрасывает сам.
+type Relay struct {
+	store     repo.Outbox
+	publisher Publisher
+	logger    *zap.Logger
+	settings  Settings
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// mu - Drain выполняется по одному и защищает окно повторов
+	mu sync.Mutex
+	// seen - недавно отправленные ID; recent - они же в порядке отправки для вытеснения старых
+	seen   map[string]struct{}
+	recent []string
+	next   int
+
+	published  atomic.Int64
+	duplicates atomic.Int64
+}
+
+// NewRelay - конструктор Relay; отправка запускается методом Start
+func NewRelay(store repo.Outbox, publisher Publisher, logger *zap.Logger, opts ...Option) *Relay {
+	r := &Relay{
+		store:     store,
+		publisher: publisher,
+		logger:    logger,
+		settings:  DefaultSettings(),
+		wake:      make(chan struct{}, 1),
+		seen:      make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.recent = make([]string, r.settings.DedupWindow)
+	return r
+}
+
+// RegisterMetrics - регистрация метрик outbox
+func (r *Relay) RegisterMetrics(registry *metrics.Registry) {
+	registry.NewCounterFunc("calendar_outbox_published_total", "Outbox messages published to the change feed.", func() float64 {
+		return float64(r.published.Load())
+	})
+	registry.NewCounterFunc("calendar_outbox_duplicates_total", "Outbox messages skipped because they were already published.", func() float64 {
+		return float64(r.duplicates.Load())
+	})
+}
+
+// Start - запуск отправки; сообщения, оставшиеся с прошлого запуска, отправляются сразу
+func (r *Relay) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.run(ctx)
+}
+
+// Wake - уведомление о новых сообщениях; не блокируется
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close - остановка отправки. Неотправленные сообщения остаются в outbox до следующего запуска.
+func (r *Relay) Close() error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	<-r.done
+	return nil
+}
+
+// run - цикл отправки: outbox читается до конца после каждого Wake и раз в PollInterval
+func (r *Relay) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.settings.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Drain(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Warn("Failed to relay outbox messages, will retry", zappretty.Field("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain - отправка всех сообщений outbox, накопленных к моменту вызова
+func (r *Relay) Drain(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "Outbox.Drain")
+	defer span.EndErr(&err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := 0
+	defer func() {
+		span.SetAttributes(tracing.Int("outbox.messages", total))
+	}()
+
+	for {
+		messages, err := r.store.PendingMessages(ctx, r.settings.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]string, 0, len(messages))
+		for _, msg := range messages {
+			if r.isDuplicate(msg.ID) {
+				r.duplicates.Add(1)
+				ids = append(ids, msg.ID)
+				continue
+			}
+
+			change := msg.Change
+			change.MessageID = msg.ID
+			if published := r.publisher.Publish(change); published.ID == "" {
+				// Шина остановлена: отправленное подтверждаем, остальное ждет следующего запуска
+				return r.store.AckMessages(ctx, ids)
+			}
+			r.remember(msg.ID)
+			r.published.Add(1)
+			ids = append(ids, msg.ID)
+		}
+
+		// Подтверждение может не дойти до хранилища: сообщения вернутся и будут отброшены как повторы
+		if err := r.store.AckMessages(ctx, ids); err != nil {
+			return err
+		}
+		total += len(messages)
+
+		if len(messages) < r.settings.BatchSize {
+			return nil
+		}
+	}
+}
+
+// isDuplicate - сообщение уже отправлено этим Relay; вызывается под r.mu
+func (r *Relay) isDuplicate(id string) bool {
+	_, ok := r.seen[id]
+	return ok
+}
+
+// remember - запоминание отправленного ID с вытеснением самого старого; вызывается под r.mu
+func (r *Relay) remember(id string) {
+	if len(r.recent) == 0 {
+		return
+	}
+	if old := r.recent[r.next]; old != "" {
+		delete(r.seen, old)
+	}
+	r.recent[r.next] = id
+	r.seen[id] = struct{}{}
+	r.next = (r.next + 1) % len(r.recent)
+}
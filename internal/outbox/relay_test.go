@@ -0,0 +1,178 @@
+package outbox
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"calendar-server/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// memoryStore - outbox в памяти с возможностью сбоя подтверждения
+type memoryStore struct {
+	mu       sync.Mutex
+	messages []domain.OutboxMessage
+	ackErr   error
+}
+
+func (s *memoryStore) PendingMessages(_ context.Context, limit int) ([]domain.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.messages[:min(limit, len(s.messages))]), nil
+}
+
+func (s *memoryStore) AckMessages(_ context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ackErr != nil {
+		return s.ackErr
+	}
+	s.messages = slices.DeleteFunc(s.messages, func(msg domain.OutboxMessage) bool {
+		return slices.Contains(ids, msg.ID)
+	})
+	return nil
+}
+
+func (s *memoryStore) add(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.messages = append(s.messages, domain.OutboxMessage{
+			ID:     id,
+			Change: domain.Change{Type: domain.ChangeCreated, EventID: "event-" + id},
+		})
+	}
+}
+
+func (s *memoryStore) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.messages)
+}
+
+// recordingPublisher - запоминает опубликованные изменения; после closeAfter публикаций ведет себя как остановленная шина
+type recordingPublisher struct {
+	mu         sync.Mutex
+	changes    []domain.Change
+	closeAfter int
+}
+
+func (p *recordingPublisher) Publish(change domain.Change) domain.Change {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closeAfter > 0 && len(p.changes) >= p.closeAfter {
+		return change
+	}
+	p.changes = append(p.changes, change)
+	change.ID = fmt.Sprint(len(p.changes))
+	return change
+}
+
+func (p *recordingPublisher) messageIDs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]string, 0, len(p.changes))
+	for _, change := range p.changes {
+		ids = append(ids, change.MessageID)
+	}
+	return ids
+}
+
+func newTestRelay(store *memoryStore, publisher *recordingPublisher) *Relay {
+	logger, _ := zap.NewDevelopment()
+	settings := DefaultSettings()
+	settings.BatchSize = 2
+	settings.PollInterval = time.Hour
+	return NewRelay(store, publisher, logger, WithSettings(settings))
+}
+
+func TestRelay_Drain(t *testing.T) {
+	store := &memoryStore{}
+	publisher := &recordingPublisher{}
+	relay := newTestRelay(store, publisher)
+
+	store.add("m1", "m2", "m3")
+	if err := relay.Drain(context.Background()); err != nil {
+		t.Fatalf("Failed to drain outbox: %v", err)
+	}
+
+	if got := publisher.messageIDs(); !slices.Equal(got, []string{"m1", "m2", "m3"}) {
+		t.Errorf("Expected messages published in order with IDs, got %v", got)
+	}
+	if store.pending() != 0 {
+		t.Errorf("Expected outbox to be empty, got %d messages", store.pending())
+	}
+	if relay.published.Load() != 3 {
+		t.Errorf("Expected 3 published messages, got %d", relay.published.Load())
+	}
+}
+
+func TestRelay_AckFailure(t *testing.T) {
+	store := &memoryStore{ackErr: stdErrors.New("store unavailable")}
+	publisher := &recordingPublisher{}
+	relay := newTestRelay(store, publisher)
+
+	store.add("m1")
+	if err := relay.Drain(context.Background()); err == nil {
+		t.Fatal("Expected ack error")
+	}
+	if store.pending() != 1 {
+		t.Fatalf("Expected message to stay in outbox, got %d", store.pending())
+	}
+
+	// Сообщение вернулось из outbox, но повторно не публикуется
+	store.ackErr = nil
+	if err := relay.Drain(context.Background()); err != nil {
+		t.Fatalf("Failed to drain outbox: %v", err)
+	}
+	if got := publisher.messageIDs(); !slices.Equal(got, []string{"m1"}) {
+		t.Errorf("Expected m1 published once, got %v", got)
+	}
+	if relay.duplicates.Load() != 1 || store.pending() != 0 {
+		t.Errorf("Expected duplicate to be acked, got %d duplicates and %d pending", relay.duplicates.Load(), store.pending())
+	}
+}
+
+func TestRelay_PublisherClosed(t *testing.T) {
+	store := &memoryStore{}
+	publisher := &recordingPublisher{closeAfter: 1}
+	relay := newTestRelay(store, publisher)
+
+	store.add("m1", "m2", "m3")
+	if err := relay.Drain(context.Background()); err != nil {
+		t.Fatalf("Failed to drain outbox: %v", err)
+	}
+
+	if got := publisher.messageIDs(); !slices.Equal(got, []string{"m1"}) {
+		t.Errorf("Expected only m1 published, got %v", got)
+	}
+	if store.pending() != 2 {
+		t.Errorf("Expected unpublished messages to stay in outbox, got %d", store.pending())
+	}
+}
+
+func TestRelay_Wake(t *testing.T) {
+	store := &memoryStore{}
+	publisher := &recordingPublisher{}
+	relay := newTestRelay(store, publisher)
+
+	relay.Start()
+	defer relay.Close()
+
+	store.add("m1")
+	relay.Wake()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.pending() != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := publisher.messageIDs(); !slices.Equal(got, []string{"m1"}) {
+		t.Errorf("Expected m1 published after Wake, got %v", got)
+	}
+}
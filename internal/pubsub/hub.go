@@ -0,0 +1,256 @@
+// Package pubsub - внутрипроцессная шина изменений событий календаря для ленты изменений.
+package pubsub
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/metrics"
+)
+
+// Причины закрытия подписки
+var (
+	// ErrSlowSubscriber - подписчик не успевал читать, и его буфер переполнился
+	ErrSlowSubscriber = stdErrors.New("subscriber buffer overflow")
+	// ErrClosed - шина остановлена
+	ErrClosed = stdErrors.New("hub closed")
+)
+
+const (
+	// DefaultBuffer - размер буфера подписчика по умолчанию
+	DefaultBuffer = 64
+	// DefaultHistory - количество последних изменений для возобновления чтения по умолчанию
+	DefaultHistory = 1024
+)
+
+// Filter - изменения, на которые подписан клиент
+type Filter struct {
+	TenantID string
+	// UserID - владелец событий; пустое значение означает всех пользователей арендатора
+	UserID string
+	// AllTenants - изменения всех арендаторов, для внутренних потребителей вроде вебхуков
+	AllTenants bool
+}
+
+// match - изменение подходит под фильтр
+func (f Filter) match(change domain.Change) bool {
+	return (f.AllTenants || change.TenantID == f.TenantID) && (f.UserID == "" || change.UserID == f.UserID)
+}
+
+// entry - изменение в истории с его порядковым номером
+type entry struct {
+	seq    uint64
+	change domain.Change
+}
+
+// Hub - шина изменений. Publish не блокируется: подписчик, переполнивший буфер, отключается
+// и может переподключиться с Last-Event-ID, получив пропущенное из истории.
+// Идентификатор изменения "<эпоха>-<номер>" включает эпоху запуска, поэтому позиции из
+// прошлого запуска сервера не принимаются за текущие.
+type Hub struct {
+	mu          sync.Mutex
+	epoch       string
+	seq         uint64
+	history     []entry
+	start       int
+	size        int
+	buffer      int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+
+	published atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// NewHub - конструктор шины: buffer - размер буфера подписчика, history - длина истории
+func NewHub(buffer, history int) *Hub {
+	return &Hub{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		history:     make([]entry, history),
+		buffer:      buffer,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// RegisterMetrics - метрики подписчиков и отправленных изменений
+func (h *Hub) RegisterMetrics(registry *metrics.Registry) {
+	registry.NewGaugeFunc("calendar_stream_subscribers", "Number of active change feed subscribers.", func() float64 {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return float64(len(h.subscribers))
+	})
+	registry.NewCounterFunc("calendar_stream_published_total", "Changes published to the change feed.", func() float64 {
+		return float64(h.published.Load())
+	})
+	registry.NewCounterFunc("calendar_stream_dropped_total", "Subscribers disconnected because their buffer overflowed.", func() float64 {
+		return float64(h.dropped.Load())
+	})
+}
+
+// Publish - рассылка изменения подписчикам; возвращает изменение с присвоенным ID
+func (h *Hub) Publish(change domain.Change) domain.Change {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return change
+	}
+
+	h.seq++
+	change.ID = h.id(h.seq)
+	h.remember(entry{seq: h.seq, change: change})
+	h.published.Add(1)
+
+	for sub := range h.subscribers {
+		if !sub.filter.match(change) {
+			continue
+		}
+		select {
+		case sub.ch <- change:
+		default:
+			h.dropped.Add(1)
+			h.remove(sub, ErrSlowSubscriber)
+		}
+	}
+	return change
+}
+
+// Subscribe - подписка на изменения. С непустым lastEventID подписчик сначала получает изменения
+// после него из истории; если история их уже не содержит, Subscription.Reset сообщает, что клиенту
+// нужно перечитать данные целиком. Некорректный lastEventID - errors.ErrInvalidLastEventID.
+func (h *Hub) Subscribe(filter Filter, lastEventID string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var replay []domain.Change
+	reset := false
+	if lastEventID != "" {
+		epoch, seq, err := parseID(lastEventID)
+		if err != nil {
+			return nil, err
+		}
+
+		oldest := h.seq - uint64(h.size) + 1
+		switch {
+		case epoch != h.epoch || seq > h.seq || seq+1 < oldest:
+			reset = true
+		default:
+			for i := 0; i < h.size; i++ {
+				e := h.history[(h.start+i)%len(h.history)]
+				if e.seq > seq && filter.match(e.change) {
+					replay = append(replay, e.change)
+				}
+			}
+		}
+	}
+
+	// Пропущенные изменения помещаются в буфер целиком и не вызывают переполнения
+	sub := &Subscription{
+		hub:    h,
+		filter: filter,
+		ch:     make(chan domain.Change, h.buffer+len(replay)),
+		Reset:  reset,
+	}
+	for _, change := range replay {
+		sub.ch <- change
+	}
+
+	if h.closed {
+		sub.err = ErrClosed
+		close(sub.ch)
+		return sub, nil
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Close - остановка шины: все подписки закрываются, новые закрываются сразу
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subscribers {
+		h.remove(sub, ErrClosed)
+	}
+}
+
+// remember - добавление изменения в кольцевой буфер истории
+func (h *Hub) remember(e entry) {
+	if len(h.history) == 0 {
+		return
+	}
+	if h.size < len(h.history) {
+		h.history[(h.start+h.size)%len(h.history)] = e
+		h.size++
+		return
+	}
+	h.history[h.start] = e
+	h.start = (h.start + 1) % len(h.history)
+}
+
+// remove - отключение подписчика; вызывается под h.mu
+func (h *Hub) remove(sub *Subscription, reason error) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	sub.err = reason
+	close(sub.ch)
+}
+
+// id - идентификатор изменения с номером seq
+func (h *Hub) id(seq uint64) string {
+	return h.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// parseID - разбор идентификатора "<эпоха>-<номер>"
+func parseID(id string) (string, uint64, error) {
+	epoch, number, ok := strings.Cut(id, "-")
+	seq, err := strconv.ParseUint(number, 10, 64)
+	if !ok || epoch == "" || err != nil {
+		return "", 0, errors.WithParams(
+			fmt.Errorf("%w: %q", errors.ErrInvalidLastEventID, id),
+			errors.Params{"id": id},
+		)
+	}
+	return epoch, seq, nil
+}
+
+// Subscription - подписка на изменения
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	ch     chan domain.Change
+	// err - причина закрытия; читается после закрытия ch
+	err error
+	// Reset - пропущенные изменения недоступны, клиенту нужно перечитать данные
+	Reset bool
+}
+
+// C - канал изменений; закрывается при отключении подписчика
+func (s *Subscription) C() <-chan domain.Change {
+	return s.ch
+}
+
+// Err - причина закрытия канала: ErrSlowSubscriber или ErrClosed; nil, пока подписка активна
+// или закрыта самим подписчиком
+func (s *Subscription) Err() error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.err
+}
+
+// Close - отписка
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s, nil)
+}
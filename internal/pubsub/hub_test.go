@@ -0,0 +1,176 @@
+package pubsub
+
+import (
+	stdErrors "errors"
+	"testing"
+
+	"calendar-server/internal/domain"
+	"calendar-server/pkg/errors"
+)
+
+func change(tenantID, userID, eventID string) domain.Change {
+	return domain.Change{Type: domain.ChangeCreated, TenantID: tenantID, UserID: userID, EventID: eventID}
+}
+
+// drain - изменения, уже находящиеся в канале подписки
+func drain(sub *Subscription) []string {
+	var ids []string
+	for {
+		select {
+		case c, ok := <-sub.C():
+			if !ok {
+				return ids
+			}
+			ids = append(ids, c.EventID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestHub_Filter(t *testing.T) {
+	hub := NewHub(DefaultBuffer, DefaultHistory)
+
+	user, err := hub.Subscribe(Filter{TenantID: "acme", UserID: "user-1"}, "")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	tenant, err := hub.Subscribe(Filter{TenantID: "acme"}, "")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	all, err := hub.Subscribe(Filter{AllTenants: true}, "")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	hub.Publish(change("acme", "user-1", "a"))
+	hub.Publish(change("acme", "user-2", "b"))
+	hub.Publish(change("other", "user-1", "c"))
+
+	if got := drain(user); len(got) != 1 || got[0] != "a" {
+		t.Errorf("Expected only own change a, got %v", got)
+	}
+	if got := drain(tenant); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("Expected tenant changes a and b, got %v", got)
+	}
+	if got := drain(all); len(got) != 3 {
+		t.Errorf("Expected changes of all tenants, got %v", got)
+	}
+}
+
+func TestHub_Resume(t *testing.T) {
+	hub := NewHub(DefaultBuffer, 3)
+	filter := Filter{TenantID: "acme"}
+
+	first := hub.Publish(change("acme", "user-1", "a"))
+	hub.Publish(change("acme", "user-1", "b"))
+	third := hub.Publish(change("acme", "user-1", "c"))
+
+	testCases := []struct {
+		name        string
+		lastEventID string
+		expected    []string
+		reset       bool
+	}{
+		{name: "from the start", lastEventID: "", expected: nil},
+		{name: "after first", lastEventID: first.ID, expected: []string{"b", "c"}},
+		{name: "up to date", lastEventID: third.ID, expected: nil},
+		{name: "previous run", lastEventID: "0-1", reset: true},
+		{name: "future position", lastEventID: hub.id(10), reset: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sub, err := hub.Subscribe(filter, tc.lastEventID)
+			if err != nil {
+				t.Fatalf("Failed to subscribe: %v", err)
+			}
+			defer sub.Close()
+
+			if sub.Reset != tc.reset {
+				t.Errorf("Expected reset %v, got %v", tc.reset, sub.Reset)
+			}
+			got := drain(sub)
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Expected replay %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("Expected replay %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+
+	// История вмещает три изменения: первое вытеснено, продолжить после него нельзя
+	hub.Publish(change("acme", "user-1", "d"))
+	hub.Publish(change("acme", "user-1", "e"))
+	sub, err := hub.Subscribe(filter, first.ID)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if !sub.Reset {
+		t.Error("Expected reset after the position left the history")
+	}
+}
+
+func TestHub_InvalidLastEventID(t *testing.T) {
+	hub := NewHub(DefaultBuffer, DefaultHistory)
+
+	for _, id := range []string{"garbage", "-1", "epoch-x", "epoch--1"} {
+		if _, err := hub.Subscribe(Filter{}, id); !stdErrors.Is(err, errors.ErrInvalidLastEventID) {
+			t.Errorf("Expected ErrInvalidLastEventID for %q, got %v", id, err)
+		}
+	}
+}
+
+func TestHub_SlowSubscriber(t *testing.T) {
+	hub := NewHub(2, DefaultHistory)
+
+	slow, err := hub.Subscribe(Filter{TenantID: "acme"}, "")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		hub.Publish(change("acme", "user-1", id))
+	}
+
+	if got := drain(slow); len(got) != 2 {
+		t.Errorf("Expected buffered changes before disconnect, got %v", got)
+	}
+	if _, ok := <-slow.C(); ok {
+		t.Fatal("Expected channel of slow subscriber to be closed")
+	}
+	if !stdErrors.Is(slow.Err(), ErrSlowSubscriber) {
+		t.Errorf("Expected ErrSlowSubscriber, got %v", slow.Err())
+	}
+	if hub.dropped.Load() != 1 {
+		t.Errorf("Expected 1 dropped subscriber, got %d", hub.dropped.Load())
+	}
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub(DefaultBuffer, DefaultHistory)
+
+	sub, err := hub.Subscribe(Filter{}, "")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	hub.Close()
+
+	if _, ok := <-sub.C(); ok || !stdErrors.Is(sub.Err(), ErrClosed) {
+		t.Errorf("Expected subscription closed with ErrClosed, got %v", sub.Err())
+	}
+
+	late, err := hub.Subscribe(Filter{}, "")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if _, ok := <-late.C(); ok {
+		t.Error("Expected subscription to a closed hub to be closed")
+	}
+
+	// Закрытие подписки после остановки шины не должно паниковать
+	sub.Close()
+	late.Close()
+}
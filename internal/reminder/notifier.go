@@ -0,0 +1,243 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/internal/webhook"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// EventType - значение заголовка webhook.HeaderEvent и поля type уведомления
+const EventType = "reminder"
+
+// Notification - напоминание, готовое к доставке
+type Notification struct {
+	// ID - ID срабатывания; одинаков при повторной доставке, по нему получатель отбрасывает повторы
+	ID            string       `json:"id"`
+	TenantID      string       `json:"-"`
+	UserID        string       `json:"user_id"`
+	Event         domain.Event `json:"event"`
+	MinutesBefore int          `json:"minutes_before"`
+	// StartsAt - начало события; для события на весь день - полночь его даты
+	StartsAt time.Time `json:"starts_at"`
+	FireAt   time.Time `json:"fire_at"`
+}
+
+// Notifier - способ доставки напоминаний
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier - запись напоминаний в лог
+type LogNotifier struct {
+	logger *zap.Logger
+}
+
+// NewLogNotifier - конструктор LogNotifier
+func NewLogNotifier(logger *zap.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// Notify - запись напоминания в лог
+func (n *LogNotifier) Notify(_ context.Context, notification Notification) error {
+	n.logger.Info("Event reminder",
+		zappretty.Field("reminder_id", notification.ID),
+		zappretty.Field("tenant_id", notification.TenantID),
+		zappretty.Field("user_id", notification.UserID),
+		zappretty.Field("event_id", notification.Event.ID),
+		zappretty.Field("title", notification.Event.Title),
+		zappretty.Field("starts_at", notification.StartsAt),
+		zappretty.Field("minutes_before", notification.MinutesBefore),
+	)
+	return nil
+}
+
+// WebhookPayload - тело запроса способа webhook
+type WebhookPayload struct {
+	Type      string       `json:"type"`
+	CreatedAt time.Time    `json:"created_at"`
+	Reminder  Notification `json:"reminder"`
+}
+
+// WebhookNotifier - отправка напоминаний POST-запросом с подписью в формате вебхуков
+// (заголовки webhook.HeaderDelivery, webhook.HeaderEvent и webhook.HeaderSignature)
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookNotifier - конструктор WebhookNotifier; таймаут запроса задает контекст Notify
+func NewWebhookNotifier(url, secret string, client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{url: url, secret: secret, client: client}
+}
+
+// Notify - отправка напоминания; ответ не 2xx считается ошибкой
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	now := time.Now().UTC()
+	body, err := json.Marshal(WebhookPayload{Type: EventType, CreatedAt: now, Reminder: notification})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhook.UserAgent)
+	req.Header.Set(webhook.HeaderDelivery, notification.ID)
+	req.Header.Set(webhook.HeaderEvent, EventType)
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(n.secret, now, body))
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("reminder webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPSettings - параметры способа email
+type SMTPSettings struct {
+	// Addr - сервер host:port
+	Addr string
+	// From - адрес отправителя
+	From string
+	// Username, Password - аутентификация PLAIN; пустой Username отключает ее
+	Username string
+	Password string
+	// Domain - домен получателя для ID пользователей, которые не являются адресами
+	Domain string
+}
+
+// SMTPNotifier - отправка напоминаний письмом. Сервер, предлагающий STARTTLS, получает письмо по TLS.
+type SMTPNotifier struct {
+	settings SMTPSettings
+}
+
+// NewSMTPNotifier - конструктор SMTPNotifier
+func NewSMTPNotifier(settings SMTPSettings) *SMTPNotifier {
+	return &SMTPNotifier{settings: settings}
+}
+
+// Notify - отправка письма владельцу события
+func (n *SMTPNotifier) Notify(ctx context.Context, notification Notification) error {
+	to, err := n.recipient(notification.UserID)
+	if err != nil {
+		return err
+	}
+	return n.send(ctx, to, n.message(to, notification))
+}
+
+// recipient - адрес получателя: ID пользователя с @ или ID в домене Domain
+func (n *SMTPNotifier) recipient(userID string) (string, error) {
+	if strings.Contains(userID, "@") {
+		return userID, nil
+	}
+	if n.settings.Domain == "" {
+		return "", fmt.Errorf("user %q has no email address and no recipient domain is configured", userID)
+	}
+	return userID + "@" + n.settings.Domain, nil
+}
+
+// message - письмо в формате RFC 5322; Message-ID выводится из ID срабатывания для отбрасывания повторов
+func (n *SMTPNotifier) message(to string, notification Notification) []byte {
+	event := notification.Event
+	when := notification.StartsAt.Format("2006-01-02 15:04 MST")
+	if event.AllDay() {
+		when = event.Date + " (all day)"
+	}
+
+	var b strings.Builder
+	header := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", n.settings.From)
+	header("To", to)
+	header("Subject", mime.QEncoding.Encode("utf-8", "Reminder: "+event.Title))
+	header("Date", notification.FireAt.Format(time.RFC1123Z))
+	header("Message-ID", "<"+notification.ID+"@calendar-server>")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	b.WriteString(event.Title + "\r\n")
+	b.WriteString("Starts: " + when + "\r\n")
+	b.WriteString("Event ID: " + event.ID + "\r\n")
+	return []byte(b.String())
+}
+
+// send - доставка письма; в отличие от smtp.SendMail соблюдает отмену и срок ctx
+func (n *SMTPNotifier) send(ctx context.Context, to string, msg []byte) error {
+	host, _, err := net.SplitHostPort(n.settings.Addr)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.settings.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if n.settings.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.settings.Username, n.settings.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.settings.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
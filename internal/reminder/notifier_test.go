@@ -0,0 +1,217 @@
+package reminder
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/internal/webhook"
+)
+
+func testNotification() Notification {
+	return Notification{
+		ID:            "rem_0123456789abcdef01234567",
+		TenantID:      "acme",
+		UserID:        "user-1",
+		Event:         domain.Event{ID: "standup", UserID: "user-1", Date: "2025-01-15", Title: "Планерка", StartTime: "10:00"},
+		MinutesBefore: 15,
+		StartsAt:      at("2025-01-15 10:00"),
+		FireAt:        at("2025-01-15 09:45"),
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		body    []byte
+		headers http.Header
+		status  = http.StatusNoContent
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header.Clone()
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, "whsec_test", server.Client())
+	if err := notifier.Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+
+	mu.Lock()
+	if err := webhook.Verify("whsec_test", headers.Get(webhook.HeaderSignature), body, webhook.DefaultTolerance, time.Now()); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+	if headers.Get(webhook.HeaderDelivery) != testNotification().ID || headers.Get(webhook.HeaderEvent) != EventType {
+		t.Errorf("Unexpected delivery headers %v", headers)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Type != EventType || payload.Reminder.Event.ID != "standup" {
+		t.Errorf("Unexpected payload %s (%v)", body, err)
+	}
+	status = http.StatusServiceUnavailable
+	mu.Unlock()
+
+	if err := notifier.Notify(context.Background(), testNotification()); err == nil {
+		t.Error("Expected error for 503 response")
+	}
+}
+
+// smtpServer - минимальный сервер SMTP, принимающий письма без проверок
+type smtpServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &smtpServer{listener: listener}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP stand-in")
+	var msg smtpMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			msg = smtpMessage{from: strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) received() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	server := newSMTPServer(t)
+
+	tests := []struct {
+		name    string
+		userID  string
+		domain  string
+		wantTo  string
+		wantErr bool
+	}{
+		{name: "user ID is an address", userID: "alice@example.com", wantTo: "alice@example.com"},
+		{name: "recipient domain", userID: "user-1", domain: "example.com", wantTo: "user-1@example.com"},
+		{name: "no address", userID: "user-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := NewSMTPNotifier(SMTPSettings{
+				Addr:   server.listener.Addr().String(),
+				From:   "calendar@localhost",
+				Domain: tt.domain,
+			})
+			notification := testNotification()
+			notification.UserID = tt.userID
+
+			before := len(server.received())
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := notifier.Notify(ctx, notification)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			messages := server.received()
+			if len(messages) != before+1 {
+				t.Fatalf("Expected one message, got %d", len(messages)-before)
+			}
+			msg := messages[len(messages)-1]
+			if msg.from != "calendar@localhost" || len(msg.to) != 1 || msg.to[0] != tt.wantTo {
+				t.Errorf("Unexpected envelope from %q to %v", msg.from, msg.to)
+			}
+			for _, want := range []string{
+				"Message-ID: <" + notification.ID + "@calendar-server>",
+				"Subject: =?utf-8?q?",
+				"Starts: 2025-01-15 10:00 UTC",
+			} {
+				if !strings.Contains(msg.data, want) {
+					t.Errorf("Expected message to contain %q, got:\n%s", want, msg.data)
+				}
+			}
+		})
+	}
+}
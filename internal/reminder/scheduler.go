@@ -0,0 +1,653 @@
+// Package reminder - планирование напоминаний о событиях и их доставка через
+// подключаемые способы (лог, вебхук, email).
+package reminder
+
+import (
+	"container/heap"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stdErrors "errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/internal/pubsub"
+	repo "calendar-server/internal/repository/reminder_repository"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/metrics"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// clockJumpThreshold - расхождение системных и монотонных часов, после которого напоминания пересчитываются
+const clockJumpThreshold = time.Minute
+
+// Source - события, по которым планируются напоминания (реализуется хранилищем событий в памяти)
+type Source interface {
+	GetPage(ctx context.Context, userID, afterID string, limit int) ([]domain.Event, error)
+	CountByTenant(ctx context.Context) (map[string]int, error)
+}
+
+// Settings - параметры планировщика напоминаний
+type Settings struct {
+	// Location - часовой пояс, в котором задано время событий
+	Location *time.Location
+	// DefaultMethod - способ доставки напоминаний, у которых он не указан
+	DefaultMethod string
+	// Horizon - срабатывания в пределах Horizon от текущего времени держатся в очереди;
+	// более поздние попадают в нее при очередном пересчете
+	Horizon time.Duration
+	// ResyncInterval - интервал полного пересчета по хранилищу; должен быть меньше Horizon
+	ResyncInterval time.Duration
+	// CheckInterval - наибольший интервал сверки с часами, даже если ближайшее срабатывание позже
+	CheckInterval time.Duration
+	// CatchUp - опоздавшие не больше этого напоминания отправляются, остальные записываются пропущенными
+	CatchUp time.Duration
+	// MaxAttempts, RetryDelay - попытки доставки одного напоминания и задержка между ними
+	MaxAttempts int
+	RetryDelay  time.Duration
+	// Timeout - время на одну попытку доставки
+	Timeout time.Duration
+	// Retention - время хранения итогов срабатываний; должно быть больше Horizon
+	Retention time.Duration
+	// PageSize - события, читаемые из хранилища за один запрос при пересчете
+	PageSize int
+}
+
+// DefaultSettings - очередь на сутки вперед, пересчет раз в 10 минут, 3 попытки с интервалом в минуту
+func DefaultSettings() Settings {
+	return Settings{
+		Location:       time.UTC,
+		DefaultMethod:  domain.ReminderLog,
+		Horizon:        24 * time.Hour,
+		ResyncInterval: 10 * time.Minute,
+		CheckInterval:  30 * time.Second,
+		CatchUp:        time.Hour,
+		MaxAttempts:    3,
+		RetryDelay:     time.Minute,
+		Timeout:        10 * time.Second,
+		Retention:      7 * 24 * time.Hour,
+		PageSize:       500,
+	}
+}
+
+// Option - функциональная опция Scheduler
+type Option func(*Scheduler)
+
+// WithSettings - параметры планировщика
+func WithSettings(settings Settings) Option {
+	return func(s *Scheduler) {
+		s.settings = settings
+	}
+}
+
+// WithNotifier - способ доставки напоминаний method
+func WithNotifier(method string, notifier Notifier) Option {
+	return func(s *Scheduler) {
+		s.notifiers[method] = notifier
+	}
+}
+
+// WithClock - источник текущего времени
+func WithClock(now func() time.Time) Option {
+	return func(s *Scheduler) {
+		s.now = now
+	}
+}
+
+// eventKey - событие арендатора
+type eventKey struct {
+	tenantID string
+	eventID  string
+}
+
+// entry - запланированное срабатывание напоминания
+type entry struct {
+	firing   domain.ReminderFiring
+	event    domain.Event
+	startsAt time.Time
+	// due - время следующей попытки: FireAt или время повтора
+	due   time.Time
+	index int
+}
+
+// queue - очередь срабатываний по времени попытки (container/heap)
+type queue []*entry
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	if !q[i].due.Equal(q[j].due) {
+		return q[i].due.Before(q[j].due)
+	}
+	return q[i].firing.ID < q[j].firing.ID
+}
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *queue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	e.index = -1
+	return e
+}
+
+// Scheduler - планировщик напоминаний. Ближайшие срабатывания держатся в куче по времени; очередь
+// обновляется по ленте изменений и полностью пересчитывается по хранилищу раз в ResyncInterval,
+// после перезапуска и при сдвиге системных часов. Итог каждого срабатывания записывается в
+// хранилище, поэтому после перезапуска сработавшие напоминания не повторяются. Итог записывается
+// после доставки: сбой между ними приводит к повторной доставке с тем же ID.
+type Scheduler struct {
+	source    Source
+	repo      repo.ReminderRepository
+	logger    *zap.Logger
+	settings  Settings
+	notifiers map[string]Notifier
+	now       func() time.Time
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu    sync.Mutex
+	queue queue
+	// entries - срабатывания в очереди по ID; byEvent - их ID по событиям
+	entries map[string]*entry
+	byEvent map[eventKey][]string
+
+	outcomes *metrics.CounterVec
+}
+
+// NewScheduler - конструктор Scheduler; планирование запускается методом Start
+func NewScheduler(source Source, repo repo.ReminderRepository, logger *zap.Logger, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		source:    source,
+		repo:      repo,
+		logger:    logger,
+		settings:  DefaultSettings(),
+		notifiers: make(map[string]Notifier),
+		now:       time.Now,
+		wake:      make(chan struct{}, 1),
+		entries:   make(map[string]*entry),
+		byEvent:   make(map[eventKey][]string),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// RegisterMetrics - регистрация метрик напоминаний
+func (s *Scheduler) RegisterMetrics(registry *metrics.Registry) {
+	s.outcomes = registry.NewCounterVec("calendar_reminders_total",
+		"Reminder delivery outcomes: sent, retried, failed or missed.", "outcome")
+	registry.NewGaugeFunc("calendar_reminders_scheduled", "Number of reminders waiting in the scheduler queue.", func() float64 {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return float64(len(s.queue))
+	})
+}
+
+// Start - пересчет напоминаний по хранилищу и запуск планирования
+func (s *Scheduler) Start(ctx context.Context) error {
+	if err := s.Resync(ctx); err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(runCtx)
+	return nil
+}
+
+// Consume - подписка на шину и обновление очереди по изменениям событий до отмены ctx или закрытия шины
+func (s *Scheduler) Consume(ctx context.Context, hub *pubsub.Hub) error {
+	sub, err := hub.Subscribe(pubsub.Filter{AllTenants: true}, "")
+	if err != nil {
+		return err
+	}
+	go s.consume(ctx, hub, sub)
+	return nil
+}
+
+// consume - чтение изменений. Отставший подписчик переподписывается и пересчитывает очередь
+// по хранилищу вместо чтения пропущенных изменений.
+func (s *Scheduler) consume(ctx context.Context, hub *pubsub.Hub, sub *pubsub.Subscription) {
+	for {
+	read:
+		for {
+			select {
+			case <-ctx.Done():
+				sub.Close()
+				return
+			case change, ok := <-sub.C():
+				if !ok {
+					break read
+				}
+				if err := s.Apply(ctx, change); err != nil {
+					s.logger.Error("Failed to reschedule event reminders",
+						zappretty.Field("event_id", change.EventID),
+						zappretty.Field("error", err),
+					)
+				}
+			}
+		}
+
+		if err := sub.Err(); !stdErrors.Is(err, pubsub.ErrSlowSubscriber) {
+			return
+		}
+		s.logger.Warn("Reminder scheduler fell behind the change feed, resyncing")
+
+		next, err := hub.Subscribe(pubsub.Filter{AllTenants: true}, "")
+		if err != nil {
+			s.logger.Error("Reminder scheduler failed to subscribe to changes", zappretty.Field("error", err))
+			return
+		}
+		sub = next
+		if err := s.Resync(ctx); err != nil {
+			s.logger.Error("Failed to resync reminders", zappretty.Field("error", err))
+		}
+	}
+}
+
+// Apply - замена запланированных напоминаний события по изменению
+func (s *Scheduler) Apply(ctx context.Context, change domain.Change) error {
+	var planned []*entry
+	if change.Type != domain.ChangeDeleted && change.Event != nil {
+		var err error
+		if planned, err = s.plan(ctx, change.TenantID, *change.Event, s.now()); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.set(eventKey{tenantID: change.TenantID, eventID: change.EventID}, planned)
+	s.mu.Unlock()
+
+	s.Wake()
+	return nil
+}
+
+// Resync - пересчет очереди по всем событиям хранилища; состояние повторов сохраняется
+func (s *Scheduler) Resync(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "Reminder.Resync")
+	defer span.EndErr(&err)
+
+	now := s.now()
+	if _, err := s.repo.Prune(ctx, now.Add(-s.settings.Retention)); err != nil {
+		return err
+	}
+
+	counts, err := s.source.CountByTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	planned := make(map[eventKey][]*entry)
+	for tenantID := range counts {
+		tenantCtx := tenant.WithID(ctx, tenantID)
+		afterID := ""
+		for {
+			page, err := s.source.GetPage(tenantCtx, "", afterID, s.settings.PageSize)
+			if err != nil {
+				return err
+			}
+			for _, event := range page {
+				entries, err := s.plan(ctx, tenantID, event, now)
+				if err != nil {
+					return err
+				}
+				if len(entries) > 0 {
+					planned[eventKey{tenantID: tenantID, eventID: event.ID}] = entries
+				}
+			}
+			if len(page) < s.settings.PageSize {
+				break
+			}
+			afterID = page[len(page)-1].ID
+		}
+	}
+
+	s.mu.Lock()
+	previous := s.entries
+	s.queue = s.queue[:0]
+	s.entries = make(map[string]*entry)
+	s.byEvent = make(map[eventKey][]string)
+	for key, entries := range planned {
+		for _, e := range entries {
+			if old, ok := previous[e.firing.ID]; ok {
+				e.due = old.due
+				e.firing.Attempts = old.firing.Attempts
+			}
+		}
+		s.set(key, entries)
+	}
+	scheduled := len(s.queue)
+	s.mu.Unlock()
+
+	span.SetAttributes(tracing.Int("reminder.scheduled", scheduled))
+	s.Wake()
+	return nil
+}
+
+// Wake - пересмотр ближайшего срабатывания; не блокируется
+func (s *Scheduler) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close - остановка планирования. Недоставленные напоминания будут запланированы при следующем запуске.
+func (s *Scheduler) Close() error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// run - цикл планировщика: доставка наступивших напоминаний, сон до ближайшего (не дольше
+// CheckInterval) и пересчет по таймеру или после сдвига системных часов
+func (s *Scheduler) run(ctx context.Context) {
+	defer close(s.done)
+
+	resync := time.NewTicker(s.settings.ResyncInterval)
+	defer resync.Stop()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	lastWall, lastMono := s.now().Round(0), time.Now()
+	for {
+		// Системные часы, переставленные вперед или назад, расходятся с монотонными
+		wall, mono := s.now().Round(0), time.Now()
+		if drift := wall.Sub(lastWall) - mono.Sub(lastMono); drift > clockJumpThreshold || drift < -clockJumpThreshold {
+			s.logger.Warn("Clock jump detected, rescheduling reminders", zappretty.Field("drift", drift))
+			s.resync(ctx)
+		}
+		lastWall, lastMono = wall, mono
+
+		s.fireDue(ctx)
+
+		timer.Reset(s.nextWait())
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C:
+		case <-resync.C:
+			s.resync(ctx)
+		}
+	}
+}
+
+// resync - пересчет очереди из цикла планировщика с записью ошибки в лог
+func (s *Scheduler) resync(ctx context.Context) {
+	if err := s.Resync(ctx); err != nil && ctx.Err() == nil {
+		s.logger.Warn("Failed to resync reminders, will retry", zappretty.Field("error", err))
+	}
+}
+
+// nextWait - время до ближайшего срабатывания, но не больше CheckInterval
+func (s *Scheduler) nextWait() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := s.settings.CheckInterval
+	if len(s.queue) > 0 {
+		wait = min(wait, max(s.queue[0].due.Sub(s.now()), 0))
+	}
+	return wait
+}
+
+// fireDue - доставка всех напоминаний, время попытки которых наступило
+func (s *Scheduler) fireDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := s.now()
+
+		s.mu.Lock()
+		if len(s.queue) == 0 || s.queue[0].due.After(now) {
+			s.mu.Unlock()
+			return
+		}
+		e := heap.Pop(&s.queue).(*entry)
+		s.forget(e)
+		s.mu.Unlock()
+
+		s.deliver(ctx, e, now)
+	}
+}
+
+// deliver - попытка доставки напоминания; неудачная попытка возвращает его в очередь
+func (s *Scheduler) deliver(ctx context.Context, e *entry, now time.Time) {
+	var err error
+	ctx, span := tracing.Start(ctx, "Reminder.Deliver",
+		tracing.String("reminder.id", e.firing.ID),
+		tracing.String("reminder.method", e.firing.Method),
+	)
+	defer span.EndErr(&err)
+
+	// Напоминание могло сработать до пересчета очереди или до перезапуска
+	fired, err := s.repo.Fired(ctx, e.firing.ID)
+	if err != nil {
+		// Следующий пересчет вернет напоминание в очередь
+		s.logger.Error("Failed to check reminder firing",
+			zappretty.Field("reminder_id", e.firing.ID),
+			zappretty.Field("error", err),
+		)
+		return
+	}
+	if fired {
+		return
+	}
+
+	if late := now.Sub(e.firing.FireAt); late > s.settings.CatchUp {
+		s.record(ctx, e, now, domain.ReminderMissed, fmt.Errorf("late by %s", late.Round(time.Second)))
+		return
+	}
+
+	notifier, ok := s.notifiers[e.firing.Method]
+	if !ok {
+		s.record(ctx, e, now, domain.ReminderFailed, fmt.Errorf("reminder method %q is not configured", e.firing.Method))
+		return
+	}
+
+	e.firing.Attempts++
+	notifyCtx, cancel := context.WithTimeout(ctx, s.settings.Timeout)
+	err = notifier.Notify(notifyCtx, Notification{
+		ID:            e.firing.ID,
+		TenantID:      e.firing.TenantID,
+		UserID:        e.firing.UserID,
+		Event:         e.event,
+		MinutesBefore: e.firing.MinutesBefore,
+		StartsAt:      e.startsAt,
+		FireAt:        e.firing.FireAt,
+	})
+	cancel()
+
+	switch {
+	case err == nil:
+		s.record(ctx, e, now, domain.ReminderSent, nil)
+	case ctx.Err() != nil:
+		// Остановка: попытка не засчитывается, напоминание будет запланировано при следующем запуске
+	case e.firing.Attempts < s.settings.MaxAttempts:
+		s.count("retried")
+		e.due = now.Add(s.settings.RetryDelay)
+		e.firing.LastError = err.Error()
+		s.logger.Warn("Reminder delivery failed, retry scheduled",
+			zappretty.Field("reminder_id", e.firing.ID),
+			zappretty.Field("method", e.firing.Method),
+			zappretty.Field("attempts", e.firing.Attempts),
+			zappretty.Field("error", err),
+		)
+
+		s.mu.Lock()
+		// Пока шла попытка, событие могло измениться и напоминание - снова попасть в очередь
+		if _, queued := s.entries[e.firing.ID]; !queued {
+			s.add(e)
+		}
+		s.mu.Unlock()
+	default:
+		s.record(ctx, e, now, domain.ReminderFailed, err)
+	}
+}
+
+// record - запись итога срабатывания
+func (s *Scheduler) record(ctx context.Context, e *entry, now time.Time, status string, cause error) {
+	s.count(status)
+
+	firing := e.firing
+	firing.Status = status
+	firing.FiredAt = now.UTC()
+	if cause != nil {
+		firing.LastError = cause.Error()
+	}
+
+	fields := []zap.Field{
+		zappretty.Field("reminder_id", firing.ID),
+		zappretty.Field("event_id", firing.EventID),
+		zappretty.Field("user_id", firing.UserID),
+		zappretty.Field("method", firing.Method),
+	}
+	switch status {
+	case domain.ReminderSent:
+		s.logger.Debug("Reminder sent", fields...)
+	case domain.ReminderMissed:
+		s.logger.Warn("Reminder missed", append(fields, zappretty.Field("error", firing.LastError))...)
+	default:
+		s.logger.Error("Reminder delivery failed", append(fields, zappretty.Field("error", firing.LastError))...)
+	}
+
+	if err := s.repo.Record(ctx, firing); err != nil && !stdErrors.Is(err, errors.ErrReminderFired) {
+		s.logger.Error("Failed to record reminder firing", append(fields, zappretty.Field("error", err))...)
+	}
+}
+
+// count - учет итога в метрике
+func (s *Scheduler) count(outcome string) {
+	if s.outcomes != nil {
+		s.outcomes.Inc(outcome)
+	}
+}
+
+// plan - несработавшие напоминания события, время которых в пределах Horizon от now.
+// Прошедшие напоминания попадают в очередь, чтобы быть отправленными с опозданием или записанными пропущенными.
+func (s *Scheduler) plan(ctx context.Context, tenantID string, event domain.Event, now time.Time) ([]*entry, error) {
+	if len(event.Reminders) == 0 {
+		return nil, nil
+	}
+	startsAt, err := s.startsAt(event)
+	if err != nil {
+		// Хранилище содержит только проверенные события; некорректное пропускается
+		return nil, nil
+	}
+
+	var entries []*entry
+	for _, reminder := range event.Reminders {
+		fireAt := startsAt.Add(-time.Duration(reminder.MinutesBefore) * time.Minute)
+		if fireAt.Before(now.Add(-s.settings.Horizon)) || fireAt.After(now.Add(s.settings.Horizon)) {
+			continue
+		}
+
+		id := firingID(tenantID, event.ID, reminder, fireAt)
+		fired, err := s.repo.Fired(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if fired {
+			continue
+		}
+
+		method := reminder.Method
+		if method == "" {
+			method = s.settings.DefaultMethod
+		}
+		entries = append(entries, &entry{
+			firing: domain.ReminderFiring{
+				ID:            id,
+				TenantID:      tenantID,
+				UserID:        event.UserID,
+				EventID:       event.ID,
+				MinutesBefore: reminder.MinutesBefore,
+				Method:        method,
+				FireAt:        fireAt.UTC(),
+			},
+			event:    event,
+			startsAt: startsAt,
+			due:      fireAt,
+		})
+	}
+	return entries, nil
+}
+
+// startsAt - начало события в часовом поясе Location; событие на весь день начинается в полночь
+func (s *Scheduler) startsAt(event domain.Event) (time.Time, error) {
+	layout, value := "2006-01-02", event.Date
+	if !event.AllDay() {
+		layout, value = layout+" 15:04", value+" "+event.StartTime
+	}
+	return time.ParseInLocation(layout, value, s.settings.Location)
+}
+
+// set - замена срабатываний события в очереди; вызывается под s.mu
+func (s *Scheduler) set(key eventKey, entries []*entry) {
+	for _, id := range s.byEvent[key] {
+		if e, ok := s.entries[id]; ok {
+			heap.Remove(&s.queue, e.index)
+			delete(s.entries, id)
+		}
+	}
+	delete(s.byEvent, key)
+
+	for _, e := range entries {
+		s.add(e)
+	}
+}
+
+// add - постановка срабатывания в очередь; вызывается под s.mu
+func (s *Scheduler) add(e *entry) {
+	heap.Push(&s.queue, e)
+	s.entries[e.firing.ID] = e
+	key := eventKey{tenantID: e.firing.TenantID, eventID: e.firing.EventID}
+	s.byEvent[key] = append(s.byEvent[key], e.firing.ID)
+}
+
+// forget - удаление извлеченного из кучи срабатывания из индексов; вызывается под s.mu
+func (s *Scheduler) forget(e *entry) {
+	delete(s.entries, e.firing.ID)
+	key := eventKey{tenantID: e.firing.TenantID, eventID: e.firing.EventID}
+	ids := slices.DeleteFunc(s.byEvent[key], func(id string) bool { return id == e.firing.ID })
+	if len(ids) == 0 {
+		delete(s.byEvent, key)
+		return
+	}
+	s.byEvent[key] = ids
+}
+
+// firingID - ID срабатывания: одинаков для того же напоминания события в то же время
+func firingID(tenantID, eventID string, reminder domain.Reminder, fireAt time.Time) string {
+	sum := sha256.Sum256([]byte(tenantID + "\x00" + eventID + "\x00" + fireAt.UTC().Format(time.RFC3339) +
+		"\x00" + strconv.Itoa(reminder.MinutesBefore) + "\x00" + reminder.Method))
+	return "rem_" + hex.EncodeToString(sum[:12])
+}
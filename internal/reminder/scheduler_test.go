@@ -0,0 +1,334 @@
+package reminder
+
+import (
+	"context"
+	stdErrors "errors"
+	"sync"
+	"testing"
+	"time"
+
+	"calendar-server/internal/domain"
+	eventRepository "calendar-server/internal/repository/event_repository/inmemory"
+	reminderRepository "calendar-server/internal/repository/reminder_repository/inmemory"
+	"calendar-server/internal/tenant"
+
+	"go.uber.org/zap"
+)
+
+// fakeClock - управляемые часы планировщика
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// recordingNotifier - запоминает напоминания; первые failures попыток завершаются ошибкой
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []Notification
+	failures      int
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.failures > 0 {
+		n.failures--
+		return stdErrors.New("receiver unavailable")
+	}
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func (n *recordingNotifier) sent() []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Notification(nil), n.notifications...)
+}
+
+type testScheduler struct {
+	*Scheduler
+	events   *eventRepository.EventRepository
+	firings  *reminderRepository.ReminderRepository
+	notifier *recordingNotifier
+	clock    *fakeClock
+}
+
+func at(value string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04", value)
+	return t
+}
+
+func setupScheduler(t *testing.T, events *eventRepository.EventRepository, firings *reminderRepository.ReminderRepository) testScheduler {
+	t.Helper()
+	logger, _ := zap.NewDevelopment()
+	if events == nil {
+		events = eventRepository.NewEventRepository(logger)
+	}
+	if firings == nil {
+		firings = reminderRepository.NewReminderRepository(logger)
+	}
+
+	ts := testScheduler{
+		events:   events,
+		firings:  firings,
+		notifier: &recordingNotifier{},
+		clock:    &fakeClock{now: at("2025-01-15 09:40")},
+	}
+	ts.Scheduler = NewScheduler(events, firings, logger,
+		WithClock(ts.clock.Now),
+		WithNotifier(domain.ReminderLog, ts.notifier),
+	)
+	return ts
+}
+
+func (ts testScheduler) status(t *testing.T, tenantID, eventID string) map[int]string {
+	t.Helper()
+	firings, err := ts.firings.ListByEventID(context.Background(), tenantID, eventID)
+	if err != nil {
+		t.Fatalf("Failed to list firings: %v", err)
+	}
+	statuses := make(map[int]string, len(firings))
+	for _, firing := range firings {
+		statuses[firing.MinutesBefore] = firing.Status
+	}
+	return statuses
+}
+
+func TestScheduler_FiresDueReminders(t *testing.T) {
+	ts := setupScheduler(t, nil, nil)
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	_ = ts.events.Create(ctx, domain.Event{
+		ID: "standup", UserID: "user-1", Date: "2025-01-15", Title: "Standup", StartTime: "10:00",
+		Reminders: []domain.Reminder{{MinutesBefore: 15}, {MinutesBefore: 60}},
+	}, tenant.Quota{})
+	// Весь день: напоминание за сутки должно было сработать в полночь, опоздание больше CatchUp
+	_ = ts.events.Create(ctx, domain.Event{
+		ID: "offsite", UserID: "user-1", Date: "2025-01-16", Title: "Offsite",
+		Reminders: []domain.Reminder{{MinutesBefore: 24 * 60}},
+	}, tenant.Quota{})
+	_ = ts.events.Create(ctx, domain.Event{ID: "quiet", UserID: "user-1", Date: "2025-01-15", Title: "No reminders", StartTime: "09:45"}, tenant.Quota{})
+
+	if err := ts.Resync(context.Background()); err != nil {
+		t.Fatalf("Failed to resync: %v", err)
+	}
+	ts.fireDue(context.Background())
+
+	// Напоминание за час опоздало на 40 минут и отправляется сразу
+	sent := ts.notifier.sent()
+	if len(sent) != 1 || sent[0].MinutesBefore != 60 || sent[0].TenantID != "acme" || sent[0].Event.ID != "standup" {
+		t.Fatalf("Expected late 60-minute reminder of standup, got %+v", sent)
+	}
+	if !sent[0].StartsAt.Equal(at("2025-01-15 10:00")) || !sent[0].FireAt.Equal(at("2025-01-15 09:00")) {
+		t.Errorf("Unexpected reminder times %+v", sent[0])
+	}
+	if got := ts.status(t, "acme", "offsite"); got[24*60] != domain.ReminderMissed {
+		t.Errorf("Expected offsite reminder to be missed, got %v", got)
+	}
+
+	ts.clock.Set(at("2025-01-15 09:44"))
+	ts.fireDue(context.Background())
+	if len(ts.notifier.sent()) != 1 {
+		t.Fatalf("Expected 15-minute reminder to wait, got %+v", ts.notifier.sent())
+	}
+
+	ts.clock.Set(at("2025-01-15 09:45"))
+	ts.fireDue(context.Background())
+	if sent := ts.notifier.sent(); len(sent) != 2 || sent[1].MinutesBefore != 15 {
+		t.Fatalf("Expected 15-minute reminder, got %+v", sent)
+	}
+
+	want := map[int]string{15: domain.ReminderSent, 60: domain.ReminderSent}
+	if got := ts.status(t, "acme", "standup"); len(got) != 2 || got[15] != want[15] || got[60] != want[60] {
+		t.Errorf("Expected both standup reminders recorded as sent, got %v", got)
+	}
+
+	// Часы отведены назад: сработавшие напоминания не повторяются
+	ts.clock.Set(at("2025-01-15 09:00"))
+	if err := ts.Resync(context.Background()); err != nil {
+		t.Fatalf("Failed to resync: %v", err)
+	}
+	ts.clock.Set(at("2025-01-15 09:50"))
+	ts.fireDue(context.Background())
+	if sent := ts.notifier.sent(); len(sent) != 2 {
+		t.Errorf("Expected no repeated reminders after clock jump, got %+v", sent)
+	}
+}
+
+func TestScheduler_Restart(t *testing.T) {
+	ts := setupScheduler(t, nil, nil)
+	ctx := context.Background()
+
+	_ = ts.events.Create(ctx, domain.Event{
+		ID: "standup", UserID: "user-1", Date: "2025-01-15", Title: "Standup", StartTime: "10:00",
+		Reminders: []domain.Reminder{{MinutesBefore: 30}, {MinutesBefore: 5}},
+	}, tenant.Quota{})
+	if err := ts.Resync(ctx); err != nil {
+		t.Fatalf("Failed to resync: %v", err)
+	}
+	ts.fireDue(ctx)
+	if len(ts.notifier.sent()) != 1 {
+		t.Fatalf("Expected 30-minute reminder before restart, got %+v", ts.notifier.sent())
+	}
+
+	// Новый планировщик с теми же хранилищами: сервер перезапущен во время 5-минутного напоминания
+	restarted := setupScheduler(t, ts.events, ts.firings)
+	restarted.clock.Set(at("2025-01-15 09:56"))
+	if err := restarted.Resync(ctx); err != nil {
+		t.Fatalf("Failed to resync after restart: %v", err)
+	}
+	restarted.fireDue(ctx)
+
+	sent := restarted.notifier.sent()
+	if len(sent) != 1 || sent[0].MinutesBefore != 5 {
+		t.Errorf("Expected only the 5-minute reminder after restart, got %+v", sent)
+	}
+}
+
+func TestScheduler_Retry(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		wantStatus string
+		wantSent   int
+	}{
+		{name: "succeeds on last attempt", failures: 2, wantStatus: domain.ReminderSent, wantSent: 1},
+		{name: "attempts exhausted", failures: 3, wantStatus: domain.ReminderFailed, wantSent: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := setupScheduler(t, nil, nil)
+			ts.notifier.failures = tt.failures
+			ctx := context.Background()
+
+			_ = ts.events.Create(ctx, domain.Event{
+				ID: "standup", UserID: "user-1", Date: "2025-01-15", Title: "Standup", StartTime: "10:00",
+				Reminders: []domain.Reminder{{MinutesBefore: 20}},
+			}, tenant.Quota{})
+			if err := ts.Resync(ctx); err != nil {
+				t.Fatalf("Failed to resync: %v", err)
+			}
+
+			for minute := range 4 {
+				ts.clock.Set(at("2025-01-15 09:40").Add(time.Duration(minute) * time.Minute))
+				ts.fireDue(ctx)
+			}
+
+			if len(ts.notifier.sent()) != tt.wantSent {
+				t.Errorf("Expected %d notifications, got %+v", tt.wantSent, ts.notifier.sent())
+			}
+			firings, _ := ts.firings.ListByEventID(ctx, tenant.DefaultID, "standup")
+			if len(firings) != 1 || firings[0].Status != tt.wantStatus || firings[0].Attempts != 3 {
+				t.Errorf("Expected %s after 3 attempts, got %+v", tt.wantStatus, firings)
+			}
+		})
+	}
+}
+
+func TestScheduler_Apply(t *testing.T) {
+	ts := setupScheduler(t, nil, nil)
+	ctx := context.Background()
+
+	event := domain.Event{
+		ID: "standup", UserID: "user-1", Date: "2025-01-15", Title: "Standup", StartTime: "10:00",
+		Reminders: []domain.Reminder{{MinutesBefore: 10}},
+	}
+	if err := ts.Apply(ctx, domain.Change{Type: domain.ChangeCreated, TenantID: tenant.DefaultID, EventID: event.ID, Event: &event}); err != nil {
+		t.Fatalf("Failed to apply change: %v", err)
+	}
+
+	// Событие перенесено на 10:30: напоминание в 09:50 заменяется напоминанием в 10:20
+	moved := event
+	moved.StartTime = "10:30"
+	if err := ts.Apply(ctx, domain.Change{Type: domain.ChangeUpdated, TenantID: tenant.DefaultID, EventID: event.ID, Event: &moved}); err != nil {
+		t.Fatalf("Failed to apply change: %v", err)
+	}
+	ts.clock.Set(at("2025-01-15 09:55"))
+	ts.fireDue(ctx)
+	if sent := ts.notifier.sent(); len(sent) != 0 {
+		t.Fatalf("Expected no reminder for the old start time, got %+v", sent)
+	}
+
+	ts.clock.Set(at("2025-01-15 10:20"))
+	ts.fireDue(ctx)
+	if sent := ts.notifier.sent(); len(sent) != 1 || !sent[0].StartsAt.Equal(at("2025-01-15 10:30")) {
+		t.Fatalf("Expected reminder for the new start time, got %+v", sent)
+	}
+
+	// Удаленное событие не напоминает о себе
+	other := domain.Event{
+		ID: "retro", UserID: "user-1", Date: "2025-01-15", Title: "Retro", StartTime: "11:00",
+		Reminders: []domain.Reminder{{MinutesBefore: 30}},
+	}
+	_ = ts.Apply(ctx, domain.Change{Type: domain.ChangeCreated, TenantID: tenant.DefaultID, EventID: other.ID, Event: &other})
+	_ = ts.Apply(ctx, domain.Change{Type: domain.ChangeDeleted, TenantID: tenant.DefaultID, EventID: other.ID})
+	ts.clock.Set(at("2025-01-15 10:45"))
+	ts.fireDue(ctx)
+	if sent := ts.notifier.sent(); len(sent) != 1 {
+		t.Errorf("Expected no reminder of deleted event, got %+v", sent)
+	}
+}
+
+func TestScheduler_UnconfiguredMethod(t *testing.T) {
+	ts := setupScheduler(t, nil, nil)
+	ctx := context.Background()
+
+	_ = ts.events.Create(ctx, domain.Event{
+		ID: "standup", UserID: "user-1", Date: "2025-01-15", Title: "Standup", StartTime: "10:00",
+		Reminders: []domain.Reminder{{MinutesBefore: 30, Method: domain.ReminderEmail}},
+	}, tenant.Quota{})
+	if err := ts.Resync(ctx); err != nil {
+		t.Fatalf("Failed to resync: %v", err)
+	}
+	ts.fireDue(ctx)
+
+	firings, _ := ts.firings.ListByEventID(ctx, tenant.DefaultID, "standup")
+	if len(firings) != 1 || firings[0].Status != domain.ReminderFailed || firings[0].Method != domain.ReminderEmail {
+		t.Errorf("Expected failed email reminder, got %+v", firings)
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	events := eventRepository.NewEventRepository(logger)
+	notifier := &recordingNotifier{}
+	settings := DefaultSettings()
+	settings.CheckInterval = 10 * time.Millisecond
+	scheduler := NewScheduler(events, reminderRepository.NewReminderRepository(logger), logger,
+		WithSettings(settings),
+		WithNotifier(domain.ReminderLog, notifier),
+	)
+
+	// Событие через 5 минут с напоминанием за 5 минут срабатывает сразу
+	start := time.Now().UTC().Add(5 * time.Minute)
+	_ = events.Create(context.Background(), domain.Event{
+		ID: "soon", UserID: "user-1", Date: start.Format("2006-01-02"), Title: "Soon", StartTime: start.Format("15:04"),
+		Reminders: []domain.Reminder{{MinutesBefore: 5}},
+	}, tenant.Quota{})
+
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+	defer scheduler.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(notifier.sent()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if sent := notifier.sent(); len(sent) != 1 || sent[0].Event.ID != "soon" {
+		t.Errorf("Expected reminder from running scheduler, got %+v", sent)
+	}
+}
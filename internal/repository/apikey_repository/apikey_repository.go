@@ -0,0 +1,18 @@
+package apikey_repository
+
+import (
+	"calendar-server/internal/domain"
+	"context"
+	"time"
+)
+
+// APIKeyRepository определяет контракт для работы с хранилищем API-ключей.
+// Выборки по ID и пользователю ограничены арендатором из контекста.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key domain.APIKey) error
+	Update(ctx context.Context, key domain.APIKey) error
+	GetByID(ctx context.Context, keyID string) (domain.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (domain.APIKey, error)
+	ListByUserID(ctx context.Context, userID string) ([]domain.APIKey, error)
+	TouchLastUsed(ctx context.Context, keyID string, at time.Time) error
+}
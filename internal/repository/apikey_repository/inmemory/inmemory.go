@@ -0,0 +1,160 @@
+package inmemory
+
+import (
+	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// APIKeyRepository - реализация хранилища API-ключей в памяти
+type APIKeyRepository struct {
+	mu     sync.RWMutex
+	keys   map[string]domain.APIKey
+	byHash map[string]string
+	logger *zap.Logger
+}
+
+// NewAPIKeyRepository - конструктор хранилища API-ключей в памяти
+func NewAPIKeyRepository(logger *zap.Logger) *APIKeyRepository {
+	return &APIKeyRepository{
+		keys:   make(map[string]domain.APIKey),
+		byHash: make(map[string]string),
+		logger: logger,
+	}
+}
+
+// Create - сохранение нового ключа
+func (r *APIKeyRepository) Create(ctx context.Context, key domain.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.keys[key.ID]; exists {
+		return errors.ErrAPIKeyConflict
+	}
+
+	r.keys[key.ID] = cloneKey(key)
+	r.byHash[key.Hash] = key.ID
+	r.logger.Debug("API key stored in repository",
+		zappretty.Field("key_id", key.ID),
+		zappretty.Field("user_id", key.UserID),
+	)
+	return nil
+}
+
+// Update - обновление ключа (ротация, отзыв)
+func (r *APIKeyRepository) Update(ctx context.Context, key domain.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.keys[key.ID]
+	if !exists {
+		return errors.ErrAPIKeyNotFound
+	}
+
+	if existing.Hash != key.Hash {
+		delete(r.byHash, existing.Hash)
+		r.byHash[key.Hash] = key.ID
+	}
+	r.keys[key.ID] = cloneKey(key)
+	return nil
+}
+
+// GetByID - получение ключа по ID
+func (r *APIKeyRepository) GetByID(ctx context.Context, keyID string) (domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.APIKey{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, exists := r.keys[keyID]
+	if !exists || key.TenantID != tenant.FromContext(ctx) {
+		return domain.APIKey{}, errors.ErrAPIKeyNotFound
+	}
+	return cloneKey(key), nil
+}
+
+// GetByHash - получение ключа по хешу предъявленного секрета.
+// Поиск выполняется до определения арендатора, поэтому не ограничен разделом.
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.APIKey{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keyID, exists := r.byHash[hash]
+	if !exists {
+		return domain.APIKey{}, errors.ErrAPIKeyNotFound
+	}
+	return cloneKey(r.keys[keyID]), nil
+}
+
+// ListByUserID - получение ключей пользователя арендатора, отсортированных по дате создания
+func (r *APIKeyRepository) ListByUserID(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+
+	var keys []domain.APIKey
+	for _, key := range r.keys {
+		if key.UserID == userID && key.TenantID == tenantID {
+			keys = append(keys, cloneKey(key))
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// TouchLastUsed - фиксация времени последнего использования ключа
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, keyID string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, exists := r.keys[keyID]
+	if !exists {
+		return errors.ErrAPIKeyNotFound
+	}
+	key.LastUsedAt = &at
+	r.keys[keyID] = key
+	return nil
+}
+
+// cloneKey копирует ключ, чтобы вызывающий не мог изменить срез scopes в хранилище
+func cloneKey(key domain.APIKey) domain.APIKey {
+	key.Scopes = append([]string(nil), key.Scopes...)
+	return key
+}
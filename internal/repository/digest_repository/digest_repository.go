@@ -0,0 +1,21 @@
+package digest_repository
+
+import (
+	"calendar-server/internal/domain"
+	"context"
+)
+
+// DigestRepository определяет контракт для работы с хранилищем подписок на сводки.
+// Операции выполняются в разделе арендатора из контекста, кроме ListAll.
+type DigestRepository interface {
+	// Save - создание или замена подписки пользователя на сводку периода
+	Save(ctx context.Context, subscription domain.DigestSubscription) error
+	// Get - подписка пользователя; отсутствие - errors.ErrDigestNotFound
+	Get(ctx context.Context, userID, period string) (domain.DigestSubscription, error)
+	ListByUserID(ctx context.Context, userID string) ([]domain.DigestSubscription, error)
+	Delete(ctx context.Context, userID, period string) error
+	// ListAll - подписки всех арендаторов для планировщика
+	ListAll(ctx context.Context) ([]domain.DigestSubscription, error)
+	// MarkSent - запись местной даты отправленной или пропущенной сводки
+	MarkSent(ctx context.Context, userID, period, date string) error
+}
@@ -0,0 +1,157 @@
+package inmemory
+
+import (
+	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
+	"context"
+	"sort"
+	"sync"
+
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// subscriptionKey - подписка определяется арендатором, пользователем и периодом
+type subscriptionKey struct {
+	tenantID string
+	userID   string
+	period   string
+}
+
+// DigestRepository - реализация хранилища подписок на сводки в памяти
+type DigestRepository struct {
+	mu            sync.RWMutex
+	subscriptions map[subscriptionKey]domain.DigestSubscription
+	logger        *zap.Logger
+}
+
+// NewDigestRepository - конструктор хранилища подписок на сводки в памяти
+func NewDigestRepository(logger *zap.Logger) *DigestRepository {
+	return &DigestRepository{
+		subscriptions: make(map[subscriptionKey]domain.DigestSubscription),
+		logger:        logger,
+	}
+}
+
+// Save - создание или замена подписки
+func (r *DigestRepository) Save(ctx context.Context, subscription domain.DigestSubscription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := subscriptionKey{tenantID: subscription.TenantID, userID: subscription.UserID, period: subscription.Period}
+	r.subscriptions[key] = subscription
+	r.logger.Debug("Digest subscription stored in repository",
+		zappretty.Field("user_id", subscription.UserID),
+		zappretty.Field("period", subscription.Period),
+	)
+	return nil
+}
+
+// Get - подписка пользователя арендатора
+func (r *DigestRepository) Get(ctx context.Context, userID, period string) (domain.DigestSubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.DigestSubscription{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscription, exists := r.subscriptions[r.key(ctx, userID, period)]
+	if !exists {
+		return domain.DigestSubscription{}, errors.ErrDigestNotFound
+	}
+	return subscription, nil
+}
+
+// ListByUserID - подписки пользователя арендатора: сначала дневная, затем недельная
+func (r *DigestRepository) ListByUserID(ctx context.Context, userID string) ([]domain.DigestSubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var subscriptions []domain.DigestSubscription
+	for _, period := range domain.DigestPeriods {
+		if subscription, exists := r.subscriptions[r.key(ctx, userID, period)]; exists {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+// Delete - удаление подписки пользователя арендатора
+func (r *DigestRepository) Delete(ctx context.Context, userID, period string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := r.key(ctx, userID, period)
+	if _, exists := r.subscriptions[key]; !exists {
+		return errors.ErrDigestNotFound
+	}
+	delete(r.subscriptions, key)
+	return nil
+}
+
+// ListAll - подписки всех арендаторов, упорядоченные по арендатору, пользователю и периоду
+func (r *DigestRepository) ListAll(ctx context.Context) ([]domain.DigestSubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscriptions := make([]domain.DigestSubscription, 0, len(r.subscriptions))
+	for _, subscription := range r.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i], subscriptions[j]
+		if a.TenantID != b.TenantID {
+			return a.TenantID < b.TenantID
+		}
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		return a.Period < b.Period
+	})
+	return subscriptions, nil
+}
+
+// MarkSent - запись даты последней сводки; более ранняя дата не заменяет записанную
+func (r *DigestRepository) MarkSent(ctx context.Context, userID, period, date string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := r.key(ctx, userID, period)
+	subscription, exists := r.subscriptions[key]
+	if !exists {
+		return errors.ErrDigestNotFound
+	}
+	if date > subscription.LastDate {
+		subscription.LastDate = date
+		r.subscriptions[key] = subscription
+	}
+	return nil
+}
+
+// key - ключ подписки в разделе арендатора из контекста
+func (r *DigestRepository) key(ctx context.Context, userID, period string) subscriptionKey {
+	return subscriptionKey{tenantID: tenant.FromContext(ctx), userID: userID, period: period}
+}
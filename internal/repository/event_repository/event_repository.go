@@ -2,15 +2,58 @@ package event_repository
 
 import (
 	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
 	"context"
 )
 
-// EventRepository определяет контракт для работы с хранилищем событий
+// EventRepository определяет контракт для работы с хранилищем событий.
+// Все операции выполняются в разделе арендатора из контекста (см. tenant.FromContext).
 type EventRepository interface {
-	Create(ctx context.Context, event domain.Event) error
-	Update(ctx context.Context, event domain.Event) error
+	// Create - создание события. Лимиты количества событий из quota проверяются атомарно со вставкой,
+	// поэтому параллельные вызовы не превышают их; при превышении возвращается *errors.QuotaError.
+	Create(ctx context.Context, event domain.Event, quota tenant.Quota) error
+	// Update - обновление события; лимит событий на дату проверяется атомарно, если меняется дата или владелец,
+	// а лимит событий пользователя - если меняется владелец
+	Update(ctx context.Context, event domain.Event, quota tenant.Quota) error
 	Delete(ctx context.Context, eventID string) error
+	GetByID(ctx context.Context, eventID string) (domain.Event, error)
+	Count(ctx context.Context) (int, error)
+	CountByUserID(ctx context.Context, userID string) (int, error)
 	GetByUserIDAndDate(ctx context.Context, userID, date string) ([]domain.Event, error)
 	GetByUserIDAndWeek(ctx context.Context, userID, date string) ([]domain.Event, error)
 	GetByUserIDAndMonth(ctx context.Context, userID, date string) ([]domain.Event, error)
+	// GetByUserIDAndRange - события пользователя с датами от from до to включительно
+	GetByUserIDAndRange(ctx context.Context, userID, from, to string) ([]domain.Event, error)
+	// GetPage - до limit событий в порядке ID, следующих за afterID; пустой userID означает всех пользователей.
+	// Позволяет обойти большое количество событий порциями, не загружая их все в память.
+	GetPage(ctx context.Context, userID, afterID string, limit int) ([]domain.Event, error)
+}
+
+// EventAdminRepository определяет операции обслуживания хранилища, недоступные обычным пользователям
+type EventAdminRepository interface {
+	CountByUser(ctx context.Context) (map[string]int, error)
+	DeleteByUserID(ctx context.Context, userID string) (int, error)
+	// ReassignUser - передача событий; лимиты событий нового владельца из quota проверяются атомарно
+	ReassignUser(ctx context.Context, fromUserID, toUserID string, eventIDs []string, quota tenant.Quota) (int, error)
+}
+
+// EventStats - необязательный интерфейс хранилища для статистики по всем арендаторам
+type EventStats interface {
+	CountByTenant(ctx context.Context) (map[string]int, error)
+}
+
+// Outbox - необязательный интерфейс хранилища с outbox: Create, Update и Delete, а также DeleteByUserID и
+// ReassignUser для каждого затронутого события записывают доменное событие об изменении в той же операции,
+// что и само изменение. Смена владельца записывается удалением у прежнего владельца и изменением у нового.
+// Сообщения хранятся до подтверждения.
+type Outbox interface {
+	// PendingMessages - до limit неподтвержденных сообщений всех арендаторов в порядке записи
+	PendingMessages(ctx context.Context, limit int) ([]domain.OutboxMessage, error)
+	// AckMessages - удаление отправленных сообщений; неизвестные ID пропускаются
+	AckMessages(ctx context.Context, ids []string) error
+}
+
+// Pinger - необязательный интерфейс хранилища для проверки готовности к работе
+type Pinger interface {
+	Ping(ctx context.Context) error
 }
@@ -2,34 +2,126 @@ package inmemory
 
 import (
 	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
 	"sort"
 	"sync"
 	"time"
 
 	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
 	"calendar-server/pkg/logger/zappretty"
 
 	"go.uber.org/zap"
 )
 
-// EventRepository - реализация хранилища событий в памяти
+// EventRepository - реализация хранилища событий в памяти.
+// События разделены по арендаторам: каждый метод работает только с разделом
+// арендатора из контекста, поэтому запросы не видят чужие данные.
 type EventRepository struct {
-	mu     sync.RWMutex
-	events map[string]domain.Event
+	mu      sync.RWMutex
+	tenants map[string]map[string]domain.Event
+	// ids - отсортированные ID событий каждого раздела, по которым GetPage продолжает обход без сортировки
+	ids    map[string][]string
 	logger *zap.Logger
+	// outboxEnabled - изменения записываются в outbox; outbox - неподтвержденные сообщения в порядке записи
+	outboxEnabled bool
+	outbox        []domain.OutboxMessage
+}
+
+// Option - функциональная опция EventRepository
+type Option func(*EventRepository)
+
+// WithOutbox - запись доменных событий об изменениях в outbox (см. event_repository.Outbox).
+// Без потребителя, подтверждающего сообщения, outbox растет неограниченно.
+func WithOutbox() Option {
+	return func(r *EventRepository) {
+		r.outboxEnabled = true
+	}
 }
 
 // NewEventRepository - конструктор хранилища событий в памяти
-func NewEventRepository(logger *zap.Logger) *EventRepository {
-	return &EventRepository{
-		events: make(map[string]domain.Event),
-		logger: logger,
+func NewEventRepository(logger *zap.Logger, opts ...Option) *EventRepository {
+	r := &EventRepository{
+		tenants: make(map[string]map[string]domain.Event),
+		ids:     make(map[string][]string),
+		logger:  logger,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Ping - проверка доступности хранилища: блокировка должна захватываться до истечения ctx
+func (r *EventRepository) Ping(ctx context.Context) error {
+	acquired := make(chan struct{})
+	go func() {
+		r.mu.RLock()
+		r.mu.RUnlock()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// log возвращает логгер из контекста запроса, либо логгер хранилища
+func (r *EventRepository) log(ctx context.Context) *zap.Logger {
+	return ctxlog.FromContext(ctx, r.logger)
+}
+
+// partition возвращает раздел арендатора для чтения; может быть nil
+func (r *EventRepository) partition(ctx context.Context) map[string]domain.Event {
+	return r.tenants[tenant.FromContext(ctx)]
+}
+
+// writablePartition возвращает раздел арендатора, создавая его при необходимости
+func (r *EventRepository) writablePartition(ctx context.Context) map[string]domain.Event {
+	tenantID := tenant.FromContext(ctx)
+	events, ok := r.tenants[tenantID]
+	if !ok {
+		events = make(map[string]domain.Event)
+		r.tenants[tenantID] = events
+	}
+	return events
+}
+
+// addID - добавление ID в упорядоченный индекс раздела; вызывается под r.mu
+func (r *EventRepository) addID(ctx context.Context, id string) {
+	tenantID := tenant.FromContext(ctx)
+	ids := r.ids[tenantID]
+	i, _ := slices.BinarySearch(ids, id)
+	r.ids[tenantID] = slices.Insert(ids, i, id)
+}
+
+// removeID - удаление ID из упорядоченного индекса раздела; вызывается под r.mu
+func (r *EventRepository) removeID(ctx context.Context, id string) {
+	tenantID := tenant.FromContext(ctx)
+	ids := r.ids[tenantID]
+	if i, found := slices.BinarySearch(ids, id); found {
+		r.ids[tenantID] = slices.Delete(ids, i, i+1)
 	}
 }
 
-// Create - создание события
-func (r *EventRepository) Create(ctx context.Context, event domain.Event) error {
+// pruneIDs - удаление из индекса ID, которых больше нет в разделе events; вызывается под r.mu
+func (r *EventRepository) pruneIDs(ctx context.Context, events map[string]domain.Event) {
+	tenantID := tenant.FromContext(ctx)
+	r.ids[tenantID] = slices.DeleteFunc(r.ids[tenantID], func(id string) bool {
+		_, exists := events[id]
+		return !exists
+	})
+}
+
+// Create - создание события; лимиты количества событий из quota проверяются под той же блокировкой
+func (r *EventRepository) Create(ctx context.Context, event domain.Event, quota tenant.Quota) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
@@ -37,27 +129,36 @@ func (r *EventRepository) Create(ctx context.Context, event domain.Event) error
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.logger.Debug("Creating event in repository",
+	r.log(ctx).Debug("Creating event in repository",
 		zappretty.Field("event_id", event.ID),
 		zappretty.Field("user_id", event.UserID),
 	)
 
-	if _, exists := r.events[event.ID]; exists {
-		r.logger.Warn("Event conflict - ID already exists",
+	events := r.writablePartition(ctx)
+	if _, exists := events[event.ID]; exists {
+		r.log(ctx).Warn("Event conflict - ID already exists",
 			zappretty.Field("event_id", event.ID),
 		)
 		return errors.ErrEventConflict
 	}
+	if err := checkQuota(events, event, nil, quota); err != nil {
+		return err
+	}
 
-	r.events[event.ID] = event
-	r.logger.Debug("Event created successfully in repository",
+	// Напоминания копируются, чтобы изменения среза вызывающим не меняли хранимое событие
+	event.Reminders = slices.Clone(event.Reminders)
+	events[event.ID] = event
+	r.addID(ctx, event.ID)
+	r.record(ctx, domain.ChangeCreated, event)
+	r.log(ctx).Debug("Event created successfully in repository",
 		zappretty.Field("event_id", event.ID),
 	)
 	return nil
 }
 
-// Update - обновление события
-func (r *EventRepository) Update(ctx context.Context, event domain.Event) error {
+// Update - обновление события; лимиты событий на дату и, при смене владельца, событий пользователя
+// из quota проверяются под той же блокировкой
+func (r *EventRepository) Update(ctx context.Context, event domain.Event, quota tenant.Quota) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
@@ -65,19 +166,26 @@ func (r *EventRepository) Update(ctx context.Context, event domain.Event) error
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.logger.Debug("Updating event in repository",
+	r.log(ctx).Debug("Updating event in repository",
 		zappretty.Field("event_id", event.ID),
 	)
 
-	if _, exists := r.events[event.ID]; !exists {
-		r.logger.Warn("Event not found for update",
+	events := r.partition(ctx)
+	previous, exists := events[event.ID]
+	if !exists {
+		r.log(ctx).Warn("Event not found for update",
 			zappretty.Field("event_id", event.ID),
 		)
 		return errors.ErrEventNotFound
 	}
+	if err := checkQuota(events, event, &previous, quota); err != nil {
+		return err
+	}
 
-	r.events[event.ID] = event
-	r.logger.Debug("Event updated successfully in repository",
+	event.Reminders = slices.Clone(event.Reminders)
+	events[event.ID] = event
+	r.recordMove(ctx, previous, event)
+	r.log(ctx).Debug("Event updated successfully in repository",
 		zappretty.Field("event_id", event.ID),
 	)
 	return nil
@@ -92,24 +200,44 @@ func (r *EventRepository) Delete(ctx context.Context, eventID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.logger.Debug("Deleting event in repository",
+	r.log(ctx).Debug("Deleting event in repository",
 		zappretty.Field("event_id", eventID),
 	)
 
-	if _, exists := r.events[eventID]; !exists {
-		r.logger.Warn("Event not found for deletion",
+	events := r.partition(ctx)
+	existing, exists := events[eventID]
+	if !exists {
+		r.log(ctx).Warn("Event not found for deletion",
 			zappretty.Field("event_id", eventID),
 		)
 		return errors.ErrEventNotFound
 	}
 
-	delete(r.events, eventID)
-	r.logger.Debug("Event deleted successfully from repository",
+	delete(events, eventID)
+	r.removeID(ctx, eventID)
+	r.record(ctx, domain.ChangeDeleted, existing)
+	r.log(ctx).Debug("Event deleted successfully from repository",
 		zappretty.Field("event_id", eventID),
 	)
 	return nil
 }
 
+// GetByID - получение события по ID
+func (r *EventRepository) GetByID(ctx context.Context, eventID string) (domain.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Event{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	event, exists := r.partition(ctx)[eventID]
+	if !exists {
+		return domain.Event{}, errors.ErrEventNotFound
+	}
+	return event, nil
+}
+
 // GetByUserIDAndDate - получение событий по ID пользователя и дате
 func (r *EventRepository) GetByUserIDAndDate(ctx context.Context, userID, date string) ([]domain.Event, error) {
 	if err := ctx.Err(); err != nil {
@@ -120,7 +248,7 @@ func (r *EventRepository) GetByUserIDAndDate(ctx context.Context, userID, date s
 	defer r.mu.RUnlock()
 
 	var events []domain.Event
-	for _, event := range r.events {
+	for _, event := range r.partition(ctx) {
 		if event.UserID == userID && event.Date == date {
 			events = append(events, event)
 		}
@@ -143,7 +271,7 @@ func (r *EventRepository) GetByUserIDAndWeek(ctx context.Context, userID, date s
 	defer r.mu.RUnlock()
 
 	var events []domain.Event
-	for _, event := range r.events {
+	for _, event := range r.partition(ctx) {
 		if event.UserID != userID {
 			continue
 		}
@@ -176,7 +304,7 @@ func (r *EventRepository) GetByUserIDAndMonth(ctx context.Context, userID, date
 	defer r.mu.RUnlock()
 
 	var events []domain.Event
-	for _, event := range r.events {
+	for _, event := range r.partition(ctx) {
 		if event.UserID != userID {
 			continue
 		}
@@ -195,12 +323,365 @@ func (r *EventRepository) GetByUserIDAndMonth(ctx context.Context, userID, date
 	return events, nil
 }
 
-// sortEvents сортирует события по дате и названию
+// GetByUserIDAndRange - получение событий пользователя за период, включая границы
+func (r *EventRepository) GetByUserIDAndRange(ctx context.Context, userID, from, to string) ([]domain.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []domain.Event
+	for _, event := range r.partition(ctx) {
+		// Даты в формате YYYY-MM-DD сравниваются как строки
+		if event.UserID == userID && event.Date >= from && event.Date <= to {
+			events = append(events, event)
+		}
+	}
+
+	sortEvents(events)
+	return events, nil
+}
+
+// GetPage - получение порции событий в порядке ID после afterID. Обход начинается с позиции afterID
+// в упорядоченном индексе и останавливается, набрав limit событий.
+func (r *EventRepository) GetPage(ctx context.Context, userID, afterID string, limit int) ([]domain.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := r.partition(ctx)
+	ids := r.ids[tenant.FromContext(ctx)]
+	start, found := slices.BinarySearch(ids, afterID)
+	if found {
+		start++
+	}
+
+	var page []domain.Event
+	for _, id := range ids[start:] {
+		event := events[id]
+		if userID != "" && event.UserID != userID {
+			continue
+		}
+		page = append(page, event)
+		if limit > 0 && len(page) == limit {
+			break
+		}
+	}
+	return page, nil
+}
+
+// Count - количество событий арендатора
+func (r *EventRepository) Count(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.partition(ctx)), nil
+}
+
+// CountByTenant - количество событий в каждом разделе арендатора
+func (r *EventRepository) CountByTenant(ctx context.Context) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int, len(r.tenants))
+	for tenantID, events := range r.tenants {
+		counts[tenantID] = len(events)
+	}
+	return counts, nil
+}
+
+// CountByUserID - количество событий пользователя
+func (r *EventRepository) CountByUserID(ctx context.Context, userID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, event := range r.partition(ctx) {
+		if event.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountByUser - количество событий каждого пользователя
+func (r *EventRepository) CountByUser(ctx context.Context) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, event := range r.partition(ctx) {
+		counts[event.UserID]++
+	}
+	return counts, nil
+}
+
+// DeleteByUserID - удаление всех событий пользователя
+func (r *EventRepository) DeleteByUserID(ctx context.Context, userID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	events := r.partition(ctx)
+	for id, event := range events {
+		if event.UserID == userID {
+			delete(events, id)
+			r.record(ctx, domain.ChangeDeleted, event)
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		r.pruneIDs(ctx, events)
+	}
+
+	r.log(ctx).Debug("User events deleted from repository",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("count", deleted),
+	)
+	return deleted, nil
+}
+
+// ReassignUser - передача событий другому пользователю; пустой eventIDs означает все события.
+// Лимиты событий нового владельца из quota проверяются до изменений: при превышении ничего не передается.
+func (r *EventRepository) ReassignUser(ctx context.Context, fromUserID, toUserID string, eventIDs []string, quota tenant.Quota) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := r.partition(ctx)
+	if len(eventIDs) > 0 {
+		// Проверяем все ID до изменений, чтобы операция была атомарной
+		for _, id := range eventIDs {
+			event, exists := events[id]
+			if !exists || event.UserID != fromUserID {
+				return 0, errors.ErrEventNotFound
+			}
+		}
+	}
+
+	if fromUserID != toUserID {
+		var moving []domain.Event
+		for id, event := range events {
+			if event.UserID == fromUserID && (len(eventIDs) == 0 || containsID(eventIDs, id)) {
+				moving = append(moving, event)
+			}
+		}
+		if err := checkReassignQuota(events, toUserID, moving, quota); err != nil {
+			return 0, err
+		}
+	}
+
+	reassigned := 0
+	for id, event := range events {
+		if event.UserID != fromUserID || (len(eventIDs) > 0 && !containsID(eventIDs, id)) {
+			continue
+		}
+		reassigned++
+		if fromUserID == toUserID {
+			// Передача самому себе ничего не меняет и в outbox не попадает
+			continue
+		}
+		moved := event
+		moved.UserID = toUserID
+		events[id] = moved
+		r.recordMove(ctx, event, moved)
+	}
+
+	r.log(ctx).Debug("Events reassigned in repository",
+		zappretty.Field("from_user_id", fromUserID),
+		zappretty.Field("to_user_id", toUserID),
+		zappretty.Field("count", reassigned),
+	)
+	return reassigned, nil
+}
+
+// checkQuota - проверка лимитов количества событий перед записью event в раздел; вызывается под r.mu.
+// previous - прежнее состояние изменяемого события, nil для нового. Изменение, оставляющее событие
+// у того же пользователя в той же дате, лимиты не затрагивает.
+func checkQuota(events map[string]domain.Event, event domain.Event, previous *domain.Event, quota tenant.Quota) error {
+	if previous == nil {
+		if err := checkLimit(errors.LimitTenantEvents, quota.MaxEvents, func() int {
+			return len(events)
+		}); err != nil {
+			return err
+		}
+	}
+	// Новое событие или событие другого владельца добавляется к событиям пользователя
+	if previous == nil || previous.UserID != event.UserID {
+		if err := checkLimit(errors.LimitUserEvents, quota.MaxEventsPerUser, func() int {
+			return countEvents(events, event.UserID, "")
+		}); err != nil {
+			return err
+		}
+	}
+
+	if previous != nil && previous.UserID == event.UserID && previous.Date == event.Date {
+		return nil
+	}
+	return checkLimit(errors.LimitDayEvents, quota.MaxEventsPerDay, func() int {
+		return countEvents(events, event.UserID, event.Date)
+	})
+}
+
+// checkReassignQuota - проверка лимитов пользователя toUserID, которому передаются события moving; вызывается под r.mu
+func checkReassignQuota(events map[string]domain.Event, toUserID string, moving []domain.Event, quota tenant.Quota) error {
+	if limit := quota.MaxEventsPerUser; limit > 0 {
+		if current := countEvents(events, toUserID, ""); current+len(moving) > limit {
+			return &errors.QuotaError{Limit: errors.LimitUserEvents, Max: limit, Current: current}
+		}
+	}
+
+	if limit := quota.MaxEventsPerDay; limit > 0 {
+		perDate := make(map[string]int)
+		for _, event := range moving {
+			perDate[event.Date]++
+		}
+		for date, count := range perDate {
+			if current := countEvents(events, toUserID, date); current+count > limit {
+				return &errors.QuotaError{Limit: errors.LimitDayEvents, Max: limit, Current: current}
+			}
+		}
+	}
+	return nil
+}
+
+// checkLimit сравнивает текущее значение счетчика с лимитом; limit <= 0 отключает проверку
+func checkLimit(name string, limit int, current func() int) error {
+	if limit <= 0 {
+		return nil
+	}
+	if count := current(); count >= limit {
+		return &errors.QuotaError{Limit: name, Max: limit, Current: count}
+	}
+	return nil
+}
+
+// countEvents - количество событий пользователя; непустой date ограничивает подсчет датой
+func countEvents(events map[string]domain.Event, userID, date string) int {
+	count := 0
+	for _, event := range events {
+		if event.UserID == userID && (date == "" || event.Date == date) {
+			count++
+		}
+	}
+	return count
+}
+
+// containsID проверяет наличие ID в списке
+func containsID(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sortEvents сортирует события по дате, времени начала и названию; события на весь день идут первыми
 func sortEvents(events []domain.Event) {
 	sort.Slice(events, func(i, j int) bool {
-		if events[i].Date == events[j].Date {
-			return events[i].Title < events[j].Title
+		if events[i].Date != events[j].Date {
+			return events[i].Date < events[j].Date
 		}
-		return events[i].Date < events[j].Date
+		if events[i].StartTime != events[j].StartTime {
+			return events[i].StartTime < events[j].StartTime
+		}
+		return events[i].Title < events[j].Title
 	})
 }
+
+// PendingMessages - до limit неподтвержденных сообщений outbox в порядке записи
+func (r *EventRepository) PendingMessages(ctx context.Context, limit int) ([]domain.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.outbox[:min(limit, len(r.outbox))]), nil
+}
+
+// AckMessages - удаление отправленных сообщений outbox
+func (r *EventRepository) AckMessages(ctx context.Context, ids []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.outbox = slices.DeleteFunc(r.outbox, func(msg domain.OutboxMessage) bool {
+		return slices.Contains(ids, msg.ID)
+	})
+	return nil
+}
+
+// record - запись доменного события об изменении в outbox; вызывается под r.mu вместе с изменением
+func (r *EventRepository) record(ctx context.Context, changeType string, event domain.Event) {
+	if !r.outboxEnabled {
+		return
+	}
+
+	now := time.Now().UTC()
+	change := domain.Change{
+		Type:     changeType,
+		TenantID: tenant.FromContext(ctx),
+		UserID:   event.UserID,
+		EventID:  event.ID,
+		At:       now,
+	}
+	if changeType != domain.ChangeDeleted {
+		change.Event = &event
+	}
+
+	r.outbox = append(r.outbox, domain.OutboxMessage{
+		ID:        newMessageID(),
+		Change:    change,
+		CreatedAt: now,
+	})
+}
+
+// recordMove - запись изменения события из previous в event. При смене владельца прежний получает
+// удаление, иначе его подписчики не узнают, что событие ушло из календаря.
+func (r *EventRepository) recordMove(ctx context.Context, previous, event domain.Event) {
+	if previous.UserID != event.UserID {
+		r.record(ctx, domain.ChangeDeleted, previous)
+	}
+	r.record(ctx, domain.ChangeUpdated, event)
+}
+
+// newMessageID - случайный ID сообщения outbox
+func newMessageID() string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return "msg_" + hex.EncodeToString(buf)
+}
@@ -2,10 +2,13 @@ package inmemory
 
 import (
 	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
 	"calendar-server/pkg/errors"
 	"context"
 	stdErrors "errors"
+	"slices"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -27,12 +30,12 @@ func TestEventRepository_Create(t *testing.T) {
 		Title:  "Test Event",
 	}
 
-	err := repo.Create(ctx, event)
+	err := repo.Create(ctx, event, tenant.Quota{})
 	if err != nil {
 		t.Fatalf("Failed to create event: %v", err)
 	}
 
-	err = repo.Create(ctx, event)
+	err = repo.Create(ctx, event, tenant.Quota{})
 	if !stdErrors.Is(err, errors.ErrEventConflict) {
 		t.Errorf("Expected ErrEventConflict for duplicate event ID, got %v", err)
 	}
@@ -48,13 +51,13 @@ func TestEventRepository_Update(t *testing.T) {
 		Title:  "Original Title",
 	}
 
-	err := repo.Create(ctx, event)
+	err := repo.Create(ctx, event, tenant.Quota{})
 	if err != nil {
 		t.Fatalf("Failed to create event: %v", err)
 	}
 
 	event.Title = "Updated Title"
-	err = repo.Update(ctx, event)
+	err = repo.Update(ctx, event, tenant.Quota{})
 	if err != nil {
 		t.Fatalf("Failed to update event: %v", err)
 	}
@@ -65,7 +68,7 @@ func TestEventRepository_Update(t *testing.T) {
 		Date:   "2025-01-15",
 		Title:  "Test",
 	}
-	err = repo.Update(ctx, nonExistentEvent)
+	err = repo.Update(ctx, nonExistentEvent, tenant.Quota{})
 	if !stdErrors.Is(err, errors.ErrEventNotFound) {
 		t.Errorf("Expected ErrEventNotFound when updating non-existent event, got %v", err)
 	}
@@ -81,7 +84,7 @@ func TestEventRepository_Delete(t *testing.T) {
 		Title:  "Test Event",
 	}
 
-	err := repo.Create(ctx, event)
+	err := repo.Create(ctx, event, tenant.Quota{})
 	if err != nil {
 		t.Fatalf("Failed to create event: %v", err)
 	}
@@ -108,7 +111,7 @@ func TestEventRepository_GetByUserIDAndDate(t *testing.T) {
 	}
 
 	for _, event := range events {
-		err := repo.Create(ctx, event)
+		err := repo.Create(ctx, event, tenant.Quota{})
 		if err != nil {
 			t.Fatalf("Failed to create event: %v", err)
 		}
@@ -138,7 +141,7 @@ func TestEventRepository_GetByUserIDAndWeek(t *testing.T) {
 	}
 
 	for _, event := range events {
-		err := repo.Create(ctx, event)
+		err := repo.Create(ctx, event, tenant.Quota{})
 		if err != nil {
 			t.Fatalf("Failed to create event: %v", err)
 		}
@@ -164,7 +167,7 @@ func TestEventRepository_GetByUserIDAndMonth(t *testing.T) {
 	}
 
 	for _, event := range events {
-		err := repo.Create(ctx, event)
+		err := repo.Create(ctx, event, tenant.Quota{})
 		if err != nil {
 			t.Fatalf("Failed to create event: %v", err)
 		}
@@ -229,12 +232,12 @@ func TestEventRepository_ContextCancellation(t *testing.T) {
 		Title:  "Test Event",
 	}
 
-	err := repo.Create(cancelledCtx, event)
+	err := repo.Create(cancelledCtx, event, tenant.Quota{})
 	if err == nil {
 		t.Error("Expected error when context is cancelled")
 	}
 
-	err = repo.Update(cancelledCtx, event)
+	err = repo.Update(cancelledCtx, event, tenant.Quota{})
 	if err == nil {
 		t.Error("Expected error when context is cancelled")
 	}
@@ -263,7 +266,7 @@ func TestEventRepository_ConcurrentAccess(t *testing.T) {
 				Date:   "2025-01-15",
 				Title:  "Concurrent Event",
 			}
-			err := repo.Create(ctx, event)
+			err := repo.Create(ctx, event, tenant.Quota{})
 			if err != nil {
 				t.Errorf("Failed to create event in goroutine: %v", err)
 			}
@@ -284,3 +287,477 @@ func TestEventRepository_ConcurrentAccess(t *testing.T) {
 		t.Fatalf("Expected 10 events, got %d", len(result))
 	}
 }
+
+func TestEventRepository_GetByID(t *testing.T) {
+	repo, ctx := setupTest()
+
+	event := domain.Event{
+		ID:     "test-1",
+		UserID: "user-1",
+		Date:   "2025-01-15",
+		Title:  "Test Event",
+	}
+
+	if err := repo.Create(ctx, event, tenant.Quota{}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, "test-1")
+	if err != nil {
+		t.Fatalf("Failed to get event: %v", err)
+	}
+	if !got.Equal(event) {
+		t.Errorf("Expected %+v, got %+v", event, got)
+	}
+
+	_, err = repo.GetByID(ctx, "non-existent")
+	if !stdErrors.Is(err, errors.ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+}
+
+func TestEventRepository_AdminOperations(t *testing.T) {
+	repo, ctx := setupTest()
+
+	events := []domain.Event{
+		{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "Event 1"},
+		{ID: "2", UserID: "user-1", Date: "2025-01-16", Title: "Event 2"},
+		{ID: "3", UserID: "user-1", Date: "2025-01-17", Title: "Event 3"},
+		{ID: "4", UserID: "user-2", Date: "2025-01-15", Title: "Event 4"},
+	}
+	for _, event := range events {
+		if err := repo.Create(ctx, event, tenant.Quota{}); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	counts, err := repo.CountByUser(ctx)
+	if err != nil {
+		t.Fatalf("Failed to count events: %v", err)
+	}
+	if counts["user-1"] != 3 || counts["user-2"] != 1 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+
+	if _, err := repo.ReassignUser(ctx, "user-1", "user-3", []string{"1", "4"}, tenant.Quota{}); !stdErrors.Is(err, errors.ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound for event of another user, got %v", err)
+	}
+
+	reassigned, err := repo.ReassignUser(ctx, "user-1", "user-3", []string{"1"}, tenant.Quota{})
+	if err != nil || reassigned != 1 {
+		t.Fatalf("Expected 1 reassigned event, got %d, %v", reassigned, err)
+	}
+
+	reassigned, err = repo.ReassignUser(ctx, "user-1", "user-2", nil, tenant.Quota{})
+	if err != nil || reassigned != 2 {
+		t.Fatalf("Expected 2 reassigned events, got %d, %v", reassigned, err)
+	}
+
+	deleted, err := repo.DeleteByUserID(ctx, "user-2")
+	if err != nil || deleted != 3 {
+		t.Fatalf("Expected 3 deleted events, got %d, %v", deleted, err)
+	}
+
+	counts, _ = repo.CountByUser(ctx)
+	if len(counts) != 1 || counts["user-3"] != 1 {
+		t.Errorf("Unexpected counts after cleanup %v", counts)
+	}
+}
+
+func TestEventRepository_Quota(t *testing.T) {
+	repo, ctx := setupTest()
+	quota := tenant.Quota{MaxEventsPerUser: 3, MaxEventsPerDay: 2}
+
+	for _, event := range []domain.Event{
+		{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "One"},
+		{ID: "2", UserID: "user-1", Date: "2025-01-15", Title: "Two"},
+		{ID: "3", UserID: "user-2", Date: "2025-01-15", Title: "Three"},
+		{ID: "4", UserID: "user-2", Date: "2025-01-16", Title: "Four"},
+	} {
+		if err := repo.Create(ctx, event, quota); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		run       func() error
+		wantLimit string
+	}{
+		{
+			name: "create over day limit",
+			run: func() error {
+				return repo.Create(ctx, domain.Event{ID: "5", UserID: "user-1", Date: "2025-01-15", Title: "Five"}, quota)
+			},
+			wantLimit: errors.LimitDayEvents,
+		},
+		{
+			name: "update into full date",
+			run: func() error {
+				return repo.Update(ctx, domain.Event{ID: "4", UserID: "user-1", Date: "2025-01-15", Title: "Four"}, quota)
+			},
+			wantLimit: errors.LimitDayEvents,
+		},
+		{
+			name: "update within the same date",
+			run: func() error {
+				return repo.Update(ctx, domain.Event{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "Renamed"}, quota)
+			},
+		},
+		{
+			name: "reassign over user limit",
+			run: func() error {
+				_, err := repo.ReassignUser(ctx, "user-2", "user-1", nil, quota)
+				return err
+			},
+			wantLimit: errors.LimitUserEvents,
+		},
+		{
+			name: "reassign into full date",
+			run: func() error {
+				_, err := repo.ReassignUser(ctx, "user-2", "user-1", []string{"3"}, quota)
+				return err
+			},
+			wantLimit: errors.LimitDayEvents,
+		},
+		{
+			name: "reassign within limits",
+			run: func() error {
+				_, err := repo.ReassignUser(ctx, "user-2", "user-1", []string{"4"}, quota)
+				return err
+			},
+		},
+		{
+			name: "update owner over user limit",
+			run: func() error {
+				return repo.Update(ctx, domain.Event{ID: "3", UserID: "user-1", Date: "2025-01-17", Title: "Three"}, quota)
+			},
+			wantLimit: errors.LimitUserEvents,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			var quotaErr *errors.QuotaError
+			if !stdErrors.As(err, &quotaErr) || quotaErr.Limit != tt.wantLimit {
+				t.Errorf("Expected %s quota error, got %v", tt.wantLimit, err)
+			}
+		})
+	}
+
+	if count, _ := repo.CountByUserID(ctx, "user-1"); count != 3 {
+		t.Errorf("Expected 3 events of user-1, got %d", count)
+	}
+}
+
+func TestEventRepository_TenantIsolation(t *testing.T) {
+	repo, ctx := setupTest()
+
+	tenantA := tenant.WithID(ctx, "dept-a")
+	tenantB := tenant.WithID(ctx, "dept-b")
+
+	event := domain.Event{ID: "shared-id", UserID: "user-1", Date: "2025-01-15", Title: "Dept A"}
+	if err := repo.Create(tenantA, event, tenant.Quota{}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	// Одинаковые ID в разных арендаторах не конфликтуют
+	event.Title = "Dept B"
+	if err := repo.Create(tenantB, event, tenant.Quota{}); err != nil {
+		t.Fatalf("Failed to create event with same ID in another tenant: %v", err)
+	}
+
+	got, err := repo.GetByID(tenantA, "shared-id")
+	if err != nil || got.Title != "Dept A" {
+		t.Errorf("Expected event of dept-a, got %+v, %v", got, err)
+	}
+
+	events, _ := repo.GetByUserIDAndMonth(tenantB, "user-1", "2025-01-01")
+	if len(events) != 1 || events[0].Title != "Dept B" {
+		t.Errorf("Expected only dept-b event, got %v", events)
+	}
+
+	if _, err := repo.GetByID(ctx, "shared-id"); !stdErrors.Is(err, errors.ErrEventNotFound) {
+		t.Errorf("Expected default tenant not to see other tenants, got %v", err)
+	}
+
+	if err := repo.Delete(tenantB, "shared-id"); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+	if count, _ := repo.Count(tenantA); count != 1 {
+		t.Errorf("Expected dept-a to keep its event, got count %d", count)
+	}
+}
+
+func TestEventRepository_Ping(t *testing.T) {
+	repo, ctx := setupTest()
+
+	if err := repo.Ping(ctx); err != nil {
+		t.Errorf("Expected healthy repository, got %v", err)
+	}
+
+	// Удерживаемая блокировка записи означает, что хранилище не отвечает
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := repo.Ping(timeoutCtx); !stdErrors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestEventRepository_CountByTenant(t *testing.T) {
+	repo, ctx := setupTest()
+
+	_ = repo.Create(tenant.WithID(ctx, "dept-a"), domain.Event{ID: "1", UserID: "u", Date: "2025-01-15", Title: "A"}, tenant.Quota{})
+	_ = repo.Create(tenant.WithID(ctx, "dept-a"), domain.Event{ID: "2", UserID: "u", Date: "2025-01-15", Title: "A"}, tenant.Quota{})
+	_ = repo.Create(ctx, domain.Event{ID: "1", UserID: "u", Date: "2025-01-15", Title: "Default"}, tenant.Quota{})
+
+	counts, err := repo.CountByTenant(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if counts["dept-a"] != 2 || counts[tenant.DefaultID] != 1 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+}
+
+func TestEventRepository_GetByUserIDAndRange(t *testing.T) {
+	repo, ctx := setupTest()
+
+	events := []domain.Event{
+		{ID: "1", UserID: "user-1", Date: "2024-12-31", Title: "Before"},
+		{ID: "2", UserID: "user-1", Date: "2025-01-01", Title: "Lunch", StartTime: "12:00"},
+		{ID: "3", UserID: "user-1", Date: "2025-01-01", Title: "Holiday"},
+		{ID: "4", UserID: "user-1", Date: "2025-01-31", Title: "Last day"},
+		{ID: "5", UserID: "user-2", Date: "2025-01-10", Title: "Other user"},
+	}
+	for _, event := range events {
+		if err := repo.Create(ctx, event, tenant.Quota{}); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	result, err := repo.GetByUserIDAndRange(ctx, "user-1", "2025-01-01", "2025-01-31")
+	if err != nil {
+		t.Fatalf("Failed to get events for range: %v", err)
+	}
+
+	// Границы включаются, события на весь день идут перед событиями со временем
+	expected := []string{"3", "2", "4"}
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(result))
+	}
+	for i, id := range expected {
+		if result[i].ID != id {
+			t.Errorf("Expected event %s at position %d, got %s", id, i, result[i].ID)
+		}
+	}
+}
+
+func TestEventRepository_GetPage(t *testing.T) {
+	repo, ctx := setupTest()
+
+	for _, event := range []domain.Event{
+		{ID: "c", UserID: "user-1", Date: "2025-01-01", Title: "C"},
+		{ID: "a", UserID: "user-1", Date: "2025-01-03", Title: "A"},
+		{ID: "d", UserID: "user-2", Date: "2025-01-02", Title: "D"},
+		{ID: "b", UserID: "user-1", Date: "2025-01-02", Title: "B"},
+	} {
+		if err := repo.Create(ctx, event, tenant.Quota{}); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		userID   string
+		afterID  string
+		limit    int
+		expected []string
+	}{
+		{name: "first page", userID: "user-1", limit: 2, expected: []string{"a", "b"}},
+		{name: "next page", userID: "user-1", afterID: "b", limit: 2, expected: []string{"c"}},
+		{name: "all users", afterID: "a", limit: 10, expected: []string{"b", "c", "d"}},
+		{name: "after last", afterID: "d", limit: 10, expected: nil},
+		{name: "cursor between IDs", afterID: "bb", limit: 10, expected: []string{"c", "d"}},
+		{name: "without limit", userID: "user-1", expected: []string{"a", "b", "c"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			events, err := repo.GetPage(ctx, tc.userID, tc.afterID, tc.limit)
+			if err != nil {
+				t.Fatalf("Failed to get page: %v", err)
+			}
+			if len(events) != len(tc.expected) {
+				t.Fatalf("Expected %d events, got %d", len(tc.expected), len(events))
+			}
+			for i, id := range tc.expected {
+				if events[i].ID != id {
+					t.Errorf("Expected event %s at position %d, got %s", id, i, events[i].ID)
+				}
+			}
+		})
+	}
+
+	// Порядок обхода сохраняется после удалений и вставок, события других арендаторов не попадают в него
+	if err := repo.Delete(ctx, "b"); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+	if _, err := repo.DeleteByUserID(ctx, "user-2"); err != nil {
+		t.Fatalf("Failed to delete user events: %v", err)
+	}
+	_ = repo.Create(ctx, domain.Event{ID: "ab", UserID: "user-3", Date: "2025-01-04", Title: "AB"}, tenant.Quota{})
+	_ = repo.Create(tenant.WithID(ctx, "dept-a"), domain.Event{ID: "aa", UserID: "user-1", Date: "2025-01-04", Title: "AA"}, tenant.Quota{})
+
+	events, err := repo.GetPage(ctx, "", "", 0)
+	if err != nil {
+		t.Fatalf("Failed to get page: %v", err)
+	}
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	if !slices.Equal(ids, []string{"a", "ab", "c"}) {
+		t.Errorf("Expected [a ab c] after changes, got %v", ids)
+	}
+}
+
+func TestEventRepository_Outbox(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := NewEventRepository(logger, WithOutbox())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	event := domain.Event{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "Standup"}
+	if err := repo.Create(ctx, event, tenant.Quota{}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	event.Title = "Retro"
+	if err := repo.Update(ctx, event, tenant.Quota{}); err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+	if err := repo.Delete(ctx, "1"); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+
+	// Неудачные изменения не записываются
+	_ = repo.Create(ctx, domain.Event{ID: "2", UserID: "user-1", Date: "2025-01-15", Title: "A"}, tenant.Quota{})
+	if err := repo.Create(ctx, domain.Event{ID: "2", UserID: "user-1", Date: "2025-01-15", Title: "B"}, tenant.Quota{}); !stdErrors.Is(err, errors.ErrEventConflict) {
+		t.Fatalf("Expected ErrEventConflict, got %v", err)
+	}
+	if err := repo.Delete(ctx, "missing"); !stdErrors.Is(err, errors.ErrEventNotFound) {
+		t.Fatalf("Expected ErrEventNotFound, got %v", err)
+	}
+
+	messages, err := repo.PendingMessages(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	wantTypes := []string{domain.ChangeCreated, domain.ChangeUpdated, domain.ChangeDeleted, domain.ChangeCreated}
+	if len(messages) != len(wantTypes) {
+		t.Fatalf("Expected %d messages, got %+v", len(wantTypes), messages)
+	}
+	for i, msg := range messages {
+		if msg.Change.Type != wantTypes[i] {
+			t.Errorf("Expected message %d of type %s, got %s", i, wantTypes[i], msg.Change.Type)
+		}
+		if msg.ID == "" || msg.Change.TenantID != "acme" || msg.Change.UserID != "user-1" {
+			t.Errorf("Unexpected message %+v", msg)
+		}
+	}
+	if messages[1].Change.Event == nil || messages[1].Change.Event.Title != "Retro" {
+		t.Errorf("Expected updated event in message, got %+v", messages[1].Change.Event)
+	}
+	if messages[2].Change.Event != nil || messages[2].Change.EventID != "1" {
+		t.Errorf("Expected deletion without event body, got %+v", messages[2].Change)
+	}
+
+	if limited, _ := repo.PendingMessages(ctx, 2); len(limited) != 2 || limited[0].ID != messages[0].ID {
+		t.Errorf("Expected first 2 messages, got %+v", limited)
+	}
+
+	if err := repo.AckMessages(ctx, []string{messages[0].ID, messages[2].ID}); err != nil {
+		t.Fatalf("Failed to ack messages: %v", err)
+	}
+	rest, _ := repo.PendingMessages(ctx, 10)
+	if len(rest) != 2 || rest[0].ID != messages[1].ID || rest[1].ID != messages[3].ID {
+		t.Errorf("Expected unacked messages in order, got %+v", rest)
+	}
+}
+
+func TestEventRepository_OutboxDisabled(t *testing.T) {
+	repo, ctx := setupTest()
+
+	_ = repo.Create(ctx, domain.Event{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "Standup"}, tenant.Quota{})
+	if messages, _ := repo.PendingMessages(ctx, 10); len(messages) != 0 {
+		t.Errorf("Expected no outbox messages without WithOutbox, got %+v", messages)
+	}
+}
+
+func TestEventRepository_OutboxAdminOperations(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := NewEventRepository(logger, WithOutbox())
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	for _, event := range []domain.Event{
+		{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "Event 1"},
+		{ID: "2", UserID: "user-1", Date: "2025-01-16", Title: "Event 2"},
+		{ID: "3", UserID: "user-2", Date: "2025-01-15", Title: "Event 3"},
+	} {
+		if err := repo.Create(ctx, event, tenant.Quota{}); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+	created, _ := repo.PendingMessages(ctx, 10)
+	ids := make([]string, 0, len(created))
+	for _, msg := range created {
+		ids = append(ids, msg.ID)
+	}
+	if err := repo.AckMessages(ctx, ids); err != nil {
+		t.Fatalf("Failed to ack messages: %v", err)
+	}
+
+	// Неудачная передача не записывает изменений
+	if _, err := repo.ReassignUser(ctx, "user-1", "user-3", []string{"1", "3"}, tenant.Quota{}); !stdErrors.Is(err, errors.ErrEventNotFound) {
+		t.Fatalf("Expected ErrEventNotFound, got %v", err)
+	}
+	// Передача самому себе ничего не меняет
+	if count, err := repo.ReassignUser(ctx, "user-1", "user-1", nil, tenant.Quota{}); err != nil || count != 2 {
+		t.Fatalf("Expected 2 events reassigned to the same user, got %d, %v", count, err)
+	}
+	if _, err := repo.ReassignUser(ctx, "user-1", "user-3", []string{"1"}, tenant.Quota{}); err != nil {
+		t.Fatalf("Failed to reassign events: %v", err)
+	}
+	if _, err := repo.DeleteByUserID(ctx, "user-1"); err != nil {
+		t.Fatalf("Failed to delete user events: %v", err)
+	}
+
+	messages, err := repo.PendingMessages(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %+v", messages)
+	}
+	left, reassigned, deleted := messages[0].Change, messages[1].Change, messages[2].Change
+	if left.Type != domain.ChangeDeleted || left.EventID != "1" || left.UserID != "user-1" ||
+		left.TenantID != "acme" || left.Event != nil {
+		t.Errorf("Expected deletion for the previous owner, got %+v", left)
+	}
+	if reassigned.Type != domain.ChangeUpdated || reassigned.EventID != "1" || reassigned.UserID != "user-3" ||
+		reassigned.TenantID != "acme" || reassigned.Event == nil || reassigned.Event.UserID != "user-3" {
+		t.Errorf("Expected update to the new owner, got %+v", reassigned)
+	}
+	if deleted.Type != domain.ChangeDeleted || deleted.EventID != "2" || deleted.UserID != "user-1" ||
+		deleted.TenantID != "acme" || deleted.Event != nil {
+		t.Errorf("Expected deletion of the remaining event, got %+v", deleted)
+	}
+}
@@ -0,0 +1,222 @@
+package instrumented
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"calendar-server/internal/domain"
+	repo "calendar-server/internal/repository/event_repository"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/metrics"
+	"calendar-server/pkg/tracing"
+)
+
+// Repository - хранилище, поддерживающее пользовательские и административные операции
+type Repository interface {
+	repo.EventRepository
+	repo.EventAdminRepository
+}
+
+// EventRepository - декоратор хранилища событий: измеряет длительность операций
+// и открывает спан трассировки на каждый вызов
+type EventRepository struct {
+	next     Repository
+	duration *metrics.HistogramVec
+}
+
+// NewEventRepository - конструктор декоратора; registry может быть nil, если метрики отключены.
+// Если хранилище реализует repo.EventStats, регистрируется и метрика количества событий по арендаторам.
+func NewEventRepository(next Repository, registry *metrics.Registry) *EventRepository {
+	r := &EventRepository{next: next}
+	if registry == nil {
+		return r
+	}
+
+	r.duration = registry.NewHistogramVec(
+		"calendar_repository_operation_duration_seconds",
+		"Duration of event repository operations.",
+		nil, "operation", "status",
+	)
+	if stats, ok := next.(repo.EventStats); ok {
+		registry.Register(eventCountCollector(stats))
+	}
+
+	return r
+}
+
+// start - начало спана операции
+func (r *EventRepository) start(ctx context.Context, operation string, attrs ...tracing.Attribute) (context.Context, *tracing.Span, time.Time) {
+	ctx, span := tracing.Start(ctx, "EventRepository."+operation, attrs...)
+	return ctx, span, time.Now()
+}
+
+// finish - завершение спана и запись длительности операции
+func (r *EventRepository) finish(span *tracing.Span, operation string, start time.Time, err error) {
+	span.RecordError(err)
+	span.End()
+
+	if r.duration == nil {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	r.duration.Observe(time.Since(start).Seconds(), operation, status)
+}
+
+// Create - создание события
+func (r *EventRepository) Create(ctx context.Context, event domain.Event, quota tenant.Quota) error {
+	ctx, span, start := r.start(ctx, "Create", tracing.String("event.id", event.ID), tracing.String("user.id", event.UserID))
+	err := r.next.Create(ctx, event, quota)
+	r.finish(span, "create", start, err)
+	return err
+}
+
+// Update - обновление события
+func (r *EventRepository) Update(ctx context.Context, event domain.Event, quota tenant.Quota) error {
+	ctx, span, start := r.start(ctx, "Update", tracing.String("event.id", event.ID), tracing.String("user.id", event.UserID))
+	err := r.next.Update(ctx, event, quota)
+	r.finish(span, "update", start, err)
+	return err
+}
+
+// Delete - удаление события
+func (r *EventRepository) Delete(ctx context.Context, eventID string) error {
+	ctx, span, start := r.start(ctx, "Delete", tracing.String("event.id", eventID))
+	err := r.next.Delete(ctx, eventID)
+	r.finish(span, "delete", start, err)
+	return err
+}
+
+// GetByID - получение события по ID
+func (r *EventRepository) GetByID(ctx context.Context, eventID string) (domain.Event, error) {
+	ctx, span, start := r.start(ctx, "GetByID", tracing.String("event.id", eventID))
+	event, err := r.next.GetByID(ctx, eventID)
+	r.finish(span, "get_by_id", start, err)
+	return event, err
+}
+
+// Count - количество событий арендатора
+func (r *EventRepository) Count(ctx context.Context) (int, error) {
+	ctx, span, start := r.start(ctx, "Count")
+	count, err := r.next.Count(ctx)
+	r.finish(span, "count", start, err)
+	return count, err
+}
+
+// CountByUserID - количество событий пользователя
+func (r *EventRepository) CountByUserID(ctx context.Context, userID string) (int, error) {
+	ctx, span, start := r.start(ctx, "CountByUserID", tracing.String("user.id", userID))
+	count, err := r.next.CountByUserID(ctx, userID)
+	r.finish(span, "count_by_user_id", start, err)
+	return count, err
+}
+
+// GetByUserIDAndDate - получение событий за день
+func (r *EventRepository) GetByUserIDAndDate(ctx context.Context, userID, date string) ([]domain.Event, error) {
+	ctx, span, start := r.start(ctx, "GetByUserIDAndDate", tracing.String("user.id", userID), tracing.String("date", date))
+	events, err := r.next.GetByUserIDAndDate(ctx, userID, date)
+	r.finish(span, "get_by_date", start, err)
+	return events, err
+}
+
+// GetByUserIDAndWeek - получение событий за неделю
+func (r *EventRepository) GetByUserIDAndWeek(ctx context.Context, userID, date string) ([]domain.Event, error) {
+	ctx, span, start := r.start(ctx, "GetByUserIDAndWeek", tracing.String("user.id", userID), tracing.String("date", date))
+	events, err := r.next.GetByUserIDAndWeek(ctx, userID, date)
+	r.finish(span, "get_by_week", start, err)
+	return events, err
+}
+
+// GetByUserIDAndMonth - получение событий за месяц
+func (r *EventRepository) GetByUserIDAndMonth(ctx context.Context, userID, date string) ([]domain.Event, error) {
+	ctx, span, start := r.start(ctx, "GetByUserIDAndMonth", tracing.String("user.id", userID), tracing.String("date", date))
+	events, err := r.next.GetByUserIDAndMonth(ctx, userID, date)
+	r.finish(span, "get_by_month", start, err)
+	return events, err
+}
+
+// GetByUserIDAndRange - получение событий за период
+func (r *EventRepository) GetByUserIDAndRange(ctx context.Context, userID, from, to string) ([]domain.Event, error) {
+	ctx, span, start := r.start(ctx, "GetByUserIDAndRange",
+		tracing.String("user.id", userID), tracing.String("date.from", from), tracing.String("date.to", to))
+	events, err := r.next.GetByUserIDAndRange(ctx, userID, from, to)
+	r.finish(span, "get_by_range", start, err)
+	return events, err
+}
+
+// GetPage - получение порции событий в порядке ID
+func (r *EventRepository) GetPage(ctx context.Context, userID, afterID string, limit int) ([]domain.Event, error) {
+	ctx, span, start := r.start(ctx, "GetPage",
+		tracing.String("user.id", userID), tracing.String("page.after", afterID), tracing.Int("page.limit", limit))
+	events, err := r.next.GetPage(ctx, userID, afterID, limit)
+	r.finish(span, "get_page", start, err)
+	return events, err
+}
+
+// CountByUser - количество событий каждого пользователя
+func (r *EventRepository) CountByUser(ctx context.Context) (map[string]int, error) {
+	ctx, span, start := r.start(ctx, "CountByUser")
+	counts, err := r.next.CountByUser(ctx)
+	r.finish(span, "count_by_user", start, err)
+	return counts, err
+}
+
+// DeleteByUserID - удаление всех событий пользователя
+func (r *EventRepository) DeleteByUserID(ctx context.Context, userID string) (int, error) {
+	ctx, span, start := r.start(ctx, "DeleteByUserID", tracing.String("user.id", userID))
+	deleted, err := r.next.DeleteByUserID(ctx, userID)
+	r.finish(span, "delete_by_user_id", start, err)
+	return deleted, err
+}
+
+// ReassignUser - передача событий другому пользователю
+func (r *EventRepository) ReassignUser(ctx context.Context, fromUserID, toUserID string, eventIDs []string, quota tenant.Quota) (int, error) {
+	ctx, span, start := r.start(ctx, "ReassignUser", tracing.String("from_user.id", fromUserID), tracing.String("to_user.id", toUserID))
+	reassigned, err := r.next.ReassignUser(ctx, fromUserID, toUserID, eventIDs, quota)
+	r.finish(span, "reassign_user", start, err)
+	return reassigned, err
+}
+
+// Ping - проверка доступности исходного хранилища, если оно реализует repo.Pinger
+func (r *EventRepository) Ping(ctx context.Context) error {
+	pinger, ok := r.next.(repo.Pinger)
+	if !ok {
+		return nil
+	}
+	ctx, span, start := r.start(ctx, "Ping")
+	err := pinger.Ping(ctx)
+	r.finish(span, "ping", start, err)
+	return err
+}
+
+// eventCountCollector - метрика количества хранимых событий по арендаторам
+func eventCountCollector(stats repo.EventStats) metrics.Collector {
+	return metrics.CollectorFunc(func() []metrics.Family {
+		family := metrics.Family{
+			Name: "calendar_events",
+			Help: "Number of stored events by tenant.",
+			Type: metrics.TypeGauge,
+		}
+
+		counts, err := stats.CountByTenant(context.Background())
+		if err != nil {
+			return []metrics.Family{family}
+		}
+		tenantIDs := make([]string, 0, len(counts))
+		for tenantID := range counts {
+			tenantIDs = append(tenantIDs, tenantID)
+		}
+		sort.Strings(tenantIDs)
+
+		for _, tenantID := range tenantIDs {
+			family.Samples = append(family.Samples, metrics.Sample{
+				Labels: []metrics.Label{{Name: "tenant", Value: tenantID}},
+				Value:  float64(counts[tenantID]),
+			})
+		}
+		return []metrics.Family{family}
+	})
+}
@@ -0,0 +1,102 @@
+package inmemory
+
+import (
+	"calendar-server/internal/domain"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// ReminderRepository - реализация хранилища срабатываний напоминаний в памяти
+type ReminderRepository struct {
+	mu      sync.RWMutex
+	firings map[string]domain.ReminderFiring
+	logger  *zap.Logger
+}
+
+// NewReminderRepository - конструктор хранилища срабатываний напоминаний в памяти
+func NewReminderRepository(logger *zap.Logger) *ReminderRepository {
+	return &ReminderRepository{
+		firings: make(map[string]domain.ReminderFiring),
+		logger:  logger,
+	}
+}
+
+// Record - сохранение итога срабатывания
+func (r *ReminderRepository) Record(ctx context.Context, firing domain.ReminderFiring) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.firings[firing.ID]; exists {
+		return errors.ErrReminderFired
+	}
+	r.firings[firing.ID] = firing
+	r.logger.Debug("Reminder firing stored in repository",
+		zappretty.Field("firing_id", firing.ID),
+		zappretty.Field("status", firing.Status),
+	)
+	return nil
+}
+
+// Fired - записан ли итог срабатывания
+func (r *ReminderRepository) Fired(ctx context.Context, firingID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exists := r.firings[firingID]
+	return exists, nil
+}
+
+// ListByEventID - срабатывания напоминаний события, новые первыми
+func (r *ReminderRepository) ListByEventID(ctx context.Context, tenantID, eventID string) ([]domain.ReminderFiring, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var firings []domain.ReminderFiring
+	for _, firing := range r.firings {
+		if firing.TenantID == tenantID && firing.EventID == eventID {
+			firings = append(firings, firing)
+		}
+	}
+	sort.Slice(firings, func(i, j int) bool {
+		return firings[i].FireAt.After(firings[j].FireAt)
+	})
+	return firings, nil
+}
+
+// Prune - удаление итогов срабатываний, запланированных раньше before
+func (r *ReminderRepository) Prune(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pruned := 0
+	for id, firing := range r.firings {
+		if firing.FireAt.Before(before) {
+			delete(r.firings, id)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
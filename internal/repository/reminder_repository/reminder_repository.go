@@ -0,0 +1,20 @@
+package reminder_repository
+
+import (
+	"calendar-server/internal/domain"
+	"context"
+	"time"
+)
+
+// ReminderRepository определяет контракт для хранилища итогов срабатывания напоминаний.
+// ID срабатывания выводится с учетом арендатора, поэтому операции не зависят от арендатора в контексте.
+type ReminderRepository interface {
+	// Record - сохранение итога срабатывания; уже записанный ID - errors.ErrReminderFired
+	Record(ctx context.Context, firing domain.ReminderFiring) error
+	// Fired - записан ли итог срабатывания с этим ID
+	Fired(ctx context.Context, firingID string) (bool, error)
+	// ListByEventID - срабатывания напоминаний события арендатора, новые первыми
+	ListByEventID(ctx context.Context, tenantID, eventID string) ([]domain.ReminderFiring, error)
+	// Prune - удаление итогов срабатываний, запланированных раньше before
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
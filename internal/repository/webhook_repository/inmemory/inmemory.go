@@ -0,0 +1,314 @@
+package inmemory
+
+import (
+	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
+	"context"
+	"sort"
+	"sync"
+
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// DeliveryLogSize - успешные доставки, которые хранятся для журнала каждого вебхука.
+// Ожидающие и недоставленные доставки хранятся до удаления вебхука.
+const DeliveryLogSize = 100
+
+// WebhookRepository - реализация хранилища вебхуков в памяти
+type WebhookRepository struct {
+	mu    sync.RWMutex
+	hooks map[string]domain.Webhook
+	// deliveries - доставки по ID; order - ID доставок каждого вебхука в порядке создания
+	deliveries map[string]domain.WebhookDelivery
+	order      map[string][]string
+	logger     *zap.Logger
+}
+
+// NewWebhookRepository - конструктор хранилища вебхуков в памяти
+func NewWebhookRepository(logger *zap.Logger) *WebhookRepository {
+	return &WebhookRepository{
+		hooks:      make(map[string]domain.Webhook),
+		deliveries: make(map[string]domain.WebhookDelivery),
+		order:      make(map[string][]string),
+		logger:     logger,
+	}
+}
+
+// Create - сохранение нового вебхука
+func (r *WebhookRepository) Create(ctx context.Context, hook domain.Webhook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks[hook.ID] = cloneWebhook(hook)
+	r.logger.Debug("Webhook stored in repository",
+		zappretty.Field("webhook_id", hook.ID),
+		zappretty.Field("user_id", hook.UserID),
+	)
+	return nil
+}
+
+// Delete - удаление вебхука арендатора и его доставок
+func (r *WebhookRepository) Delete(ctx context.Context, webhookID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hook, exists := r.hooks[webhookID]
+	if !exists || hook.TenantID != tenant.FromContext(ctx) {
+		return errors.ErrWebhookNotFound
+	}
+
+	delete(r.hooks, webhookID)
+	for _, id := range r.order[webhookID] {
+		delete(r.deliveries, id)
+	}
+	delete(r.order, webhookID)
+	return nil
+}
+
+// GetByID - получение вебхука арендатора по ID
+func (r *WebhookRepository) GetByID(ctx context.Context, webhookID string) (domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Webhook{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hook, exists := r.hooks[webhookID]
+	if !exists || hook.TenantID != tenant.FromContext(ctx) {
+		return domain.Webhook{}, errors.ErrWebhookNotFound
+	}
+	return cloneWebhook(hook), nil
+}
+
+// ListByUserID - вебхуки пользователя арендатора, отсортированные по дате создания
+func (r *WebhookRepository) ListByUserID(ctx context.Context, userID string) ([]domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+
+	var hooks []domain.Webhook
+	for _, hook := range r.hooks {
+		if hook.UserID == userID && hook.TenantID == tenantID {
+			hooks = append(hooks, cloneWebhook(hook))
+		}
+	}
+	sortWebhooks(hooks)
+	return hooks, nil
+}
+
+// ListMatching - вебхуки арендатора, подходящие под изменение
+func (r *WebhookRepository) ListMatching(ctx context.Context, change domain.Change) ([]domain.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+
+	var hooks []domain.Webhook
+	for _, hook := range r.hooks {
+		if hook.TenantID == tenantID && hook.Matches(change) {
+			hooks = append(hooks, cloneWebhook(hook))
+		}
+	}
+	sortWebhooks(hooks)
+	return hooks, nil
+}
+
+// CreateDelivery - сохранение новой доставки существующего вебхука
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.hooks[delivery.WebhookID]; !exists {
+		return errors.ErrWebhookNotFound
+	}
+	if _, exists := r.deliveries[delivery.ID]; exists {
+		return errors.ErrDeliveryConflict
+	}
+
+	r.deliveries[delivery.ID] = delivery
+	r.order[delivery.WebhookID] = append(r.order[delivery.WebhookID], delivery.ID)
+	return nil
+}
+
+// UpdateDelivery - сохранение результата попытки доставки
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.deliveries[delivery.ID]; !exists {
+		return errors.ErrDeliveryNotFound
+	}
+
+	r.deliveries[delivery.ID] = delivery
+	if delivery.Status == domain.DeliverySucceeded {
+		r.pruneSucceeded(delivery.WebhookID)
+	}
+	return nil
+}
+
+// GetDelivery - получение доставки арендатора по ID
+func (r *WebhookRepository) GetDelivery(ctx context.Context, deliveryID string) (domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, exists := r.deliveries[deliveryID]
+	if !exists || delivery.TenantID != tenant.FromContext(ctx) {
+		return domain.WebhookDelivery{}, errors.ErrDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+// ListDeliveries - журнал доставок вебхука арендатора, новые первыми
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID, status string, limit int) ([]domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+
+	var deliveries []domain.WebhookDelivery
+	ids := r.order[webhookID]
+	for i := len(ids) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		delivery := r.deliveries[ids[i]]
+		if delivery.TenantID == tenantID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// ListDeliveriesByUserID - доставки всех вебхуков пользователя арендатора, новые первыми
+func (r *WebhookRepository) ListDeliveriesByUserID(ctx context.Context, userID, status string, limit int) ([]domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+
+	var deliveries []domain.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.UserID == userID && delivery.TenantID == tenantID && delivery.Status == status {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sortNewestFirst(deliveries)
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// ListPending - ожидающие доставки всех арендаторов в порядке создания
+func (r *WebhookRepository) ListPending(ctx context.Context) ([]domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var deliveries []domain.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == domain.DeliveryPending {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].ID < deliveries[j].ID
+		}
+		return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+	})
+	return deliveries, nil
+}
+
+// pruneSucceeded - удаление самых старых успешных доставок сверх DeliveryLogSize; вызывается под r.mu
+func (r *WebhookRepository) pruneSucceeded(webhookID string) {
+	ids := r.order[webhookID]
+
+	succeeded := 0
+	for _, id := range ids {
+		if r.deliveries[id].Status == domain.DeliverySucceeded {
+			succeeded++
+		}
+	}
+	if succeeded <= DeliveryLogSize {
+		return
+	}
+
+	kept := ids[:0]
+	for _, id := range ids {
+		if succeeded > DeliveryLogSize && r.deliveries[id].Status == domain.DeliverySucceeded {
+			delete(r.deliveries, id)
+			succeeded--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	r.order[webhookID] = kept
+}
+
+// sortWebhooks - сортировка по дате создания и ID
+func sortWebhooks(hooks []domain.Webhook) {
+	sort.Slice(hooks, func(i, j int) bool {
+		if hooks[i].CreatedAt.Equal(hooks[j].CreatedAt) {
+			return hooks[i].ID < hooks[j].ID
+		}
+		return hooks[i].CreatedAt.Before(hooks[j].CreatedAt)
+	})
+}
+
+// sortNewestFirst - сортировка доставок от новых к старым
+func sortNewestFirst(deliveries []domain.WebhookDelivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].ID > deliveries[j].ID
+		}
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+}
+
+// cloneWebhook копирует вебхук, чтобы вызывающий не мог изменить срез event_types в хранилище
+func cloneWebhook(hook domain.Webhook) domain.Webhook {
+	hook.EventTypes = append([]string(nil), hook.EventTypes...)
+	return hook
+}
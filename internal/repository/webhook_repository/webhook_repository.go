@@ -0,0 +1,29 @@
+package webhook_repository
+
+import (
+	"calendar-server/internal/domain"
+	"context"
+)
+
+// WebhookRepository определяет контракт для работы с хранилищем вебхуков и их доставок.
+// Операции выполняются в разделе арендатора из контекста, кроме ListPending.
+type WebhookRepository interface {
+	Create(ctx context.Context, hook domain.Webhook) error
+	// Delete - удаление вебхука вместе с журналом его доставок
+	Delete(ctx context.Context, webhookID string) error
+	GetByID(ctx context.Context, webhookID string) (domain.Webhook, error)
+	ListByUserID(ctx context.Context, userID string) ([]domain.Webhook, error)
+	// ListMatching - вебхуки арендатора, которым отправляется изменение
+	ListMatching(ctx context.Context, change domain.Change) ([]domain.Webhook, error)
+
+	// CreateDelivery - сохранение доставки; существующий ID - errors.ErrDeliveryConflict
+	CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+	GetDelivery(ctx context.Context, deliveryID string) (domain.WebhookDelivery, error)
+	// ListDeliveries - до limit доставок вебхука, новые первыми; пустой status означает все состояния
+	ListDeliveries(ctx context.Context, webhookID, status string, limit int) ([]domain.WebhookDelivery, error)
+	// ListDeliveriesByUserID - до limit доставок вебхуков пользователя в состоянии status, новые первыми
+	ListDeliveriesByUserID(ctx context.Context, userID, status string, limit int) ([]domain.WebhookDelivery, error)
+	// ListPending - ожидающие доставки всех арендаторов, чтобы продолжить их после перезапуска
+	ListPending(ctx context.Context) ([]domain.WebhookDelivery, error)
+}
@@ -0,0 +1,134 @@
+package tenant
+
+import (
+	"context"
+	"sort"
+)
+
+// DefaultID - арендатор, используемый при отсутствии явного идентификатора
+const DefaultID = "default"
+
+// Quota - ограничения арендатора; нулевое значение означает отсутствие ограничения
+type Quota struct {
+	// MaxEvents - максимальное количество событий арендатора
+	MaxEvents int `json:"max_events,omitempty"`
+	// MaxEventsPerUser - максимальное количество событий одного пользователя
+	MaxEventsPerUser int `json:"max_events_per_user,omitempty"`
+	// MaxEventsPerDay - максимальное количество событий пользователя на одну дату
+	MaxEventsPerDay int `json:"max_events_per_day,omitempty"`
+	// MaxTitleLength - максимальная длина названия события в символах; ограничивает правило
+	// проверки max_title_length (см. Registry.Validation)
+	MaxTitleLength int `json:"max_title_length,omitempty"`
+}
+
+// Merge - квота, в которой заданные в override лимиты заменяют лимиты q
+func (q Quota) Merge(override Quota) Quota {
+	if override.MaxEvents != 0 {
+		q.MaxEvents = override.MaxEvents
+	}
+	if override.MaxEventsPerUser != 0 {
+		q.MaxEventsPerUser = override.MaxEventsPerUser
+	}
+	if override.MaxEventsPerDay != 0 {
+		q.MaxEventsPerDay = override.MaxEventsPerDay
+	}
+	if override.MaxTitleLength != 0 {
+		q.MaxTitleLength = override.MaxTitleLength
+	}
+	return q
+}
+
+// Settings - настройки арендатора
+type Settings struct {
+	DisplayName string `json:"display_name,omitempty"`
+	// Disabled - запросы арендатора отклоняются
+	Disabled   bool            `json:"disabled,omitempty"`
+	Quota      Quota           `json:"quota"`
+	Validation ValidationRules `json:"validation"`
+}
+
+type tenantKey struct{}
+
+// WithID - кладет идентификатор арендатора в контекст
+func WithID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// FromContext - идентификатор арендатора из контекста или DefaultID
+func FromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(tenantKey{}).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return DefaultID
+}
+
+// Registry - реестр известных арендаторов и их настроек
+type Registry struct {
+	tenants  map[string]Settings
+	defaults Settings
+}
+
+// NewRegistry - конструктор Registry; пустой реестр принимает любого арендатора с настройками по умолчанию.
+// Квота и правила проверки из defaults применяются ко всем арендаторам, значения из их настроек имеют приоритет.
+func NewRegistry(defaults Settings, tenants map[string]Settings) *Registry {
+	copied := make(map[string]Settings, len(tenants))
+	for id, settings := range tenants {
+		copied[id] = settings
+	}
+	return &Registry{tenants: copied, defaults: defaults}
+}
+
+// Strict - задан ли список арендаторов; строгий реестр принимает только перечисленных
+func (r *Registry) Strict() bool {
+	return r != nil && len(r.tenants) > 0
+}
+
+// Lookup - настройки арендатора; ok=false, если арендатор неизвестен строгому реестру
+func (r *Registry) Lookup(tenantID string) (Settings, bool) {
+	if !r.Strict() {
+		return Settings{}, true
+	}
+	settings, ok := r.tenants[tenantID]
+	return settings, ok
+}
+
+// Settings - настройки арендатора или нулевые настройки для неизвестного
+func (r *Registry) Settings(tenantID string) Settings {
+	settings, _ := r.Lookup(tenantID)
+	return settings
+}
+
+// Quota - действующая квота арендатора с учетом квоты по умолчанию
+func (r *Registry) Quota(tenantID string) Quota {
+	if r == nil {
+		return Quota{}
+	}
+	return r.defaults.Quota.Merge(r.Settings(tenantID).Quota)
+}
+
+// Validation - действующие правила проверки событий арендатора. Длину названия ограничивает
+// меньшее из правила max_title_length и квоты, чтобы она проверялась в одном месте.
+func (r *Registry) Validation(tenantID string) ValidationRules {
+	rules := DefaultValidationRules()
+	if r == nil {
+		return rules
+	}
+	rules = rules.Merge(r.defaults.Validation).Merge(r.Settings(tenantID).Validation)
+	if limit := r.Quota(tenantID).MaxTitleLength; limit > 0 && limit < rules.MaxTitleLength {
+		rules.MaxTitleLength = limit
+	}
+	return rules
+}
+
+// IDs - идентификаторы сконфигурированных арендаторов
+func (r *Registry) IDs() []string {
+	if r == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(r.tenants))
+	for id := range r.tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
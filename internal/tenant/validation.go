@@ -0,0 +1,87 @@
+package tenant
+
+import (
+	"fmt"
+	"regexp"
+
+	"calendar-server/internal/domain"
+)
+
+// DefaultIDPattern - допустимые символы идентификаторов событий и пользователей
+const DefaultIDPattern = `^[A-Za-z0-9._:@-]+$`
+
+// ValidationRules - правила проверки событий; нулевые значения не переопределяют правила по умолчанию
+type ValidationRules struct {
+	// MinYear, MaxYear - допустимый диапазон лет в датах событий
+	MinYear int `json:"min_year,omitempty"`
+	MaxYear int `json:"max_year,omitempty"`
+	// MinTitleLength, MaxTitleLength - допустимая длина названия в символах
+	MinTitleLength int `json:"min_title_length,omitempty"`
+	MaxTitleLength int `json:"max_title_length,omitempty"`
+	// MaxIDLength - максимальная длина идентификаторов
+	MaxIDLength int `json:"max_id_length,omitempty"`
+	// IDPattern - регулярное выражение для идентификаторов события и пользователя
+	IDPattern string `json:"id_pattern,omitempty"`
+	// RequiredFields - дополнительные обязательные поля события (JSON-имена)
+	RequiredFields []string `json:"required_fields,omitempty"`
+}
+
+// DefaultValidationRules - правила, действующие без настроек
+func DefaultValidationRules() ValidationRules {
+	return ValidationRules{
+		MinYear:        1900,
+		MaxYear:        2100,
+		MinTitleLength: 1,
+		MaxTitleLength: 500,
+		MaxIDLength:    128,
+		IDPattern:      DefaultIDPattern,
+	}
+}
+
+// Merge - правила, в которых заданные в override значения заменяют значения v
+func (v ValidationRules) Merge(override ValidationRules) ValidationRules {
+	if override.MinYear != 0 {
+		v.MinYear = override.MinYear
+	}
+	if override.MaxYear != 0 {
+		v.MaxYear = override.MaxYear
+	}
+	if override.MinTitleLength != 0 {
+		v.MinTitleLength = override.MinTitleLength
+	}
+	if override.MaxTitleLength != 0 {
+		v.MaxTitleLength = override.MaxTitleLength
+	}
+	if override.MaxIDLength != 0 {
+		v.MaxIDLength = override.MaxIDLength
+	}
+	if override.IDPattern != "" {
+		v.IDPattern = override.IDPattern
+	}
+	if len(override.RequiredFields) > 0 {
+		v.RequiredFields = override.RequiredFields
+	}
+	return v
+}
+
+// Validate - проверка согласованности правил; вызывается при загрузке конфигурации
+func (v ValidationRules) Validate() error {
+	if v.MinYear > v.MaxYear {
+		return fmt.Errorf("min_year %d is greater than max_year %d", v.MinYear, v.MaxYear)
+	}
+	if v.MinTitleLength < 1 || v.MinTitleLength > v.MaxTitleLength {
+		return fmt.Errorf("invalid title length range %d-%d", v.MinTitleLength, v.MaxTitleLength)
+	}
+	if v.MaxIDLength < 1 {
+		return fmt.Errorf("max_id_length must be positive, got %d", v.MaxIDLength)
+	}
+	if _, err := regexp.Compile(v.IDPattern); err != nil {
+		return fmt.Errorf("invalid id_pattern: %w", err)
+	}
+	for _, field := range v.RequiredFields {
+		if _, ok := (domain.Event{}).Field(field); !ok {
+			return fmt.Errorf("unknown required field %q", field)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package admin_usecase
+
+import (
+	"context"
+	stdErrors "errors"
+	"sort"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/domain"
+	repo "calendar-server/internal/repository/event_repository"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// AdminUseCaseContract - контракт операций обслуживания данных
+type AdminUseCaseContract interface {
+	ListUsers(ctx context.Context) ([]domain.UserSummary, error)
+	DeleteUserEvents(ctx context.Context, userID string) (int, error)
+	ReassignEvents(ctx context.Context, fromUserID, toUserID string, eventIDs []string) (int, error)
+}
+
+// AdminUseCase - реализация AdminUseCaseContract
+type AdminUseCase struct {
+	repo   repo.EventAdminRepository
+	logger *zap.Logger
+	// outbox - relay изменений, которые хранилище записывает в outbox при удалении и передаче событий
+	outbox OutboxRelay
+	// tenants - реестр арендаторов: квота ограничивает события, передаваемые пользователю
+	tenants *tenant.Registry
+}
+
+// OutboxRelay - отправка изменений, записанных хранилищем в outbox (реализуется outbox.Relay)
+type OutboxRelay interface {
+	Wake()
+}
+
+// Option - функциональная опция AdminUseCase
+type Option func(*AdminUseCase)
+
+// WithOutbox - будить relay после удаления и передачи событий, чтобы подписчики сразу получили изменения
+func WithOutbox(relay OutboxRelay) Option {
+	return func(uc *AdminUseCase) {
+		uc.outbox = relay
+	}
+}
+
+// WithTenants - подключает реестр арендаторов с их квотами
+func WithTenants(registry *tenant.Registry) Option {
+	return func(uc *AdminUseCase) {
+		uc.tenants = registry
+	}
+}
+
+// NewAdminUseCase - конструктор AdminUseCase
+func NewAdminUseCase(repo repo.EventAdminRepository, logger *zap.Logger, opts ...Option) *AdminUseCase {
+	uc := &AdminUseCase{
+		repo:   repo,
+		logger: logger,
+	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+// log - логгер из контекста запроса, либо логгер use case
+func (uc *AdminUseCase) log(ctx context.Context) *zap.Logger {
+	return ctxlog.FromContext(ctx, uc.logger)
+}
+
+// ListUsers - список пользователей с количеством событий
+func (uc *AdminUseCase) ListUsers(ctx context.Context) ([]domain.UserSummary, error) {
+	if err := uc.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	counts, err := uc.repo.CountByUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]domain.UserSummary, 0, len(counts))
+	for userID, count := range counts {
+		users = append(users, domain.UserSummary{UserID: userID, EventCount: count})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].UserID < users[j].UserID
+	})
+	return users, nil
+}
+
+// DeleteUserEvents - принудительное удаление всех событий пользователя
+func (uc *AdminUseCase) DeleteUserEvents(ctx context.Context, userID string) (int, error) {
+	if err := uc.requireAdmin(ctx); err != nil {
+		return 0, err
+	}
+	if userID == "" {
+		return 0, errors.ErrEmptyUserID
+	}
+
+	deleted, err := uc.repo.DeleteByUserID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	uc.wake(deleted)
+
+	uc.log(ctx).Warn("Admin deleted user events",
+		zappretty.Field("admin_id", adminID(ctx)),
+		zappretty.Field("user_id", userID),
+		zappretty.Field("count", deleted),
+	)
+	return deleted, nil
+}
+
+// ReassignEvents - передача событий от одного пользователя другому в пределах квоты получателя
+func (uc *AdminUseCase) ReassignEvents(ctx context.Context, fromUserID, toUserID string, eventIDs []string) (int, error) {
+	if err := uc.requireAdmin(ctx); err != nil {
+		return 0, err
+	}
+	if fromUserID == "" || toUserID == "" {
+		return 0, errors.ErrEmptyUserID
+	}
+
+	reassigned, err := uc.repo.ReassignUser(ctx, fromUserID, toUserID, eventIDs, uc.tenants.Quota(tenant.FromContext(ctx)))
+	if err != nil {
+		if stdErrors.Is(err, errors.ErrQuotaExceeded) {
+			uc.log(ctx).Warn("Event quota of the new owner exceeded",
+				zappretty.Field("error", err),
+				zappretty.Field("to_user_id", toUserID),
+			)
+		}
+		return 0, err
+	}
+	uc.wake(reassigned)
+
+	uc.log(ctx).Warn("Admin reassigned events",
+		zappretty.Field("admin_id", adminID(ctx)),
+		zappretty.Field("from_user_id", fromUserID),
+		zappretty.Field("to_user_id", toUserID),
+		zappretty.Field("count", reassigned),
+	)
+	return reassigned, nil
+}
+
+// wake - уведомление relay о записанных изменениях
+func (uc *AdminUseCase) wake(changed int) {
+	if uc.outbox != nil && changed > 0 {
+		uc.outbox.Wake()
+	}
+}
+
+// requireAdmin проверяет роль вызывающего независимо от middleware
+func (uc *AdminUseCase) requireAdmin(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok || !identity.IsAdmin() {
+		return errors.ErrAdminOnly
+	}
+	return nil
+}
+
+// adminID возвращает ID администратора для журнала аудита
+func adminID(ctx context.Context) string {
+	identity, _ := auth.FromContext(ctx)
+	return identity.UserID
+}
@@ -0,0 +1,171 @@
+package admin_usecase
+
+import (
+	"calendar-server/internal/auth"
+	"calendar-server/internal/domain"
+	"calendar-server/internal/repository/event_repository/inmemory"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func setupTestUseCase(t *testing.T) (*AdminUseCase, context.Context) {
+	logger, _ := zap.NewDevelopment()
+	repo := inmemory.NewEventRepository(logger)
+
+	ctx := context.Background()
+	for _, event := range []domain.Event{
+		{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "Event 1"},
+		{ID: "2", UserID: "user-1", Date: "2025-01-16", Title: "Event 2"},
+		{ID: "3", UserID: "user-2", Date: "2025-01-15", Title: "Event 3"},
+	} {
+		if err := repo.Create(ctx, event, tenant.Quota{}); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	admin := auth.WithIdentity(ctx, auth.Identity{UserID: "ops", Role: auth.RoleAdmin})
+	return NewAdminUseCase(repo, logger), admin
+}
+
+func TestAdminUseCase_RequiresAdmin(t *testing.T) {
+	uc, _ := setupTestUseCase(t)
+
+	user := auth.WithIdentity(context.Background(), auth.Identity{UserID: "user-1"})
+	if _, err := uc.ListUsers(user); !stdErrors.Is(err, errors.ErrAdminOnly) {
+		t.Errorf("Expected ErrAdminOnly for regular user, got %v", err)
+	}
+	if _, err := uc.DeleteUserEvents(context.Background(), "user-1"); !stdErrors.Is(err, errors.ErrAdminOnly) {
+		t.Errorf("Expected ErrAdminOnly without identity, got %v", err)
+	}
+}
+
+func TestAdminUseCase_Operations(t *testing.T) {
+	uc, ctx := setupTestUseCase(t)
+
+	users, err := uc.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	expected := []domain.UserSummary{{UserID: "user-1", EventCount: 2}, {UserID: "user-2", EventCount: 1}}
+	if len(users) != len(expected) || users[0] != expected[0] || users[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, users)
+	}
+
+	if _, err := uc.ReassignEvents(ctx, "user-1", "", nil); !stdErrors.Is(err, errors.ErrEmptyUserID) {
+		t.Errorf("Expected ErrEmptyUserID, got %v", err)
+	}
+
+	reassigned, err := uc.ReassignEvents(ctx, "user-1", "user-2", nil)
+	if err != nil || reassigned != 2 {
+		t.Fatalf("Expected 2 reassigned events, got %d, %v", reassigned, err)
+	}
+
+	deleted, err := uc.DeleteUserEvents(ctx, "user-2")
+	if err != nil || deleted != 3 {
+		t.Fatalf("Expected 3 deleted events, got %d, %v", deleted, err)
+	}
+
+	users, _ = uc.ListUsers(ctx)
+	if len(users) != 0 {
+		t.Errorf("Expected no users left, got %v", users)
+	}
+}
+
+func TestAdminUseCase_ReassignQuota(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := inmemory.NewEventRepository(logger)
+	registry := tenant.NewRegistry(tenant.Settings{}, map[string]tenant.Settings{
+		"acme": {Quota: tenant.Quota{MaxEventsPerUser: 2}},
+	})
+	uc := NewAdminUseCase(repo, logger, WithTenants(registry))
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	for _, event := range []domain.Event{
+		{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "Event 1"},
+		{ID: "2", UserID: "user-1", Date: "2025-01-16", Title: "Event 2"},
+		{ID: "3", UserID: "user-2", Date: "2025-01-15", Title: "Event 3"},
+	} {
+		if err := repo.Create(ctx, event, tenant.Quota{}); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+	admin := auth.WithIdentity(ctx, auth.Identity{UserID: "ops", Role: auth.RoleAdmin})
+
+	if _, err := uc.ReassignEvents(admin, "user-1", "user-2", nil); !stdErrors.Is(err, errors.ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if count, _ := repo.CountByUserID(ctx, "user-2"); count != 1 {
+		t.Errorf("Expected rejected reassignment to leave events in place, got %d events of user-2", count)
+	}
+
+	reassigned, err := uc.ReassignEvents(admin, "user-1", "user-2", []string{"1"})
+	if err != nil || reassigned != 1 {
+		t.Errorf("Expected 1 reassigned event, got %d, %v", reassigned, err)
+	}
+}
+
+// countingRelay - считает уведомления relay
+type countingRelay struct {
+	wakes int
+}
+
+func (r *countingRelay) Wake() {
+	r.wakes++
+}
+
+func TestAdminUseCase_Outbox(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := inmemory.NewEventRepository(logger, inmemory.WithOutbox())
+	relay := &countingRelay{}
+	uc := NewAdminUseCase(repo, logger, WithOutbox(relay))
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	for _, event := range []domain.Event{
+		{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "Event 1"},
+		{ID: "2", UserID: "user-1", Date: "2025-01-16", Title: "Event 2"},
+	} {
+		if err := repo.Create(ctx, event, tenant.Quota{}); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+	admin := auth.WithIdentity(ctx, auth.Identity{UserID: "ops", Role: auth.RoleAdmin})
+
+	if _, err := uc.ReassignEvents(admin, "user-1", "user-2", []string{"1"}); err != nil {
+		t.Fatalf("Failed to reassign events: %v", err)
+	}
+	if _, err := uc.DeleteUserEvents(admin, "user-1"); err != nil {
+		t.Fatalf("Failed to delete user events: %v", err)
+	}
+	// Удаление без затронутых событий не будит relay
+	if _, err := uc.DeleteUserEvents(admin, "nobody"); err != nil {
+		t.Fatalf("Failed to delete user events: %v", err)
+	}
+	if relay.wakes != 2 {
+		t.Errorf("Expected relay to be woken twice, got %d", relay.wakes)
+	}
+
+	messages, err := repo.PendingMessages(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	want := []domain.Change{
+		{Type: domain.ChangeCreated, UserID: "user-1", EventID: "1"},
+		{Type: domain.ChangeCreated, UserID: "user-1", EventID: "2"},
+		{Type: domain.ChangeDeleted, UserID: "user-1", EventID: "1"},
+		{Type: domain.ChangeUpdated, UserID: "user-2", EventID: "1"},
+		{Type: domain.ChangeDeleted, UserID: "user-1", EventID: "2"},
+	}
+	if len(messages) != len(want) {
+		t.Fatalf("Expected %d outbox messages, got %+v", len(want), messages)
+	}
+	for i, msg := range messages {
+		if got := msg.Change; got.Type != want[i].Type || got.UserID != want[i].UserID || got.EventID != want[i].EventID {
+			t.Errorf("Expected message %d to be %+v, got %+v", i, want[i], got)
+		}
+	}
+}
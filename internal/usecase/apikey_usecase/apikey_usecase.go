@@ -0,0 +1,253 @@
+package apikey_usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/domain"
+	repo "calendar-server/internal/repository/apikey_repository"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// KeyPrefix - префикс, по которому API-ключи отличаются от других токенов
+const KeyPrefix = "csk_"
+
+// APIKeyUseCaseContract - контракт для управления API-ключами
+type APIKeyUseCaseContract interface {
+	IssueKey(ctx context.Context, name string, scopes []string) (domain.APIKey, string, error)
+	ListKeys(ctx context.Context) ([]domain.APIKey, error)
+	RotateKey(ctx context.Context, keyID string) (domain.APIKey, string, error)
+	RevokeKey(ctx context.Context, keyID string) error
+}
+
+// APIKeyUseCase - реализация APIKeyUseCaseContract и auth.Authenticator
+type APIKeyUseCase struct {
+	repo   repo.APIKeyRepository
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewAPIKeyUseCase - конструктор APIKeyUseCase
+func NewAPIKeyUseCase(repo repo.APIKeyRepository, logger *zap.Logger) *APIKeyUseCase {
+	return &APIKeyUseCase{
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// log - логгер из контекста запроса, либо логгер use case
+func (uc *APIKeyUseCase) log(ctx context.Context) *zap.Logger {
+	return ctxlog.FromContext(ctx, uc.logger)
+}
+
+// IssueKey - выпуск нового ключа; секрет возвращается только один раз
+func (uc *APIKeyUseCase) IssueKey(ctx context.Context, name string, scopes []string) (domain.APIKey, string, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return domain.APIKey{}, "", errors.ErrUnauthorized
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return domain.APIKey{}, "", errors.ErrEmptyAPIKeyName
+	}
+
+	if len(scopes) == 0 {
+		scopes = []string{auth.ScopeEventsRead}
+	}
+	for _, scope := range scopes {
+		// Ключ не может получить больше прав, чем у того, кто его выпускает
+		if !auth.IsKnownScope(scope) || !identity.HasScope(scope) {
+			uc.log(ctx).Warn("API key scope rejected",
+				zappretty.Field("scope", scope),
+				zappretty.Field("user_id", identity.UserID),
+			)
+			return domain.APIKey{}, "", errors.ErrInvalidScope
+		}
+	}
+
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	key := domain.APIKey{
+		ID:        "key_" + id,
+		UserID:    identity.UserID,
+		TenantID:  tenant.FromContext(ctx),
+		Name:      name,
+		Scopes:    append([]string(nil), scopes...),
+		Hash:      hashSecret(secret),
+		CreatedAt: uc.now().UTC(),
+	}
+
+	if err := uc.repo.Create(ctx, key); err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	uc.log(ctx).Info("API key issued",
+		zappretty.Field("key_id", key.ID),
+		zappretty.Field("user_id", key.UserID),
+		zappretty.Field("scopes", key.Scopes),
+	)
+	return key, secret, nil
+}
+
+// ListKeys - список ключей вызывающего
+func (uc *APIKeyUseCase) ListKeys(ctx context.Context) ([]domain.APIKey, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, errors.ErrUnauthorized
+	}
+	return uc.repo.ListByUserID(ctx, identity.UserID)
+}
+
+// RotateKey - замена секрета ключа с сохранением ID и прав
+func (uc *APIKeyUseCase) RotateKey(ctx context.Context, keyID string) (domain.APIKey, string, error) {
+	key, err := uc.ownedKey(ctx, keyID)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+	if key.Revoked() {
+		return domain.APIKey{}, "", errors.ErrAPIKeyNotFound
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	rotatedAt := uc.now().UTC()
+	key.Hash = hashSecret(secret)
+	key.RotatedAt = &rotatedAt
+
+	if err := uc.repo.Update(ctx, key); err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	uc.log(ctx).Info("API key rotated",
+		zappretty.Field("key_id", key.ID),
+		zappretty.Field("user_id", key.UserID),
+	)
+	return key, secret, nil
+}
+
+// RevokeKey - отзыв ключа; повторный отзыв не является ошибкой
+func (uc *APIKeyUseCase) RevokeKey(ctx context.Context, keyID string) error {
+	key, err := uc.ownedKey(ctx, keyID)
+	if err != nil {
+		return err
+	}
+	if key.Revoked() {
+		return nil
+	}
+
+	revokedAt := uc.now().UTC()
+	key.RevokedAt = &revokedAt
+
+	if err := uc.repo.Update(ctx, key); err != nil {
+		return err
+	}
+
+	uc.log(ctx).Info("API key revoked",
+		zappretty.Field("key_id", key.ID),
+		zappretty.Field("user_id", key.UserID),
+	)
+	return nil
+}
+
+// Authenticate - реализация auth.Authenticator для API-ключей
+func (uc *APIKeyUseCase) Authenticate(ctx context.Context, token string) (auth.Identity, error) {
+	if !strings.HasPrefix(token, KeyPrefix) {
+		return auth.Identity{}, errors.ErrInvalidToken
+	}
+
+	key, err := uc.repo.GetByHash(ctx, hashSecret(token))
+	if err != nil || key.Revoked() {
+		return auth.Identity{}, errors.ErrInvalidToken
+	}
+
+	if err := uc.repo.TouchLastUsed(ctx, key.ID, uc.now().UTC()); err != nil {
+		uc.log(ctx).Warn("Failed to record API key usage",
+			zappretty.Field("key_id", key.ID),
+			zappretty.Field("error", err),
+		)
+	}
+
+	return auth.Identity{
+		UserID:   key.UserID,
+		Method:   "api_key",
+		KeyID:    key.ID,
+		Scopes:   key.Scopes,
+		TenantID: key.TenantID,
+	}, nil
+}
+
+// ownedKey - получение ключа, принадлежащего вызывающему. Как и при выпуске, управлять можно
+// только ключом, права которого есть у вызывающего: иначе узкий ключ получил бы секрет широкого.
+func (uc *APIKeyUseCase) ownedKey(ctx context.Context, keyID string) (domain.APIKey, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return domain.APIKey{}, errors.ErrUnauthorized
+	}
+
+	key, err := uc.repo.GetByID(ctx, keyID)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	// Чужие ключи неотличимы от несуществующих
+	if key.UserID != identity.UserID {
+		return domain.APIKey{}, errors.ErrAPIKeyNotFound
+	}
+	for _, scope := range key.Scopes {
+		if !identity.HasScope(scope) {
+			uc.log(ctx).Warn("Management of API key with wider scopes rejected",
+				zappretty.Field("key_id", key.ID),
+				zappretty.Field("scope", scope),
+				zappretty.Field("user_id", identity.UserID),
+			)
+			return domain.APIKey{}, errors.ErrInsufficientScope
+		}
+	}
+	return key, nil
+}
+
+// newSecret генерирует новый секрет ключа
+func newSecret() (string, error) {
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+	return KeyPrefix + secret, nil
+}
+
+// hashSecret вычисляет хеш секрета для хранения
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString возвращает n криптографически случайных байт в указанной кодировке
+func randomString(n int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return encode(buf), nil
+}
@@ -0,0 +1,143 @@
+package apikey_usecase
+
+import (
+	"calendar-server/internal/auth"
+	"calendar-server/internal/repository/apikey_repository/inmemory"
+	"calendar-server/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func setupTestUseCase() (*APIKeyUseCase, context.Context) {
+	logger, _ := zap.NewDevelopment()
+	uc := NewAPIKeyUseCase(inmemory.NewAPIKeyRepository(logger), logger)
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{UserID: "user-1"})
+	return uc, ctx
+}
+
+func TestAPIKeyUseCase_IssueAndAuthenticate(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	key, secret, err := uc.IssueKey(ctx, "ci bot", []string{auth.ScopeEventsRead})
+	if err != nil {
+		t.Fatalf("Failed to issue key: %v", err)
+	}
+	if !strings.HasPrefix(secret, KeyPrefix) {
+		t.Errorf("Expected secret with prefix %q, got %q", KeyPrefix, secret)
+	}
+	if key.Hash == "" || strings.Contains(key.Hash, secret) {
+		t.Error("Expected only the hash of the secret to be stored")
+	}
+
+	identity, err := uc.Authenticate(context.Background(), secret)
+	if err != nil {
+		t.Fatalf("Failed to authenticate with issued key: %v", err)
+	}
+	if identity.UserID != "user-1" || identity.KeyID != key.ID {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+	if identity.HasScope(auth.ScopeEventsWrite) {
+		t.Error("Read-only key must not have write scope")
+	}
+
+	keys, err := uc.ListKeys(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Errorf("Expected one key with last-used timestamp, got %+v", keys)
+	}
+}
+
+func TestAPIKeyUseCase_ScopeEscalation(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	if _, _, err := uc.IssueKey(ctx, "bot", []string{"admin:everything"}); !stdErrors.Is(err, errors.ErrInvalidScope) {
+		t.Errorf("Expected ErrInvalidScope for unknown scope, got %v", err)
+	}
+
+	readOnly := auth.WithIdentity(context.Background(), auth.Identity{
+		UserID: "user-1",
+		Scopes: []string{auth.ScopeEventsRead, auth.ScopeKeysManage},
+	})
+	if _, _, err := uc.IssueKey(readOnly, "bot", []string{auth.ScopeEventsWrite}); !stdErrors.Is(err, errors.ErrInvalidScope) {
+		t.Errorf("Expected ErrInvalidScope when escalating scopes, got %v", err)
+	}
+
+	if _, _, err := uc.IssueKey(ctx, "  ", nil); !stdErrors.Is(err, errors.ErrEmptyAPIKeyName) {
+		t.Errorf("Expected ErrEmptyAPIKeyName, got %v", err)
+	}
+}
+
+func TestAPIKeyUseCase_RotateAndRevoke(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	key, oldSecret, err := uc.IssueKey(ctx, "bot", nil)
+	if err != nil {
+		t.Fatalf("Failed to issue key: %v", err)
+	}
+
+	other := auth.WithIdentity(context.Background(), auth.Identity{UserID: "user-2"})
+	if _, _, err := uc.RotateKey(other, key.ID); !stdErrors.Is(err, errors.ErrAPIKeyNotFound) {
+		t.Errorf("Expected ErrAPIKeyNotFound for another user's key, got %v", err)
+	}
+
+	rotated, newSecret, err := uc.RotateKey(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	if rotated.ID != key.ID || rotated.RotatedAt == nil {
+		t.Errorf("Unexpected rotated key %+v", rotated)
+	}
+	if _, err := uc.Authenticate(ctx, oldSecret); !stdErrors.Is(err, errors.ErrInvalidToken) {
+		t.Errorf("Expected old secret to be rejected after rotation, got %v", err)
+	}
+	if _, err := uc.Authenticate(ctx, newSecret); err != nil {
+		t.Errorf("Expected new secret to be accepted, got %v", err)
+	}
+
+	if err := uc.RevokeKey(ctx, key.ID); err != nil {
+		t.Fatalf("Failed to revoke key: %v", err)
+	}
+	if _, err := uc.Authenticate(ctx, newSecret); !stdErrors.Is(err, errors.ErrInvalidToken) {
+		t.Errorf("Expected revoked key to be rejected, got %v", err)
+	}
+	if err := uc.RevokeKey(ctx, key.ID); err != nil {
+		t.Errorf("Expected repeated revoke to succeed, got %v", err)
+	}
+	if _, _, err := uc.RotateKey(ctx, key.ID); !stdErrors.Is(err, errors.ErrAPIKeyNotFound) {
+		t.Errorf("Expected revoked key rotation to fail, got %v", err)
+	}
+}
+
+func TestAPIKeyUseCase_ManageWiderKey(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	wide, _, err := uc.IssueKey(ctx, "deploy", []string{auth.ScopeEventsRead, auth.ScopeEventsWrite})
+	if err != nil {
+		t.Fatalf("Failed to issue key: %v", err)
+	}
+	narrow := auth.WithIdentity(context.Background(), auth.Identity{
+		UserID: "user-1",
+		Scopes: []string{auth.ScopeEventsRead, auth.ScopeKeysManage},
+	})
+
+	if _, secret, err := uc.RotateKey(narrow, wide.ID); !stdErrors.Is(err, errors.ErrInsufficientScope) || secret != "" {
+		t.Errorf("Expected ErrInsufficientScope when rotating a wider key, got %q, %v", secret, err)
+	}
+	if err := uc.RevokeKey(narrow, wide.ID); !stdErrors.Is(err, errors.ErrInsufficientScope) {
+		t.Errorf("Expected ErrInsufficientScope when revoking a wider key, got %v", err)
+	}
+
+	readOnly, _, err := uc.IssueKey(ctx, "reader", []string{auth.ScopeEventsRead})
+	if err != nil {
+		t.Fatalf("Failed to issue key: %v", err)
+	}
+	if _, _, err := uc.RotateKey(narrow, readOnly.ID); err != nil {
+		t.Errorf("Expected key with covered scopes to rotate, got %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package digest_usecase
+
+import (
+	"context"
+	stdErrors "errors"
+	"slices"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/digest"
+	"calendar-server/internal/domain"
+	repo "calendar-server/internal/repository/digest_repository"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSendAt - время отправки сводки, если оно не указано
+	DefaultSendAt = "07:00"
+	// DefaultWeekday - день отправки недельной сводки, если он не указан
+	DefaultWeekday = "monday"
+)
+
+// DigestUseCaseContract - контракт для управления подписками на сводки и их предпросмотра
+type DigestUseCaseContract interface {
+	SaveSubscription(ctx context.Context, subscription domain.DigestSubscription) (domain.DigestSubscription, error)
+	ListSubscriptions(ctx context.Context, userID string) ([]domain.DigestSubscription, error)
+	DeleteSubscription(ctx context.Context, userID, period string) error
+	Preview(ctx context.Context, userID, period, date string) (digest.Message, error)
+}
+
+// DigestUseCase - реализация DigestUseCaseContract
+type DigestUseCase struct {
+	repo      repo.DigestRepository
+	generator *digest.Generator
+	logger    *zap.Logger
+	// timeZone, sendAt - значения для подписок, в которых они не указаны
+	timeZone string
+	sendAt   string
+	now      func() time.Time
+}
+
+// Option - функциональная опция DigestUseCase
+type Option func(*DigestUseCase)
+
+// WithDefaults - часовой пояс и время отправки для подписок, в которых они не указаны
+func WithDefaults(timeZone, sendAt string) Option {
+	return func(uc *DigestUseCase) {
+		uc.timeZone = timeZone
+		uc.sendAt = sendAt
+	}
+}
+
+// WithClock - источник текущего времени
+func WithClock(now func() time.Time) Option {
+	return func(uc *DigestUseCase) {
+		uc.now = now
+	}
+}
+
+// NewDigestUseCase - конструктор DigestUseCase
+func NewDigestUseCase(repo repo.DigestRepository, generator *digest.Generator, logger *zap.Logger, opts ...Option) *DigestUseCase {
+	uc := &DigestUseCase{
+		repo:      repo,
+		generator: generator,
+		logger:    logger,
+		timeZone:  "UTC",
+		sendAt:    DefaultSendAt,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+// log - логгер из контекста запроса, либо логгер use case
+func (uc *DigestUseCase) log(ctx context.Context) *zap.Logger {
+	return ctxlog.FromContext(ctx, uc.logger)
+}
+
+// SaveSubscription - создание или изменение подписки на сводку. Пользователь управляет своими
+// подписками, администратор - подписками любого пользователя. Новое расписание действует со
+// следующего времени отправки: сводка, время которой сегодня уже прошло, не отправляется.
+func (uc *DigestUseCase) SaveSubscription(ctx context.Context, subscription domain.DigestSubscription) (domain.DigestSubscription, error) {
+	userID, err := uc.owner(ctx, subscription.UserID)
+	if err != nil {
+		return domain.DigestSubscription{}, err
+	}
+	if err := validatePeriod(subscription.Period); err != nil {
+		return domain.DigestSubscription{}, err
+	}
+
+	saved := domain.DigestSubscription{
+		UserID:   userID,
+		TenantID: tenant.FromContext(ctx),
+		Period:   subscription.Period,
+		TimeZone: subscription.TimeZone,
+		SendAt:   subscription.SendAt,
+		Weekday:  subscription.Weekday,
+	}
+	if saved.TimeZone == "" {
+		saved.TimeZone = uc.timeZone
+	}
+	loc, err := time.LoadLocation(saved.TimeZone)
+	if err != nil || saved.TimeZone == "Local" {
+		return domain.DigestSubscription{}, digest.InvalidSetting("time_zone", saved.TimeZone)
+	}
+	if saved.SendAt == "" {
+		saved.SendAt = uc.sendAt
+	}
+	if _, err := digest.ParseSendAt(saved.SendAt); err != nil {
+		return domain.DigestSubscription{}, err
+	}
+	switch {
+	case saved.Period == domain.DigestDaily:
+		saved.Weekday = ""
+	case saved.Weekday == "":
+		saved.Weekday = DefaultWeekday
+	default:
+		if _, ok := digest.Weekdays[saved.Weekday]; !ok {
+			return domain.DigestSubscription{}, digest.InvalidSetting("weekday", saved.Weekday)
+		}
+	}
+
+	existing, err := uc.repo.Get(ctx, userID, saved.Period)
+	switch {
+	case err == nil:
+		saved.LastDate = existing.LastDate
+	case !stdErrors.Is(err, errors.ErrDigestNotFound):
+		return domain.DigestSubscription{}, err
+	}
+	now := uc.now()
+	if date, at, ok := digest.Slot(saved, loc, now); ok && !now.Before(at) && date > saved.LastDate {
+		saved.LastDate = date
+	}
+	saved.UpdatedAt = now.UTC()
+
+	if err := uc.repo.Save(ctx, saved); err != nil {
+		return domain.DigestSubscription{}, err
+	}
+
+	uc.log(ctx).Info("Digest subscription saved",
+		zappretty.Field("user_id", saved.UserID),
+		zappretty.Field("period", saved.Period),
+		zappretty.Field("time_zone", saved.TimeZone),
+		zappretty.Field("send_at", saved.SendAt),
+	)
+	return saved, nil
+}
+
+// ListSubscriptions - подписки пользователя; пустой userID означает вызывающего
+func (uc *DigestUseCase) ListSubscriptions(ctx context.Context, userID string) ([]domain.DigestSubscription, error) {
+	userID, err := uc.owner(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.ListByUserID(ctx, userID)
+}
+
+// DeleteSubscription - удаление подписки пользователя на сводку периода
+func (uc *DigestUseCase) DeleteSubscription(ctx context.Context, userID, period string) error {
+	userID, err := uc.owner(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := validatePeriod(period); err != nil {
+		return err
+	}
+	if err := uc.repo.Delete(ctx, userID, period); err != nil {
+		return err
+	}
+
+	uc.log(ctx).Info("Digest subscription deleted",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("period", period),
+	)
+	return nil
+}
+
+// Preview - сводка в том виде, в котором она будет отправлена. Без date сводка строится на
+// сегодняшний день в часовом поясе подписки или, если подписки нет, в часовом поясе по умолчанию.
+func (uc *DigestUseCase) Preview(ctx context.Context, userID, period, date string) (digest.Message, error) {
+	userID, err := uc.owner(ctx, userID)
+	if err != nil {
+		return digest.Message{}, err
+	}
+	if err := validatePeriod(period); err != nil {
+		return digest.Message{}, err
+	}
+
+	if date == "" {
+		timeZone := uc.timeZone
+		subscription, err := uc.repo.Get(ctx, userID, period)
+		switch {
+		case err == nil:
+			timeZone = subscription.TimeZone
+		case !stdErrors.Is(err, errors.ErrDigestNotFound):
+			return digest.Message{}, err
+		}
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return digest.Message{}, digest.InvalidSetting("time_zone", timeZone)
+		}
+		date = uc.now().In(loc).Format("2006-01-02")
+	}
+
+	generated, err := uc.generator.Generate(ctx, userID, period, date)
+	if err != nil {
+		return digest.Message{}, err
+	}
+	msg, err := uc.generator.Render(generated)
+	if err != nil {
+		return digest.Message{}, err
+	}
+	msg.ID = digest.MessageID(tenant.FromContext(ctx), generated)
+	return msg, nil
+}
+
+// owner - пользователь, подписками которого управляет вызывающий; пустой userID означает его самого
+func (uc *DigestUseCase) owner(ctx context.Context, userID string) (string, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return "", errors.ErrUnauthorized
+	}
+	userID = identity.UserIDOr(userID)
+	if userID == "" {
+		return "", errors.ErrEmptyUserID
+	}
+	if userID != identity.UserID && !identity.IsAdmin() {
+		return "", errors.ErrForbidden
+	}
+	return userID, nil
+}
+
+// validatePeriod - проверка периода сводки
+func validatePeriod(period string) error {
+	if !slices.Contains(domain.DigestPeriods, period) {
+		return digest.InvalidSetting("period", period)
+	}
+	return nil
+}
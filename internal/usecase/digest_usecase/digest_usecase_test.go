@@ -0,0 +1,230 @@
+package digest_usecase
+
+import (
+	"calendar-server/internal/auth"
+	"calendar-server/internal/digest"
+	"calendar-server/internal/domain"
+	digestRepository "calendar-server/internal/repository/digest_repository/inmemory"
+	eventRepository "calendar-server/internal/repository/event_repository/inmemory"
+	"calendar-server/internal/tenant"
+	eventUseCase "calendar-server/internal/usecase/event_usecase"
+	"calendar-server/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func setupTestUseCase(t *testing.T, now time.Time) (*DigestUseCase, context.Context) {
+	t.Helper()
+	logger, _ := zap.NewDevelopment()
+	events := eventUseCase.NewEventUseCase(eventRepository.NewEventRepository(logger), logger)
+	uc := NewDigestUseCase(digestRepository.NewDigestRepository(logger), digest.NewGenerator(events), logger,
+		WithDefaults("Europe/Moscow", "08:00"),
+		WithClock(func() time.Time { return now }),
+	)
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	for _, event := range []domain.Event{
+		{ID: "standup", UserID: "user-1", Date: "2025-01-15", Title: "Standup", StartTime: "10:00"},
+		{ID: "review", UserID: "user-1", Date: "2025-01-16", Title: "Review", StartTime: "15:00"},
+		{ID: "lunch", UserID: "user-2", Date: "2025-01-15", Title: "Lunch", StartTime: "13:00"},
+	} {
+		if err := events.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+	return uc, auth.WithIdentity(ctx, auth.Identity{UserID: "user-1"})
+}
+
+func TestDigestUseCase_SaveSubscription(t *testing.T) {
+	// 2025-01-15 09:00 в Москве
+	uc, ctx := setupTestUseCase(t, time.Date(2025, 1, 15, 6, 0, 0, 0, time.UTC))
+	admin := auth.WithIdentity(ctx, auth.Identity{UserID: "admin", Role: auth.RoleAdmin})
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		subscription domain.DigestSubscription
+		want         domain.DigestSubscription
+		wantErr      error
+	}{
+		{
+			name:         "defaults",
+			ctx:          ctx,
+			subscription: domain.DigestSubscription{Period: domain.DigestWeekly},
+			want: domain.DigestSubscription{
+				UserID: "user-1", Period: domain.DigestWeekly, TimeZone: "Europe/Moscow", SendAt: "08:00", Weekday: "monday",
+			},
+		},
+		{
+			name:         "send time later today",
+			ctx:          ctx,
+			subscription: domain.DigestSubscription{Period: domain.DigestDaily, TimeZone: "America/New_York", SendAt: "07:30"},
+			want: domain.DigestSubscription{
+				UserID: "user-1", Period: domain.DigestDaily, TimeZone: "America/New_York", SendAt: "07:30",
+			},
+		},
+		{
+			name:         "send time already passed today in the new time zone",
+			ctx:          ctx,
+			subscription: domain.DigestSubscription{Period: domain.DigestDaily, SendAt: "07:30", Weekday: "friday"},
+			want: domain.DigestSubscription{
+				UserID: "user-1", Period: domain.DigestDaily, TimeZone: "Europe/Moscow", SendAt: "07:30", LastDate: "2025-01-15",
+			},
+		},
+		{
+			name:         "admin manages another user",
+			ctx:          admin,
+			subscription: domain.DigestSubscription{UserID: "user-2", Period: domain.DigestWeekly, Weekday: "sunday", SendAt: "18:00"},
+			want: domain.DigestSubscription{
+				UserID: "user-2", Period: domain.DigestWeekly, TimeZone: "Europe/Moscow", SendAt: "18:00", Weekday: "sunday",
+			},
+		},
+		{
+			name:         "another user",
+			ctx:          ctx,
+			subscription: domain.DigestSubscription{UserID: "user-2", Period: domain.DigestDaily},
+			wantErr:      errors.ErrForbidden,
+		},
+		{
+			name:         "unknown period",
+			ctx:          ctx,
+			subscription: domain.DigestSubscription{Period: "monthly"},
+			wantErr:      errors.ErrInvalidDigest,
+		},
+		{
+			name:         "unknown time zone",
+			ctx:          ctx,
+			subscription: domain.DigestSubscription{Period: domain.DigestDaily, TimeZone: "Mars/Olympus"},
+			wantErr:      errors.ErrInvalidDigest,
+		},
+		{
+			name:         "invalid send time",
+			ctx:          ctx,
+			subscription: domain.DigestSubscription{Period: domain.DigestDaily, SendAt: "7:00"},
+			wantErr:      errors.ErrInvalidDigest,
+		},
+		{
+			name:         "unknown weekday",
+			ctx:          ctx,
+			subscription: domain.DigestSubscription{Period: domain.DigestWeekly, Weekday: "Monday"},
+			wantErr:      errors.ErrInvalidDigest,
+		},
+		{
+			name:         "unauthenticated",
+			ctx:          tenant.WithID(context.Background(), "acme"),
+			subscription: domain.DigestSubscription{Period: domain.DigestDaily},
+			wantErr:      errors.ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved, err := uc.SaveSubscription(tt.ctx, tt.subscription)
+			if !stdErrors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			tt.want.TenantID = "acme"
+			saved.UpdatedAt = time.Time{}
+			if saved != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, saved)
+			}
+		})
+	}
+}
+
+func TestDigestUseCase_ListAndDelete(t *testing.T) {
+	uc, ctx := setupTestUseCase(t, time.Date(2025, 1, 15, 3, 0, 0, 0, time.UTC))
+
+	for _, period := range []string{domain.DigestWeekly, domain.DigestDaily} {
+		if _, err := uc.SaveSubscription(ctx, domain.DigestSubscription{Period: period}); err != nil {
+			t.Fatalf("Failed to save subscription: %v", err)
+		}
+	}
+	// Повторное сохранение заменяет подписку
+	if _, err := uc.SaveSubscription(ctx, domain.DigestSubscription{Period: domain.DigestDaily, SendAt: "06:00"}); err != nil {
+		t.Fatalf("Failed to save subscription: %v", err)
+	}
+
+	subscriptions, err := uc.ListSubscriptions(ctx, "")
+	if err != nil {
+		t.Fatalf("Failed to list subscriptions: %v", err)
+	}
+	if len(subscriptions) != 2 || subscriptions[0].Period != domain.DigestDaily || subscriptions[0].SendAt != "06:00" {
+		t.Fatalf("Expected daily and weekly subscriptions, got %+v", subscriptions)
+	}
+	if _, err := uc.ListSubscriptions(ctx, "user-2"); !stdErrors.Is(err, errors.ErrForbidden) {
+		t.Errorf("Expected forbidden for another user, got %v", err)
+	}
+
+	if err := uc.DeleteSubscription(ctx, "", domain.DigestDaily); err != nil {
+		t.Fatalf("Failed to delete subscription: %v", err)
+	}
+	if err := uc.DeleteSubscription(ctx, "", domain.DigestDaily); !stdErrors.Is(err, errors.ErrDigestNotFound) {
+		t.Errorf("Expected not found on second delete, got %v", err)
+	}
+	if subscriptions, _ := uc.ListSubscriptions(ctx, ""); len(subscriptions) != 1 {
+		t.Errorf("Expected one subscription left, got %+v", subscriptions)
+	}
+}
+
+func TestDigestUseCase_Preview(t *testing.T) {
+	// 2025-01-15 22:00 UTC - в Москве уже 16 января
+	uc, ctx := setupTestUseCase(t, time.Date(2025, 1, 15, 22, 0, 0, 0, time.UTC))
+	if _, err := uc.SaveSubscription(ctx, domain.DigestSubscription{Period: domain.DigestWeekly, TimeZone: "UTC"}); err != nil {
+		t.Fatalf("Failed to save subscription: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		userID   string
+		period   string
+		date     string
+		wantFrom string
+		wantText string
+		wantErr  error
+	}{
+		{name: "explicit date", ctx: ctx, period: domain.DigestDaily, date: "2025-01-15", wantFrom: "2025-01-15", wantText: "Standup"},
+		{name: "today in default time zone", ctx: ctx, period: domain.DigestDaily, wantFrom: "2025-01-16", wantText: "Review"},
+		{name: "today in subscription time zone", ctx: ctx, period: domain.DigestWeekly, wantFrom: "2025-01-13", wantText: "2 events scheduled."},
+		{name: "another user", ctx: ctx, userID: "user-2", period: domain.DigestDaily, wantErr: errors.ErrForbidden},
+		{
+			name: "admin previews another user", userID: "user-2", period: domain.DigestDaily, date: "2025-01-15",
+			ctx:      auth.WithIdentity(ctx, auth.Identity{UserID: "admin", Role: auth.RoleAdmin}),
+			wantFrom: "2025-01-15", wantText: "Lunch",
+		},
+		{name: "invalid date", ctx: ctx, period: domain.DigestDaily, date: "tomorrow", wantErr: errors.ErrInvalidDate},
+		{name: "unknown period", ctx: ctx, period: "yearly", wantErr: errors.ErrInvalidDigest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := uc.Preview(tt.ctx, tt.userID, tt.period, tt.date)
+			if !stdErrors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if msg.Digest.From != tt.wantFrom {
+				t.Errorf("Expected digest from %s, got %s", tt.wantFrom, msg.Digest.From)
+			}
+			if !strings.Contains(msg.Text, tt.wantText) || msg.HTML == "" {
+				t.Errorf("Expected text to contain %q, got:\n%s", tt.wantText, msg.Text)
+			}
+			if !strings.HasPrefix(msg.ID, "dig_") {
+				t.Errorf("Expected digest ID, got %q", msg.ID)
+			}
+		})
+	}
+}
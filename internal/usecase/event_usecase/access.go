@@ -0,0 +1,46 @@
+package event_usecase
+
+import (
+	"context"
+
+	"calendar-server/internal/auth"
+	"calendar-server/pkg/errors"
+)
+
+// authorizeUser проверяет, что вызывающий действует от имени владельца календаря.
+// Внутренние вызовы без личности в контексте и администраторы проверку не проходят.
+func (uc *EventUseCase) authorizeUser(ctx context.Context, userID string) error {
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.IsAdmin() {
+		return nil
+	}
+	if identity.UserID != userID {
+		return errors.ErrForbidden
+	}
+	return nil
+}
+
+// authorizeEvent проверяет, что существующее событие принадлежит вызывающему.
+func (uc *EventUseCase) authorizeEvent(ctx context.Context, eventID string) error {
+	if _, ok := auth.FromContext(ctx); !ok {
+		return nil
+	}
+
+	existing, err := uc.repo.GetByID(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	return uc.authorizeUser(ctx, existing.UserID)
+}
+
+// authorizeUsers проверяет доступ к событиям пользователя; пустой userID означает
+// всех пользователей арендатора, и такие операции доступны только администратору.
+func (uc *EventUseCase) authorizeUsers(ctx context.Context, userID string) error {
+	if userID != "" {
+		return uc.authorizeUser(ctx, userID)
+	}
+	if identity, ok := auth.FromContext(ctx); ok && !identity.IsAdmin() {
+		return errors.ErrAdminOnly
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package event_usecase
+
+import (
+	"context"
+	stdErrors "errors"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/internal/pubsub"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+)
+
+// errNoChangeFeed - шина изменений не подключена
+var errNoChangeFeed = stdErrors.New("change feed is not configured")
+
+// WithChanges - подключает шину изменений: созданные, измененные и удаленные события публикуются в нее
+func WithChanges(hub *pubsub.Hub) Option {
+	return func(uc *EventUseCase) {
+		uc.changes = hub
+	}
+}
+
+// OutboxRelay - отправка изменений, записанных хранилищем в outbox (реализуется outbox.Relay)
+type OutboxRelay interface {
+	Wake()
+}
+
+// WithOutbox - изменения записывает хранилище в outbox в той же операции, что и само изменение;
+// use case не публикует их в шину сам, а только будит relay
+func WithOutbox(relay OutboxRelay) Option {
+	return func(uc *EventUseCase) {
+		uc.outbox = relay
+	}
+}
+
+// SubscribeChanges - подписка на изменения событий пользователя; пустой userID означает всех
+// пользователей арендатора и доступен только администратору. lastEventID - последнее полученное
+// клиентом изменение, с которого продолжается чтение.
+func (uc *EventUseCase) SubscribeChanges(ctx context.Context, userID, lastEventID string) (sub *pubsub.Subscription, err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.SubscribeChanges",
+		tracing.String("user.id", userID),
+		tracing.String("stream.last_event_id", lastEventID),
+	)
+	defer span.EndErr(&err)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if uc.changes == nil {
+		return nil, errNoChangeFeed
+	}
+
+	if err := uc.authorizeUsers(ctx, userID); err != nil {
+		uc.log(ctx).Warn("Attempt to subscribe to changes of another user",
+			zappretty.Field("user_id", userID),
+		)
+		return nil, err
+	}
+
+	return uc.changes.Subscribe(pubsub.Filter{TenantID: tenant.FromContext(ctx), UserID: userID}, lastEventID)
+}
+
+// publish - уведомление подписчиков об изменении события
+func (uc *EventUseCase) publish(ctx context.Context, changeType string, event domain.Event) {
+	if uc.outbox != nil {
+		uc.outbox.Wake()
+		return
+	}
+	if uc.changes == nil {
+		return
+	}
+
+	change := domain.Change{
+		Type:     changeType,
+		TenantID: tenant.FromContext(ctx),
+		UserID:   event.UserID,
+		EventID:  event.ID,
+		At:       time.Now().UTC(),
+	}
+	if changeType != domain.ChangeDeleted {
+		change.Event = &event
+	}
+	uc.changes.Publish(change)
+}
@@ -1,11 +1,17 @@
 package event_usecase
 
 import (
+	"calendar-server/internal/pubsub"
 	repo "calendar-server/internal/repository/event_repository"
 	"context"
+	stdErrors "errors"
 
 	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
 	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
 
 	"go.uber.org/zap"
 )
@@ -15,107 +21,284 @@ type EventUseCaseContract interface {
 	CreateEvent(ctx context.Context, event domain.Event) error
 	UpdateEvent(ctx context.Context, event domain.Event) error
 	DeleteEvent(ctx context.Context, eventID string) error
+	GetEvent(ctx context.Context, eventID string) (domain.Event, error)
 	GetEventsForDay(ctx context.Context, userID, date string) ([]domain.Event, error)
 	GetEventsForWeek(ctx context.Context, userID, date string) ([]domain.Event, error)
 	GetEventsForMonth(ctx context.Context, userID, date string) ([]domain.Event, error)
+	GetEventsForRange(ctx context.Context, userID, from, to string) ([]domain.Event, error)
+	GetUsage(ctx context.Context, userID, date string) (domain.Usage, error)
+	ImportEvents(ctx context.Context, userID string, candidates []domain.ImportCandidate, opts domain.ImportOptions) (domain.ImportReport, error)
+	ExportEvents(ctx context.Context, filter domain.ExportFilter, fn func(domain.Event) error) error
 }
 
+// DefaultMaxRangeDays - максимальная длина запрашиваемого периода по умолчанию
+const DefaultMaxRangeDays = 731
+
 // EventUseCase - реализация EventUseCaseContract
 type EventUseCase struct {
-	repo   repo.EventRepository
-	logger *zap.Logger
+	repo    repo.EventRepository
+	logger  *zap.Logger
+	tenants *tenant.Registry
+	// maxRangeDays - максимальная длина периода в GetEventsForRange
+	maxRangeDays int
+	// maxImportEvents - максимальное количество событий в ImportEvents
+	maxImportEvents int
+	// changes - шина изменений событий; nil отключает публикацию
+	changes *pubsub.Hub
+	// outbox - relay изменений из outbox хранилища; если задан, изменения публикует он
+	outbox OutboxRelay
+	// defaultReminderMethod - способ доставки напоминаний без явно указанного способа
+	defaultReminderMethod string
+}
+
+// Option - функциональная опция EventUseCase
+type Option func(*EventUseCase)
+
+// WithTenants - подключает реестр арендаторов с их квотами
+func WithTenants(registry *tenant.Registry) Option {
+	return func(uc *EventUseCase) {
+		uc.tenants = registry
+	}
+}
+
+// WithMaxRangeDays - ограничивает длину периода, запрашиваемого одним вызовом
+func WithMaxRangeDays(days int) Option {
+	return func(uc *EventUseCase) {
+		uc.maxRangeDays = days
+	}
+}
+
+// WithMaxImportEvents - ограничивает количество событий в одном импорте; 0 снимает ограничение
+func WithMaxImportEvents(count int) Option {
+	return func(uc *EventUseCase) {
+		uc.maxImportEvents = count
+	}
+}
+
+// WithDefaultReminderMethod - способ доставки, которым планировщик отправляет напоминания без способа;
+// нужен, чтобы напоминание без способа и с тем же способом считались повтором
+func WithDefaultReminderMethod(method string) Option {
+	return func(uc *EventUseCase) {
+		uc.defaultReminderMethod = method
+	}
 }
 
 // NewEventUseCase - конструктор EventUseCase
-func NewEventUseCase(repo repo.EventRepository, logger *zap.Logger) *EventUseCase {
-	return &EventUseCase{
-		repo:   repo,
-		logger: logger,
+func NewEventUseCase(repo repo.EventRepository, logger *zap.Logger, opts ...Option) *EventUseCase {
+	uc := &EventUseCase{
+		repo:                  repo,
+		logger:                logger,
+		maxRangeDays:          DefaultMaxRangeDays,
+		maxImportEvents:       DefaultMaxImportEvents,
+		defaultReminderMethod: domain.ReminderLog,
+	}
+	for _, opt := range opts {
+		opt(uc)
 	}
+	return uc
+}
+
+// log - логгер из контекста запроса, либо логгер use case
+func (uc *EventUseCase) log(ctx context.Context) *zap.Logger {
+	return ctxlog.FromContext(ctx, uc.logger)
 }
 
 // CreateEvent - метод создания события
-func (uc *EventUseCase) CreateEvent(ctx context.Context, event domain.Event) error {
-	uc.logger.Debug("Creating event in usecase",
+func (uc *EventUseCase) CreateEvent(ctx context.Context, event domain.Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.CreateEvent",
+		tracing.String("event.id", event.ID),
+		tracing.String("user.id", event.UserID),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Creating event in usecase",
 		zappretty.Field("event_id", event.ID),
 		zappretty.Field("user_id", event.UserID),
 	)
 
 	if err := ctx.Err(); err != nil {
-		uc.logger.Warn("Context cancelled before creating event")
+		uc.log(ctx).Warn("Context cancelled before creating event")
 		return err
 	}
 
-	if err := uc.validateEvent(event); err != nil {
-		uc.logger.Warn("Event validation failed",
+	if err := uc.validateEvent(ctx, event); err != nil {
+		uc.log(ctx).Warn("Event validation failed",
 			zappretty.Field("error", err),
 			zappretty.Field("event_id", event.ID),
 		)
 		return err
 	}
 
-	return uc.repo.Create(ctx, event)
+	if err := uc.authorizeUser(ctx, event.UserID); err != nil {
+		uc.log(ctx).Warn("Attempt to create event in another user's calendar",
+			zappretty.Field("event_id", event.ID),
+			zappretty.Field("user_id", event.UserID),
+		)
+		return err
+	}
+
+	err = uc.repo.Create(ctx, event, uc.quota(ctx))
+	if stdErrors.Is(err, errors.ErrQuotaExceeded) {
+		uc.log(ctx).Warn("Event quota exceeded",
+			zappretty.Field("error", err),
+			zappretty.Field("tenant_id", tenant.FromContext(ctx)),
+			zappretty.Field("user_id", event.UserID),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	uc.publish(ctx, domain.ChangeCreated, event)
+	return nil
 }
 
 // UpdateEvent - метод обновления события
-func (uc *EventUseCase) UpdateEvent(ctx context.Context, event domain.Event) error {
-	uc.logger.Debug("Updating event in usecase",
+func (uc *EventUseCase) UpdateEvent(ctx context.Context, event domain.Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.UpdateEvent",
+		tracing.String("event.id", event.ID),
+		tracing.String("user.id", event.UserID),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Updating event in usecase",
 		zappretty.Field("event_id", event.ID),
 	)
 
 	if err := ctx.Err(); err != nil {
-		uc.logger.Warn("Context cancelled before updating event")
+		uc.log(ctx).Warn("Context cancelled before updating event")
 		return err
 	}
 
-	if err := uc.validateEvent(event); err != nil {
-		uc.logger.Warn("Event validation failed during update",
+	if err := uc.validateEvent(ctx, event); err != nil {
+		uc.log(ctx).Warn("Event validation failed during update",
 			zappretty.Field("error", err),
 			zappretty.Field("event_id", event.ID),
 		)
 		return err
 	}
 
-	return uc.repo.Update(ctx, event)
+	if err := uc.authorizeUser(ctx, event.UserID); err != nil {
+		return err
+	}
+	if err := uc.authorizeEvent(ctx, event.ID); err != nil {
+		uc.log(ctx).Warn("Update of event not owned by caller rejected",
+			zappretty.Field("error", err),
+			zappretty.Field("event_id", event.ID),
+		)
+		return err
+	}
+
+	err = uc.repo.Update(ctx, event, uc.quota(ctx))
+	if stdErrors.Is(err, errors.ErrQuotaExceeded) {
+		uc.log(ctx).Warn("Event quota exceeded on update",
+			zappretty.Field("error", err),
+			zappretty.Field("event_id", event.ID),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	uc.publish(ctx, domain.ChangeUpdated, event)
+	return nil
 }
 
 // DeleteEvent - метод удаления события
-func (uc *EventUseCase) DeleteEvent(ctx context.Context, eventID string) error {
-	uc.logger.Debug("Deleting event in usecase",
+func (uc *EventUseCase) DeleteEvent(ctx context.Context, eventID string) (err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.DeleteEvent",
+		tracing.String("event.id", eventID),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Deleting event in usecase",
 		zappretty.Field("event_id", eventID),
 	)
 
 	if err := ctx.Err(); err != nil {
-		uc.logger.Warn("Context cancelled before deleting event")
+		uc.log(ctx).Warn("Context cancelled before deleting event")
 		return err
 	}
 
 	if err := uc.validateEventID(eventID); err != nil {
-		uc.logger.Warn("Empty event ID provided for deletion")
+		uc.log(ctx).Warn("Empty event ID provided for deletion")
+		return err
+	}
+
+	// Владелец нужен и для проверки доступа, и подписчикам ленты изменений
+	existing, err := uc.repo.GetByID(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if err := uc.authorizeUser(ctx, existing.UserID); err != nil {
+		uc.log(ctx).Warn("Deletion of event not owned by caller rejected",
+			zappretty.Field("error", err),
+			zappretty.Field("event_id", eventID),
+		)
 		return err
 	}
 
-	return uc.repo.Delete(ctx, eventID)
+	if err := uc.repo.Delete(ctx, eventID); err != nil {
+		return err
+	}
+	uc.publish(ctx, domain.ChangeDeleted, existing)
+	return nil
+}
+
+// GetEvent - метод получения события по ID независимо от его даты
+func (uc *EventUseCase) GetEvent(ctx context.Context, eventID string) (event domain.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.GetEvent",
+		tracing.String("event.id", eventID),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Getting event in usecase",
+		zappretty.Field("event_id", eventID),
+	)
+
+	if err := ctx.Err(); err != nil {
+		return domain.Event{}, err
+	}
+
+	if err := uc.validateEventID(eventID); err != nil {
+		return domain.Event{}, err
+	}
+
+	event, err = uc.repo.GetByID(ctx, eventID)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	if err := uc.authorizeUser(ctx, event.UserID); err != nil {
+		return domain.Event{}, err
+	}
+	return event, nil
 }
 
 // GetEventsForDay - метод получения событий для конкретной даты
-func (uc *EventUseCase) GetEventsForDay(ctx context.Context, userID, date string) ([]domain.Event, error) {
-	uc.logger.Debug("Getting events for day in usecase",
+func (uc *EventUseCase) GetEventsForDay(ctx context.Context, userID, date string) (events []domain.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.GetEventsForDay",
+		tracing.String("user.id", userID),
+		tracing.String("date", date),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Getting events for day in usecase",
 		zappretty.Field("user_id", userID),
 		zappretty.Field("date", date),
 	)
 
 	if err := ctx.Err(); err != nil {
-		uc.logger.Warn("Context cancelled before getting events")
+		uc.log(ctx).Warn("Context cancelled before getting events")
 		return nil, err
 	}
 
 	if err := uc.validateUserID(userID); err != nil {
-		uc.logger.Warn("Empty user ID provided for events query")
+		uc.log(ctx).Warn("Empty user ID provided for events query")
 		return nil, err
 	}
 	if err := uc.validateDate(date); err != nil {
-		uc.logger.Warn("Invalid date provided for events query")
+		uc.log(ctx).Warn("Invalid date provided for events query")
+		return nil, err
+	}
+	if err := uc.authorizeUser(ctx, userID); err != nil {
 		return nil, err
 	}
 
@@ -123,8 +306,14 @@ func (uc *EventUseCase) GetEventsForDay(ctx context.Context, userID, date string
 }
 
 // GetEventsForWeek - метод получения событий за неделю
-func (uc *EventUseCase) GetEventsForWeek(ctx context.Context, userID, date string) ([]domain.Event, error) {
-	uc.logger.Debug("Getting events for week in usecase",
+func (uc *EventUseCase) GetEventsForWeek(ctx context.Context, userID, date string) (events []domain.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.GetEventsForWeek",
+		tracing.String("user.id", userID),
+		tracing.String("date", date),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Getting events for week in usecase",
 		zappretty.Field("user_id", userID),
 		zappretty.Field("date", date),
 	)
@@ -139,13 +328,22 @@ func (uc *EventUseCase) GetEventsForWeek(ctx context.Context, userID, date strin
 	if err := uc.validateDate(date); err != nil {
 		return nil, err
 	}
+	if err := uc.authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
 
 	return uc.repo.GetByUserIDAndWeek(ctx, userID, date)
 }
 
 // GetEventsForMonth - метод получения событий за месяц
-func (uc *EventUseCase) GetEventsForMonth(ctx context.Context, userID, date string) ([]domain.Event, error) {
-	uc.logger.Debug("Getting events for month in usecase",
+func (uc *EventUseCase) GetEventsForMonth(ctx context.Context, userID, date string) (events []domain.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.GetEventsForMonth",
+		tracing.String("user.id", userID),
+		tracing.String("date", date),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Getting events for month in usecase",
 		zappretty.Field("user_id", userID),
 		zappretty.Field("date", date),
 	)
@@ -160,6 +358,74 @@ func (uc *EventUseCase) GetEventsForMonth(ctx context.Context, userID, date stri
 	if err := uc.validateDate(date); err != nil {
 		return nil, err
 	}
+	if err := uc.authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
 
 	return uc.repo.GetByUserIDAndMonth(ctx, userID, date)
 }
+
+// GetEventsForRange - метод получения событий за период от from до to включительно
+func (uc *EventUseCase) GetEventsForRange(ctx context.Context, userID, from, to string) (events []domain.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.GetEventsForRange",
+		tracing.String("user.id", userID),
+		tracing.String("date.from", from),
+		tracing.String("date.to", to),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Getting events for range in usecase",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("from", from),
+		zappretty.Field("to", to),
+	)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := uc.validateUserID(userID); err != nil {
+		return nil, err
+	}
+	if err := uc.validateRange(from, to); err != nil {
+		uc.log(ctx).Warn("Invalid range provided for events query", zappretty.Field("error", err))
+		return nil, err
+	}
+	if err := uc.authorizeUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return uc.repo.GetByUserIDAndRange(ctx, userID, from, to)
+}
+
+// GetUsage - метод получения текущего использования квот; date необязателен
+func (uc *EventUseCase) GetUsage(ctx context.Context, userID, date string) (usage domain.Usage, err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.GetUsage",
+		tracing.String("user.id", userID),
+		tracing.String("date", date),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Getting quota usage in usecase",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("date", date),
+	)
+
+	if err := ctx.Err(); err != nil {
+		return domain.Usage{}, err
+	}
+
+	if err := uc.validateUserID(userID); err != nil {
+		return domain.Usage{}, err
+	}
+	if date != "" {
+		if err := uc.validateDate(date); err != nil {
+			return domain.Usage{}, err
+		}
+	}
+	if err := uc.authorizeUser(ctx, userID); err != nil {
+		return domain.Usage{}, err
+	}
+
+	return uc.usage(ctx, userID, date)
+}
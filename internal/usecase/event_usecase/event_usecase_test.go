@@ -1,10 +1,18 @@
 package event_usecase
 
 import (
+	"calendar-server/internal/auth"
 	"calendar-server/internal/domain"
+	"calendar-server/internal/pubsub"
+	"calendar-server/internal/repository/event_repository/inmemory"
+	"calendar-server/internal/tenant"
 	"calendar-server/pkg/errors"
 	"context"
 	stdErrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,7 +29,7 @@ func newMockEventRepository() *mockEventRepository {
 	}
 }
 
-func (m *mockEventRepository) Create(ctx context.Context, event domain.Event) error {
+func (m *mockEventRepository) Create(ctx context.Context, event domain.Event, quota tenant.Quota) error {
 	if _, exists := m.events[event.ID]; exists {
 		return errors.ErrEventConflict
 	}
@@ -29,7 +37,7 @@ func (m *mockEventRepository) Create(ctx context.Context, event domain.Event) er
 	return nil
 }
 
-func (m *mockEventRepository) Update(ctx context.Context, event domain.Event) error {
+func (m *mockEventRepository) Update(ctx context.Context, event domain.Event, quota tenant.Quota) error {
 	if _, exists := m.events[event.ID]; !exists {
 		return errors.ErrEventNotFound
 	}
@@ -45,6 +53,28 @@ func (m *mockEventRepository) Delete(ctx context.Context, eventID string) error
 	return nil
 }
 
+func (m *mockEventRepository) GetByID(ctx context.Context, eventID string) (domain.Event, error) {
+	event, exists := m.events[eventID]
+	if !exists {
+		return domain.Event{}, errors.ErrEventNotFound
+	}
+	return event, nil
+}
+
+func (m *mockEventRepository) Count(ctx context.Context) (int, error) {
+	return len(m.events), nil
+}
+
+func (m *mockEventRepository) CountByUserID(ctx context.Context, userID string) (int, error) {
+	count := 0
+	for _, event := range m.events {
+		if event.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
 func (m *mockEventRepository) GetByUserIDAndDate(ctx context.Context, userID, date string) ([]domain.Event, error) {
 	var result []domain.Event
 	for _, event := range m.events {
@@ -100,6 +130,32 @@ func (m *mockEventRepository) GetByUserIDAndMonth(ctx context.Context, userID, d
 	return result, nil
 }
 
+func (m *mockEventRepository) GetByUserIDAndRange(ctx context.Context, userID, from, to string) ([]domain.Event, error) {
+	var result []domain.Event
+	for _, event := range m.events {
+		if event.UserID == userID && event.Date >= from && event.Date <= to {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockEventRepository) GetPage(ctx context.Context, userID, afterID string, limit int) ([]domain.Event, error) {
+	var result []domain.Event
+	for _, event := range m.events {
+		if event.ID > afterID && (userID == "" || event.UserID == userID) {
+			result = append(result, event)
+		}
+	}
+	slices.SortFunc(result, func(a, b domain.Event) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
 func setupTestUseCase() (*EventUseCase, context.Context) {
 	logger, _ := zap.NewDevelopment()
 	repo := newMockEventRepository()
@@ -370,3 +426,698 @@ func TestEventUseCase_ContextCancellation(t *testing.T) {
 		t.Error("Expected error when context is cancelled")
 	}
 }
+
+func TestEventUseCase_Authorization(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	owner := auth.WithIdentity(ctx, auth.Identity{UserID: "user-1"})
+	intruder := auth.WithIdentity(ctx, auth.Identity{UserID: "user-2"})
+
+	event := domain.Event{ID: "test-1", UserID: "user-1", Date: "2025-01-15", Title: "Private"}
+	if err := uc.CreateEvent(owner, event); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	if err := uc.CreateEvent(intruder, domain.Event{ID: "test-2", UserID: "user-1", Date: "2025-01-15", Title: "Spoof"}); !stdErrors.Is(err, errors.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden when creating in another calendar, got %v", err)
+	}
+
+	hijack := event
+	hijack.UserID = "user-2"
+	if err := uc.UpdateEvent(intruder, hijack); !stdErrors.Is(err, errors.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden when taking over another user's event, got %v", err)
+	}
+
+	if err := uc.DeleteEvent(intruder, "test-1"); !stdErrors.Is(err, errors.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden when deleting another user's event, got %v", err)
+	}
+
+	if _, err := uc.GetEventsForDay(intruder, "user-1", "2025-01-15"); !stdErrors.Is(err, errors.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden when reading another calendar, got %v", err)
+	}
+
+	if _, err := uc.GetEvent(intruder, "test-1"); !stdErrors.Is(err, errors.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden when reading another user's event, got %v", err)
+	}
+	if got, err := uc.GetEvent(owner, "test-1"); err != nil || got.Title != "Private" {
+		t.Errorf("Expected owner to read event, got %+v, %v", got, err)
+	}
+
+	events, err := uc.GetEventsForDay(owner, "user-1", "2025-01-15")
+	if err != nil {
+		t.Fatalf("Failed to get own events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected 1 event, got %d", len(events))
+	}
+
+	if err := uc.DeleteEvent(owner, "test-1"); err != nil {
+		t.Errorf("Owner failed to delete event: %v", err)
+	}
+}
+
+func TestEventUseCase_TenantQuota(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	registry := tenant.NewRegistry(tenant.Settings{}, map[string]tenant.Settings{
+		"dept-a": {Quota: tenant.Quota{MaxEvents: 1}},
+	})
+	uc := NewEventUseCase(inmemory.NewEventRepository(logger), logger, WithTenants(registry))
+	ctx := tenant.WithID(context.Background(), "dept-a")
+
+	if err := uc.CreateEvent(ctx, domain.Event{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "First"}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	err := uc.CreateEvent(ctx, domain.Event{ID: "2", UserID: "user-1", Date: "2025-01-15", Title: "Second"})
+	if !stdErrors.Is(err, errors.ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+}
+
+func TestEventUseCase_UserQuotas(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	registry := tenant.NewRegistry(tenant.Settings{Quota: tenant.Quota{MaxEventsPerUser: 3, MaxEventsPerDay: 2, MaxTitleLength: 10}}, nil)
+	uc := NewEventUseCase(inmemory.NewEventRepository(logger), logger, WithTenants(registry))
+	ctx := context.Background()
+
+	for _, event := range []domain.Event{
+		{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "One"},
+		{ID: "2", UserID: "user-1", Date: "2025-01-15", Title: "Two"},
+	} {
+		if err := uc.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	var quotaErr *errors.QuotaError
+
+	err := uc.CreateEvent(ctx, domain.Event{ID: "3", UserID: "user-1", Date: "2025-01-15", Title: "Three"})
+	if !stdErrors.As(err, &quotaErr) || quotaErr.Limit != errors.LimitDayEvents {
+		t.Errorf("Expected day events quota error, got %v", err)
+	}
+
+	// Лимит длины названия из квоты проверяется вместе с правилами проверки
+	err = uc.CreateEvent(ctx, domain.Event{ID: "3", UserID: "user-1", Date: "2025-01-16", Title: "Очень длинное"})
+	var verr *errors.ValidationError
+	if !stdErrors.As(err, &verr) || !stdErrors.Is(err, errors.ErrTitleLength) {
+		t.Errorf("Expected title length violation, got %v", err)
+	}
+
+	if err := uc.CreateEvent(ctx, domain.Event{ID: "3", UserID: "user-1", Date: "2025-01-16", Title: "Три"}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	err = uc.CreateEvent(ctx, domain.Event{ID: "4", UserID: "user-1", Date: "2025-01-17", Title: "Four"})
+	if !stdErrors.As(err, &quotaErr) || quotaErr.Limit != errors.LimitUserEvents {
+		t.Errorf("Expected user events quota error, got %v", err)
+	}
+	if !stdErrors.Is(err, errors.ErrQuotaExceeded) {
+		t.Errorf("Expected quota error to match ErrQuotaExceeded, got %v", err)
+	}
+
+	// Перенос события в заполненную дату тоже ограничен
+	err = uc.UpdateEvent(ctx, domain.Event{ID: "3", UserID: "user-1", Date: "2025-01-15", Title: "Три"})
+	if !stdErrors.As(err, &quotaErr) || quotaErr.Limit != errors.LimitDayEvents {
+		t.Errorf("Expected day events quota error on update, got %v", err)
+	}
+	if err := uc.UpdateEvent(ctx, domain.Event{ID: "1", UserID: "user-1", Date: "2025-01-15", Title: "Renamed"}); err != nil {
+		t.Errorf("Expected update within the same date to pass, got %v", err)
+	}
+
+	usage, err := uc.GetUsage(ctx, "user-1", "2025-01-15")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage.UserEvents != (domain.UsageMeter{Used: 3, Limit: 3}) || usage.DayEvents == nil || usage.DayEvents.Used != 2 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+}
+
+func TestEventUseCase_QuotaConcurrent(t *testing.T) {
+	registry := tenant.NewRegistry(tenant.Settings{Quota: tenant.Quota{MaxEventsPerUser: 5}}, nil)
+	uc := NewEventUseCase(inmemory.NewEventRepository(zap.NewNop()), zap.NewNop(), WithTenants(registry))
+	ctx := context.Background()
+
+	const attempts = 50
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		created  int
+		rejected int
+	)
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := uc.CreateEvent(ctx, domain.Event{ID: fmt.Sprintf("event-%d", i), UserID: "user-1", Date: "2025-01-15", Title: "Parallel"})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case stdErrors.Is(err, errors.ErrQuotaExceeded):
+				rejected++
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created != 5 || rejected != attempts-5 {
+		t.Errorf("Expected 5 created and %d rejected, got %d and %d", attempts-5, created, rejected)
+	}
+}
+
+func TestEventUseCase_ValidationAggregates(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	err := uc.CreateEvent(ctx, domain.Event{ID: "bad id", UserID: "", Date: "1812-06-24", Title: "Line\nbreak"})
+
+	var verr *errors.ValidationError
+	if !stdErrors.As(err, &verr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+
+	expected := map[string]error{
+		"id":      errors.ErrInvalidID,
+		"user_id": errors.ErrEmptyUserID,
+		"date":    errors.ErrDateOutOfRange,
+		"title":   errors.ErrControlCharacters,
+	}
+	if len(verr.Violations) != len(expected) {
+		t.Fatalf("Expected %d violations, got %v", len(expected), verr.Violations)
+	}
+	for _, v := range verr.Violations {
+		if !stdErrors.Is(v.Err, expected[v.Field]) {
+			t.Errorf("Expected %v for field %s, got %v", expected[v.Field], v.Field, v.Err)
+		}
+	}
+
+	described := errors.Describe(err)
+	if described.Code != errors.CodeValidationFailed || len(described.Fields) != len(expected) {
+		t.Errorf("Expected validation_failed with %d fields, got %s with %v", len(expected), described.Code, described.Fields)
+	}
+}
+
+func TestEventUseCase_TenantValidationRules(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	registry := tenant.NewRegistry(tenant.Settings{
+		Validation: tenant.ValidationRules{MaxTitleLength: 20},
+	}, map[string]tenant.Settings{
+		"strict": {Validation: tenant.ValidationRules{MinYear: 2020, MinTitleLength: 3, IDPattern: `^[0-9]+$`}},
+	})
+	uc := NewEventUseCase(newMockEventRepository(), logger, WithTenants(registry))
+	strict := tenant.WithID(context.Background(), "strict")
+
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		event     domain.Event
+		expectErr error
+	}{
+		{
+			name:      "default tenant accepts old dates",
+			ctx:       context.Background(),
+			event:     domain.Event{ID: "evt-1", UserID: "user-1", Date: "1999-01-15", Title: "Ok"},
+			expectErr: nil,
+		},
+		{
+			name:      "default tenant title limit",
+			ctx:       context.Background(),
+			event:     domain.Event{ID: "evt-2", UserID: "user-1", Date: "2025-01-15", Title: "A title that is far too long"},
+			expectErr: errors.ErrTitleLength,
+		},
+		{
+			name:      "strict tenant date bound",
+			ctx:       strict,
+			event:     domain.Event{ID: "1", UserID: "2", Date: "2019-12-31", Title: "Old"},
+			expectErr: errors.ErrDateOutOfRange,
+		},
+		{
+			name:      "strict tenant id pattern",
+			ctx:       strict,
+			event:     domain.Event{ID: "evt-1", UserID: "2", Date: "2025-01-15", Title: "New"},
+			expectErr: errors.ErrInvalidID,
+		},
+		{
+			name:      "strict tenant minimum title length",
+			ctx:       strict,
+			event:     domain.Event{ID: "1", UserID: "2", Date: "2025-01-15", Title: "No"},
+			expectErr: errors.ErrTitleLength,
+		},
+		{
+			name:      "strict tenant valid event",
+			ctx:       strict,
+			event:     domain.Event{ID: "1", UserID: "2", Date: "2025-01-15", Title: "New"},
+			expectErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := uc.CreateEvent(tc.ctx, tc.event)
+			if !stdErrors.Is(err, tc.expectErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestEventUseCase_EventTimes(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	testCases := []struct {
+		name      string
+		start     string
+		end       string
+		expectErr error
+	}{
+		{name: "all day", expectErr: nil},
+		{name: "start only", start: "09:30", expectErr: nil},
+		{name: "start and end", start: "09:30", end: "10:15", expectErr: nil},
+		{name: "malformed start", start: "9.30", expectErr: errors.ErrInvalidTime},
+		{name: "end without start", end: "10:00", expectErr: errors.ErrInvalidTime},
+		{name: "end before start", start: "10:00", end: "09:00", expectErr: errors.ErrInvalidTime},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			event := domain.Event{
+				ID:        fmt.Sprintf("time-%d", i),
+				UserID:    "user-1",
+				Date:      "2025-01-15",
+				Title:     "Meeting",
+				StartTime: tc.start,
+				EndTime:   tc.end,
+			}
+			err := uc.CreateEvent(ctx, event)
+			if !stdErrors.Is(err, tc.expectErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestEventUseCase_GetEventsForRange(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	uc := NewEventUseCase(newMockEventRepository(), logger, WithMaxRangeDays(31))
+	ctx := context.Background()
+
+	for _, event := range []domain.Event{
+		{ID: "1", UserID: "user-1", Date: "2025-01-01", Title: "First"},
+		{ID: "2", UserID: "user-1", Date: "2025-01-31", Title: "Last"},
+		{ID: "3", UserID: "user-1", Date: "2025-02-01", Title: "Outside"},
+	} {
+		if err := uc.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	events, err := uc.GetEventsForRange(ctx, "user-1", "2025-01-01", "2025-01-31")
+	if err != nil {
+		t.Fatalf("Failed to get events for range: %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("Expected 2 events, got %d", len(events))
+	}
+
+	testCases := []struct {
+		name      string
+		from      string
+		to        string
+		expectErr error
+	}{
+		{name: "reversed range", from: "2025-01-31", to: "2025-01-01", expectErr: errors.ErrInvalidRange},
+		{name: "range too long", from: "2025-01-01", to: "2025-02-01", expectErr: errors.ErrInvalidRange},
+		{name: "invalid date", from: "2025-01-01", to: "tomorrow", expectErr: errors.ErrInvalidDate},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := uc.GetEventsForRange(ctx, "user-1", tc.from, tc.to)
+			if !stdErrors.Is(err, tc.expectErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestEventUseCase_ImportEvents(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := newMockEventRepository()
+	uc := NewEventUseCase(repo, logger)
+	ctx := context.Background()
+
+	for _, event := range []domain.Event{
+		{ID: "same", UserID: "user-1", Date: "2025-01-01", Title: "Same"},
+		{ID: "changed", UserID: "user-1", Date: "2025-01-02", Title: "Old title"},
+		{ID: "stale", UserID: "user-1", Date: "2025-01-03", Title: "Not in file"},
+		{ID: "foreign", UserID: "user-2", Date: "2025-01-04", Title: "Other user"},
+	} {
+		if err := uc.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	candidates := []domain.ImportCandidate{
+		{Source: "line 1", Event: domain.Event{ID: "same", Date: "2025-01-01", Title: "Same"}},
+		{Source: "line 2", Event: domain.Event{ID: "changed", Date: "2025-01-02", Title: "New title"}},
+		{Source: "line 3", Event: domain.Event{ID: "new", Date: "2025-01-05", Title: "New"}},
+		{Source: "line 4", Event: domain.Event{ID: "foreign", Date: "2025-01-04", Title: "Takeover"}},
+		{Source: "line 5", Event: domain.Event{ID: "invalid", Date: "2025-13-01", Title: "Bad date"}},
+		{Source: "line 6", Err: errors.ErrInvalidCalendar},
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		report, err := uc.ImportEvents(ctx, "user-1", candidates, domain.ImportOptions{Mode: domain.ImportReplace, DryRun: true})
+		if err != nil {
+			t.Fatalf("Failed to import events: %v", err)
+		}
+		if report.Created != 1 || report.Updated != 1 || report.Skipped != 1 || report.Failed != 3 || report.Deleted != 1 {
+			t.Errorf("Expected 1/1/1/3/1 created/updated/skipped/failed/deleted, got %+v", report)
+		}
+		if _, err := repo.GetByID(ctx, "new"); !stdErrors.Is(err, errors.ErrEventNotFound) {
+			t.Errorf("Expected dry run not to create events, got %v", err)
+		}
+		if _, err := repo.GetByID(ctx, "stale"); err != nil {
+			t.Errorf("Expected dry run not to delete events, got %v", err)
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		report, err := uc.ImportEvents(ctx, "user-1", candidates, domain.ImportOptions{Mode: domain.ImportMerge})
+		if err != nil {
+			t.Fatalf("Failed to import events: %v", err)
+		}
+		if report.Created != 1 || report.Updated != 1 || report.Deleted != 0 {
+			t.Errorf("Expected 1 created, 1 updated and nothing deleted, got %+v", report)
+		}
+		if event, _ := repo.GetByID(ctx, "changed"); event.Title != "New title" {
+			t.Errorf("Expected updated title, got %q", event.Title)
+		}
+		if event, _ := repo.GetByID(ctx, "foreign"); event.UserID != "user-2" {
+			t.Errorf("Expected event of another user to be kept, got owner %q", event.UserID)
+		}
+		for _, item := range report.Items {
+			if item.EventID == "foreign" && item.Code != string(errors.CodeEventConflict) {
+				t.Errorf("Expected %s for event of another user, got %+v", errors.CodeEventConflict, item)
+			}
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		report, err := uc.ImportEvents(ctx, "user-1", candidates, domain.ImportOptions{Mode: domain.ImportReplace})
+		if err != nil {
+			t.Fatalf("Failed to import events: %v", err)
+		}
+		if report.Skipped != 3 || report.Deleted != 1 {
+			t.Errorf("Expected 3 skipped and 1 deleted, got %+v", report)
+		}
+		if _, err := repo.GetByID(ctx, "stale"); !stdErrors.Is(err, errors.ErrEventNotFound) {
+			t.Errorf("Expected event missing from import to be deleted, got %v", err)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := uc.ImportEvents(ctx, "user-1", candidates, domain.ImportOptions{Mode: "append"})
+		if !stdErrors.Is(err, errors.ErrInvalidImportOption) {
+			t.Errorf("Expected error %v, got %v", errors.ErrInvalidImportOption, err)
+		}
+
+		limited := NewEventUseCase(repo, logger, WithMaxImportEvents(2))
+		_, err = limited.ImportEvents(ctx, "user-1", candidates, domain.ImportOptions{})
+		if !stdErrors.Is(err, errors.ErrImportTooLarge) {
+			t.Errorf("Expected error %v, got %v", errors.ErrImportTooLarge, err)
+		}
+	})
+}
+
+func TestEventUseCase_ExportEvents(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	// Событий больше одной порции хранилища, чтобы выгрузка прошла несколько страниц
+	total := exportPageSize*2 + 10
+	for i := 0; i < total; i++ {
+		event := domain.Event{ID: fmt.Sprintf("event-%04d", i), UserID: "user-1", Date: "2025-01-01", Title: "Event"}
+		if i%2 == 1 {
+			event.UserID, event.Date = "user-2", "2025-02-01"
+		}
+		if err := uc.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	admin := auth.WithIdentity(ctx, auth.Identity{UserID: "admin", Role: auth.RoleAdmin})
+	owner := auth.WithIdentity(ctx, auth.Identity{UserID: "user-1"})
+
+	testCases := []struct {
+		name          string
+		ctx           context.Context
+		filter        domain.ExportFilter
+		expectedCount int
+		expectedErr   error
+	}{
+		{name: "own events", ctx: owner, filter: domain.ExportFilter{UserID: "user-1"}, expectedCount: total / 2},
+		{name: "tenant by admin", ctx: admin, filter: domain.ExportFilter{}, expectedCount: total},
+		{name: "tenant for period", ctx: admin, filter: domain.ExportFilter{From: "2025-01-15", To: "2025-02-15"}, expectedCount: total / 2},
+		{name: "another user", ctx: owner, filter: domain.ExportFilter{UserID: "user-2"}, expectedErr: errors.ErrForbidden},
+		{name: "tenant by user", ctx: owner, filter: domain.ExportFilter{}, expectedErr: errors.ErrAdminOnly},
+		{name: "invalid date", ctx: owner, filter: domain.ExportFilter{UserID: "user-1", From: "2025-1-1"}, expectedErr: errors.ErrInvalidDate},
+		{name: "reversed period", ctx: owner, filter: domain.ExportFilter{UserID: "user-1", From: "2025-02-01", To: "2025-01-01"}, expectedErr: errors.ErrInvalidRange},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ids []string
+			err := uc.ExportEvents(tc.ctx, tc.filter, func(event domain.Event) error {
+				ids = append(ids, event.ID)
+				return nil
+			})
+			if !stdErrors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if len(ids) != tc.expectedCount {
+				t.Errorf("Expected %d events, got %d", tc.expectedCount, len(ids))
+			}
+			if !slices.IsSorted(ids) || len(slices.Compact(slices.Clone(ids))) != len(ids) {
+				t.Error("Expected each event once in ID order")
+			}
+		})
+	}
+
+	stop := stdErrors.New("client disconnected")
+	calls := 0
+	err := uc.ExportEvents(admin, domain.ExportFilter{}, func(domain.Event) error {
+		calls++
+		return stop
+	})
+	if !stdErrors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected export to stop on the first callback error, got %v after %d calls", err, calls)
+	}
+}
+
+func TestEventUseCase_ImportEvents_Tenant(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	for _, event := range []domain.Event{
+		{ID: "kept", UserID: "user-1", Date: "2025-01-01", Title: "Kept"},
+		{ID: "stale", UserID: "user-2", Date: "2025-01-02", Title: "Not in file"},
+	} {
+		if err := uc.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	candidates := []domain.ImportCandidate{
+		{Source: "line 2", Event: domain.Event{ID: "kept", UserID: "user-1", Date: "2025-01-01", Title: "Kept"}},
+		{Source: "line 3", Event: domain.Event{ID: "new", UserID: "user-3", Date: "2025-01-03", Title: "New"}},
+		{Source: "line 4", Event: domain.Event{ID: "orphan", Date: "2025-01-04", Title: "No owner"}},
+	}
+
+	owner := auth.WithIdentity(ctx, auth.Identity{UserID: "user-1"})
+	if _, err := uc.ImportEvents(owner, "", candidates, domain.ImportOptions{}); !stdErrors.Is(err, errors.ErrAdminOnly) {
+		t.Errorf("Expected error %v, got %v", errors.ErrAdminOnly, err)
+	}
+
+	admin := auth.WithIdentity(ctx, auth.Identity{UserID: "admin", Role: auth.RoleAdmin})
+	report, err := uc.ImportEvents(admin, "", candidates, domain.ImportOptions{Mode: domain.ImportReplace})
+	if err != nil {
+		t.Fatalf("Failed to import events: %v", err)
+	}
+	if report.Skipped != 1 || report.Created != 1 || report.Failed != 1 || report.Deleted != 1 {
+		t.Errorf("Expected 1/1/1/1 skipped/created/failed/deleted, got %+v", report)
+	}
+	if report.Items[2].Code != string(errors.CodeEmptyUserID) {
+		t.Errorf("Expected %s for row without owner, got %+v", errors.CodeEmptyUserID, report.Items[2])
+	}
+	if event, err := uc.repo.GetByID(ctx, "new"); err != nil || event.UserID != "user-3" {
+		t.Errorf("Expected event of user-3 from the file, got %+v (%v)", event, err)
+	}
+	if _, err := uc.repo.GetByID(ctx, "stale"); !stdErrors.Is(err, errors.ErrEventNotFound) {
+		t.Errorf("Expected tenant event missing from import to be deleted, got %v", err)
+	}
+}
+
+func TestEventUseCase_Changes(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	hub := pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory)
+	uc := NewEventUseCase(newMockEventRepository(), logger, WithChanges(hub))
+	ctx := context.Background()
+
+	owner := auth.WithIdentity(ctx, auth.Identity{UserID: "user-1"})
+	admin := auth.WithIdentity(ctx, auth.Identity{UserID: "admin", Role: auth.RoleAdmin})
+
+	if _, err := uc.SubscribeChanges(owner, "user-2", ""); !stdErrors.Is(err, errors.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden when subscribing to another user, got %v", err)
+	}
+	if _, err := uc.SubscribeChanges(owner, "", ""); !stdErrors.Is(err, errors.ErrAdminOnly) {
+		t.Errorf("Expected ErrAdminOnly when subscribing to the tenant, got %v", err)
+	}
+
+	sub, err := uc.SubscribeChanges(owner, "user-1", "")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer sub.Close()
+
+	event := domain.Event{ID: "test-1", UserID: "user-1", Date: "2025-01-15", Title: "Title"}
+	if err := uc.CreateEvent(owner, event); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	event.Title = "Updated"
+	if err := uc.UpdateEvent(owner, event); err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+	if err := uc.CreateEvent(admin, domain.Event{ID: "test-2", UserID: "user-2", Date: "2025-01-15", Title: "Other"}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	if err := uc.DeleteEvent(owner, "test-1"); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+
+	expected := []string{domain.ChangeCreated, domain.ChangeUpdated, domain.ChangeDeleted}
+	for _, changeType := range expected {
+		select {
+		case change := <-sub.C():
+			if change.Type != changeType || change.EventID != "test-1" || change.ID == "" {
+				t.Errorf("Expected %s of test-1 with position, got %+v", changeType, change)
+			}
+			if (change.Event == nil) != (changeType == domain.ChangeDeleted) {
+				t.Errorf("Expected event state only for %s, got %+v", changeType, change.Event)
+			}
+		default:
+			t.Fatalf("Expected %s change", changeType)
+		}
+	}
+	select {
+	case change := <-sub.C():
+		t.Errorf("Expected no changes of other users, got %+v", change)
+	default:
+	}
+
+	// Неудачная операция не публикуется
+	if err := uc.DeleteEvent(owner, "test-1"); !stdErrors.Is(err, errors.ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+	select {
+	case change := <-sub.C():
+		t.Errorf("Expected no change for a failed delete, got %+v", change)
+	default:
+	}
+}
+
+// countingRelay - считает уведомления о новых сообщениях outbox
+type countingRelay struct {
+	wakes int
+}
+
+func (r *countingRelay) Wake() {
+	r.wakes++
+}
+
+func TestEventUseCase_Outbox(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	hub := pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory)
+	relay := &countingRelay{}
+	uc := NewEventUseCase(newMockEventRepository(), logger, WithChanges(hub), WithOutbox(relay))
+	owner := auth.WithIdentity(context.Background(), auth.Identity{UserID: "user-1"})
+
+	sub, err := uc.SubscribeChanges(owner, "user-1", "")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer sub.Close()
+
+	event := domain.Event{ID: "test-1", UserID: "user-1", Date: "2025-01-15", Title: "Title"}
+	if err := uc.CreateEvent(owner, event); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	if err := uc.DeleteEvent(owner, "test-1"); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+
+	// Изменения публикует relay из outbox, а не сценарий напрямую
+	select {
+	case change := <-sub.C():
+		t.Errorf("Expected no direct publication with outbox, got %+v", change)
+	default:
+	}
+	if relay.wakes != 2 {
+		t.Errorf("Expected relay to be woken twice, got %d", relay.wakes)
+	}
+}
+
+func TestEventUseCase_Reminders(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	tooMany := make([]domain.Reminder, domain.MaxReminders+1)
+	for i := range tooMany {
+		tooMany[i].MinutesBefore = i
+	}
+
+	tests := []struct {
+		name      string
+		reminders []domain.Reminder
+		wantErr   error
+	}{
+		{name: "no reminders"},
+		{name: "valid", reminders: []domain.Reminder{{MinutesBefore: 15}, {MinutesBefore: 15, Method: domain.ReminderEmail}, {MinutesBefore: domain.MaxReminderMinutes}}},
+		{name: "too many", reminders: tooMany, wantErr: errors.ErrInvalidReminder},
+		{name: "negative offset", reminders: []domain.Reminder{{MinutesBefore: -5}}, wantErr: errors.ErrInvalidReminder},
+		{name: "offset too large", reminders: []domain.Reminder{{MinutesBefore: domain.MaxReminderMinutes + 1}}, wantErr: errors.ErrInvalidReminder},
+		{name: "unknown method", reminders: []domain.Reminder{{MinutesBefore: 15, Method: "sms"}}, wantErr: errors.ErrInvalidReminder},
+		{name: "duplicate", reminders: []domain.Reminder{{MinutesBefore: 15}, {MinutesBefore: 15}}, wantErr: errors.ErrInvalidReminder},
+		{name: "duplicate of default method", reminders: []domain.Reminder{{MinutesBefore: 15}, {MinutesBefore: 15, Method: domain.ReminderLog}}, wantErr: errors.ErrInvalidReminder},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := domain.Event{ID: fmt.Sprintf("test-%d", i), UserID: "user-1", Date: "2025-01-15", Title: "Title", Reminders: tt.reminders}
+			if err := uc.CreateEvent(ctx, event); !stdErrors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEventUseCase_ImportKeepsReminders(t *testing.T) {
+	uc, ctx := setupTestUseCase()
+
+	reminders := []domain.Reminder{{MinutesBefore: 30}}
+	if err := uc.CreateEvent(ctx, domain.Event{ID: "standup", UserID: "user-1", Date: "2025-01-15", Title: "Standup", Reminders: reminders}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	// Файл без напоминаний не меняет их и не считается изменением события
+	candidates := []domain.ImportCandidate{{Source: "line 1", Event: domain.Event{ID: "standup", Date: "2025-01-15", Title: "Standup"}}}
+	report, err := uc.ImportEvents(ctx, "user-1", candidates, domain.ImportOptions{Mode: domain.ImportMerge})
+	if err != nil {
+		t.Fatalf("Failed to import events: %v", err)
+	}
+	if report.Skipped != 1 {
+		t.Errorf("Expected unchanged event to be skipped, got %+v", report)
+	}
+	if event, _ := uc.repo.GetByID(ctx, "standup"); !slices.Equal(event.Reminders, reminders) {
+		t.Errorf("Expected reminders to be kept, got %+v", event.Reminders)
+	}
+}
@@ -0,0 +1,101 @@
+package event_usecase
+
+import (
+	"context"
+	"fmt"
+
+	"calendar-server/internal/domain"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+)
+
+// exportPageSize - количество событий, читаемых из хранилища за один запрос при обходе
+const exportPageSize = 500
+
+// ExportEvents - потоковая выгрузка событий: fn вызывается для каждого события в порядке ID.
+// События читаются из хранилища порциями, поэтому выгрузка не держит их все в памяти.
+// Пустой filter.UserID выгружает события всех пользователей арендатора и доступен только администратору.
+// Ошибка fn прерывает выгрузку и возвращается вызывающему.
+func (uc *EventUseCase) ExportEvents(ctx context.Context, filter domain.ExportFilter, fn func(domain.Event) error) (err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.ExportEvents",
+		tracing.String("user.id", filter.UserID),
+		tracing.String("date.from", filter.From),
+		tracing.String("date.to", filter.To),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Exporting events in usecase",
+		zappretty.Field("user_id", filter.UserID),
+		zappretty.Field("from", filter.From),
+		zappretty.Field("to", filter.To),
+	)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := uc.validateExportFilter(filter); err != nil {
+		return err
+	}
+	if err := uc.authorizeUsers(ctx, filter.UserID); err != nil {
+		uc.log(ctx).Warn("Attempt to export events of another user",
+			zappretty.Field("user_id", filter.UserID),
+		)
+		return err
+	}
+
+	exported := 0
+	err = uc.eachEvent(ctx, filter.UserID, func(event domain.Event) error {
+		if !filter.Match(event) {
+			return nil
+		}
+		exported++
+		return fn(event)
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.log(ctx).Info("Events exported",
+		zappretty.Field("user_id", filter.UserID),
+		zappretty.Field("count", exported),
+	)
+	return nil
+}
+
+// validateExportFilter проверяет необязательные границы периода и их порядок
+func (uc *EventUseCase) validateExportFilter(filter domain.ExportFilter) error {
+	for _, date := range []string{filter.From, filter.To} {
+		if date != "" && !isValidDate(date) {
+			return errors.ErrInvalidDate
+		}
+	}
+	if filter.From != "" && filter.To != "" && filter.To < filter.From {
+		return errors.WithParams(
+			fmt.Errorf("%w: %s is after %s", errors.ErrInvalidRange, filter.From, filter.To),
+			errors.Params{errors.ParamRule: "order", "from": filter.From, "to": filter.To},
+		)
+	}
+	return nil
+}
+
+// eachEvent обходит события пользователя (пустой userID - всего арендатора) порциями в порядке ID
+func (uc *EventUseCase) eachEvent(ctx context.Context, userID string, fn func(domain.Event) error) error {
+	afterID := ""
+	for {
+		events, err := uc.repo.GetPage(ctx, userID, afterID, exportPageSize)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if len(events) < exportPageSize {
+			return nil
+		}
+		afterID = events[len(events)-1].ID
+	}
+}
@@ -0,0 +1,187 @@
+package event_usecase
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+
+	"calendar-server/internal/domain"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/tracing"
+)
+
+// DefaultMaxImportEvents - максимальное количество событий в одном импорте по умолчанию
+const DefaultMaxImportEvents = 10000
+
+// ImportEvents - метод импорта событий в календарь пользователя.
+// Ошибки отдельных событий попадают в отчет и не прерывают импорт; в режиме DryRun хранилище не меняется.
+// Пустой userID означает импорт в календари всех пользователей арендатора: владелец берется из события,
+// а при замене удаляются отсутствующие в импорте события арендатора. Такой импорт доступен только администратору.
+func (uc *EventUseCase) ImportEvents(ctx context.Context, userID string, candidates []domain.ImportCandidate, opts domain.ImportOptions) (report domain.ImportReport, err error) {
+	ctx, span := tracing.Start(ctx, "EventUseCase.ImportEvents",
+		tracing.String("user.id", userID),
+		tracing.String("import.mode", opts.Mode),
+		tracing.Int("import.events", len(candidates)),
+	)
+	defer span.EndErr(&err)
+
+	uc.log(ctx).Debug("Importing events in usecase",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("mode", opts.Mode),
+		zappretty.Field("dry_run", opts.DryRun),
+		zappretty.Field("events", len(candidates)),
+	)
+
+	if err := ctx.Err(); err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	if opts.Mode == "" {
+		opts.Mode = domain.ImportMerge
+	}
+	if opts.Mode != domain.ImportMerge && opts.Mode != domain.ImportReplace {
+		return domain.ImportReport{}, errors.WithParams(
+			fmt.Errorf("%w: mode must be merge or replace, got %s", errors.ErrInvalidImportOption, opts.Mode),
+			errors.Params{errors.ParamRule: "mode", "mode": opts.Mode},
+		)
+	}
+	if uc.maxImportEvents > 0 && len(candidates) > uc.maxImportEvents {
+		return domain.ImportReport{}, errors.WithParams(
+			fmt.Errorf("%w: at most %d events, got %d", errors.ErrImportTooLarge, uc.maxImportEvents, len(candidates)),
+			errors.Params{errors.ParamRule: "events", "max": uc.maxImportEvents, "count": len(candidates)},
+		)
+	}
+	if err := uc.authorizeUsers(ctx, userID); err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	report = domain.ImportReport{Mode: opts.Mode, DryRun: opts.DryRun, Items: []domain.ImportItem{}}
+	// imported - идентификаторы из импорта, включая неудачные: при замене их события не удаляются
+	imported := make(map[string]bool, len(candidates))
+
+	for _, candidate := range candidates {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		event := candidate.Event
+		if userID != "" {
+			event.UserID = userID
+		}
+		item := domain.ImportItem{Source: candidate.Source, EventID: event.ID}
+
+		err := candidate.Err
+		if err == nil && event.ID != "" && imported[event.ID] {
+			err = errors.ErrEventConflict
+		}
+		if event.ID != "" {
+			imported[event.ID] = true
+		}
+		if err == nil {
+			item.Status, err = uc.importEvent(ctx, event, opts.DryRun)
+		}
+		if err == nil && !opts.DryRun {
+			switch item.Status {
+			case domain.ImportCreated:
+				uc.publish(ctx, domain.ChangeCreated, event)
+			case domain.ImportUpdated:
+				uc.publish(ctx, domain.ChangeUpdated, event)
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			described := errors.Describe(err)
+			item.Status = domain.ImportFailed
+			item.Code = string(described.Code)
+			item.Error = described.Detail
+		}
+		report.Add(item)
+	}
+
+	if opts.Mode == domain.ImportReplace {
+		if err := uc.removeNotImported(ctx, userID, imported, opts.DryRun, &report); err != nil {
+			return report, err
+		}
+	}
+
+	uc.log(ctx).Info("Events imported",
+		zappretty.Field("user_id", userID),
+		zappretty.Field("mode", report.Mode),
+		zappretty.Field("dry_run", report.DryRun),
+		zappretty.Field("created", report.Created),
+		zappretty.Field("updated", report.Updated),
+		zappretty.Field("skipped", report.Skipped),
+		zappretty.Field("failed", report.Failed),
+		zappretty.Field("deleted", report.Deleted),
+	)
+
+	return report, nil
+}
+
+// importEvent создает или обновляет одно событие; неизмененные события пропускаются
+func (uc *EventUseCase) importEvent(ctx context.Context, event domain.Event, dryRun bool) (string, error) {
+	if err := uc.validateEvent(ctx, event); err != nil {
+		return "", err
+	}
+
+	existing, err := uc.repo.GetByID(ctx, event.ID)
+	switch {
+	case stdErrors.Is(err, errors.ErrEventNotFound):
+		if dryRun {
+			err = uc.checkQuota(ctx, event, false)
+		} else {
+			err = uc.repo.Create(ctx, event, uc.quota(ctx))
+		}
+		if err != nil {
+			return "", err
+		}
+		return domain.ImportCreated, nil
+	case err != nil:
+		return "", err
+	}
+
+	// Событие другого пользователя импорт не перезаписывает
+	if existing.UserID != event.UserID {
+		return "", errors.ErrEventConflict
+	}
+	// iCalendar и CSV не передают напоминания: без них в записи остаются заданные раньше
+	if event.Reminders == nil {
+		event.Reminders = existing.Reminders
+	}
+	if existing.Equal(event) {
+		return domain.ImportSkipped, nil
+	}
+
+	if dryRun {
+		err = uc.checkQuota(ctx, event, true)
+	} else {
+		err = uc.repo.Update(ctx, event, uc.quota(ctx))
+	}
+	if err != nil {
+		return "", err
+	}
+	return domain.ImportUpdated, nil
+}
+
+// removeNotImported удаляет события пользователя (пустой userID - арендатора), отсутствующие в импорте
+func (uc *EventUseCase) removeNotImported(ctx context.Context, userID string, imported map[string]bool, dryRun bool, report *domain.ImportReport) error {
+	return uc.eachEvent(ctx, userID, func(event domain.Event) error {
+		if imported[event.ID] {
+			return nil
+		}
+		if !dryRun {
+			err := uc.repo.Delete(ctx, event.ID)
+			if err != nil && !stdErrors.Is(err, errors.ErrEventNotFound) {
+				return err
+			}
+			if err == nil {
+				uc.publish(ctx, domain.ChangeDeleted, event)
+			}
+		}
+		report.Add(domain.ImportItem{EventID: event.ID, Status: domain.ImportDeleted})
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+package event_usecase
+
+import (
+	"context"
+
+	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+)
+
+// quota - действующая квота арендатора из контекста. Лимиты количества событий проверяет
+// хранилище атомарно с записью (см. event_repository.EventRepository), длину названия - validateEvent.
+func (uc *EventUseCase) quota(ctx context.Context) tenant.Quota {
+	return uc.tenants.Quota(tenant.FromContext(ctx))
+}
+
+// checkQuota проверяет лимиты квоты без записи - для пробного импорта, который не вызывает хранилище.
+// Результат может устареть к моменту записи, поэтому для изменений лимиты проверяет хранилище.
+func (uc *EventUseCase) checkQuota(ctx context.Context, event domain.Event, isUpdate bool) error {
+	quota := uc.quota(ctx)
+
+	if !isUpdate {
+		if err := uc.checkLimit(errors.LimitTenantEvents, quota.MaxEvents, func() (int, error) {
+			return uc.repo.Count(ctx)
+		}); err != nil {
+			return err
+		}
+		if err := uc.checkLimit(errors.LimitUserEvents, quota.MaxEventsPerUser, func() (int, error) {
+			return uc.repo.CountByUserID(ctx, event.UserID)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if quota.MaxEventsPerDay <= 0 {
+		return nil
+	}
+	if isUpdate {
+		// Событие, оставшееся в той же дате, не увеличивает количество событий в ней
+		existing, err := uc.repo.GetByID(ctx, event.ID)
+		if err != nil {
+			return err
+		}
+		if existing.Date == event.Date && existing.UserID == event.UserID {
+			return nil
+		}
+	}
+	return uc.checkLimit(errors.LimitDayEvents, quota.MaxEventsPerDay, func() (int, error) {
+		events, err := uc.repo.GetByUserIDAndDate(ctx, event.UserID, event.Date)
+		return len(events), err
+	})
+}
+
+// checkLimit сравнивает текущее значение счетчика с лимитом; limit <= 0 отключает проверку.
+func (uc *EventUseCase) checkLimit(name string, limit int, current func() (int, error)) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	count, err := current()
+	if err != nil {
+		return err
+	}
+	if count >= limit {
+		return &errors.QuotaError{Limit: name, Max: limit, Current: count}
+	}
+	return nil
+}
+
+// usage собирает текущее использование квот пользователем.
+func (uc *EventUseCase) usage(ctx context.Context, userID, date string) (domain.Usage, error) {
+	tenantID := tenant.FromContext(ctx)
+	quota := uc.quota(ctx)
+
+	tenantEvents, err := uc.repo.Count(ctx)
+	if err != nil {
+		return domain.Usage{}, err
+	}
+	userEvents, err := uc.repo.CountByUserID(ctx, userID)
+	if err != nil {
+		return domain.Usage{}, err
+	}
+
+	usage := domain.Usage{
+		TenantID:       tenantID,
+		UserID:         userID,
+		TenantEvents:   domain.UsageMeter{Used: tenantEvents, Limit: quota.MaxEvents},
+		UserEvents:     domain.UsageMeter{Used: userEvents, Limit: quota.MaxEventsPerUser},
+		MaxTitleLength: uc.tenants.Validation(tenantID).MaxTitleLength,
+	}
+
+	if date != "" {
+		dayEvents, err := uc.repo.GetByUserIDAndDate(ctx, userID, date)
+		if err != nil {
+			return domain.Usage{}, err
+		}
+		usage.Date = date
+		usage.DayEvents = &domain.UsageMeter{Used: len(dayEvents), Limit: quota.MaxEventsPerDay}
+	}
+
+	return usage, nil
+}
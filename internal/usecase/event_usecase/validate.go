@@ -2,25 +2,177 @@ package event_usecase
 
 import (
 	"calendar-server/internal/domain"
+	"calendar-server/internal/tenant"
 	"calendar-server/pkg/errors"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-// validateEvent проверяет валидность всей структуры Event.
-func (uc *EventUseCase) validateEvent(event domain.Event) error {
-	if event.ID == "" {
-		return errors.ErrEmptyEventID
+// timeLayout - формат времени начала и окончания события
+const timeLayout = "15:04"
+
+// idPatterns - скомпилированные шаблоны идентификаторов по их тексту
+var idPatterns sync.Map
+
+// validateEvent проверяет валидность всей структуры Event по правилам арендатора из контекста.
+// Возвращает все найденные нарушения сразу в виде *errors.ValidationError.
+func (uc *EventUseCase) validateEvent(ctx context.Context, event domain.Event) error {
+	rules := uc.tenants.Validation(tenant.FromContext(ctx))
+	verr := &errors.ValidationError{}
+
+	validateID(verr, "id", event.ID, errors.ErrEmptyEventID, rules)
+	validateID(verr, "user_id", event.UserID, errors.ErrEmptyUserID, rules)
+
+	if date, err := time.Parse("2006-01-02", event.Date); err != nil {
+		verr.Add("date", errors.ErrInvalidDate)
+	} else if date.Year() < rules.MinYear || date.Year() > rules.MaxYear {
+		verr.Add("date", errors.WithParams(
+			fmt.Errorf("%w: year must be between %d and %d", errors.ErrDateOutOfRange, rules.MinYear, rules.MaxYear),
+			errors.Params{"min": rules.MinYear, "max": rules.MaxYear},
+		))
 	}
-	if event.UserID == "" {
-		return errors.ErrEmptyUserID
+
+	switch length := utf8.RuneCountInString(event.Title); {
+	case event.Title == "":
+		verr.Add("title", errors.ErrEmptyTitle)
+	case hasControlCharacters(event.Title):
+		verr.Add("title", errors.ErrControlCharacters)
+	case length < rules.MinTitleLength || length > rules.MaxTitleLength:
+		verr.Add("title", errors.WithParams(
+			fmt.Errorf("%w: must be %d-%d characters, got %d", errors.ErrTitleLength, rules.MinTitleLength, rules.MaxTitleLength, length),
+			errors.Params{"min": rules.MinTitleLength, "max": rules.MaxTitleLength, "length": length},
+		))
 	}
-	if event.Title == "" {
-		return errors.ErrEmptyTitle
+
+	validateTimes(verr, event)
+	validateReminders(verr, event.Reminders, uc.defaultReminderMethod)
+
+	for _, field := range rules.RequiredFields {
+		if verr.Has(field) {
+			continue
+		}
+		if value, ok := event.Field(field); ok && value == "" {
+			verr.Add(field, errors.ErrRequiredField)
+		}
 	}
-	if !isValidDate(event.Date) {
-		return errors.ErrInvalidDate
+
+	return verr.Err()
+}
+
+// validateID проверяет обязательный идентификатор: длину, управляющие символы и шаблон
+func validateID(verr *errors.ValidationError, field, id string, errEmpty error, rules tenant.ValidationRules) {
+	switch {
+	case id == "":
+		verr.Add(field, errEmpty)
+	case hasControlCharacters(id):
+		verr.Add(field, errors.ErrControlCharacters)
+	case len(id) > rules.MaxIDLength:
+		verr.Add(field, errors.WithParams(
+			fmt.Errorf("%w: longer than %d bytes", errors.ErrInvalidID, rules.MaxIDLength),
+			errors.Params{errors.ParamRule: "length", "max": rules.MaxIDLength},
+		))
+	case !idPattern(rules.IDPattern).MatchString(id):
+		verr.Add(field, errors.WithParams(
+			fmt.Errorf("%w: must match %s", errors.ErrInvalidID, rules.IDPattern),
+			errors.Params{errors.ParamRule: "pattern", "pattern": rules.IDPattern},
+		))
 	}
-	return nil
+}
+
+// validateTimes проверяет необязательное время: формат HH:MM, окончание только вместе с началом и позже него
+func validateTimes(verr *errors.ValidationError, event domain.Event) {
+	var start, end time.Time
+	var err error
+
+	if event.StartTime != "" {
+		if start, err = time.Parse(timeLayout, event.StartTime); err != nil {
+			verr.Add("start_time", errors.ErrInvalidTime)
+		}
+	}
+	if event.EndTime == "" {
+		return
+	}
+	if end, err = time.Parse(timeLayout, event.EndTime); err != nil || event.StartTime == "" {
+		verr.Add("end_time", errors.ErrInvalidTime)
+		return
+	}
+	if !verr.Has("start_time") && !end.After(start) {
+		verr.Add("end_time", errors.ErrInvalidTime)
+	}
+}
+
+// validateReminders проверяет количество напоминаний, их смещение, способ доставки и повторы.
+// Пустой способ при поиске повторов считается способом defaultMethod, которым его отправит планировщик.
+func validateReminders(verr *errors.ValidationError, reminders []domain.Reminder, defaultMethod string) {
+	if len(reminders) > domain.MaxReminders {
+		verr.Add("reminders", errors.WithParams(
+			fmt.Errorf("%w: at most %d reminders per event", errors.ErrInvalidReminder, domain.MaxReminders),
+			errors.Params{errors.ParamRule: "count", "max": domain.MaxReminders},
+		))
+		return
+	}
+
+	normalized := make([]domain.Reminder, 0, len(reminders))
+	for _, reminder := range reminders {
+		if reminder.Method == "" {
+			reminder.Method = defaultMethod
+		}
+		normalized = append(normalized, reminder)
+	}
+
+	for i, reminder := range reminders {
+		switch {
+		case reminder.MinutesBefore < 0 || reminder.MinutesBefore > domain.MaxReminderMinutes:
+			verr.Add("reminders", errors.WithParams(
+				fmt.Errorf("%w: minutes_before must be between 0 and %d, got %d", errors.ErrInvalidReminder, domain.MaxReminderMinutes, reminder.MinutesBefore),
+				errors.Params{errors.ParamRule: "minutes", "max": domain.MaxReminderMinutes, "minutes": reminder.MinutesBefore},
+			))
+		case reminder.Method != "" && !slices.Contains(domain.ReminderMethods, reminder.Method):
+			verr.Add("reminders", errors.WithParams(
+				fmt.Errorf("%w: unknown method %q", errors.ErrInvalidReminder, reminder.Method),
+				errors.Params{errors.ParamRule: "method", "method": reminder.Method},
+			))
+		case slices.Contains(normalized[:i], normalized[i]):
+			verr.Add("reminders", errors.WithParams(
+				fmt.Errorf("%w: %d minutes before via %q is listed twice", errors.ErrInvalidReminder, reminder.MinutesBefore, normalized[i].Method),
+				errors.Params{errors.ParamRule: "duplicate", "minutes": reminder.MinutesBefore, "method": normalized[i].Method},
+			))
+		default:
+			continue
+		}
+		// Одного нарушения достаточно, чтобы клиент исправил список
+		return
+	}
+}
+
+// idPattern - скомпилированный шаблон; шаблоны проверяются при загрузке конфигурации,
+// поэтому некорректный заменяется шаблоном по умолчанию
+func idPattern(pattern string) *regexp.Regexp {
+	if re, ok := idPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = regexp.MustCompile(tenant.DefaultIDPattern)
+	}
+	idPatterns.Store(pattern, re)
+	return re
+}
+
+// hasControlCharacters - содержит ли строка управляющие символы, включая переводы строк
+func hasControlCharacters(s string) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
 }
 
 // ValidateEventID проверяет валидность ID события.
@@ -47,6 +199,32 @@ func (uc *EventUseCase) validateDate(date string) error {
 	return nil
 }
 
+// validateRange проверяет даты периода, их порядок и длину периода
+func (uc *EventUseCase) validateRange(from, to string) error {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return errors.ErrInvalidDate
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return errors.ErrInvalidDate
+	}
+
+	if end.Before(start) {
+		return errors.WithParams(
+			fmt.Errorf("%w: %s is after %s", errors.ErrInvalidRange, from, to),
+			errors.Params{errors.ParamRule: "order", "from": from, "to": to},
+		)
+	}
+	if days := int(end.Sub(start).Hours()/24) + 1; uc.maxRangeDays > 0 && days > uc.maxRangeDays {
+		return errors.WithParams(
+			fmt.Errorf("%w: at most %d days, got %d", errors.ErrInvalidRange, uc.maxRangeDays, days),
+			errors.Params{errors.ParamRule: "length", "max": uc.maxRangeDays, "days": days},
+		)
+	}
+	return nil
+}
+
 // isValidDate проверяет корректность формата даты "YYYY-MM-DD".
 func isValidDate(date string) bool {
 	_, err := time.Parse("2006-01-02", date)
@@ -0,0 +1,322 @@
+package webhook_usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"calendar-server/internal/auth"
+	"calendar-server/internal/domain"
+	repo "calendar-server/internal/repository/webhook_repository"
+	"calendar-server/internal/tenant"
+	"calendar-server/internal/webhook"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/ctxlog"
+	"calendar-server/pkg/logger/zappretty"
+
+	"go.uber.org/zap"
+)
+
+// SecretPrefix - префикс ключа подписи вебхука
+const SecretPrefix = "whsec_"
+
+const (
+	// DefaultDeliveryLimit - количество доставок в журнале, если limit не указан
+	DefaultDeliveryLimit = 50
+	// MaxDeliveryLimit - максимальное количество доставок в одном ответе
+	MaxDeliveryLimit = 200
+)
+
+// eventTypes - типы изменений, на которые можно подписаться
+var eventTypes = []string{domain.ChangeCreated, domain.ChangeUpdated, domain.ChangeDeleted}
+
+// deliveryStatuses - состояния доставки для фильтра журнала
+var deliveryStatuses = []string{domain.DeliveryPending, domain.DeliverySucceeded, domain.DeliveryDead}
+
+// Redeliverer - планирование попытки доставки (реализуется webhook.Dispatcher)
+type Redeliverer interface {
+	Enqueue(delivery domain.WebhookDelivery)
+}
+
+// WebhookUseCaseContract - контракт для управления вебхуками и их доставками
+type WebhookUseCaseContract interface {
+	CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, string, error)
+	ListWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID string) error
+	ListDeliveries(ctx context.Context, webhookID, status string, limit int) ([]domain.WebhookDelivery, error)
+	ListDeadLetters(ctx context.Context, limit int) ([]domain.WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, deliveryID string) (domain.WebhookDelivery, error)
+}
+
+// WebhookUseCase - реализация WebhookUseCaseContract
+type WebhookUseCase struct {
+	repo       repo.WebhookRepository
+	dispatcher Redeliverer
+	logger     *zap.Logger
+	addresses  webhook.AddressPolicy
+	now        func() time.Time
+}
+
+// Option - функциональная опция WebhookUseCase
+type Option func(*WebhookUseCase)
+
+// WithAddressPolicy - адреса, на которые разрешено создавать вебхуки; должна совпадать с политикой диспетчера
+func WithAddressPolicy(policy webhook.AddressPolicy) Option {
+	return func(uc *WebhookUseCase) {
+		uc.addresses = policy
+	}
+}
+
+// NewWebhookUseCase - конструктор WebhookUseCase
+func NewWebhookUseCase(repo repo.WebhookRepository, dispatcher Redeliverer, logger *zap.Logger, opts ...Option) *WebhookUseCase {
+	uc := &WebhookUseCase{
+		repo:       repo,
+		dispatcher: dispatcher,
+		logger:     logger,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
+}
+
+// log - логгер из контекста запроса, либо логгер use case
+func (uc *WebhookUseCase) log(ctx context.Context) *zap.Logger {
+	return ctxlog.FromContext(ctx, uc.logger)
+}
+
+// CreateWebhook - создание подписки; ключ подписи возвращается только один раз.
+// Пользователь подписывается на свой календарь, администратор - на любой или на весь арендатор.
+func (uc *WebhookUseCase) CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, string, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return domain.Webhook{}, "", errors.ErrUnauthorized
+	}
+
+	if err := uc.validateURL(hook.URL); err != nil {
+		return domain.Webhook{}, "", err
+	}
+
+	types := make([]string, 0, len(hook.EventTypes))
+	for _, eventType := range hook.EventTypes {
+		if !slices.Contains(eventTypes, eventType) {
+			return domain.Webhook{}, "", errors.WithParams(
+				fmt.Errorf("%w: %q", errors.ErrInvalidEventType, eventType),
+				errors.Params{"type": eventType},
+			)
+		}
+		if !slices.Contains(types, eventType) {
+			types = append(types, eventType)
+		}
+	}
+	// Подписка на все типы хранится пустым списком, чтобы получать и будущие типы
+	if len(types) == len(eventTypes) {
+		types = nil
+	}
+
+	filterUserID := hook.FilterUserID
+	if !identity.IsAdmin() {
+		if filterUserID == "" {
+			filterUserID = identity.UserID
+		}
+		if filterUserID != identity.UserID {
+			return domain.Webhook{}, "", errors.ErrForbidden
+		}
+	}
+
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return domain.Webhook{}, "", err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return domain.Webhook{}, "", err
+	}
+
+	created := domain.Webhook{
+		ID:           "wh_" + id,
+		UserID:       identity.UserID,
+		TenantID:     tenant.FromContext(ctx),
+		URL:          hook.URL,
+		EventTypes:   types,
+		FilterUserID: filterUserID,
+		Secret:       SecretPrefix + secret,
+		CreatedAt:    uc.now().UTC(),
+	}
+	if err := uc.repo.Create(ctx, created); err != nil {
+		return domain.Webhook{}, "", err
+	}
+
+	uc.log(ctx).Info("Webhook created",
+		zappretty.Field("webhook_id", created.ID),
+		zappretty.Field("user_id", created.UserID),
+		zappretty.Field("url", created.URL),
+	)
+	return created, created.Secret, nil
+}
+
+// ListWebhooks - вебхуки вызывающего
+func (uc *WebhookUseCase) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, errors.ErrUnauthorized
+	}
+	return uc.repo.ListByUserID(ctx, identity.UserID)
+}
+
+// DeleteWebhook - удаление вебхука вместе с журналом доставок; ожидающие доставки отменяются
+func (uc *WebhookUseCase) DeleteWebhook(ctx context.Context, webhookID string) error {
+	hook, err := uc.ownedWebhook(ctx, webhookID)
+	if err != nil {
+		return err
+	}
+	if err := uc.repo.Delete(ctx, hook.ID); err != nil {
+		return err
+	}
+
+	uc.log(ctx).Info("Webhook deleted",
+		zappretty.Field("webhook_id", hook.ID),
+		zappretty.Field("user_id", hook.UserID),
+	)
+	return nil
+}
+
+// ListDeliveries - журнал доставок вебхука, новые первыми; пустой status означает все состояния
+func (uc *WebhookUseCase) ListDeliveries(ctx context.Context, webhookID, status string, limit int) ([]domain.WebhookDelivery, error) {
+	limit, err := deliveryLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	if status != "" && !slices.Contains(deliveryStatuses, status) {
+		return nil, InvalidDeliveryFilter(status)
+	}
+
+	hook, err := uc.ownedWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.ListDeliveries(ctx, hook.ID, status, limit)
+}
+
+// ListDeadLetters - недоставленные доставки всех вебхуков вызывающего, новые первыми
+func (uc *WebhookUseCase) ListDeadLetters(ctx context.Context, limit int) ([]domain.WebhookDelivery, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, errors.ErrUnauthorized
+	}
+
+	limit, err := deliveryLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.ListDeliveriesByUserID(ctx, identity.UserID, domain.DeliveryDead, limit)
+}
+
+// RetryDelivery - повтор недоставленной доставки с полным запасом попыток
+func (uc *WebhookUseCase) RetryDelivery(ctx context.Context, deliveryID string) (domain.WebhookDelivery, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return domain.WebhookDelivery{}, errors.ErrUnauthorized
+	}
+
+	delivery, err := uc.repo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	// Чужие доставки неотличимы от несуществующих
+	if delivery.UserID != identity.UserID {
+		return domain.WebhookDelivery{}, errors.ErrDeliveryNotFound
+	}
+	if delivery.Status != domain.DeliveryDead {
+		return domain.WebhookDelivery{}, errors.WithParams(
+			fmt.Errorf("%w: delivery is %s", errors.ErrDeliveryNotDead, delivery.Status),
+			errors.Params{"status": delivery.Status},
+		)
+	}
+
+	delivery.Status = domain.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = nil
+	delivery.CompletedAt = nil
+	if err := uc.repo.UpdateDelivery(ctx, delivery); err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	uc.dispatcher.Enqueue(delivery)
+
+	uc.log(ctx).Info("Webhook delivery retried",
+		zappretty.Field("delivery_id", delivery.ID),
+		zappretty.Field("webhook_id", delivery.WebhookID),
+	)
+	return delivery, nil
+}
+
+// InvalidDeliveryFilter - ошибка фильтра журнала доставок со значением value
+func InvalidDeliveryFilter(value string) error {
+	return errors.WithParams(
+		fmt.Errorf("%w: %q", errors.ErrInvalidDeliveryFilter, value),
+		errors.Params{"value": value, "max": MaxDeliveryLimit},
+	)
+}
+
+// ownedWebhook - получение вебхука, принадлежащего вызывающему
+func (uc *WebhookUseCase) ownedWebhook(ctx context.Context, webhookID string) (domain.Webhook, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return domain.Webhook{}, errors.ErrUnauthorized
+	}
+
+	hook, err := uc.repo.GetByID(ctx, webhookID)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	// Чужие вебхуки неотличимы от несуществующих
+	if hook.UserID != identity.UserID {
+		return domain.Webhook{}, errors.ErrWebhookNotFound
+	}
+	return hook, nil
+}
+
+// validateURL - адрес вебхука должен быть абсолютным URL http или https с хостом, разрешенным политикой адресов.
+// Адреса, полученные из DNS, проверяет клиент диспетчера при каждом соединении.
+func (uc *WebhookUseCase) validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.WithParams(
+			fmt.Errorf("%w: %q", errors.ErrInvalidWebhookURL, raw),
+			errors.Params{"url": raw},
+		)
+	}
+	if !uc.addresses.PermitsHost(u.Hostname()) {
+		return errors.WithParams(
+			fmt.Errorf("%w: %q", errors.ErrWebhookURLForbidden, raw),
+			errors.Params{"url": raw},
+		)
+	}
+	return nil
+}
+
+// deliveryLimit - проверка limit журнала доставок; 0 означает значение по умолчанию
+func deliveryLimit(limit int) (int, error) {
+	switch {
+	case limit == 0:
+		return DefaultDeliveryLimit, nil
+	case limit < 0 || limit > MaxDeliveryLimit:
+		return 0, InvalidDeliveryFilter(fmt.Sprint(limit))
+	}
+	return limit, nil
+}
+
+// randomString возвращает n криптографически случайных байт в указанной кодировке
+func randomString(n int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return encode(buf), nil
+}
@@ -0,0 +1,287 @@
+package webhook_usecase
+
+import (
+	"calendar-server/internal/auth"
+	"calendar-server/internal/domain"
+	"calendar-server/internal/repository/webhook_repository/inmemory"
+	"calendar-server/internal/tenant"
+	"calendar-server/internal/webhook"
+	"calendar-server/pkg/errors"
+	"context"
+	stdErrors "errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// recordingDispatcher - запоминает запланированные доставки
+type recordingDispatcher struct {
+	enqueued []domain.WebhookDelivery
+}
+
+func (d *recordingDispatcher) Enqueue(delivery domain.WebhookDelivery) {
+	d.enqueued = append(d.enqueued, delivery)
+}
+
+func setupTestUseCase() (*WebhookUseCase, *inmemory.WebhookRepository, *recordingDispatcher, context.Context) {
+	logger, _ := zap.NewDevelopment()
+	repo := inmemory.NewWebhookRepository(logger)
+	dispatcher := &recordingDispatcher{}
+	uc := NewWebhookUseCase(repo, dispatcher, logger)
+	ctx := tenant.WithID(context.Background(), "acme")
+	ctx = auth.WithIdentity(ctx, auth.Identity{UserID: "user-1"})
+	return uc, repo, dispatcher, ctx
+}
+
+func TestWebhookUseCase_CreateWebhook(t *testing.T) {
+	uc, _, _, ctx := setupTestUseCase()
+	admin := auth.WithIdentity(ctx, auth.Identity{UserID: "admin", Role: auth.RoleAdmin})
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		hook       domain.Webhook
+		wantErr    error
+		wantFilter string
+		wantTypes  int
+	}{
+		{
+			name:       "own calendar by default",
+			ctx:        ctx,
+			hook:       domain.Webhook{URL: "https://example.com/hook"},
+			wantFilter: "user-1",
+		},
+		{
+			name:       "duplicate event types",
+			ctx:        ctx,
+			hook:       domain.Webhook{URL: "http://hooks.example.com:9000/hook", EventTypes: []string{"created", "deleted", "created"}},
+			wantFilter: "user-1",
+			wantTypes:  2,
+		},
+		{
+			name:       "all event types stored as empty",
+			ctx:        ctx,
+			hook:       domain.Webhook{URL: "https://example.com/hook", EventTypes: []string{"created", "updated", "deleted"}},
+			wantFilter: "user-1",
+		},
+		{
+			name:       "admin subscribes to the whole tenant",
+			ctx:        admin,
+			hook:       domain.Webhook{URL: "https://example.com/hook"},
+			wantFilter: "",
+		},
+		{
+			name:       "admin subscribes to another user",
+			ctx:        admin,
+			hook:       domain.Webhook{URL: "https://example.com/hook", FilterUserID: "user-2"},
+			wantFilter: "user-2",
+		},
+		{
+			name:    "another user's calendar",
+			ctx:     ctx,
+			hook:    domain.Webhook{URL: "https://example.com/hook", FilterUserID: "user-2"},
+			wantErr: errors.ErrForbidden,
+		},
+		{
+			name:    "relative URL",
+			ctx:     ctx,
+			hook:    domain.Webhook{URL: "/hook"},
+			wantErr: errors.ErrInvalidWebhookURL,
+		},
+		{
+			name:    "unsupported scheme",
+			ctx:     ctx,
+			hook:    domain.Webhook{URL: "ftp://example.com/hook"},
+			wantErr: errors.ErrInvalidWebhookURL,
+		},
+		{
+			name:    "loopback address",
+			ctx:     ctx,
+			hook:    domain.Webhook{URL: "http://localhost:9000/hook"},
+			wantErr: errors.ErrWebhookURLForbidden,
+		},
+		{
+			name:    "private address",
+			ctx:     ctx,
+			hook:    domain.Webhook{URL: "http://10.0.0.5/hook"},
+			wantErr: errors.ErrWebhookURLForbidden,
+		},
+		{
+			name:    "cloud metadata address",
+			ctx:     admin,
+			hook:    domain.Webhook{URL: "http://169.254.169.254/latest/meta-data/"},
+			wantErr: errors.ErrWebhookURLForbidden,
+		},
+		{
+			name:    "IPv6 loopback address",
+			ctx:     ctx,
+			hook:    domain.Webhook{URL: "http://[::1]:8080/hook"},
+			wantErr: errors.ErrWebhookURLForbidden,
+		},
+		{
+			name:    "unknown event type",
+			ctx:     ctx,
+			hook:    domain.Webhook{URL: "https://example.com/hook", EventTypes: []string{"moved"}},
+			wantErr: errors.ErrInvalidEventType,
+		},
+		{
+			name:    "unauthenticated",
+			ctx:     context.Background(),
+			hook:    domain.Webhook{URL: "https://example.com/hook"},
+			wantErr: errors.ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook, secret, err := uc.CreateWebhook(tt.ctx, tt.hook)
+			if !stdErrors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if !strings.HasPrefix(secret, SecretPrefix) || hook.Secret != secret {
+				t.Errorf("Expected secret with prefix %q, got %q", SecretPrefix, secret)
+			}
+			if hook.TenantID != "acme" {
+				t.Errorf("Expected tenant acme, got %q", hook.TenantID)
+			}
+			if hook.FilterUserID != tt.wantFilter {
+				t.Errorf("Expected filter user %q, got %q", tt.wantFilter, hook.FilterUserID)
+			}
+			if len(hook.EventTypes) != tt.wantTypes {
+				t.Errorf("Expected %d event types, got %v", tt.wantTypes, hook.EventTypes)
+			}
+		})
+	}
+}
+
+func TestWebhookUseCase_AllowedNetworks(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	policy := webhook.AddressPolicy{Allowed: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")}}
+	uc := NewWebhookUseCase(inmemory.NewWebhookRepository(logger), &recordingDispatcher{}, logger, WithAddressPolicy(policy))
+	ctx := tenant.WithID(context.Background(), "acme")
+	ctx = auth.WithIdentity(ctx, auth.Identity{UserID: "user-1"})
+
+	if _, _, err := uc.CreateWebhook(ctx, domain.Webhook{URL: "http://10.20.1.2:8080/hook"}); err != nil {
+		t.Errorf("Expected allowed network to be accepted, got %v", err)
+	}
+	if _, _, err := uc.CreateWebhook(ctx, domain.Webhook{URL: "http://10.30.1.2:8080/hook"}); !stdErrors.Is(err, errors.ErrWebhookURLForbidden) {
+		t.Errorf("Expected ErrWebhookURLForbidden outside allowed networks, got %v", err)
+	}
+}
+
+func TestWebhookUseCase_Ownership(t *testing.T) {
+	uc, _, _, ctx := setupTestUseCase()
+	other := auth.WithIdentity(ctx, auth.Identity{UserID: "user-2"})
+
+	hook, _, err := uc.CreateWebhook(ctx, domain.Webhook{URL: "https://example.com/hook"})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	if hooks, _ := uc.ListWebhooks(other); len(hooks) != 0 {
+		t.Errorf("Expected no webhooks for another user, got %+v", hooks)
+	}
+	if _, err := uc.ListDeliveries(other, hook.ID, "", 0); !stdErrors.Is(err, errors.ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound for another user's deliveries, got %v", err)
+	}
+	if err := uc.DeleteWebhook(other, hook.ID); !stdErrors.Is(err, errors.ErrWebhookNotFound) {
+		t.Errorf("Expected ErrWebhookNotFound when deleting another user's webhook, got %v", err)
+	}
+
+	if err := uc.DeleteWebhook(ctx, hook.ID); err != nil {
+		t.Fatalf("Failed to delete webhook: %v", err)
+	}
+	if hooks, _ := uc.ListWebhooks(ctx); len(hooks) != 0 {
+		t.Errorf("Expected webhook to be deleted, got %+v", hooks)
+	}
+}
+
+func TestWebhookUseCase_DeliveryFilter(t *testing.T) {
+	uc, _, _, ctx := setupTestUseCase()
+
+	hook, _, err := uc.CreateWebhook(ctx, domain.Webhook{URL: "https://example.com/hook"})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		status  string
+		limit   int
+		wantErr error
+	}{
+		{name: "defaults"},
+		{name: "status", status: domain.DeliveryDead, limit: MaxDeliveryLimit},
+		{name: "unknown status", status: "lost", wantErr: errors.ErrInvalidDeliveryFilter},
+		{name: "negative limit", limit: -1, wantErr: errors.ErrInvalidDeliveryFilter},
+		{name: "limit too large", limit: MaxDeliveryLimit + 1, wantErr: errors.ErrInvalidDeliveryFilter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := uc.ListDeliveries(ctx, hook.ID, tt.status, tt.limit); !stdErrors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWebhookUseCase_RetryDelivery(t *testing.T) {
+	uc, repo, dispatcher, ctx := setupTestUseCase()
+	other := auth.WithIdentity(ctx, auth.Identity{UserID: "user-2"})
+
+	hook, _, err := uc.CreateWebhook(ctx, domain.Webhook{URL: "https://example.com/hook"})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	completedAt := time.Now().UTC()
+	for _, delivery := range []domain.WebhookDelivery{
+		{ID: "whd_dead", Status: domain.DeliveryDead, Attempts: 8, CompletedAt: &completedAt},
+		{ID: "whd_ok", Status: domain.DeliverySucceeded, Attempts: 1, CompletedAt: &completedAt},
+	} {
+		delivery.WebhookID = hook.ID
+		delivery.UserID = hook.UserID
+		delivery.TenantID = hook.TenantID
+		delivery.CreatedAt = completedAt
+		if err := repo.CreateDelivery(ctx, delivery); err != nil {
+			t.Fatalf("Failed to create delivery: %v", err)
+		}
+	}
+
+	dead, err := uc.ListDeadLetters(ctx, 0)
+	if err != nil {
+		t.Fatalf("Failed to list dead letters: %v", err)
+	}
+	if len(dead) != 1 || dead[0].ID != "whd_dead" {
+		t.Errorf("Expected only whd_dead in dead letters, got %+v", dead)
+	}
+
+	if _, err := uc.RetryDelivery(other, "whd_dead"); !stdErrors.Is(err, errors.ErrDeliveryNotFound) {
+		t.Errorf("Expected ErrDeliveryNotFound for another user's delivery, got %v", err)
+	}
+	if _, err := uc.RetryDelivery(ctx, "whd_ok"); !stdErrors.Is(err, errors.ErrDeliveryNotDead) {
+		t.Errorf("Expected ErrDeliveryNotDead for succeeded delivery, got %v", err)
+	}
+
+	retried, err := uc.RetryDelivery(ctx, "whd_dead")
+	if err != nil {
+		t.Fatalf("Failed to retry delivery: %v", err)
+	}
+	if retried.Status != domain.DeliveryPending || retried.Attempts != 0 || retried.CompletedAt != nil {
+		t.Errorf("Expected pending delivery with reset attempts, got %+v", retried)
+	}
+	if len(dispatcher.enqueued) != 1 || dispatcher.enqueued[0].ID != "whd_dead" {
+		t.Errorf("Expected whd_dead to be enqueued, got %+v", dispatcher.enqueued)
+	}
+	if dead, _ := uc.ListDeadLetters(ctx, 0); len(dead) != 0 {
+		t.Errorf("Expected no dead letters after retry, got %+v", dead)
+	}
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// deniedNetworks - диапазоны вне IsLoopback, IsPrivate и link-local, недоступные вебхукам:
+// "этот" хост, адреса провайдера (CGNAT, в том числе метаданные Alibaba Cloud 100.100.100.200),
+// тестовые сети и зарезервированные адреса
+var deniedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// AddressPolicy - адреса, на которые разрешено отправлять вебхуки. По умолчанию запрещены
+// локальные, частные, link-local (включая сервис метаданных облака 169.254.169.254), multicast
+// и зарезервированные адреса; Allowed разрешает отдельные сети, например для получателей
+// во внутренней сети.
+type AddressPolicy struct {
+	Allowed []netip.Prefix
+}
+
+// Permits - разрешен ли адрес получателя
+func (p AddressPolicy) Permits(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, network := range p.Allowed {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsMulticast() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range deniedNetworks {
+		if network.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// PermitsHost - проверка хоста из URL без обращения к DNS: IP-адрес сверяется с политикой,
+// localhost запрещен. Адреса остальных имен проверяются при соединении (см. NewClient),
+// так как ответ DNS может измениться после создания вебхука.
+func (p AddressPolicy) PermitsHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return p.Permits(addr)
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return p.Permits(netip.AddrFrom4([4]byte{127, 0, 0, 1})) || p.Permits(netip.IPv6Loopback())
+	}
+	return true
+}
+
+// NewClient - HTTP-клиент доставки: соединения с адресами, запрещенными policy, отклоняются после
+// разрешения имени, перенаправления не выполняются (ответ 3xx считается неудачной попыткой),
+// переменные окружения прокси не учитываются
+func NewClient(policy AddressPolicy) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !policy.Permits(addrPort.Addr()) {
+				return fmt.Errorf("webhook address %s is not allowed", addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,529 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	mathRand "math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/internal/pubsub"
+	repo "calendar-server/internal/repository/webhook_repository"
+	"calendar-server/internal/tenant"
+	"calendar-server/pkg/errors"
+	"calendar-server/pkg/logger/zappretty"
+	"calendar-server/pkg/metrics"
+	"calendar-server/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// UserAgent - заголовок User-Agent запросов доставки
+const UserAgent = "calendar-server-webhooks/1"
+
+// maxErrorBody - часть тела ответа получателя, которая сохраняется в журнале доставки
+const maxErrorBody = 512
+
+// Payload - тело запроса доставки
+type Payload struct {
+	// ID - ID доставки, совпадает с заголовком HeaderDelivery
+	ID        string        `json:"id"`
+	Type      string        `json:"type"`
+	WebhookID string        `json:"webhook_id"`
+	CreatedAt time.Time     `json:"created_at"`
+	Change    domain.Change `json:"change"`
+}
+
+// Settings - параметры доставки вебхуков
+type Settings struct {
+	// Workers - количество одновременных запросов
+	Workers int
+	// MaxAttempts - попытки до переноса доставки в список недоставленных
+	MaxAttempts int
+	// Backoff - задержка перед первым повтором; каждая следующая вдвое больше
+	Backoff time.Duration
+	// MaxBackoff - верхняя граница задержки, в том числе указанной получателем в Retry-After
+	MaxBackoff time.Duration
+	// Timeout - время на один запрос
+	Timeout time.Duration
+}
+
+// DefaultSettings - 4 отправителя, 8 попыток с задержкой от секунды до часа, 10 секунд на запрос
+func DefaultSettings() Settings {
+	return Settings{
+		Workers:     4,
+		MaxAttempts: 8,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Hour,
+		Timeout:     10 * time.Second,
+	}
+}
+
+// Option - функциональная опция Dispatcher
+type Option func(*Dispatcher)
+
+// WithSettings - параметры доставки
+func WithSettings(settings Settings) Option {
+	return func(d *Dispatcher) {
+		d.settings = settings
+	}
+}
+
+// WithClient - HTTP-клиент для запросов доставки вместо NewClient; таймаут задается Settings.Timeout
+func WithClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithAddressPolicy - адреса получателей, разрешенные клиенту доставки по умолчанию
+func WithAddressPolicy(policy AddressPolicy) Option {
+	return func(d *Dispatcher) {
+		d.addresses = policy
+	}
+}
+
+// job - доставка, готовая к попытке
+type job struct {
+	tenantID   string
+	deliveryID string
+}
+
+// Dispatcher - пул отправителей вебхуков. Доставки хранятся в репозитории до первой попытки,
+// поэтому после перезапуска Start продолжает ожидающие доставки с сохраненного времени повтора.
+// Гарантия - хотя бы одна доставка: получатель отбрасывает повторы по заголовку HeaderDelivery.
+type Dispatcher struct {
+	repo      repo.WebhookRepository
+	logger    *zap.Logger
+	client    *http.Client
+	addresses AddressPolicy
+	settings  Settings
+	now       func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	jobs   chan job
+	wg     sync.WaitGroup
+
+	mu sync.Mutex
+	// scheduled - таймеры доставок, ожидающих попытки, по ID доставки
+	scheduled map[string]*time.Timer
+
+	outcomes *metrics.CounterVec
+}
+
+// NewDispatcher - конструктор Dispatcher; отправители запускаются методом Start
+func NewDispatcher(repo repo.WebhookRepository, logger *zap.Logger, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		repo:      repo,
+		logger:    logger,
+		settings:  DefaultSettings(),
+		now:       time.Now,
+		scheduled: make(map[string]*time.Timer),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.client == nil {
+		d.client = NewClient(d.addresses)
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.jobs = make(chan job, d.settings.Workers)
+	return d
+}
+
+// RegisterMetrics - регистрация метрик доставки
+func (d *Dispatcher) RegisterMetrics(registry *metrics.Registry) {
+	d.outcomes = registry.NewCounterVec("calendar_webhook_attempts_total",
+		"Webhook delivery attempts by outcome: succeeded, retried or dead.", "outcome")
+	registry.NewGaugeFunc("calendar_webhook_scheduled_deliveries", "Number of webhook deliveries waiting for an attempt.", func() float64 {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return float64(len(d.scheduled))
+	})
+}
+
+// Start - запуск отправителей и планирование доставок, ожидавших попытки до перезапуска
+func (d *Dispatcher) Start(ctx context.Context) error {
+	for range d.settings.Workers {
+		d.wg.Add(1)
+		go d.work()
+	}
+
+	pending, err := d.repo.ListPending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range pending {
+		d.Enqueue(delivery)
+	}
+	if len(pending) > 0 {
+		d.logger.Info("Resumed pending webhook deliveries", zappretty.Field("count", len(pending)))
+	}
+	return nil
+}
+
+// Consume - подписка на шину и отправка изменений в фоне до отмены ctx или закрытия шины.
+// Подписка создается до возврата, поэтому изменения, опубликованные после вызова, не теряются.
+func (d *Dispatcher) Consume(ctx context.Context, hub *pubsub.Hub) error {
+	sub, err := hub.Subscribe(pubsub.Filter{AllTenants: true}, "")
+	if err != nil {
+		return err
+	}
+	go d.consume(ctx, hub, sub)
+	return nil
+}
+
+// consume - чтение изменений. Подписчик, не успевший прочитать изменения, переподписывается
+// с последнего полученного, не теряя их.
+func (d *Dispatcher) consume(ctx context.Context, hub *pubsub.Hub, sub *pubsub.Subscription) {
+	lastID := ""
+	for {
+	read:
+		for {
+			select {
+			case <-ctx.Done():
+				sub.Close()
+				return
+			case change, ok := <-sub.C():
+				if !ok {
+					break read
+				}
+				lastID = change.ID
+				if err := d.Notify(ctx, change); err != nil {
+					d.logger.Error("Failed to create webhook deliveries",
+						zappretty.Field("change_id", change.ID),
+						zappretty.Field("error", err),
+					)
+				}
+			}
+		}
+
+		if err := sub.Err(); !stdErrors.Is(err, pubsub.ErrSlowSubscriber) {
+			return
+		}
+		d.logger.Warn("Webhook dispatcher fell behind the change feed, resubscribing",
+			zappretty.Field("last_event_id", lastID),
+		)
+
+		next, err := hub.Subscribe(pubsub.Filter{AllTenants: true}, lastID)
+		if err != nil {
+			d.logger.Error("Webhook dispatcher failed to subscribe to changes", zappretty.Field("error", err))
+			return
+		}
+		sub = next
+		if sub.Reset {
+			d.logger.Warn("Webhook dispatcher missed changes that are no longer in history",
+				zappretty.Field("last_event_id", lastID),
+			)
+		}
+	}
+}
+
+// Notify - создание доставок изменения всем подходящим вебхукам его арендатора
+func (d *Dispatcher) Notify(ctx context.Context, change domain.Change) (err error) {
+	ctx = tenant.WithID(ctx, change.TenantID)
+	ctx, span := tracing.Start(ctx, "Webhook.Notify",
+		tracing.String("change.id", change.ID),
+		tracing.String("change.type", change.Type),
+	)
+	defer span.EndErr(&err)
+
+	hooks, err := d.repo.ListMatching(ctx, change)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		id, err := deliveryID(hook.ID, change.MessageID)
+		if err != nil {
+			return err
+		}
+
+		createdAt := d.now().UTC()
+		payload, err := json.Marshal(Payload{
+			ID:        id,
+			Type:      change.Type,
+			WebhookID: hook.ID,
+			CreatedAt: createdAt,
+			Change:    change,
+		})
+		if err != nil {
+			return err
+		}
+
+		delivery := domain.WebhookDelivery{
+			ID:        id,
+			WebhookID: hook.ID,
+			UserID:    hook.UserID,
+			TenantID:  hook.TenantID,
+			ChangeID:  change.ID,
+			EventType: change.Type,
+			Status:    domain.DeliveryPending,
+			Payload:   payload,
+			CreatedAt: createdAt,
+		}
+		if err := d.repo.CreateDelivery(ctx, delivery); err != nil {
+			// Вебхук удалили между выборкой и созданием доставки или изменение пришло повторно из outbox
+			if stdErrors.Is(err, errors.ErrWebhookNotFound) || stdErrors.Is(err, errors.ErrDeliveryConflict) {
+				continue
+			}
+			return err
+		}
+		d.Enqueue(delivery)
+	}
+	return nil
+}
+
+// Enqueue - планирование попытки ожидающей доставки на NextAttemptAt или немедленно
+func (d *Dispatcher) Enqueue(delivery domain.WebhookDelivery) {
+	var delay time.Duration
+	if delivery.NextAttemptAt != nil {
+		delay = delivery.NextAttemptAt.Sub(d.now())
+	}
+
+	j := job{tenantID: delivery.TenantID, deliveryID: delivery.ID}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// После Close доставка остается ожидающей в репозитории до следующего запуска
+	if d.ctx.Err() != nil {
+		return
+	}
+	if timer, ok := d.scheduled[delivery.ID]; ok {
+		timer.Stop()
+	}
+	d.scheduled[delivery.ID] = time.AfterFunc(delay, func() {
+		select {
+		case d.jobs <- j:
+		case <-d.ctx.Done():
+		}
+	})
+}
+
+// Close - остановка отправителей. Прерванные попытки не засчитываются, доставки остаются ожидающими.
+func (d *Dispatcher) Close() error {
+	d.mu.Lock()
+	d.cancel()
+	for id, timer := range d.scheduled {
+		timer.Stop()
+		delete(d.scheduled, id)
+	}
+	d.mu.Unlock()
+
+	d.wg.Wait()
+	return nil
+}
+
+// work - цикл отправителя
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case j := <-d.jobs:
+			d.process(j)
+		}
+	}
+}
+
+// process - попытка доставки, если она еще ожидает отправки и вебхук не удален
+func (d *Dispatcher) process(j job) {
+	d.mu.Lock()
+	delete(d.scheduled, j.deliveryID)
+	d.mu.Unlock()
+
+	ctx := tenant.WithID(d.ctx, j.tenantID)
+
+	delivery, err := d.repo.GetDelivery(ctx, j.deliveryID)
+	if err != nil {
+		// Доставки удаляются вместе с вебхуком
+		if !stdErrors.Is(err, errors.ErrDeliveryNotFound) {
+			d.logger.Error("Failed to load webhook delivery",
+				zappretty.Field("delivery_id", j.deliveryID),
+				zappretty.Field("error", err),
+			)
+		}
+		return
+	}
+	if delivery.Status != domain.DeliveryPending {
+		return
+	}
+
+	hook, err := d.repo.GetByID(ctx, delivery.WebhookID)
+	if err != nil {
+		return
+	}
+
+	d.attempt(ctx, hook, delivery)
+}
+
+// attempt - отправка запроса и сохранение результата: успех, повтор или перенос в недоставленные
+func (d *Dispatcher) attempt(ctx context.Context, hook domain.Webhook, delivery domain.WebhookDelivery) {
+	ctx, span := tracing.Start(ctx, "Webhook.Deliver",
+		tracing.String("webhook.id", hook.ID),
+		tracing.String("delivery.id", delivery.ID),
+		tracing.Int("delivery.attempt", delivery.Attempts+1),
+	)
+	defer span.End()
+
+	status, retryAfter, body, err := d.send(ctx, hook, delivery)
+	if err != nil && d.ctx.Err() != nil {
+		return
+	}
+
+	now := d.now().UTC()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.LastError = ""
+	delivery.NextAttemptAt = nil
+
+	outcome := "retried"
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		outcome = domain.DeliverySucceeded
+	case err != nil:
+		delivery.LastError = err.Error()
+		span.RecordError(err)
+	default:
+		delivery.LastError = fmt.Sprintf("unexpected response status %d", status)
+		if body != "" {
+			delivery.LastError += ": " + body
+		}
+		// Остальные ответы 4xx - отказ получателя, повтор его не изменит
+		if status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests {
+			outcome = domain.DeliveryDead
+		}
+	}
+	if outcome == "retried" && delivery.Attempts >= d.settings.MaxAttempts {
+		outcome = domain.DeliveryDead
+	}
+
+	switch outcome {
+	case domain.DeliverySucceeded, domain.DeliveryDead:
+		delivery.Status = outcome
+		delivery.CompletedAt = &now
+	default:
+		next := now.Add(max(d.backoff(delivery.Attempts), min(retryAfter, d.settings.MaxBackoff)))
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+		if !stdErrors.Is(err, errors.ErrDeliveryNotFound) {
+			d.logger.Error("Failed to save webhook delivery",
+				zappretty.Field("delivery_id", delivery.ID),
+				zappretty.Field("error", err),
+			)
+		}
+		return
+	}
+	if d.outcomes != nil {
+		d.outcomes.Inc(outcome)
+	}
+
+	fields := []zap.Field{
+		zappretty.Field("webhook_id", hook.ID),
+		zappretty.Field("delivery_id", delivery.ID),
+		zappretty.Field("attempt", delivery.Attempts),
+		zappretty.Field("status", status),
+	}
+	switch outcome {
+	case domain.DeliverySucceeded:
+		d.logger.Debug("Webhook delivered", fields...)
+	case domain.DeliveryDead:
+		d.logger.Warn("Webhook delivery moved to dead letters",
+			append(fields, zappretty.Field("error", delivery.LastError))...)
+	default:
+		d.Enqueue(delivery)
+		d.logger.Info("Webhook delivery failed, retry scheduled",
+			append(fields,
+				zappretty.Field("next_attempt_at", delivery.NextAttemptAt),
+				zappretty.Field("error", delivery.LastError),
+			)...)
+	}
+}
+
+// send - запрос к получателю: код ответа, задержка из Retry-After и начало тела ответа
+func (d *Dispatcher) send(ctx context.Context, hook domain.Webhook, delivery domain.WebhookDelivery) (int, time.Duration, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.settings.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, d.now(), delivery.Payload))
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	defer resp.Body.Close()
+
+	// Тело дочитывается, чтобы соединение вернулось в пул; в журнал попадает только начало
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, retryAfter(resp.Header), string(bytes.TrimSpace(body)), nil
+}
+
+// backoff - задержка перед повтором после attempts попыток: удваивается с каждой попыткой и
+// выбирается случайно между половиной и полным значением, чтобы повторы многих доставок не совпадали
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.settings.Backoff
+	for i := 1; i < attempts && delay < d.settings.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, d.settings.MaxBackoff)
+	if half := int64(delay / 2); half > 0 {
+		delay = delay/2 + time.Duration(mathRand.Int64N(half+1))
+	}
+	return delay
+}
+
+// retryAfter - задержка из заголовка Retry-After в секундах или в виде даты
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// deliveryID - ID доставки изменения вебхуку. Для изменения из outbox ID выводится из MessageID,
+// поэтому повторно отправленное изменение не создает вторую доставку; иначе ID случайный.
+func deliveryID(webhookID, messageID string) (string, error) {
+	if messageID != "" {
+		sum := sha256.Sum256([]byte(webhookID + "/" + messageID))
+		return "whd_" + hex.EncodeToString(sum[:12]), nil
+	}
+
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whd_" + hex.EncodeToString(buf), nil
+}
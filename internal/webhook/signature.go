@@ -0,0 +1,83 @@
+// Package webhook - доставка изменений событий во внешние системы: подпись запросов,
+// пул отправителей и повторы с экспоненциальной задержкой.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	stdErrors "errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запроса доставки
+const (
+	// HeaderDelivery - ID доставки, одинаковый во всех попытках; получатель отбрасывает повторы по нему
+	HeaderDelivery = "X-Webhook-Delivery"
+	// HeaderEvent - тип изменения
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderSignature - подпись "t=<unix time>,v1=<hex HMAC-SHA256>"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// DefaultTolerance - допустимое расхождение времени подписи с часами получателя
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature - подпись отсутствует, повреждена или не совпадает
+	ErrInvalidSignature = stdErrors.New("invalid webhook signature")
+	// ErrSignatureExpired - подпись сделана раньше допустимого окна; защищает от повторной отправки перехваченного запроса
+	ErrSignatureExpired = stdErrors.New("webhook signature timestamp is outside the tolerance")
+)
+
+// Sign - значение заголовка HeaderSignature для тела body, отправленного в момент at.
+// Подписывается строка "<unix time>.<body>", чтобы время нельзя было подменить.
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac(secret, timestamp, body))
+}
+
+// Verify - проверка заголовка HeaderSignature на стороне получателя
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			// Несколько v1 допускаются на время смены секрета
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if diff := now.Sub(time.Unix(unix, 0)); diff > tolerance || diff < -tolerance {
+		return ErrSignatureExpired
+	}
+
+	expected := mac(secret, timestamp, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// mac - HMAC-SHA256 от "<timestamp>.<body>"
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,404 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"calendar-server/internal/domain"
+	"calendar-server/internal/pubsub"
+	"calendar-server/internal/repository/webhook_repository/inmemory"
+	"calendar-server/internal/tenant"
+
+	"go.uber.org/zap"
+)
+
+const testSecret = "whsec_test"
+
+// receiver - тестовый получатель вебхуков: проверяет подпись и отвечает кодами из statuses по очереди
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+	server   *httptest.Server
+}
+
+type receivedRequest struct {
+	deliveryID string
+	payload    Payload
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rc := &receiver{t: t, statuses: statuses}
+	rc.server = httptest.NewServer(http.HandlerFunc(rc.serve))
+	t.Cleanup(rc.server.Close)
+	return rc
+}
+
+func (rc *receiver) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := Verify(testSecret, r.Header.Get(HeaderSignature), body, DefaultTolerance, time.Now()); err != nil {
+		rc.t.Errorf("Expected valid signature, got %v", err)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		rc.t.Errorf("Failed to decode payload: %v", err)
+	}
+	if payload.ID != r.Header.Get(HeaderDelivery) {
+		rc.t.Errorf("Expected payload id %q to match header, got %q", r.Header.Get(HeaderDelivery), payload.ID)
+	}
+
+	rc.mu.Lock()
+	status := http.StatusOK
+	if n := len(rc.requests); n < len(rc.statuses) {
+		status = rc.statuses[n]
+	}
+	rc.requests = append(rc.requests, receivedRequest{deliveryID: payload.ID, payload: payload})
+	rc.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) received() []receivedRequest {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]receivedRequest(nil), rc.requests...)
+}
+
+func testSettings() Settings {
+	return Settings{
+		Workers:     2,
+		MaxAttempts: 3,
+		Backoff:     5 * time.Millisecond,
+		MaxBackoff:  20 * time.Millisecond,
+		Timeout:     time.Second,
+	}
+}
+
+func newTestDispatcher(t *testing.T, repo *inmemory.WebhookRepository) *Dispatcher {
+	// Тестовые получатели слушают loopback, запрещенный политикой по умолчанию
+	loopback := AddressPolicy{Allowed: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}
+	d := NewDispatcher(repo, zap.NewNop(), WithSettings(testSettings()), WithAddressPolicy(loopback))
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start dispatcher: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	return d
+}
+
+func createHook(t *testing.T, repo *inmemory.WebhookRepository, hook domain.Webhook) {
+	hook.TenantID = "acme"
+	hook.UserID = "user-1"
+	hook.Secret = testSecret
+	if err := repo.Create(tenant.WithID(context.Background(), "acme"), hook); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+}
+
+// waitDelivery - ожидание, пока единственная доставка вебхука перейдет в состояние status
+func waitDelivery(t *testing.T, repo *inmemory.WebhookRepository, webhookID, status string) domain.WebhookDelivery {
+	t.Helper()
+	ctx := tenant.WithID(context.Background(), "acme")
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries, err := repo.ListDeliveries(ctx, webhookID, "", 10)
+		if err != nil {
+			t.Fatalf("Failed to list deliveries: %v", err)
+		}
+		if len(deliveries) == 1 && deliveries[0].Status == status {
+			return deliveries[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected delivery of %s to become %s", webhookID, status)
+	return domain.WebhookDelivery{}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"whd_1"}`)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	valid := Sign(testSecret, now, body)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{name: "valid", secret: testSecret, header: valid, body: body, now: now},
+		{name: "clock skew within tolerance", secret: testSecret, header: valid, body: body, now: now.Add(-time.Minute)},
+		{name: "rotated secret", secret: testSecret, header: valid + ",v1=00ff", body: body, now: now},
+		{name: "wrong secret", secret: "other", header: valid, body: body, now: now, want: ErrInvalidSignature},
+		{name: "tampered body", secret: testSecret, header: valid, body: []byte(`{"id":"whd_2"}`), now: now, want: ErrInvalidSignature},
+		{name: "expired", secret: testSecret, header: valid, body: body, now: now.Add(time.Hour), want: ErrSignatureExpired},
+		{name: "missing signature", secret: testSecret, header: "t=1760788800", body: body, now: now, want: ErrInvalidSignature},
+		{name: "garbage", secret: testSecret, header: "garbage", body: body, now: now, want: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, DefaultTolerance, tt.now)
+			if !stdErrors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestAddressPolicy(t *testing.T) {
+	allowed := []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16"), netip.MustParsePrefix("127.0.0.1/32")}
+
+	tests := []struct {
+		host        string
+		denied      bool
+		withAllowed bool
+	}{
+		{host: "example.com"},
+		{host: "93.184.216.34"},
+		{host: "[2606:2800:220:1::1]"},
+		{host: "127.0.0.1", denied: true, withAllowed: true},
+		{host: "127.0.0.2", denied: true},
+		{host: "localhost", denied: true, withAllowed: true},
+		{host: "api.localhost.", denied: true, withAllowed: true},
+		{host: "[::1]", denied: true},
+		{host: "0.0.0.0", denied: true},
+		{host: "10.0.0.1", denied: true},
+		{host: "10.1.2.3", denied: true, withAllowed: true},
+		{host: "172.16.5.4", denied: true},
+		{host: "192.168.1.1", denied: true},
+		{host: "169.254.169.254", denied: true},
+		{host: "[::ffff:169.254.169.254]", denied: true},
+		{host: "100.100.100.200", denied: true},
+		{host: "[fd00:ec2::254]", denied: true},
+		{host: "[fe80::1]", denied: true},
+		{host: "224.0.0.1", denied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := (AddressPolicy{}).PermitsHost(tt.host); got == tt.denied {
+				t.Errorf("Expected permitted=%v by default, got %v", !tt.denied, got)
+			}
+			if got := (AddressPolicy{Allowed: allowed}).PermitsHost(tt.host); got != (!tt.denied || tt.withAllowed) {
+				t.Errorf("Expected permitted=%v with allowed networks, got %v", !tt.denied || tt.withAllowed, got)
+			}
+		})
+	}
+}
+
+func TestDispatcher_ForbiddenAddress(t *testing.T) {
+	rc := newReceiver(t)
+	repo := inmemory.NewWebhookRepository(zap.NewNop())
+	createHook(t, repo, domain.Webhook{ID: "wh-1", URL: rc.server.URL})
+
+	// Политика по умолчанию не пускает к получателю на loopback
+	d := NewDispatcher(repo, zap.NewNop(), WithSettings(testSettings()))
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start dispatcher: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+
+	change := domain.Change{ID: "e-1", Type: domain.ChangeCreated, TenantID: "acme", UserID: "user-1", EventID: "event-1"}
+	if err := d.Notify(context.Background(), change); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+
+	delivery := waitDelivery(t, repo, "wh-1", domain.DeliveryDead)
+	if !strings.Contains(delivery.LastError, "is not allowed") {
+		t.Errorf("Expected forbidden address error, got %q", delivery.LastError)
+	}
+	if n := len(rc.received()); n != 0 {
+		t.Errorf("Expected no requests to reach the receiver, got %d", n)
+	}
+}
+
+func TestDispatcher_DoesNotFollowRedirects(t *testing.T) {
+	rc := newReceiver(t)
+	redirect := httptest.NewServer(http.RedirectHandler(rc.server.URL, http.StatusTemporaryRedirect))
+	t.Cleanup(redirect.Close)
+
+	repo := inmemory.NewWebhookRepository(zap.NewNop())
+	createHook(t, repo, domain.Webhook{ID: "wh-1", URL: redirect.URL})
+	d := newTestDispatcher(t, repo)
+
+	change := domain.Change{ID: "e-1", Type: domain.ChangeCreated, TenantID: "acme", UserID: "user-1", EventID: "event-1"}
+	if err := d.Notify(context.Background(), change); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+
+	delivery := waitDelivery(t, repo, "wh-1", domain.DeliveryDead)
+	if delivery.ResponseStatus != http.StatusTemporaryRedirect {
+		t.Errorf("Expected redirect status to be recorded, got %d", delivery.ResponseStatus)
+	}
+	if n := len(rc.received()); n != 0 {
+		t.Errorf("Expected redirect not to be followed, got %d requests", n)
+	}
+}
+
+func TestDispatcher_RetryThenSuccess(t *testing.T) {
+	rc := newReceiver(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+	repo := inmemory.NewWebhookRepository(zap.NewNop())
+	createHook(t, repo, domain.Webhook{ID: "wh-1", URL: rc.server.URL})
+	d := newTestDispatcher(t, repo)
+
+	change := domain.Change{ID: "e-1", Type: domain.ChangeCreated, TenantID: "acme", UserID: "user-1", EventID: "event-1"}
+	if err := d.Notify(context.Background(), change); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+
+	delivery := waitDelivery(t, repo, "wh-1", domain.DeliverySucceeded)
+	if delivery.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", delivery.Attempts)
+	}
+	if delivery.LastError != "" || delivery.NextAttemptAt != nil || delivery.CompletedAt == nil {
+		t.Errorf("Expected completed delivery without error, got %+v", delivery)
+	}
+
+	requests := rc.received()
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(requests))
+	}
+	for _, req := range requests {
+		if req.deliveryID != delivery.ID {
+			t.Errorf("Expected delivery id %q in every attempt, got %q", delivery.ID, req.deliveryID)
+		}
+		if req.payload.Type != domain.ChangeCreated || req.payload.Change.EventID != "event-1" || req.payload.WebhookID != "wh-1" {
+			t.Errorf("Unexpected payload %+v", req.payload)
+		}
+	}
+}
+
+func TestDispatcher_DeadLetters(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int
+		wantStatus   int
+	}{
+		{
+			name:         "attempts exhausted",
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusServiceUnavailable},
+			wantAttempts: 3,
+			wantStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			name:         "rejected by receiver",
+			statuses:     []int{http.StatusGone},
+			wantAttempts: 1,
+			wantStatus:   http.StatusGone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newReceiver(t, tt.statuses...)
+			repo := inmemory.NewWebhookRepository(zap.NewNop())
+			createHook(t, repo, domain.Webhook{ID: "wh-1", URL: rc.server.URL})
+			d := newTestDispatcher(t, repo)
+
+			change := domain.Change{ID: "e-1", Type: domain.ChangeDeleted, TenantID: "acme", UserID: "user-1", EventID: "event-1"}
+			if err := d.Notify(context.Background(), change); err != nil {
+				t.Fatalf("Failed to notify: %v", err)
+			}
+
+			delivery := waitDelivery(t, repo, "wh-1", domain.DeliveryDead)
+			if delivery.Attempts != tt.wantAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.wantAttempts, delivery.Attempts)
+			}
+			if delivery.ResponseStatus != tt.wantStatus {
+				t.Errorf("Expected response status %d, got %d", tt.wantStatus, delivery.ResponseStatus)
+			}
+			if delivery.LastError == "" {
+				t.Error("Expected last error to be recorded")
+			}
+
+			// Недоставленная доставка больше не отправляется
+			time.Sleep(50 * time.Millisecond)
+			if n := len(rc.received()); n != tt.wantAttempts {
+				t.Errorf("Expected %d requests, got %d", tt.wantAttempts, n)
+			}
+		})
+	}
+}
+
+func TestDispatcher_Consume(t *testing.T) {
+	rc := newReceiver(t)
+	repo := inmemory.NewWebhookRepository(zap.NewNop())
+	createHook(t, repo, domain.Webhook{ID: "wh-all", URL: rc.server.URL})
+	createHook(t, repo, domain.Webhook{ID: "wh-user-2", URL: rc.server.URL, FilterUserID: "user-2"})
+	createHook(t, repo, domain.Webhook{ID: "wh-deleted", URL: rc.server.URL, EventTypes: []string{domain.ChangeDeleted}})
+	d := newTestDispatcher(t, repo)
+
+	hub := pubsub.NewHub(pubsub.DefaultBuffer, pubsub.DefaultHistory)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Consume(ctx, hub); err != nil {
+		t.Fatalf("Failed to consume changes: %v", err)
+	}
+
+	// Повтор сообщения outbox с тем же MessageID не создает второй доставки
+	change := domain.Change{MessageID: "msg-1", Type: domain.ChangeCreated, TenantID: "acme", UserID: "user-1", EventID: "event-1"}
+	hub.Publish(change)
+	hub.Publish(change)
+	hub.Publish(domain.Change{MessageID: "msg-2", Type: domain.ChangeCreated, TenantID: "other", UserID: "user-1", EventID: "event-2"})
+
+	delivery := waitDelivery(t, repo, "wh-all", domain.DeliverySucceeded)
+	if delivery.ChangeID == "" {
+		t.Error("Expected change id in delivery")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	requests := rc.received()
+	if len(requests) != 1 {
+		t.Fatalf("Expected exactly one request, got %d", len(requests))
+	}
+	if requests[0].payload.WebhookID != "wh-all" || requests[0].payload.Change.MessageID != "msg-1" {
+		t.Errorf("Expected msg-1 delivered to wh-all, got %+v", requests[0].payload)
+	}
+}
+
+func TestDispatcher_ResumePending(t *testing.T) {
+	rc := newReceiver(t)
+	repo := inmemory.NewWebhookRepository(zap.NewNop())
+	createHook(t, repo, domain.Webhook{ID: "wh-1", URL: rc.server.URL})
+
+	// Доставка, оставшаяся ожидающей до перезапуска
+	next := time.Now().Add(10 * time.Millisecond)
+	payload, _ := json.Marshal(Payload{ID: "whd_pending", Type: domain.ChangeUpdated, WebhookID: "wh-1"})
+	pending := domain.WebhookDelivery{
+		ID:            "whd_pending",
+		WebhookID:     "wh-1",
+		TenantID:      "acme",
+		EventType:     domain.ChangeUpdated,
+		Status:        domain.DeliveryPending,
+		Attempts:      1,
+		Payload:       payload,
+		CreatedAt:     time.Now(),
+		NextAttemptAt: &next,
+	}
+	if err := repo.CreateDelivery(tenant.WithID(context.Background(), "acme"), pending); err != nil {
+		t.Fatalf("Failed to create delivery: %v", err)
+	}
+
+	newTestDispatcher(t, repo)
+
+	delivery := waitDelivery(t, repo, "wh-1", domain.DeliverySucceeded)
+	if delivery.Attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", delivery.Attempts)
+	}
+	if requests := rc.received(); len(requests) != 1 || requests[0].deliveryID != "whd_pending" {
+		t.Errorf("Expected one request for whd_pending, got %+v", requests)
+	}
+}
@@ -8,6 +8,26 @@ var (
 	ErrMissingParameters = errors.New("missing required parameters")
 	ErrUnsupportedMedia  = errors.New("unsupported media type")
 
+	// Auth errors
+	ErrUnauthorized = errors.New("authentication required")
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrForbidden    = errors.New("access to another user's calendar is forbidden")
+	ErrAdminOnly    = errors.New("administrator role required")
+
+	// Tenant errors
+	ErrUnknownTenant  = errors.New("unknown tenant")
+	ErrTenantDisabled = errors.New("tenant is disabled")
+	ErrTenantMismatch = errors.New("token is not valid for the requested tenant")
+	ErrTenantRequired = errors.New("token is not bound to a tenant")
+	ErrQuotaExceeded  = errors.New("quota exceeded")
+
+	// API key errors
+	ErrAPIKeyNotFound    = errors.New("API key not found")
+	ErrAPIKeyConflict    = errors.New("API key with this ID already exists")
+	ErrEmptyAPIKeyName   = errors.New("API key name cannot be empty")
+	ErrInvalidScope      = errors.New("unknown or not permitted scope")
+	ErrInsufficientScope = errors.New("token does not have the required scope")
+
 	// Event errors
 	ErrEventNotFound = errors.New("event not found")
 	ErrInvalidDate   = errors.New("invalid date format, expected YYYY-MM-DD")
@@ -15,4 +35,54 @@ var (
 	ErrEmptyEventID  = errors.New("event ID cannot be empty")
 	ErrEmptyUserID   = errors.New("user ID cannot be empty")
 	ErrEmptyTitle    = errors.New("event title cannot be empty")
+	ErrInvalidTime   = errors.New("invalid time, expected HH:MM with end after start")
+	ErrInvalidRange  = errors.New("invalid date range")
+
+	// Import errors
+	ErrInvalidCalendar     = errors.New("invalid iCalendar data")
+	ErrTooManyOccurrences  = errors.New("recurring event has too many occurrences")
+	ErrInvalidImportOption = errors.New("invalid import option")
+	ErrImportTooLarge      = errors.New("import is too large")
+	ErrInvalidTimeZone     = errors.New("unknown time zone")
+	ErrInvalidRecord       = errors.New("invalid CSV or JSON Lines record")
+
+	// CalDAV errors
+	ErrInvalidXML         = errors.New("invalid XML body")
+	ErrPreconditionFailed = errors.New("resource has been modified or already exists")
+	ErrUnsupportedReport  = errors.New("unsupported REPORT type")
+
+	// Change feed errors
+	ErrInvalidLastEventID = errors.New("invalid Last-Event-ID")
+
+	// WebSocket errors
+	ErrInvalidHandshake     = errors.New("invalid WebSocket handshake")
+	ErrOriginNotAllowed     = errors.New("origin is not allowed")
+	ErrUnknownCommand       = errors.New("unknown command")
+	ErrTooManySubscriptions = errors.New("too many subscriptions")
+	ErrRateLimited          = errors.New("rate limit exceeded")
+
+	// Webhook errors
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrInvalidWebhookURL     = errors.New("webhook URL must be an absolute http or https URL")
+	ErrWebhookURLForbidden   = errors.New("webhook URL points to a forbidden address")
+	ErrInvalidEventType      = errors.New("unknown change type")
+	ErrDeliveryNotFound      = errors.New("webhook delivery not found")
+	ErrDeliveryConflict      = errors.New("webhook delivery with this ID already exists")
+	ErrDeliveryNotDead       = errors.New("only dead-lettered deliveries can be retried")
+	ErrInvalidDeliveryFilter = errors.New("invalid delivery status or limit")
+
+	// Reminder errors
+	ErrReminderFired = errors.New("reminder has already fired")
+
+	// Digest errors
+	ErrDigestNotFound = errors.New("digest subscription not found")
+	ErrInvalidDigest  = errors.New("invalid digest settings")
+
+	// Validation errors
+	ErrTitleLength       = errors.New("event title length is out of range")
+	ErrControlCharacters = errors.New("value contains control characters")
+	ErrDateOutOfRange    = errors.New("event date is out of the allowed range")
+	ErrInvalidID         = errors.New("identifier is too long or contains invalid characters")
+	ErrRequiredField     = errors.New("field is required")
+	ErrInvalidReminder   = errors.New("invalid reminder")
 )
@@ -0,0 +1,39 @@
+package errors
+
+import "errors"
+
+// ParamRule - параметр с уточнением правила; выбирает вариант локализованного сообщения
+const ParamRule = "rule"
+
+// Params - значения для подстановки в локализованные сообщения об ошибке
+type Params map[string]any
+
+// paramsError - ошибка с параметрами сообщения
+type paramsError struct {
+	err    error
+	params Params
+}
+
+// WithParams - прикрепляет к ошибке параметры сообщения; текст и errors.Is не меняются
+func WithParams(err error, params Params) error {
+	return &paramsError{err: err, params: params}
+}
+
+// Error - реализация error
+func (e *paramsError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap - исходная ошибка
+func (e *paramsError) Unwrap() error {
+	return e.err
+}
+
+// ParamsOf - параметры сообщения ошибки или nil
+func ParamsOf(err error) Params {
+	var pe *paramsError
+	if errors.As(err, &pe) {
+		return pe.params
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package errors
+
+import "fmt"
+
+// Названия лимитов квоты
+const (
+	LimitTenantEvents = "tenant_events"
+	LimitUserEvents   = "user_events"
+	LimitDayEvents    = "day_events"
+	LimitTitleLength  = "title_length"
+)
+
+// QuotaError - превышение конкретного лимита квоты
+type QuotaError struct {
+	Limit   string
+	Max     int
+	Current int
+}
+
+// Error - реализация error
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: %s limit is %d, current %d", ErrQuotaExceeded, e.Limit, e.Max, e.Current)
+}
+
+// Is - QuotaError соответствует ErrQuotaExceeded
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
@@ -0,0 +1,301 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code - стабильный машиночитаемый код ошибки; не меняется вместе с текстом сообщения
+type Code string
+
+// Коды ошибок API
+const (
+	CodeInternal          Code = "internal_error"
+	CodeInvalidJSON       Code = "invalid_json"
+	CodeMissingParameters Code = "missing_parameters"
+	CodeUnsupportedMedia  Code = "unsupported_media_type"
+
+	CodeUnauthorized Code = "unauthorized"
+	CodeInvalidToken Code = "invalid_token"
+	CodeForbidden    Code = "forbidden"
+	CodeAdminOnly    Code = "admin_only"
+
+	CodeUnknownTenant  Code = "unknown_tenant"
+	CodeTenantDisabled Code = "tenant_disabled"
+	CodeTenantMismatch Code = "tenant_mismatch"
+	CodeTenantRequired Code = "tenant_required"
+	CodeQuotaExceeded  Code = "quota_exceeded"
+	CodeTitleTooLong   Code = "title_too_long"
+
+	CodeAPIKeyNotFound    Code = "api_key_not_found"
+	CodeAPIKeyConflict    Code = "api_key_conflict"
+	CodeEmptyAPIKeyName   Code = "empty_api_key_name"
+	CodeInvalidScope      Code = "invalid_scope"
+	CodeInsufficientScope Code = "insufficient_scope"
+
+	CodeEventNotFound Code = "event_not_found"
+	CodeInvalidDate   Code = "invalid_date"
+	CodeEventConflict Code = "event_conflict"
+	CodeEmptyEventID  Code = "empty_event_id"
+	CodeEmptyUserID   Code = "empty_user_id"
+	CodeEmptyTitle    Code = "empty_title"
+	CodeInvalidTime   Code = "invalid_time"
+	CodeInvalidRange  Code = "invalid_range"
+
+	CodeInvalidCalendar     Code = "invalid_calendar"
+	CodeTooManyOccurrences  Code = "too_many_occurrences"
+	CodeInvalidImportOption Code = "invalid_import_option"
+	CodeImportTooLarge      Code = "import_too_large"
+	CodeInvalidTimeZone     Code = "invalid_time_zone"
+	CodeInvalidRecord       Code = "invalid_record"
+
+	CodeInvalidXML         Code = "invalid_xml"
+	CodePreconditionFailed Code = "precondition_failed"
+	CodeUnsupportedReport  Code = "unsupported_report"
+
+	CodeInvalidLastEventID Code = "invalid_last_event_id"
+
+	CodeInvalidHandshake     Code = "invalid_handshake"
+	CodeOriginNotAllowed     Code = "origin_not_allowed"
+	CodeUnknownCommand       Code = "unknown_command"
+	CodeTooManySubscriptions Code = "too_many_subscriptions"
+	CodeRateLimited          Code = "rate_limited"
+
+	CodeWebhookNotFound       Code = "webhook_not_found"
+	CodeInvalidWebhookURL     Code = "invalid_webhook_url"
+	CodeWebhookURLForbidden   Code = "webhook_url_forbidden"
+	CodeInvalidEventType      Code = "invalid_event_type"
+	CodeDeliveryNotFound      Code = "delivery_not_found"
+	CodeDeliveryConflict      Code = "delivery_conflict"
+	CodeDeliveryNotDead       Code = "delivery_not_dead"
+	CodeInvalidDeliveryFilter Code = "invalid_delivery_filter"
+
+	CodeReminderFired Code = "reminder_fired"
+
+	CodeDigestNotFound Code = "digest_not_found"
+	CodeInvalidDigest  Code = "invalid_digest"
+
+	CodeValidationFailed  Code = "validation_failed"
+	CodeTitleLength       Code = "invalid_title_length"
+	CodeControlCharacters Code = "control_characters"
+	CodeDateOutOfRange    Code = "date_out_of_range"
+	CodeInvalidID         Code = "invalid_id"
+	CodeRequiredField     Code = "required"
+	CodeInvalidReminder   Code = "invalid_reminder"
+)
+
+// FieldError - нарушение, относящееся к конкретному полю запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	// Params - значения для локализованного сообщения
+	Params Params `json:"-"`
+}
+
+// Error - типизированная ошибка API: код, HTTP-статус, заголовок и детали по полям.
+// Unwrap возвращает исходную ошибку, поэтому errors.Is с прежними сентинелами продолжает работать.
+type Error struct {
+	Code   Code
+	Status int
+	Title  string
+	Detail string
+	Fields []FieldError
+	// Params - значения для локализованного сообщения
+	Params Params
+	Err    error
+}
+
+// Error - реализация error
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// Unwrap - исходная ошибка
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// definition - описание известной ошибки
+type definition struct {
+	err    error
+	code   Code
+	status int
+	title  string
+	// field - поле запроса, к которому относится ошибка валидации
+	field string
+}
+
+// definitions - соответствие сентинелов кодам и статусам
+var definitions = []definition{
+	{ErrInvalidJSON, CodeInvalidJSON, http.StatusBadRequest, "Invalid JSON", ""},
+	{ErrMissingParameters, CodeMissingParameters, http.StatusBadRequest, "Missing parameters", ""},
+	{ErrUnsupportedMedia, CodeUnsupportedMedia, http.StatusBadRequest, "Unsupported media type", ""},
+
+	{ErrUnauthorized, CodeUnauthorized, http.StatusUnauthorized, "Authentication required", ""},
+	{ErrInvalidToken, CodeInvalidToken, http.StatusUnauthorized, "Invalid token", ""},
+	{ErrForbidden, CodeForbidden, http.StatusForbidden, "Forbidden", ""},
+	{ErrAdminOnly, CodeAdminOnly, http.StatusForbidden, "Administrator role required", ""},
+
+	{ErrUnknownTenant, CodeUnknownTenant, http.StatusForbidden, "Unknown tenant", ""},
+	{ErrTenantDisabled, CodeTenantDisabled, http.StatusForbidden, "Tenant disabled", ""},
+	{ErrTenantMismatch, CodeTenantMismatch, http.StatusForbidden, "Tenant mismatch", ""},
+	{ErrTenantRequired, CodeTenantRequired, http.StatusForbidden, "Tenant required", ""},
+
+	{ErrAPIKeyNotFound, CodeAPIKeyNotFound, http.StatusNotFound, "API key not found", ""},
+	{ErrAPIKeyConflict, CodeAPIKeyConflict, http.StatusConflict, "API key conflict", ""},
+	{ErrEmptyAPIKeyName, CodeEmptyAPIKeyName, http.StatusBadRequest, "Validation failed", "name"},
+	{ErrInvalidScope, CodeInvalidScope, http.StatusBadRequest, "Validation failed", "scopes"},
+	{ErrInsufficientScope, CodeInsufficientScope, http.StatusForbidden, "Insufficient scope", ""},
+
+	{ErrEventNotFound, CodeEventNotFound, http.StatusNotFound, "Event not found", ""},
+	{ErrInvalidDate, CodeInvalidDate, http.StatusBadRequest, "Validation failed", "date"},
+	{ErrEventConflict, CodeEventConflict, http.StatusConflict, "Event conflict", ""},
+	{ErrEmptyEventID, CodeEmptyEventID, http.StatusBadRequest, "Validation failed", "id"},
+	{ErrEmptyUserID, CodeEmptyUserID, http.StatusBadRequest, "Validation failed", "user_id"},
+	{ErrEmptyTitle, CodeEmptyTitle, http.StatusBadRequest, "Validation failed", "title"},
+	{ErrInvalidTime, CodeInvalidTime, http.StatusBadRequest, "Validation failed", ""},
+	{ErrInvalidRange, CodeInvalidRange, http.StatusBadRequest, "Invalid date range", ""},
+
+	{ErrInvalidCalendar, CodeInvalidCalendar, http.StatusBadRequest, "Invalid calendar", ""},
+	{ErrTooManyOccurrences, CodeTooManyOccurrences, http.StatusBadRequest, "Too many occurrences", ""},
+	{ErrInvalidImportOption, CodeInvalidImportOption, http.StatusBadRequest, "Validation failed", ""},
+	{ErrImportTooLarge, CodeImportTooLarge, http.StatusRequestEntityTooLarge, "Import too large", ""},
+	{ErrInvalidTimeZone, CodeInvalidTimeZone, http.StatusBadRequest, "Validation failed", "tz"},
+	{ErrInvalidRecord, CodeInvalidRecord, http.StatusBadRequest, "Invalid record", ""},
+
+	{ErrInvalidXML, CodeInvalidXML, http.StatusBadRequest, "Invalid XML", ""},
+	{ErrPreconditionFailed, CodePreconditionFailed, http.StatusPreconditionFailed, "Precondition failed", ""},
+	{ErrUnsupportedReport, CodeUnsupportedReport, http.StatusForbidden, "Unsupported report", ""},
+
+	{ErrInvalidLastEventID, CodeInvalidLastEventID, http.StatusBadRequest, "Validation failed", "last_event_id"},
+
+	{ErrInvalidHandshake, CodeInvalidHandshake, http.StatusBadRequest, "Invalid handshake", ""},
+	{ErrOriginNotAllowed, CodeOriginNotAllowed, http.StatusForbidden, "Origin not allowed", ""},
+	{ErrUnknownCommand, CodeUnknownCommand, http.StatusBadRequest, "Unknown command", ""},
+	{ErrTooManySubscriptions, CodeTooManySubscriptions, http.StatusBadRequest, "Too many subscriptions", ""},
+	{ErrRateLimited, CodeRateLimited, http.StatusTooManyRequests, "Too many requests", ""},
+
+	{ErrWebhookNotFound, CodeWebhookNotFound, http.StatusNotFound, "Webhook not found", ""},
+	{ErrInvalidWebhookURL, CodeInvalidWebhookURL, http.StatusBadRequest, "Validation failed", "url"},
+	{ErrWebhookURLForbidden, CodeWebhookURLForbidden, http.StatusBadRequest, "Validation failed", "url"},
+	{ErrInvalidEventType, CodeInvalidEventType, http.StatusBadRequest, "Validation failed", "event_types"},
+	{ErrDeliveryNotFound, CodeDeliveryNotFound, http.StatusNotFound, "Delivery not found", ""},
+	{ErrDeliveryConflict, CodeDeliveryConflict, http.StatusConflict, "Delivery already exists", ""},
+	{ErrDeliveryNotDead, CodeDeliveryNotDead, http.StatusConflict, "Delivery not dead-lettered", ""},
+	{ErrInvalidDeliveryFilter, CodeInvalidDeliveryFilter, http.StatusBadRequest, "Invalid delivery filter", ""},
+
+	{ErrReminderFired, CodeReminderFired, http.StatusConflict, "Reminder already fired", ""},
+
+	{ErrDigestNotFound, CodeDigestNotFound, http.StatusNotFound, "Digest not found", ""},
+	{ErrInvalidDigest, CodeInvalidDigest, http.StatusBadRequest, "Invalid digest settings", ""},
+
+	// Поле для этих ошибок задает ValidationError
+	{ErrTitleLength, CodeTitleLength, http.StatusBadRequest, "Validation failed", ""},
+	{ErrControlCharacters, CodeControlCharacters, http.StatusBadRequest, "Validation failed", ""},
+	{ErrDateOutOfRange, CodeDateOutOfRange, http.StatusBadRequest, "Validation failed", ""},
+	{ErrInvalidID, CodeInvalidID, http.StatusBadRequest, "Validation failed", ""},
+	{ErrRequiredField, CodeRequiredField, http.StatusBadRequest, "Validation failed", ""},
+	{ErrInvalidReminder, CodeInvalidReminder, http.StatusBadRequest, "Validation failed", ""},
+}
+
+// Describe - типизированное представление любой ошибки.
+// Неизвестные ошибки превращаются в internal_error без раскрытия исходного текста.
+func Describe(err error) *Error {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) && len(validationErr.Violations) > 0 {
+		return describeValidation(validationErr)
+	}
+
+	var quotaErr *QuotaError
+	if errors.As(err, &quotaErr) {
+		return describeQuota(quotaErr)
+	}
+
+	if def, ok := lookup(err); ok {
+		e := &Error{
+			Code:   def.code,
+			Status: def.status,
+			Title:  def.title,
+			Detail: err.Error(),
+			Params: ParamsOf(err),
+			Err:    err,
+		}
+		if def.field != "" {
+			e.Fields = []FieldError{{Field: def.field, Code: def.code, Message: def.err.Error(), Params: e.Params}}
+		}
+		return e
+	}
+
+	return &Error{
+		Code:   CodeInternal,
+		Status: http.StatusInternalServerError,
+		Title:  "Internal server error",
+		Detail: "internal server error",
+		Err:    err,
+	}
+}
+
+// lookup - описание первого сентинела из definitions, которому соответствует ошибка
+func lookup(err error) (definition, bool) {
+	for _, def := range definitions {
+		if errors.Is(err, def.err) {
+			return def, true
+		}
+	}
+	return definition{}, false
+}
+
+// describeValidation - одно нарушение сохраняет свой код, несколько объединяются в validation_failed
+func describeValidation(err *ValidationError) *Error {
+	e := &Error{
+		Code:   CodeValidationFailed,
+		Status: http.StatusBadRequest,
+		Title:  "Validation failed",
+		Detail: err.Error(),
+		Err:    err,
+	}
+	for _, v := range err.Violations {
+		code := CodeValidationFailed
+		if def, ok := lookup(v.Err); ok {
+			code = def.code
+		}
+		e.Fields = append(e.Fields, FieldError{Field: v.Field, Code: code, Message: v.Err.Error(), Params: ParamsOf(v.Err)})
+	}
+	if len(err.Violations) == 1 {
+		// Прежний текст ошибки для клиентов, которые разбирают сообщение
+		e.Code = e.Fields[0].Code
+		e.Detail = err.Violations[0].Err.Error()
+		e.Params = e.Fields[0].Params
+	}
+	return e
+}
+
+// describeQuota - 422 для недопустимого содержимого, 429 для исчерпанных лимитов
+func describeQuota(err *QuotaError) *Error {
+	params := Params{ParamRule: err.Limit, "limit": err.Limit, "max": err.Max, "current": err.Current}
+	if err.Limit == LimitTitleLength {
+		return &Error{
+			Code:   CodeTitleTooLong,
+			Status: http.StatusUnprocessableEntity,
+			Title:  "Validation failed",
+			Detail: err.Error(),
+			Fields: []FieldError{{Field: "title", Code: CodeTitleTooLong, Message: err.Error(), Params: params}},
+			Params: params,
+			Err:    err,
+		}
+	}
+	return &Error{
+		Code:   CodeQuotaExceeded,
+		Status: http.StatusTooManyRequests,
+		Title:  "Quota exceeded",
+		Detail: err.Error(),
+		Params: params,
+		Err:    err,
+	}
+}
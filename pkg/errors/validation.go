@@ -0,0 +1,56 @@
+package errors
+
+import "strings"
+
+// Violation - нарушение правила проверки в конкретном поле.
+// Err оборачивает один из сентинелов, по нему определяется код ошибки.
+type Violation struct {
+	Field string
+	Err   error
+}
+
+// ValidationError - все нарушения, найденные за одну проверку
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Add - добавляет нарушение
+func (e *ValidationError) Add(field string, err error) {
+	e.Violations = append(e.Violations, Violation{Field: field, Err: err})
+}
+
+// Has - есть ли уже нарушение в поле
+func (e *ValidationError) Has(field string) bool {
+	for _, v := range e.Violations {
+		if v.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Err - сама ошибка или nil, если нарушений нет
+func (e *ValidationError) Err() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error - реализация error: сообщения всех нарушений через "; "
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Field+": "+v.Err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap - ошибки всех нарушений; errors.Is находит любой из сентинелов
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		errs = append(errs, v.Err)
+	}
+	return errs
+}
@@ -0,0 +1,248 @@
+// Package i18n - каталог локализованных сообщений об ошибках и выбор языка по Accept-Language.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage - язык, используемый, если ни один из запрошенных не поддерживается
+const DefaultLanguage = "en"
+
+//go:embed catalog/*.json
+var builtin embed.FS
+
+// Messages - сообщения одного языка, ключ - код ошибки.
+// Ключ вида "code.rule" задает вариант сообщения для параметра rule.
+type Messages struct {
+	Titles   map[string]string `json:"titles"`
+	Messages map[string]string `json:"messages"`
+}
+
+// Catalog - сообщения по языкам; безопасен для конкурентного чтения
+type Catalog struct {
+	mu       sync.RWMutex
+	langs    map[string]Messages
+	fallback string
+}
+
+// NewCatalog - пустой каталог с языком по умолчанию fallback
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{langs: make(map[string]Messages), fallback: normalize(fallback)}
+}
+
+// NewBuiltinCatalog - каталог со встроенными английскими и русскими сообщениями
+func NewBuiltinCatalog(fallback string) *Catalog {
+	c := NewCatalog(fallback)
+	if err := c.Load(builtin, "catalog"); err != nil {
+		panic(fmt.Sprintf("i18n: builtin catalog: %v", err))
+	}
+	return c
+}
+
+// Default - встроенный каталог с английским языком по умолчанию
+func Default() *Catalog {
+	return NewBuiltinCatalog(DefaultLanguage)
+}
+
+// Load - загружает файлы <язык>.json из каталога dir файловой системы fsys.
+// Сообщения файла дополняют и переопределяют уже загруженные для этого языка.
+func (c *Catalog) Load(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		var m Messages
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		c.add(normalize(strings.TrimSuffix(path.Base(file), ".json")), m)
+	}
+	return nil
+}
+
+// add - объединение сообщений языка с уже загруженными
+func (c *Catalog) add(lang string, m Messages) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing := c.langs[lang]
+	if existing.Titles == nil {
+		existing.Titles = make(map[string]string)
+	}
+	if existing.Messages == nil {
+		existing.Messages = make(map[string]string)
+	}
+	for k, v := range m.Titles {
+		existing.Titles[k] = v
+	}
+	for k, v := range m.Messages {
+		existing.Messages[k] = v
+	}
+	c.langs[lang] = existing
+}
+
+// Languages - поддерживаемые языки в алфавитном порядке
+func (c *Catalog) Languages() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	langs := make([]string, 0, len(c.langs))
+	for lang := range c.langs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Has - загружены ли сообщения языка
+func (c *Catalog) Has(lang string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.langs[normalize(lang)]
+	return ok
+}
+
+// Fallback - язык по умолчанию
+func (c *Catalog) Fallback() string {
+	return c.fallback
+}
+
+// Negotiate - наиболее предпочтительный поддерживаемый язык из заголовка Accept-Language.
+// Учитываются веса q; для "ru-RU" подходит "ru". Без совпадений возвращается язык по умолчанию.
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		if _, ok := c.langs[tag]; ok {
+			return tag
+		}
+		if primary, _, found := strings.Cut(tag, "-"); found {
+			if _, ok := c.langs[primary]; ok {
+				return primary
+			}
+		}
+	}
+	return c.fallback
+}
+
+// Title - заголовок ошибки с кодом code
+func (c *Catalog) Title(lang, code string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	title, ok := c.lookup(lang, func(m Messages) (string, bool) {
+		s, ok := m.Titles[code]
+		return s, ok
+	})
+	return title, ok
+}
+
+// Message - сообщение об ошибке с кодом code и подставленными параметрами {name}.
+// Если в параметрах есть rule, сначала ищется вариант "code.rule".
+func (c *Catalog) Message(lang, code string, params map[string]any) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := []string{code}
+	if rule, ok := params["rule"]; ok {
+		keys = []string{code + "." + fmt.Sprint(rule), code}
+	}
+
+	msg, ok := c.lookup(lang, func(m Messages) (string, bool) {
+		for _, key := range keys {
+			if s, ok := m.Messages[key]; ok {
+				return s, true
+			}
+		}
+		return "", false
+	})
+	if !ok {
+		return "", false
+	}
+	return substitute(msg, params), true
+}
+
+// lookup - поиск в языке lang, затем в языке по умолчанию
+func (c *Catalog) lookup(lang string, find func(Messages) (string, bool)) (string, bool) {
+	if m, ok := c.langs[normalize(lang)]; ok {
+		if s, ok := find(m); ok {
+			return s, true
+		}
+	}
+	if m, ok := c.langs[c.fallback]; ok {
+		return find(m)
+	}
+	return "", false
+}
+
+// substitute - замена {name} значениями параметров; неизвестные заполнители остаются как есть
+func substitute(msg string, params map[string]any) string {
+	if len(params) == 0 || !strings.Contains(msg, "{") {
+		return msg
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+// languageRange - элемент Accept-Language
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// parseAcceptLanguage - языки заголовка в порядке убывания веса; q=0 исключает язык
+func parseAcceptLanguage(header string) []string {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag = normalize(tag); tag == "" {
+			continue
+		}
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, languageRange{tag: tag, q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	tags := make([]string, len(ranges))
+	for i, r := range ranges {
+		tags[i] = r.tag
+	}
+	return tags
+}
+
+// normalize - тег языка в нижнем регистре с "-" вместо "_"
+func normalize(tag string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(tag)), "_", "-")
+}
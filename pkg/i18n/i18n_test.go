@@ -0,0 +1,89 @@
+package i18n
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestCatalog_Negotiate(t *testing.T) {
+	c := Default()
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "empty header", header: "", want: "en"},
+		{name: "exact match", header: "ru", want: "ru"},
+		{name: "region falls back to primary", header: "ru-RU,ru;q=0.9", want: "ru"},
+		{name: "weights respected", header: "en;q=0.5, ru;q=0.8", want: "ru"},
+		{name: "unsupported first", header: "de-DE, ru;q=0.7", want: "ru"},
+		{name: "zero weight excluded", header: "ru;q=0, en", want: "en"},
+		{name: "nothing supported", header: "fr, de", want: "en"},
+		{name: "wildcard", header: "*", want: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Negotiate(tt.header); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCatalog_Message(t *testing.T) {
+	c := Default()
+
+	msg, ok := c.Message("ru", "date_out_of_range", map[string]any{"min": 1900, "max": 2100})
+	if !ok || msg != "год даты должен быть от 1900 до 2100" {
+		t.Errorf("Expected substituted Russian message, got %q", msg)
+	}
+
+	msg, _ = c.Message("ru", "quota_exceeded", map[string]any{"rule": "user_events", "max": 3, "current": 3})
+	if msg != "превышена квота: у пользователя не более 3 событий, сейчас 3" {
+		t.Errorf("Expected rule-specific variant, got %q", msg)
+	}
+
+	msg, _ = c.Message("en", "quota_exceeded", map[string]any{"rule": "user_events", "limit": "user_events", "max": 3, "current": 3})
+	if msg != "quota exceeded: user_events limit is 3, current 3" {
+		t.Errorf("Expected generic message without variant, got %q", msg)
+	}
+
+	if _, ok := c.Message("en", "no_such_code", nil); ok {
+		t.Error("Expected unknown code to be missing")
+	}
+}
+
+func TestCatalog_Load(t *testing.T) {
+	c := Default()
+	fsys := fstest.MapFS{
+		"de.json":    {Data: []byte(`{"titles": {"event_not_found": "Termin nicht gefunden"}, "messages": {"event_not_found": "Termin nicht gefunden"}}`)},
+		"ru.json":    {Data: []byte(`{"messages": {"event_not_found": "нет такого события"}}`)},
+		"README.txt": {Data: []byte("ignored")},
+	}
+	if err := c.Load(fsys, "."); err != nil {
+		t.Fatalf("Failed to load catalogue: %v", err)
+	}
+
+	if lang := c.Negotiate("de-AT"); lang != "de" {
+		t.Errorf("Expected dropped-in language de, got %q", lang)
+	}
+	if msg, _ := c.Message("de", "event_not_found", nil); msg != "Termin nicht gefunden" {
+		t.Errorf("Expected German message, got %q", msg)
+	}
+	// Непереведенные сообщения берутся из языка по умолчанию
+	if msg, _ := c.Message("de", "event_conflict", nil); msg != "event with this ID already exists" {
+		t.Errorf("Expected fallback message, got %q", msg)
+	}
+	if msg, _ := c.Message("ru", "event_not_found", nil); msg != "нет такого события" {
+		t.Errorf("Expected overridden message, got %q", msg)
+	}
+	if title, _ := c.Title("ru", "event_not_found"); title != "Событие не найдено" {
+		t.Errorf("Expected builtin title to survive override, got %q", title)
+	}
+
+	if err := c.Load(fstest.MapFS{"xx.json": {Data: []byte("{")}}, "."); err == nil {
+		t.Error("Expected error for malformed catalogue")
+	}
+}
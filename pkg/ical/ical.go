@@ -0,0 +1,148 @@
+// Package ical - сериализация календарей в формат iCalendar (RFC 5545).
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType - тип содержимого iCalendar
+const ContentType = "text/calendar; charset=utf-8"
+
+// maxLineOctets - максимальная длина строки без CRLF (RFC 5545, 3.1)
+const maxLineOctets = 75
+
+// Форматы значений DATE и DATE-TIME
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405"
+)
+
+// Calendar - объект VCALENDAR
+type Calendar struct {
+	// ProdID - идентификатор создавшего календарь продукта
+	ProdID string
+	// Name - отображаемое имя календаря (X-WR-CALNAME)
+	Name string
+	// Method - метод iTIP (PUBLISH для подписки); ресурсы CalDAV записываются без него
+	Method string
+	Events []Event
+}
+
+// Event - компонент VEVENT.
+// Время записывается в UTC с суффиксом Z, а с Floating - как "плавающее" локальное время без зоны.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	// Start - начало; для AllDay учитывается только дата
+	Start time.Time
+	// End - окончание, не включая его; нулевое значение не записывается
+	End    time.Time
+	AllDay bool
+	// Floating - время без привязки к часовому поясу
+	Floating bool
+	// Stamp - время формирования (DTSTAMP)
+	Stamp time.Time
+}
+
+// Encoder - запись календарей в поток
+type Encoder struct {
+	w *bufio.Writer
+}
+
+// NewEncoder - конструктор Encoder
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode - запись календаря со строками CRLF, экранированием и переносом длинных строк
+func (e *Encoder) Encode(cal Calendar) error {
+	e.line("BEGIN:VCALENDAR")
+	e.line("VERSION:2.0")
+	e.line("PRODID:" + cal.ProdID)
+	e.line("CALSCALE:GREGORIAN")
+	if cal.Method != "" {
+		e.line("METHOD:" + cal.Method)
+	}
+	if cal.Name != "" {
+		e.line("X-WR-CALNAME:" + EscapeText(cal.Name))
+	}
+
+	for _, event := range cal.Events {
+		e.event(event)
+	}
+
+	e.line("END:VCALENDAR")
+	return e.w.Flush()
+}
+
+// event - запись VEVENT
+func (e *Encoder) event(ev Event) {
+	e.line("BEGIN:VEVENT")
+	e.line("UID:" + EscapeText(ev.UID))
+	e.line("DTSTAMP:" + ev.Stamp.UTC().Format(dateTimeFormat) + "Z")
+	if ev.AllDay {
+		e.line("DTSTART;VALUE=DATE:" + ev.Start.Format(dateFormat))
+		end := ev.End
+		if end.IsZero() {
+			end = ev.Start.AddDate(0, 0, 1)
+		}
+		e.line("DTEND;VALUE=DATE:" + end.Format(dateFormat))
+	} else {
+		e.line("DTSTART:" + formatDateTime(ev.Start, ev.Floating))
+		if !ev.End.IsZero() {
+			e.line("DTEND:" + formatDateTime(ev.End, ev.Floating))
+		}
+	}
+	e.line("SUMMARY:" + EscapeText(ev.Summary))
+	if ev.Description != "" {
+		e.line("DESCRIPTION:" + EscapeText(ev.Description))
+	}
+	e.line("END:VEVENT")
+}
+
+// line - запись логической строки с переносом по 75 октетов
+func (e *Encoder) line(s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		// Перенос не должен разрывать многобайтовый символ UTF-8
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		e.w.WriteString(s[:cut])
+		e.w.WriteString("\r\n ")
+		s = s[cut:]
+		// Продолжение начинается с пробела, который входит в лимит строки
+		limit = maxLineOctets - 1
+	}
+	e.w.WriteString(s)
+	e.w.WriteString("\r\n")
+}
+
+// formatDateTime - DATE-TIME в UTC или плавающее локальное время
+func formatDateTime(t time.Time, floating bool) string {
+	if floating {
+		return t.Format(dateTimeFormat)
+	}
+	return t.UTC().Format(dateTimeFormat) + "Z"
+}
+
+// textEscaper - экранирование значений типа TEXT (RFC 5545, 3.3.11)
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", "",
+)
+
+// EscapeText - экранирование значения типа TEXT
+func EscapeText(s string) string {
+	return textEscaper.Replace(s)
+}
@@ -0,0 +1,81 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func encode(t *testing.T, cal Calendar) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(cal); err != nil {
+		t.Fatalf("Failed to encode calendar: %v", err)
+	}
+	return buf.String()
+}
+
+func TestEncoder_Events(t *testing.T) {
+	stamp := time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC)
+	out := encode(t, Calendar{
+		ProdID: "-//test//EN",
+		Events: []Event{
+			{UID: "all-day", Summary: "Holiday", Start: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), AllDay: true, Stamp: stamp},
+			{
+				UID:      "timed",
+				Summary:  "Standup",
+				Start:    time.Date(2025, 1, 15, 9, 30, 0, 0, time.UTC),
+				End:      time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+				Floating: true,
+				Stamp:    stamp,
+			},
+			{UID: "utc", Summary: "Call", Start: time.Date(2025, 1, 15, 12, 0, 0, 0, time.FixedZone("MSK", 3*3600)), Stamp: stamp},
+		},
+	})
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"VERSION:2.0\r\n",
+		"PRODID:-//test//EN\r\n",
+		"UID:all-day\r\nDTSTAMP:20250110T080000Z\r\nDTSTART;VALUE=DATE:20250115\r\nDTEND;VALUE=DATE:20250116\r\n",
+		"DTSTART:20250115T093000\r\nDTEND:20250115T100000\r\n",
+		"DTSTART:20250115T090000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "BEGIN:VEVENT") != 3 {
+		t.Errorf("Expected 3 events, got:\n%s", out)
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	got := EscapeText("a,b;c\\d\r\nnext\nline")
+	want := `a\,b\;c\\d\nnext\nline`
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestEncoder_Folding(t *testing.T) {
+	summary := strings.Repeat("Очень длинное название события ", 10)
+	out := encode(t, Calendar{ProdID: "-//test//EN", Events: []Event{{UID: "1", Summary: summary, AllDay: true}}})
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Expected at most 75 octets, got %d: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("Expected folding to keep UTF-8 intact, got %q", line)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:"+EscapeText(summary)+"\r\n") {
+		t.Errorf("Expected unfolded summary to match, got:\n%s", unfolded)
+	}
+}
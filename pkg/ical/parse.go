@@ -0,0 +1,378 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoCalendar - данные не содержат VCALENDAR
+var ErrNoCalendar = errors.New("no VCALENDAR component found")
+
+// Property - строка содержимого: имя, параметры и значение
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// ParsedEvent - VEVENT из файла; Err описывает, почему событие не удалось разобрать
+type ParsedEvent struct {
+	Event
+	// Line - номер строки BEGIN:VEVENT
+	Line int
+	// RRule - правило повторения или nil
+	RRule *RRule
+	// ExDates - исключенные повторения
+	ExDates []time.Time
+	// RecurrenceID - повторение, которое заменяет это событие
+	RecurrenceID time.Time
+	Err          error
+}
+
+// Parse - разбор iCalendar: строки CRLF или LF, перенос строк, экранирование TEXT,
+// DTSTART/DTEND в форме DATE и DATE-TIME (UTC, TZID, плавающее время), DURATION, RRULE и EXDATE.
+// TZID ищется среди поясов IANA и имен Windows, а неизвестный - в VTIMEZONE файла.
+// Ошибки отдельных событий возвращаются в ParsedEvent.Err, ошибка функции означает непригодный файл.
+func Parse(r io.Reader) ([]ParsedEvent, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events     []ParsedEvent
+		eventProps [][]Property
+		current    *ParsedEvent
+		props      []Property
+		depth      int
+		inCalendar bool
+
+		timezones = zones{}
+		// inZone - разбирается VTIMEZONE; zoneProps - его свойства, zoneRules - компоненты
+		// STANDARD и DAYLIGHT, inRule - разбирается один из них
+		inZone, inRule bool
+		zoneProps      []Property
+		zoneRules      [][]Property
+	)
+
+	for _, l := range lines {
+		prop, err := parseProperty(l.text)
+		if err != nil {
+			if current != nil && current.Err == nil {
+				current.Err = fmt.Errorf("line %d: %w", l.number, err)
+			}
+			continue
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			value := strings.ToUpper(prop.Value)
+			switch {
+			case value == "VCALENDAR":
+				inCalendar = true
+			case value == "VEVENT" && current == nil && !inZone:
+				current = &ParsedEvent{Line: l.number}
+				props = nil
+				depth = 0
+			case value == "VTIMEZONE" && current == nil && !inZone:
+				inZone = true
+				zoneProps, zoneRules = nil, nil
+			case inZone && (value == "STANDARD" || value == "DAYLIGHT"):
+				inRule = true
+				zoneRules = append(zoneRules, nil)
+			case current != nil:
+				// Вложенные компоненты (VALARM) пропускаются
+				depth++
+			}
+			continue
+		case "END":
+			value := strings.ToUpper(prop.Value)
+			if current != nil && depth > 0 {
+				depth--
+			} else if current != nil && value == "VEVENT" {
+				events = append(events, *current)
+				eventProps = append(eventProps, props)
+				current = nil
+			} else if inRule && (value == "STANDARD" || value == "DAYLIGHT") {
+				inRule = false
+			} else if inZone && value == "VTIMEZONE" {
+				if tzid, tz := buildTimezone(zoneProps, zoneRules); tzid != "" {
+					timezones[tzid] = tz
+				}
+				inZone = false
+			}
+			continue
+		}
+
+		switch {
+		case current != nil && depth == 0:
+			props = append(props, prop)
+		case inRule:
+			zoneRules[len(zoneRules)-1] = append(zoneRules[len(zoneRules)-1], prop)
+		case inZone:
+			zoneProps = append(zoneProps, prop)
+		}
+	}
+
+	if !inCalendar {
+		return nil, ErrNoCalendar
+	}
+
+	// VTIMEZONE может идти и после событий, поэтому события собираются после разбора всего файла
+	for i := range events {
+		if events[i].Err == nil {
+			events[i].Err = buildEvent(&events[i], eventProps[i], timezones)
+		}
+	}
+	return events, nil
+}
+
+// line - развернутая строка и номер ее первой физической строки
+type line struct {
+	number int
+	text   string
+}
+
+// unfold - склейка перенесенных строк (RFC 5545, 3.1)
+func unfold(r io.Reader) ([]line, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []line
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if text == "" {
+			continue
+		}
+		if (text[0] == ' ' || text[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		lines = append(lines, line{number: number, text: text})
+	}
+	return lines, scanner.Err()
+}
+
+// parseProperty - разбор строки NAME;PARAM=value;PARAM="quoted":value
+func parseProperty(s string) (Property, error) {
+	prop := Property{Params: make(map[string]string)}
+
+	i := strings.IndexAny(s, ";:")
+	if i <= 0 {
+		return prop, fmt.Errorf("malformed content line %q", s)
+	}
+	prop.Name = strings.ToUpper(s[:i])
+
+	for s[i] == ';' {
+		s = s[i+1:]
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return prop, fmt.Errorf("malformed parameter in %s", prop.Name)
+		}
+		name := strings.ToUpper(s[:eq])
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return prop, fmt.Errorf("unterminated quoted parameter in %s", prop.Name)
+			}
+			value = s[1 : end+1]
+			s = s[end+2:]
+			i = 0
+			if s == "" || (s[0] != ';' && s[0] != ':') {
+				return prop, fmt.Errorf("malformed parameter in %s", prop.Name)
+			}
+		} else {
+			i = strings.IndexAny(s, ";:")
+			if i < 0 {
+				return prop, fmt.Errorf("missing value in %s", prop.Name)
+			}
+			value = s[:i]
+			s = s[i:]
+			i = 0
+		}
+		prop.Params[name] = value
+	}
+
+	prop.Value = s[i+1:]
+	return prop, nil
+}
+
+// buildEvent - заполнение события по свойствам VEVENT
+func buildEvent(ev *ParsedEvent, props []Property, timezones zones) error {
+	var duration time.Duration
+	hasEnd := false
+
+	for _, p := range props {
+		var err error
+		switch p.Name {
+		case "UID":
+			ev.UID = p.Value
+		case "SUMMARY":
+			ev.Summary = UnescapeText(p.Value)
+		case "DESCRIPTION":
+			ev.Description = UnescapeText(p.Value)
+		case "DTSTAMP":
+			ev.Stamp, _, _, err = parseTime(p, timezones)
+		case "DTSTART":
+			ev.Start, ev.AllDay, ev.Floating, err = parseTime(p, timezones)
+		case "DTEND":
+			ev.End, _, _, err = parseTime(p, timezones)
+			hasEnd = true
+		case "DURATION":
+			duration, err = ParseDuration(p.Value)
+		case "RRULE":
+			var rule RRule
+			rule, err = ParseRRule(p.Value)
+			ev.RRule = &rule
+		case "EXDATE":
+			for _, value := range strings.Split(p.Value, ",") {
+				var t time.Time
+				t, _, _, err = parseTime(Property{Name: p.Name, Params: p.Params, Value: value}, timezones)
+				if err != nil {
+					break
+				}
+				ev.ExDates = append(ev.ExDates, t)
+			}
+		case "RECURRENCE-ID":
+			ev.RecurrenceID, _, _, err = parseTime(p, timezones)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+	}
+
+	if ev.UID == "" {
+		return errors.New("missing UID")
+	}
+	if ev.Start.IsZero() {
+		return errors.New("missing DTSTART")
+	}
+	if !hasEnd && duration > 0 {
+		ev.End = ev.Start.Add(duration)
+	}
+	return nil
+}
+
+// parseTime - значение DATE или DATE-TIME с учетом VALUE и TZID; timezones - VTIMEZONE файла
+func parseTime(p Property, timezones zones) (t time.Time, allDay, floating bool, err error) {
+	value := strings.TrimSpace(p.Value)
+
+	if strings.EqualFold(p.Params["VALUE"], "DATE") || len(value) == len(dateFormat) {
+		t, err = time.Parse(dateFormat, value)
+		return t, true, false, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse(dateTimeFormat, strings.TrimSuffix(value, "Z"))
+		return t, false, false, err
+	}
+
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if loc, err := LoadLocation(tzid); err == nil {
+			t, err = time.ParseInLocation(dateTimeFormat, value, loc)
+			return t, false, false, err
+		}
+		tz, ok := timezones[tzid]
+		if !ok || (tz.location == nil && len(tz.rules) == 0) {
+			return time.Time{}, false, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+		t, err = time.Parse(dateTimeFormat, value)
+		if err != nil {
+			return time.Time{}, false, false, err
+		}
+		return tz.in(tzid, t), false, false, nil
+	}
+
+	t, err = time.Parse(dateTimeFormat, value)
+	return t, false, true, err
+}
+
+// LoadLocation - часовой пояс по TZID; понимает идентификаторы IANA с префиксом "/" и без него
+// и имена часовых поясов Windows
+func LoadLocation(tzid string) (*time.Location, error) {
+	name := strings.TrimPrefix(tzid, "/")
+	if iana, ok := windowsZones[name]; ok {
+		name = iana
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", tzid)
+	}
+	return loc, nil
+}
+
+// ParseDuration - длительность RFC 5545 вида [+-]P[nW][nD][T[nH][nM][nS]]
+func ParseDuration(s string) (time.Duration, error) {
+	orig := s
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign = -1
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+	s = s[1:]
+
+	var total time.Duration
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			inTime = true
+			s = s[1:]
+			continue
+		}
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(s) {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		n, _ := strconv.Atoi(s[:i])
+		unit := time.Duration(n)
+		switch {
+		case s[i] == 'W' && !inTime:
+			total += unit * 7 * 24 * time.Hour
+		case s[i] == 'D' && !inTime:
+			total += unit * 24 * time.Hour
+		case s[i] == 'H' && inTime:
+			total += unit * time.Hour
+		case s[i] == 'M' && inTime:
+			total += unit * time.Minute
+		case s[i] == 'S' && inTime:
+			total += unit * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		s = s[i+1:]
+	}
+	return sign * total, nil
+}
+
+// textUnescaper - обратное EscapeText преобразование
+var textUnescaper = strings.NewReplacer(
+	`\\`, `\`,
+	`\;`, ";",
+	`\,`, ",",
+	`\n`, "\n",
+	`\N`, "\n",
+)
+
+// UnescapeText - значение типа TEXT без экранирования
+func UnescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
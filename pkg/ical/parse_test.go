@@ -0,0 +1,242 @@
+package ical
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sample = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:all-day@example.com\r\n" +
+	"DTSTART;VALUE=DATE:20250115\r\n" +
+	"SUMMARY:Holiday\\, company-wide\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"SUMMARY:Alarm must not override\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:tz@example.com\r\n" +
+	"DTSTART;TZID=Europe/Moscow:20250116T100000\r\n" +
+	"DURATION:PT1H30M\r\n" +
+	"SUMMARY:Long summary that is folded\r\n" +
+	"  across two lines\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=3\r\n" +
+	"EXDATE;TZID=Europe/Moscow:20250123T100000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20250117T090000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	events, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Failed to parse calendar: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+
+	allDay := events[0]
+	if allDay.Err != nil || !allDay.AllDay || allDay.Summary != "Holiday, company-wide" {
+		t.Errorf("Unexpected all-day event %+v", allDay)
+	}
+	if !allDay.Start.Equal(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected all-day start %v", allDay.Start)
+	}
+
+	timed := events[1]
+	if timed.Err != nil {
+		t.Fatalf("Unexpected error %v", timed.Err)
+	}
+	if timed.Summary != "Long summary that is folded across two lines" {
+		t.Errorf("Expected unfolded summary, got %q", timed.Summary)
+	}
+	if got := timed.Start.UTC(); !got.Equal(time.Date(2025, 1, 16, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected TZID to be applied, got %v", got)
+	}
+	if timed.End.Sub(timed.Start) != 90*time.Minute {
+		t.Errorf("Expected DURATION to set end, got %v", timed.End)
+	}
+	if timed.RRule == nil || timed.RRule.Freq != Weekly || timed.RRule.Count != 3 {
+		t.Errorf("Unexpected rule %+v", timed.RRule)
+	}
+	if len(timed.ExDates) != 1 {
+		t.Errorf("Expected 1 EXDATE, got %v", timed.ExDates)
+	}
+
+	if events[2].Err == nil {
+		t.Error("Expected event without UID to fail")
+	}
+}
+
+func TestParse_NoCalendar(t *testing.T) {
+	if _, err := Parse(strings.NewReader("hello\nworld\n")); err != ErrNoCalendar {
+		t.Errorf("Expected ErrNoCalendar, got %v", err)
+	}
+}
+
+func TestParse_FloatingAndUnknownZone(t *testing.T) {
+	data := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nUID:1\nDTSTART:20250115T093000\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:2\nDTSTART;TZID=Mars/Olympus:20250115T093000\nEND:VEVENT\n" +
+		"END:VCALENDAR\n"
+
+	events, err := Parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to parse calendar: %v", err)
+	}
+	if !events[0].Floating || events[0].Start.Hour() != 9 {
+		t.Errorf("Expected floating 09:30, got %+v", events[0])
+	}
+	if events[1].Err == nil {
+		t.Error("Expected unknown time zone to fail the event")
+	}
+}
+
+func TestParse_OutlookExport(t *testing.T) {
+	file, err := os.Open("testdata/outlook.ics")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer file.Close()
+
+	events, err := Parse(file)
+	if err != nil {
+		t.Fatalf("Failed to parse calendar: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+
+	testCases := []struct {
+		summary string
+		start   time.Time
+		end     time.Time
+	}{
+		// Имена Windows переводятся в пояса IANA
+		{"Планерка", time.Date(2025, 1, 20, 7, 0, 0, 0, time.UTC), time.Date(2025, 1, 20, 8, 0, 0, 0, time.UTC)},
+		{"Supplier call", time.Date(2025, 7, 15, 8, 0, 0, 0, time.UTC), time.Date(2025, 7, 15, 9, 0, 0, 0, time.UTC)},
+		// Неизвестный TZID берется из VTIMEZONE вместе с летним временем
+		{"Summer review", time.Date(2025, 7, 15, 7, 0, 0, 0, time.UTC), time.Date(2025, 7, 15, 8, 0, 0, 0, time.UTC)},
+		{"Winter review", time.Date(2025, 1, 21, 8, 0, 0, 0, time.UTC), time.Date(2025, 1, 21, 9, 0, 0, 0, time.UTC)},
+	}
+	for i, tc := range testCases {
+		event := events[i]
+		if event.Err != nil {
+			t.Errorf("Unexpected error in %q: %v", tc.summary, event.Err)
+			continue
+		}
+		if event.Summary != tc.summary || !event.Start.Equal(tc.start) || !event.End.Equal(tc.end) {
+			t.Errorf("Expected %q at %v-%v, got %q at %v-%v",
+				tc.summary, tc.start, tc.end, event.Summary, event.Start.UTC(), event.End.UTC())
+		}
+	}
+	if events[0].RRule == nil || events[0].RRule.Count != 4 {
+		t.Errorf("Expected weekly rule with COUNT=4, got %+v", events[0].RRule)
+	}
+}
+
+func TestLoadLocation_WindowsZones(t *testing.T) {
+	for windows, iana := range windowsZones {
+		if _, err := LoadLocation(windows); err != nil {
+			t.Errorf("Failed to load %q mapped to %q: %v", windows, iana, err)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		valid bool
+	}{
+		{value: "PT1H30M", want: 90 * time.Minute, valid: true},
+		{value: "P1D", want: 24 * time.Hour, valid: true},
+		{value: "P1W", want: 7 * 24 * time.Hour, valid: true},
+		{value: "-PT15M", want: -15 * time.Minute, valid: true},
+		{value: "PT", valid: false},
+		{value: "1H", valid: false},
+		{value: "P1H", valid: false},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.value)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("ParseDuration(%q): expected %v (valid %v), got %v, %v", tt.value, tt.want, tt.valid, got, err)
+		}
+	}
+}
+
+func TestRRule_Occurrences(t *testing.T) {
+	start := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+	until := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		want  []string
+	}{
+		{
+			name:  "daily with count",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: start,
+			want:  []string{"2025-01-31", "2025-02-01", "2025-02-02"},
+		},
+		{
+			name:  "monthly skips short months",
+			rule:  "FREQ=MONTHLY;COUNT=4",
+			start: start,
+			want:  []string{"2025-01-31", "2025-03-31", "2025-05-31", "2025-07-31"},
+		},
+		{
+			name:  "weekly on several days with interval",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20250125T000000Z",
+			start: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC),
+			want:  []string{"2025-01-06", "2025-01-08", "2025-01-20", "2025-01-22"},
+		},
+		{
+			name:  "last friday of month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			start: time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC),
+			want:  []string{"2025-01-31", "2025-02-28", "2025-03-28"},
+		},
+		{
+			name:  "yearly by month",
+			rule:  "FREQ=YEARLY;BYMONTH=3,9;BYMONTHDAY=1;COUNT=3",
+			start: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+			want:  []string{"2025-03-01", "2025-09-01", "2026-03-01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("Failed to parse rule: %v", err)
+			}
+			got := rule.Occurrences(tt.start, until.AddDate(1, 0, 0), 100)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i, occurrence := range got {
+				if occurrence.Format("2006-01-02") != tt.want[i] || occurrence.Hour() != 9 {
+					t.Errorf("Expected %s 09:00 at %d, got %v", tt.want[i], i, occurrence)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRRule_Invalid(t *testing.T) {
+	for _, value := range []string{"", "COUNT=3", "FREQ=SECONDLY", "FREQ=DAILY;INTERVAL=0", "FREQ=DAILY;COUNT=0", "FREQ=DAILY;COUNT=-1", "FREQ=WEEKLY;BYDAY=XX", "FREQ=MONTHLY;BYMONTHDAY=32"} {
+		if _, err := ParseRRule(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
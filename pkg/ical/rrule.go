@@ -0,0 +1,367 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency - частота повторения RRULE
+type Frequency string
+
+// Поддерживаемые частоты
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods - предел перебираемых периодов, если фильтры правила не дают повторений
+const maxPeriods = 100000
+
+// WeekdayNum - день недели из BYDAY, N - номер в месяце или году (отрицательный - с конца, 0 - каждый)
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// RRule - правило повторения (RFC 5545, 3.3.10).
+// Поддерживаются FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH и WKST.
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+// weekdays - коды дней недели
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRRule - разбор значения RRULE
+func ParseRRule(s string) (RRule, error) {
+	rule := RRule{Interval: 1, WeekStart: time.Monday}
+
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("malformed rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				return rule, fmt.Errorf("unsupported frequency %q", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err == nil && rule.Interval < 1 {
+				err = fmt.Errorf("interval must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err == nil && rule.Count < 1 {
+				err = fmt.Errorf("count must be positive")
+			}
+		case "UNTIL":
+			rule.Until, _, _, err = parseTime(Property{Value: value}, nil)
+		case "BYDAY":
+			for _, item := range strings.Split(value, ",") {
+				var wd WeekdayNum
+				if wd, err = parseWeekdayNum(item); err != nil {
+					break
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(value, ",") {
+				var day int
+				if day, err = strconv.Atoi(item); err != nil {
+					break
+				}
+				if day == 0 || day < -31 || day > 31 {
+					err = fmt.Errorf("invalid month day %d", day)
+					break
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, item := range strings.Split(value, ",") {
+				var month int
+				if month, err = strconv.Atoi(item); err != nil {
+					break
+				}
+				if month < 1 || month > 12 {
+					err = fmt.Errorf("invalid month %d", month)
+					break
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+			}
+		case "WKST":
+			day, ok := weekdays[strings.ToUpper(value)]
+			if !ok {
+				err = fmt.Errorf("invalid week start %q", value)
+			}
+			rule.WeekStart = day
+		default:
+			return rule, fmt.Errorf("unsupported rule part %s", name)
+		}
+		if err != nil {
+			return rule, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("missing FREQ")
+	}
+	return rule, nil
+}
+
+// parseWeekdayNum - элемент BYDAY вида MO, 2TU или -1FR
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+	}
+	day, ok := weekdays[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+	}
+	wd := WeekdayNum{Day: day}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid weekday %q", s)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+// Occurrences - повторения, начиная с start, не позже until и не более limit.
+// start считается первым повторением, даже если не подходит под фильтры правила.
+func (r RRule) Occurrences(start, until time.Time, limit int) []time.Time {
+	if !r.Until.IsZero() && r.Until.Before(until) {
+		until = r.Until
+	}
+
+	occurrences := []time.Time{start}
+	period := periodStart(r.Freq, start, r.WeekStart)
+
+	for i := 0; i < maxPeriods; i++ {
+		for _, candidate := range r.candidates(period, start) {
+			if !candidate.After(start) {
+				continue
+			}
+			if candidate.After(until) || (r.Count > 0 && len(occurrences) >= r.Count) || len(occurrences) >= limit {
+				return occurrences
+			}
+			occurrences = append(occurrences, candidate)
+		}
+		period = nextPeriod(r.Freq, period, r.Interval)
+		if period.After(until) {
+			break
+		}
+	}
+	return occurrences
+}
+
+// periodStart - начало периода (день, неделя, месяц, год), содержащего t
+func periodStart(freq Frequency, t time.Time, weekStart time.Weekday) time.Time {
+	y, m, d := t.Date()
+	switch freq {
+	case Weekly:
+		offset := (int(t.Weekday()) - int(weekStart) + 7) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+	case Monthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	case Yearly:
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// nextPeriod - начало следующего периода с учетом INTERVAL
+func nextPeriod(freq Frequency, period time.Time, interval int) time.Time {
+	switch freq {
+	case Weekly:
+		return period.AddDate(0, 0, 7*interval)
+	case Monthly:
+		return period.AddDate(0, interval, 0)
+	case Yearly:
+		return period.AddDate(interval, 0, 0)
+	}
+	return period.AddDate(0, 0, interval)
+}
+
+// candidates - даты периода, подходящие под правило, со временем суток из start, по возрастанию
+func (r RRule) candidates(period, start time.Time) []time.Time {
+	var days []time.Time
+
+	switch r.Freq {
+	case Daily:
+		if r.matchesDay(period) {
+			days = append(days, period)
+		}
+	case Weekly:
+		for i := 0; i < 7; i++ {
+			day := period.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+			if r.matchesDay(day) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		if r.matchesMonth(period.Month()) {
+			days = r.monthDays(period, start)
+		}
+	case Yearly:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		if len(r.ByDay) > 0 && len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 {
+			days = r.yearWeekdays(period)
+			break
+		}
+		for _, month := range months {
+			days = append(days, r.monthDays(time.Date(period.Year(), month, 1, 0, 0, 0, 0, period.Location()), start)...)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	result := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		result = append(result, time.Date(day.Year(), day.Month(), day.Day(),
+			start.Hour(), start.Minute(), start.Second(), 0, start.Location()))
+	}
+	return result
+}
+
+// matchesDay - фильтры BYMONTH, BYMONTHDAY и BYDAY без номеров для ежедневных и еженедельных правил
+func (r RRule) matchesDay(day time.Time) bool {
+	if !r.matchesMonth(day.Month()) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !containsMonthDay(r.ByMonthDay, day) {
+		return false
+	}
+	if len(r.ByDay) > 0 {
+		for _, wd := range r.ByDay {
+			if wd.Day == day.Weekday() {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchesMonth - фильтр BYMONTH
+func (r RRule) matchesMonth(month time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDays - дни месяца по BYMONTHDAY и BYDAY, иначе день месяца из start
+func (r RRule) monthDays(month, start time.Time) []time.Time {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	length := first.AddDate(0, 1, -1).Day()
+
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for d := 1; d <= length; d++ {
+			day := first.AddDate(0, 0, d-1)
+			if containsMonthDay(r.ByMonthDay, day) && (len(r.ByDay) == 0 || matchesWeekday(r.ByDay, day)) {
+				days = append(days, day)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			days = append(days, nthWeekdays(first, length, wd)...)
+		}
+	default:
+		// Месяцы без такого дня (31 февраля) пропускаются, как требует RFC 5545
+		if start.Day() <= length {
+			days = append(days, first.AddDate(0, 0, start.Day()-1))
+		}
+	}
+	return days
+}
+
+// yearWeekdays - дни года по BYDAY с номерами относительно года
+func (r RRule) yearWeekdays(year time.Time) []time.Time {
+	first := time.Date(year.Year(), time.January, 1, 0, 0, 0, 0, year.Location())
+	length := first.AddDate(1, 0, 0).Sub(first).Hours() / 24
+
+	var days []time.Time
+	for _, wd := range r.ByDay {
+		days = append(days, nthWeekdays(first, int(length+0.5), wd)...)
+	}
+	return days
+}
+
+// nthWeekdays - дни недели wd среди length дней от first: N-й, N-й с конца или все
+func nthWeekdays(first time.Time, length int, wd WeekdayNum) []time.Time {
+	var matches []time.Time
+	offset := (int(wd.Day) - int(first.Weekday()) + 7) % 7
+	for d := offset; d < length; d += 7 {
+		matches = append(matches, first.AddDate(0, 0, d))
+	}
+
+	switch {
+	case wd.N == 0:
+		return matches
+	case wd.N > 0 && wd.N <= len(matches):
+		return matches[wd.N-1 : wd.N]
+	case wd.N < 0 && -wd.N <= len(matches):
+		i := len(matches) + wd.N
+		return matches[i : i+1]
+	}
+	return nil
+}
+
+// matchesWeekday - день подходит под один из дней недели BYDAY
+func matchesWeekday(byDay []WeekdayNum, day time.Time) bool {
+	for _, wd := range byDay {
+		if wd.Day == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// containsMonthDay - день подходит под BYMONTHDAY, отрицательные значения считаются с конца месяца
+func containsMonthDay(byMonthDay []int, day time.Time) bool {
+	length := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	for _, d := range byMonthDay {
+		if d == day.Day() || (d < 0 && length+d+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timezone - часовой пояс из VTIMEZONE файла. Если X-LIC-LOCATION - известный пояс,
+// используется location, иначе смещение вычисляется по правилам STANDARD и DAYLIGHT.
+type timezone struct {
+	location *time.Location
+	rules    []zoneRule
+}
+
+// zoneRule - компонент STANDARD или DAYLIGHT: смещение, действующее с начала каждого перехода.
+// Время переходов хранится как местное время до перехода (в UTC без учета смещения).
+type zoneRule struct {
+	start      time.Time
+	offsetFrom int
+	offsetTo   int
+	rrule      *RRule
+	rdates     []time.Time
+}
+
+// zones - часовые пояса VTIMEZONE по TZID
+type zones map[string]*timezone
+
+// buildTimezone - часовой пояс по свойствам VTIMEZONE и его компонентам STANDARD и DAYLIGHT.
+// Компоненты с ошибками пропускаются: пояс без правил не применяется.
+func buildTimezone(props []Property, rules [][]Property) (string, *timezone) {
+	var tzid string
+	tz := &timezone{}
+	for _, p := range props {
+		switch p.Name {
+		case "TZID":
+			tzid = p.Value
+		case "X-LIC-LOCATION":
+			if loc, err := LoadLocation(p.Value); err == nil {
+				tz.location = loc
+			}
+		}
+	}
+	for _, props := range rules {
+		if rule, err := buildZoneRule(props); err == nil {
+			tz.rules = append(tz.rules, rule)
+		}
+	}
+	return tzid, tz
+}
+
+// buildZoneRule - правило по свойствам STANDARD или DAYLIGHT
+func buildZoneRule(props []Property) (zoneRule, error) {
+	var (
+		rule                     zoneRule
+		hasStart, hasFrom, hasTo bool
+	)
+	for _, p := range props {
+		var err error
+		switch p.Name {
+		case "DTSTART":
+			rule.start, err = time.Parse(dateTimeFormat, strings.TrimSpace(p.Value))
+			hasStart = true
+		case "TZOFFSETFROM":
+			rule.offsetFrom, err = parseOffset(p.Value)
+			hasFrom = true
+		case "TZOFFSETTO":
+			rule.offsetTo, err = parseOffset(p.Value)
+			hasTo = true
+		case "RRULE":
+			var rrule RRule
+			rrule, err = ParseRRule(p.Value)
+			rule.rrule = &rrule
+		case "RDATE":
+			for _, value := range strings.Split(p.Value, ",") {
+				var t time.Time
+				if t, err = time.Parse(dateTimeFormat, strings.TrimSpace(value)); err != nil {
+					break
+				}
+				rule.rdates = append(rule.rdates, t)
+			}
+		}
+		if err != nil {
+			return zoneRule{}, err
+		}
+	}
+	if !hasStart || !hasTo {
+		return zoneRule{}, errors.New("missing DTSTART or TZOFFSETTO")
+	}
+	if !hasFrom {
+		rule.offsetFrom = rule.offsetTo
+	}
+	return rule, nil
+}
+
+// parseOffset - смещение UTC-OFFSET вида +0300 или -043000 в секундах
+func parseOffset(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if (len(s) != 5 && len(s) != 7) || (s[0] != '+' && s[0] != '-') {
+		return 0, fmt.Errorf("invalid UTC offset %q", s)
+	}
+	digits := s[1:]
+	if len(digits) == 4 {
+		digits += "00"
+	}
+	value, err := strconv.Atoi(digits)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid UTC offset %q", s)
+	}
+	offset := value/10000*3600 + value/100%100*60 + value%100
+	if s[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+// in - момент времени для местного времени wall (в UTC без учета смещения) в этом поясе.
+// Пояс только из правил дает фиксированное смещение на момент wall: повторения события,
+// пересекающие переход, сохраняют смещение первого из них.
+func (tz *timezone) in(tzid string, wall time.Time) time.Time {
+	if tz.location != nil {
+		return time.Date(wall.Year(), wall.Month(), wall.Day(),
+			wall.Hour(), wall.Minute(), wall.Second(), 0, tz.location)
+	}
+
+	offset := tz.offset(wall)
+	return time.Date(wall.Year(), wall.Month(), wall.Day(),
+		wall.Hour(), wall.Minute(), wall.Second(), 0, time.FixedZone(tzid, offset))
+}
+
+// offset - смещение правила с последним переходом не позже wall; до первого перехода
+// действует смещение, из которого переходит самое раннее правило
+func (tz *timezone) offset(wall time.Time) int {
+	var (
+		latest time.Time
+		offset int
+		found  bool
+	)
+	for _, rule := range tz.rules {
+		if onset, ok := rule.onset(wall); ok && (!found || onset.After(latest)) {
+			latest, offset, found = onset, rule.offsetTo, true
+		}
+	}
+	if found {
+		return offset
+	}
+
+	var earliest *zoneRule
+	for i := range tz.rules {
+		if earliest == nil || tz.rules[i].start.Before(earliest.start) {
+			earliest = &tz.rules[i]
+		}
+	}
+	if earliest == nil {
+		return 0
+	}
+	return earliest.offsetFrom
+}
+
+// onset - последний переход правила не позже wall; ok=false, если правило еще не действует
+func (r zoneRule) onset(wall time.Time) (time.Time, bool) {
+	if wall.Before(r.start) {
+		return time.Time{}, false
+	}
+
+	last := r.start
+	for _, rdate := range r.rdates {
+		if !rdate.After(wall) && rdate.After(last) {
+			last = rdate
+		}
+	}
+	if r.rrule == nil {
+		return last, true
+	}
+
+	if r.rrule.Freq != Yearly || r.rrule.Count > 0 {
+		if occurrences := r.rrule.Occurrences(r.start, wall, maxPeriods); len(occurrences) > 0 {
+			if onset := occurrences[len(occurrences)-1]; onset.After(last) {
+				last = onset
+			}
+		}
+		return last, true
+	}
+
+	// Ежегодные правила перебирать с DTSTART (часто 1601 год) незачем: последний переход
+	// приходится на год wall или предыдущий
+	until := wall
+	if !r.rrule.Until.IsZero() && r.rrule.Until.Before(until) {
+		until = r.rrule.Until
+	}
+	for year := until.Year() - r.rrule.Interval; year <= until.Year(); year++ {
+		if (year-r.start.Year())%r.rrule.Interval != 0 {
+			continue
+		}
+		period := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		for _, candidate := range r.rrule.candidates(period, r.start) {
+			if !candidate.Before(r.start) && !candidate.After(until) && candidate.After(last) {
+				last = candidate
+			}
+		}
+	}
+	return last, true
+}
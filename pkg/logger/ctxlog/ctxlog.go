@@ -0,0 +1,27 @@
+package ctxlog
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a context carrying the logger
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx or fallback when there is none
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok && logger != nil {
+		return logger
+	}
+	return fallback
+}
+
+// With returns a context whose logger (or fallback) is enriched with fields
+func With(ctx context.Context, fallback *zap.Logger, fields ...zap.Field) context.Context {
+	return WithLogger(ctx, FromContext(ctx, fallback).With(fields...))
+}
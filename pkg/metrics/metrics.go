@@ -0,0 +1,424 @@
+// Package metrics - минимальная реализация метрик в текстовом формате Prometheus
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Типы метрик в формате экспозиции
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// ContentType - тип содержимого текстового формата Prometheus
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets - границы гистограммы по умолчанию, в секундах
+var DefBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Sample - одно значение метрики с метками
+type Sample struct {
+	// Suffix - окончание имени, например "_bucket" для гистограмм
+	Suffix string
+	Labels []Label
+	Value  float64
+}
+
+// Label - пара имя/значение метки
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Family - семейство метрик с общими именем, описанием и типом
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Collector - источник семейств метрик, опрашиваемый при каждом сборе
+type Collector interface {
+	Collect() []Family
+}
+
+// CollectorFunc - функция, реализующая Collector
+type CollectorFunc func() []Family
+
+// Collect - вызов функции
+func (f CollectorFunc) Collect() []Family {
+	return f()
+}
+
+// Registry - набор метрик, отдаваемых одним эндпоинтом
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+}
+
+// NewRegistry - конструктор пустого реестра
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register - добавление источника метрик
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// NewCounterVec - создание и регистрация счетчика с метками
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, labels)}
+	r.Register(c)
+	return c
+}
+
+// NewGaugeVec - создание и регистрация измерителя с метками
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec: newVec(name, help, labels)}
+	r.Register(g)
+	return g
+}
+
+// NewHistogramVec - создание и регистрация гистограммы с метками
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	h := &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: sorted,
+		series:  make(map[string]*histogram),
+	}
+	r.Register(h)
+	return h
+}
+
+// NewGaugeFunc - регистрация измерителя, значение которого вычисляется при сборе
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.Register(CollectorFunc(func() []Family {
+		return []Family{{Name: name, Help: help, Type: TypeGauge, Samples: []Sample{{Value: fn()}}}}
+	}))
+}
+
+// NewCounterFunc - регистрация счетчика, значение которого вычисляется при сборе
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.Register(CollectorFunc(func() []Family {
+		return []Family{{Name: name, Help: help, Type: TypeCounter, Samples: []Sample{{Value: fn()}}}}
+	}))
+}
+
+// Gather - сбор всех семейств, отсортированных по имени
+func (r *Registry) Gather() []Family {
+	r.mu.RLock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.RUnlock()
+
+	var families []Family
+	for _, c := range collectors {
+		families = append(families, c.Collect()...)
+	}
+	sort.SliceStable(families, func(i, j int) bool {
+		return families[i].Name < families[j].Name
+	})
+	return families
+}
+
+// WriteText - запись всех метрик в текстовом формате Prometheus
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.Gather() {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name, f.Type)
+		for _, s := range f.Samples {
+			bw.WriteString(f.Name)
+			bw.WriteString(s.Suffix)
+			writeLabels(bw, s.Labels)
+			bw.WriteByte(' ')
+			bw.WriteString(formatValue(s.Value))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler - HTTP обработчик, отдающий метрики реестра
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.WriteText(w)
+	})
+}
+
+// vec - общие данные метрик с метками
+type vec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	labels []string
+	values map[string]*series
+}
+
+// series - значение одного набора меток
+type series struct {
+	labels []Label
+	value  float64
+}
+
+func newVec(name, help string, labels []string) vec {
+	return vec{name: name, help: help, labels: labels, values: make(map[string]*series)}
+}
+
+// get возвращает серию для значений меток; вызывается под mu
+func (v *vec) get(values []string) *series {
+	key := strings.Join(values, "\xff")
+	s, ok := v.values[key]
+	if !ok {
+		s = &series{labels: makeLabels(v.labels, values)}
+		v.values[key] = s
+	}
+	return s
+}
+
+func (v *vec) collect(typ string) []Family {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	f := Family{Name: v.name, Help: v.help, Type: typ}
+	for _, s := range v.values {
+		f.Samples = append(f.Samples, Sample{Labels: s.labels, Value: s.value})
+	}
+	sortSamples(f.Samples)
+	return []Family{f}
+}
+
+// CounterVec - монотонно растущий счетчик с метками
+type CounterVec struct {
+	vec
+}
+
+// Inc - увеличение счетчика на 1
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add - увеличение счетчика на delta (отрицательные значения игнорируются)
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.get(values).value += delta
+	c.mu.Unlock()
+}
+
+// Value - текущее значение счетчика
+func (c *CounterVec) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(values).value
+}
+
+// Collect - реализация Collector
+func (c *CounterVec) Collect() []Family {
+	return c.collect(TypeCounter)
+}
+
+// GaugeVec - произвольно меняющееся значение с метками
+type GaugeVec struct {
+	vec
+}
+
+// Set - установка значения
+func (g *GaugeVec) Set(value float64, values ...string) {
+	g.mu.Lock()
+	g.get(values).value = value
+	g.mu.Unlock()
+}
+
+// Add - изменение значения на delta
+func (g *GaugeVec) Add(delta float64, values ...string) {
+	g.mu.Lock()
+	g.get(values).value += delta
+	g.mu.Unlock()
+}
+
+// Value - текущее значение
+func (g *GaugeVec) Value(values ...string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.get(values).value
+}
+
+// Collect - реализация Collector
+func (g *GaugeVec) Collect() []Family {
+	return g.collect(TypeGauge)
+}
+
+// HistogramVec - гистограмма наблюдений с метками
+type HistogramVec struct {
+	mu      sync.Mutex
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	series  map[string]*histogram
+}
+
+// histogram - накопленные наблюдения одного набора меток
+type histogram struct {
+	labels []Label
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe - добавление наблюдения
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := strings.Join(values, "\xff")
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{labels: makeLabels(h.labels, values), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+// Count - количество наблюдений для набора меток
+func (h *HistogramVec) Count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[strings.Join(values, "\xff")]; ok {
+		return s.count
+	}
+	return 0
+}
+
+// Collect - реализация Collector
+func (h *HistogramVec) Collect() []Family {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	f := Family{Name: h.name, Help: h.help, Type: TypeHistogram}
+	for _, key := range keys {
+		s := h.series[key]
+		for i, bound := range h.buckets {
+			f.Samples = append(f.Samples, Sample{
+				Suffix: "_bucket",
+				Labels: withLabel(s.labels, "le", formatValue(bound)),
+				Value:  float64(s.counts[i]),
+			})
+		}
+		f.Samples = append(f.Samples,
+			Sample{Suffix: "_bucket", Labels: withLabel(s.labels, "le", "+Inf"), Value: float64(s.count)},
+			Sample{Suffix: "_sum", Labels: s.labels, Value: s.sum},
+			Sample{Suffix: "_count", Labels: s.labels, Value: float64(s.count)},
+		)
+	}
+	return []Family{f}
+}
+
+func makeLabels(names, values []string) []Label {
+	labels := make([]Label, len(names))
+	for i, name := range names {
+		labels[i] = Label{Name: name}
+		if i < len(values) {
+			labels[i].Value = values[i]
+		}
+	}
+	return labels
+}
+
+func withLabel(labels []Label, name, value string) []Label {
+	out := make([]Label, 0, len(labels)+1)
+	out = append(out, labels...)
+	return append(out, Label{Name: name, Value: value})
+}
+
+func sortSamples(samples []Sample) {
+	sort.Slice(samples, func(i, j int) bool {
+		return labelKey(samples[i].Labels) < labelKey(samples[j].Labels)
+	})
+}
+
+func labelKey(labels []Label) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Value
+	}
+	return strings.Join(parts, "\xff")
+}
+
+func writeLabels(w *bufio.Writer, labels []Label) {
+	if len(labels) == 0 {
+		return
+	}
+	w.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(l.Name)
+		w.WriteString(`="`)
+		w.WriteString(escapeLabel(l.Value))
+		w.WriteByte('"')
+	}
+	w.WriteByte('}')
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	reg := NewRegistry()
+
+	requests := reg.NewCounterVec("http_requests_total", "Requests.", "route", "status")
+	requests.Inc("/events_for_day", "200")
+	requests.Inc("/events_for_day", "200")
+	requests.Add(3, "/create_event", "429")
+
+	latency := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	latency.Observe(0.05, "/events_for_day")
+	latency.Observe(0.5, "/events_for_day")
+
+	reg.NewGaugeFunc("answer", "Line one\nline two.", func() float64 { return 42 })
+
+	var buf strings.Builder
+	if err := reg.WriteText(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		"# HELP answer Line one\\nline two.\n# TYPE answer gauge\nanswer 42\n",
+		"# TYPE http_requests_total counter\n",
+		`http_requests_total{route="/create_event",status="429"} 3`,
+		`http_requests_total{route="/events_for_day",status="200"} 2`,
+		"# TYPE latency_seconds histogram\n",
+		`latency_seconds_bucket{route="/events_for_day",le="0.1"} 1`,
+		`latency_seconds_bucket{route="/events_for_day",le="1"} 2`,
+		`latency_seconds_bucket{route="/events_for_day",le="+Inf"} 2`,
+		`latency_seconds_sum{route="/events_for_day"} 0.55`,
+		`latency_seconds_count{route="/events_for_day"} 2`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	if strings.Index(out, "# HELP answer") > strings.Index(out, "# HELP http_requests_total") {
+		t.Error("Expected families to be sorted by name")
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("c", "C.", "path").Inc("a\"b\\c\nd")
+
+	var buf strings.Builder
+	_ = reg.WriteText(&buf)
+
+	if want := `c{path="a\"b\\c\nd"} 1`; !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected %q in output, got:\n%s", want, buf.String())
+	}
+}
+
+func TestCounterIgnoresNegative(t *testing.T) {
+	c := NewRegistry().NewCounterVec("c", "C.")
+	c.Add(2)
+	c.Add(-1)
+
+	if c.Value() != 2 {
+		t.Errorf("Expected 2, got %v", c.Value())
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+)
+
+// RegisterRuntime - регистрация метрик среды выполнения Go и процесса
+func RegisterRuntime(r *Registry) {
+	start := float64(time.Now().Unix())
+
+	r.Register(CollectorFunc(func() []Family {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+
+		gauge := func(name, help string, value float64) Family {
+			return Family{Name: name, Help: help, Type: TypeGauge, Samples: []Sample{{Value: value}}}
+		}
+		counter := func(name, help string, value float64) Family {
+			return Family{Name: name, Help: help, Type: TypeCounter, Samples: []Sample{{Value: value}}}
+		}
+
+		return []Family{
+			gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine())),
+			gauge("go_sched_gomaxprocs_threads", "Current GOMAXPROCS setting.", float64(runtime.GOMAXPROCS(0))),
+			{
+				Name: "go_info", Help: "Information about the Go environment.", Type: TypeGauge,
+				Samples: []Sample{{Labels: []Label{{Name: "version", Value: runtime.Version()}}, Value: 1}},
+			},
+			gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(ms.Alloc)),
+			counter("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", float64(ms.TotalAlloc)),
+			gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", float64(ms.Sys)),
+			gauge("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", float64(ms.HeapAlloc)),
+			gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(ms.HeapInuse)),
+			gauge("go_memstats_heap_objects", "Number of allocated objects.", float64(ms.HeapObjects)),
+			counter("go_memstats_mallocs_total", "Total number of mallocs.", float64(ms.Mallocs)),
+			counter("go_memstats_frees_total", "Total number of frees.", float64(ms.Frees)),
+			counter("go_gc_cycles_total", "Number of completed GC cycles.", float64(ms.NumGC)),
+			counter("go_gc_pause_seconds_total", "Total GC stop-the-world pause time.", float64(ms.PauseTotalNs)/1e9),
+			gauge("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", start),
+		}
+	}))
+}
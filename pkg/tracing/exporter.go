@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// JSONExporter - запись спанов в формате JSON Lines, по одному объекту на строку
+type JSONExporter struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewJSONExporter - экспортер в произвольный writer, например os.Stdout
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{enc: json.NewEncoder(w)}
+}
+
+// NewFileExporter - экспортер, дописывающий спаны в файл
+func NewFileExporter(path string) (*JSONExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONExporter{enc: json.NewEncoder(f), closer: f}, nil
+}
+
+// ExportSpan - запись спана; ошибки записи не должны влиять на обработку запросов и игнорируются
+func (e *JSONExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.enc.Encode(span)
+}
+
+// Close - закрытие файла экспортера
+func (e *JSONExporter) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
@@ -0,0 +1,119 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader - заголовок W3C Trace Context
+const TraceparentHeader = "traceparent"
+
+// TraceID - идентификатор трассы
+type TraceID [16]byte
+
+// SpanID - идентификатор спана
+type SpanID [8]byte
+
+// String - шестнадцатеричное представление
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid - идентификатор не состоит из нулей
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// String - шестнадцатеричное представление
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid - идентификатор не состоит из нулей
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext - данные спана, передаваемые между сервисами
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid - контекст содержит трассу и спан
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent - значение заголовка traceparent версии 00
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent - разбор заголовка traceparent.
+// Неизвестные будущие версии принимаются, если их префикс совпадает с форматом версии 00.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, false
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || version == "ff" || !isLowerHex(version) {
+		return SpanContext{}, false
+	}
+	if version == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+	if len(traceID) != 32 || len(spanID) != 16 || len(flags) != 2 ||
+		!isLowerHex(traceID) || !isLowerHex(spanID) || !isLowerHex(flags) {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	_, _ = hex.Decode(sc.TraceID[:], []byte(traceID))
+	_, _ = hex.Decode(sc.SpanID[:], []byte(spanID))
+	flagBits, _ := hex.DecodeString(flags)
+	sc.Sampled = flagBits[0]&0x01 == 0x01
+
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// Extract - контекст родительского спана из входящих заголовков
+func Extract(header http.Header) (SpanContext, bool) {
+	return ParseTraceparent(header.Get(TraceparentHeader))
+}
+
+// Inject - запись traceparent текущего спана в исходящие заголовки
+func Inject(ctx context.Context, header http.Header) {
+	if span := SpanFromContext(ctx); span != nil {
+		header.Set(TraceparentHeader, span.SpanContext().Traceparent())
+	}
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,218 @@
+// Package tracing - трассировка запросов в стиле OpenTelemetry с локальными экспортерами
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Статусы завершения спана
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Виды спанов
+const (
+	KindServer   = "server"
+	KindInternal = "internal"
+)
+
+// Attribute - атрибут спана
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String - строковый атрибут
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int - целочисленный атрибут
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanData - завершенный спан в виде, передаваемом экспортеру
+type SpanData struct {
+	Service      string                 `json:"service,omitempty"`
+	Name         string                 `json:"name"`
+	Kind         string                 `json:"kind"`
+	TraceID      string                 `json:"trace_id"`
+	SpanID       string                 `json:"span_id"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	DurationMS   float64                `json:"duration_ms"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Status       string                 `json:"status"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// Exporter - получатель завершенных спанов
+type Exporter interface {
+	ExportSpan(span SpanData)
+}
+
+// Tracer - источник спанов одного сервиса
+type Tracer struct {
+	service  string
+	exporter Exporter
+	now      func() time.Time
+}
+
+// NewTracer - конструктор трассировщика
+func NewTracer(service string, exporter Exporter) *Tracer {
+	return &Tracer{
+		service:  service,
+		exporter: exporter,
+		now:      time.Now,
+	}
+}
+
+// StartServer - начало корневого спана входящего запроса.
+// Если remote валиден, спан продолжает трассу вызывающего и наследует решение о сэмплировании.
+func (t *Tracer) StartServer(ctx context.Context, name string, remote SpanContext, attrs ...Attribute) (context.Context, *Span) {
+	sc := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	var parent SpanID
+	if remote.IsValid() {
+		sc.TraceID = remote.TraceID
+		sc.Sampled = remote.Sampled
+		parent = remote.SpanID
+	}
+	return t.start(ctx, name, KindServer, sc, parent, attrs)
+}
+
+func (t *Tracer) start(ctx context.Context, name, kind string, sc SpanContext, parent SpanID, attrs []Attribute) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		sc:     sc,
+		data: SpanData{
+			Service: t.service,
+			Name:    name,
+			Kind:    kind,
+			TraceID: sc.TraceID.String(),
+			SpanID:  sc.SpanID.String(),
+			Start:   t.now(),
+			Status:  StatusOK,
+		},
+	}
+	if parent.IsValid() {
+		span.data.ParentSpanID = parent.String()
+	}
+	span.SetAttributes(attrs...)
+	return ContextWithSpan(ctx, span), span
+}
+
+// Start - начало дочернего спана текущего спана контекста.
+// Без спана в контексте (трассировка отключена) возвращается nil-спан, все методы которого ничего не делают.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	sc := SpanContext{TraceID: parent.sc.TraceID, SpanID: newSpanID(), Sampled: parent.sc.Sampled}
+	return parent.tracer.start(ctx, name, KindInternal, sc, parent.sc.SpanID, attrs)
+}
+
+// Span - выполняемая операция трассы
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext - идентификаторы спана для передачи в другие сервисы
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttributes - добавление атрибутов
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil || len(attrs) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]interface{}, len(attrs))
+	}
+	for _, attr := range attrs {
+		s.data.Attributes[attr.Key] = attr.Value
+	}
+}
+
+// RecordError - пометка спана как завершившегося ошибкой
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = StatusError
+	s.data.Error = err.Error()
+}
+
+// SetStatus - явная установка статуса, например по коду HTTP-ответа
+func (s *Span) SetStatus(status, description string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = status
+	s.data.Error = description
+}
+
+// End - завершение спана и передача его экспортеру; повторные вызовы игнорируются
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = s.tracer.now()
+	s.data.DurationMS = float64(s.data.End.Sub(s.data.Start).Microseconds()) / 1000
+	data := s.data
+	s.mu.Unlock()
+
+	if s.sc.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.ExportSpan(data)
+	}
+}
+
+// EndErr - завершение спана с ошибкой, на которую указывает errp; удобно с именованным результатом в defer
+func (s *Span) EndErr(errp *error) {
+	if s == nil {
+		return
+	}
+	if errp != nil {
+		s.RecordError(*errp)
+	}
+	s.End()
+}
+
+type spanKey struct{}
+
+// ContextWithSpan - контекст с текущим спаном
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext - текущий спан контекста или nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	stdErrors "errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{name: "sampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", valid: true, sampled: true},
+		{name: "not sampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", valid: true},
+		{name: "future version", value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", valid: true, sampled: true},
+		{name: "invalid version", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "zero trace id", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "zero span id", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "uppercase", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "extra part in v00", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-x"},
+		{name: "garbage", value: "not-a-header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(tt.value)
+			if ok != tt.valid {
+				t.Fatalf("Expected valid=%v, got %v", tt.valid, ok)
+			}
+			if !ok {
+				return
+			}
+			if sc.Sampled != tt.sampled {
+				t.Errorf("Expected sampled=%v, got %v", tt.sampled, sc.Sampled)
+			}
+			if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("Unexpected trace ID %s", sc.TraceID)
+			}
+		})
+	}
+}
+
+func TestSpanHierarchy(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer("calendar-server", exporter)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, root := tracer.StartServer(context.Background(), "HTTP GET /events_for_day", remote)
+
+	childCtx, child := Start(ctx, "EventUseCase.GetEventsForDay", String("user.id", "user-1"))
+	_, grandchild := Start(childCtx, "EventRepository.GetByUserIDAndDate")
+	grandchild.RecordError(stdErrors.New("boom"))
+	grandchild.End()
+	child.End()
+	root.End()
+	root.End()
+
+	if len(exporter.spans) != 3 {
+		t.Fatalf("Expected 3 exported spans, got %d", len(exporter.spans))
+	}
+
+	byName := make(map[string]SpanData)
+	for _, span := range exporter.spans {
+		if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Span %s has trace ID %s", span.Name, span.TraceID)
+		}
+		byName[span.Name] = span
+	}
+
+	rootData := byName["HTTP GET /events_for_day"]
+	if rootData.ParentSpanID != "00f067aa0ba902b7" || rootData.Kind != KindServer {
+		t.Errorf("Unexpected root span %+v", rootData)
+	}
+	childData := byName["EventUseCase.GetEventsForDay"]
+	if childData.ParentSpanID != rootData.SpanID || childData.Attributes["user.id"] != "user-1" {
+		t.Errorf("Unexpected child span %+v", childData)
+	}
+	repoData := byName["EventRepository.GetByUserIDAndDate"]
+	if repoData.ParentSpanID != childData.SpanID || repoData.Status != StatusError || repoData.Error != "boom" {
+		t.Errorf("Unexpected repository span %+v", repoData)
+	}
+}
+
+func TestNotSampled(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer("calendar-server", exporter)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	ctx, root := tracer.StartServer(context.Background(), "root", remote)
+	_, child := Start(ctx, "child")
+	child.End()
+	root.End()
+
+	if len(exporter.spans) != 0 {
+		t.Errorf("Expected unsampled trace not to be exported, got %d spans", len(exporter.spans))
+	}
+
+	header := http.Header{}
+	Inject(ctx, header)
+	if got := header.Get(TraceparentHeader); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+root.SpanContext().SpanID.String()+"-00" {
+		t.Errorf("Unexpected injected traceparent %q", got)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "orphan")
+	if span != nil {
+		t.Fatal("Expected no span without a tracer in context")
+	}
+
+	// Методы nil-спана безопасны
+	span.SetAttributes(String("k", "v"))
+	span.RecordError(stdErrors.New("ignored"))
+	err := stdErrors.New("ignored")
+	span.EndErr(&err)
+
+	header := http.Header{}
+	Inject(ctx, header)
+	if header.Get(TraceparentHeader) != "" {
+		t.Error("Expected no traceparent without span")
+	}
+}
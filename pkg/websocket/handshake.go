@@ -0,0 +1,189 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// maxErrorBody - часть тела отказа сервера, сохраняемая в ответе Dial
+const maxErrorBody = 64 << 10
+
+// IsUpgrade - запрос является рукопожатием WebSocket
+func IsUpgrade(r *http.Request) bool {
+	return hasToken(r.Header.Values("Connection"), "upgrade") && hasToken(r.Header.Values("Upgrade"), "websocket")
+}
+
+// Protocols - подпротоколы, предложенные клиентом в Sec-WebSocket-Protocol
+func Protocols(r *http.Request) []string {
+	return headerTokens(r.Header.Values("Sec-WebSocket-Protocol"))
+}
+
+// Upgrader - рукопожатие на стороне сервера
+type Upgrader struct {
+	// Protocols - поддерживаемые подпротоколы в порядке предпочтения
+	Protocols []string
+	Config    Config
+}
+
+// Upgrade - проверка рукопожатия и переключение соединения на WebSocket.
+// Ошибки проверки оборачивают ErrBadHandshake, ответ на них записывает вызывающий.
+// Заголовки, уже установленные в w, передаются в ответе 101. После успешного Upgrade
+// w и r.Body использовать нельзя.
+func (u Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, fmt.Errorf("%w: method must be GET", ErrBadHandshake)
+	}
+	if !IsUpgrade(r) {
+		return nil, fmt.Errorf("%w: Connection and Upgrade headers must request websocket", ErrBadHandshake)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, fmt.Errorf("%w: unsupported Sec-WebSocket-Version", ErrBadHandshake)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, fmt.Errorf("%w: invalid Sec-WebSocket-Key", ErrBadHandshake)
+	}
+
+	protocol := ""
+	offered := Protocols(r)
+	for _, supported := range u.Protocols {
+		if slices.Contains(offered, supported) {
+			protocol = supported
+			break
+		}
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// Сроки, выставленные http.Server для запроса, к соединению не относятся
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	buf.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if protocol != "" {
+		buf.WriteString("Sec-WebSocket-Protocol: " + protocol + "\r\n")
+	}
+	header := w.Header().Clone()
+	for _, name := range []string{"Upgrade", "Connection", "Sec-WebSocket-Accept", "Sec-WebSocket-Protocol", "Content-Type", "Content-Length"} {
+		header.Del(name)
+	}
+	if err := header.Write(&buf); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+
+	if u.Config.WriteTimeout > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(u.Config.WriteTimeout))
+	}
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newConn(conn, rw.Reader, true, protocol, u.Config), nil
+}
+
+// Dial - рукопожатие на стороне клиента с адресом ws:// или wss://.
+// Подпротоколы передаются в header заголовком Sec-WebSocket-Protocol. При отказе сервера
+// возвращается его ответ вместе с ошибкой, оборачивающей ErrBadHandshake.
+func Dial(ctx context.Context, rawURL string, header http.Header, config Config) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	secure := false
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme, secure = "https", true
+	default:
+		return nil, nil, fmt.Errorf("websocket: unsupported URL scheme %q", u.Scheme)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if secure {
+			port = "443"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secure {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		conn = tlsConn
+	}
+	// Контекст ограничивает только рукопожатие
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: header.Clone()}
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!hasToken(resp.Header.Values("Upgrade"), "websocket") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		conn.Close()
+		return nil, resp, fmt.Errorf("%w: server responded %s", ErrBadHandshake, resp.Status)
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, resp, err
+	}
+	return newConn(conn, br, false, resp.Header.Get("Sec-WebSocket-Protocol"), config), resp, nil
+}
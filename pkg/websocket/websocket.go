@@ -0,0 +1,395 @@
+// Package websocket - протокол WebSocket (RFC 6455): рукопожатие сервера и клиента, кадры
+// и управляющие сообщения. Поддерживаются текстовые и двоичные сообщения без расширений.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// Типы сообщений и управляющих кадров (RFC 6455, 5.2)
+const (
+	continuation  = 0
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// Коды закрытия соединения (RFC 6455, 7.4.1)
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+	CloseTryAgainLater   = 1013
+)
+
+// DefaultMaxMessageSize - максимальный размер сообщения по умолчанию
+const DefaultMaxMessageSize = 64 << 10
+
+// maxControlPayload - максимальный размер данных управляющего кадра
+const maxControlPayload = 125
+
+// acceptGUID - константа вычисления Sec-WebSocket-Accept (RFC 6455, 1.3)
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	// ErrBadHandshake - запрос или ответ рукопожатия не соответствует протоколу
+	ErrBadHandshake = errors.New("bad WebSocket handshake")
+	// ErrCloseSent - сообщение закрытия уже отправлено, запись невозможна
+	ErrCloseSent = errors.New("WebSocket close already sent")
+)
+
+// CloseError - соединение закрыто сообщением с кодом Code: полученным от собеседника
+// или отправленным из-за нарушения протокола
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+// Error - реализация error
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket closed: %d", e.Code)
+	}
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// Config - ограничения соединения
+type Config struct {
+	// MaxMessageSize - максимальный размер сообщения; 0 - DefaultMaxMessageSize
+	MaxMessageSize int64
+	// ReadTimeout - соединение закрывается, если за это время не пришло ни одного кадра; 0 - без ограничения
+	ReadTimeout time.Duration
+	// WriteTimeout - время на запись одного кадра; 0 - без ограничения
+	WriteTimeout time.Duration
+}
+
+// Conn - установленное соединение WebSocket.
+// ReadMessage вызывается из одной горутины, запись допускается из нескольких.
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	server   bool
+	protocol string
+	config   Config
+
+	wmu       sync.Mutex
+	closeSent atomic.Bool
+	readErr   error
+}
+
+// newConn - соединение поверх установленного рукопожатием net.Conn
+func newConn(conn net.Conn, br *bufio.Reader, server bool, protocol string, config Config) *Conn {
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = DefaultMaxMessageSize
+	}
+	return &Conn{conn: conn, br: br, server: server, protocol: protocol, config: config}
+}
+
+// Subprotocol - подпротокол, согласованный при рукопожатии
+func (c *Conn) Subprotocol() string {
+	return c.protocol
+}
+
+// RemoteAddr - адрес собеседника
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage - следующее текстовое или двоичное сообщение. На ping отвечает pong, pong
+// пропускается. Полученное сообщение закрытия подтверждается и возвращается как *CloseError;
+// при нарушении протокола собеседнику отправляется закрытие с кодом из *CloseError.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+
+	messageType := 0
+	var message []byte
+	for {
+		// После отправки закрытия срок чтения задает закрывающая сторона
+		if c.config.ReadTimeout > 0 && !c.closeSent.Load() {
+			if err := c.conn.SetReadDeadline(time.Now().Add(c.config.ReadTimeout)); err != nil {
+				return 0, nil, c.setReadErr(err)
+			}
+		}
+
+		fin, opcode, payload, err := c.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, c.setReadErr(err)
+		}
+
+		switch opcode {
+		case PingMessage:
+			// Ошибку записи обнаружит следующее чтение или запись
+			_ = c.writeFrame(PongMessage, payload)
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			return 0, nil, c.setReadErr(c.receiveClose(payload))
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.setReadErr(c.fail(CloseProtocolError, "expected continuation frame"))
+			}
+			messageType, message = opcode, payload
+		case continuation:
+			if messageType == 0 {
+				return 0, nil, c.setReadErr(c.fail(CloseProtocolError, "unexpected continuation frame"))
+			}
+			message = append(message, payload...)
+		default:
+			return 0, nil, c.setReadErr(c.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode)))
+		}
+
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.setReadErr(c.fail(CloseInvalidPayload, "invalid UTF-8 in text message"))
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+// readFrame - чтение кадра; buffered - размер уже прочитанной части сообщения
+func (c *Conn) readFrame(buffered int64) (bool, int, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits are set")
+	}
+	// Кадры клиента маскируются, кадры сервера - нет (RFC 6455, 5.1)
+	if masked != c.server {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid frame masking")
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		if ext[0]&0x80 != 0 {
+			return false, 0, nil, c.fail(CloseProtocolError, "invalid frame length")
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if opcode >= CloseMessage && (!fin || length > maxControlPayload) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if opcode < CloseMessage && buffered+length > c.config.MaxMessageSize {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return fin, opcode, payload, nil
+}
+
+// receiveClose - подтверждение полученного закрытия тем же кодом
+func (c *Conn) receiveClose(payload []byte) error {
+	code, reason := CloseNoStatus, ""
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		reason = string(payload[2:])
+		if !utf8.ValidString(reason) {
+			return c.fail(CloseProtocolError, "invalid close reason")
+		}
+	}
+
+	if code == CloseNoStatus {
+		_ = c.writeFrame(CloseMessage, nil)
+	} else {
+		_ = c.WriteClose(code, "")
+	}
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// fail - отправка закрытия из-за нарушения протокола
+func (c *Conn) fail(code int, reason string) error {
+	_ = c.WriteClose(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// setReadErr - запоминание ошибки чтения: после нее соединение читать нельзя
+func (c *Conn) setReadErr(err error) error {
+	c.readErr = err
+	return err
+}
+
+// WriteMessage - отправка текстового или двоичного сообщения одним кадром
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// Ping - отправка ping; ответ pong продлевает ReadTimeout, как любой полученный кадр
+func (c *Conn) Ping(data []byte) error {
+	return c.writeFrame(PingMessage, data)
+}
+
+// WriteClose - отправка закрытия; причина обрезается до допустимой длины.
+// После него запись невозможна, а чтение продолжается до подтверждения собеседника.
+func (c *Conn) WriteClose(code int, reason string) error {
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return c.writeFrame(CloseMessage, append(payload, reason...))
+}
+
+// CloseSent - закрытие уже отправлено
+func (c *Conn) CloseSent() bool {
+	return c.closeSent.Load()
+}
+
+// SetReadDeadline - срок чтения, например ожидание подтверждения закрытия
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// Close - закрытие сетевого соединения без сообщения закрытия
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// writeFrame - запись кадра целиком; кадры клиента маскируются
+func (c *Conn) writeFrame(opcode int, data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.closeSent.Load() {
+		return ErrCloseSent
+	}
+	if opcode == CloseMessage {
+		c.closeSent.Store(true)
+	}
+
+	frame := make([]byte, 0, 14+len(data))
+	frame = append(frame, 0x80|byte(opcode))
+
+	maskBit := byte(0)
+	if !c.server {
+		maskBit = 0x80
+	}
+	switch length := len(data); {
+	case length <= 125:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	if c.server {
+		frame = append(frame, data...)
+	} else {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, data...)
+		maskBytes(mask, frame[start:])
+	}
+
+	if c.config.WriteTimeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteTimeout)); err != nil {
+			return err
+		}
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// maskBytes - наложение маски клиента (RFC 6455, 5.3); повторное наложение снимает ее
+func maskBytes(mask [4]byte, data []byte) {
+	for i := range data {
+		data[i] ^= mask[i%4]
+	}
+}
+
+// acceptKey - значение Sec-WebSocket-Accept для ключа клиента
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerTokens - элементы списка через запятую из всех значений заголовка
+func headerTokens(values []string) []string {
+	var tokens []string
+	for _, value := range values {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+// hasToken - список заголовка содержит token без учета регистра
+func hasToken(values []string, token string) bool {
+	for _, candidate := range headerTokens(values) {
+		if strings.EqualFold(candidate, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,252 @@
+package websocket
+
+import (
+	"context"
+	stdErrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoServer - сервер, возвращающий полученные сообщения; ошибка чтения отправляется в errs
+func echoServer(t *testing.T, config Config) (string, <-chan error) {
+	errs := make(chan error, 1)
+	upgrader := Upgrader{Protocols: []string{"echo.v1"}, Config: config}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-1")
+		conn, err := upgrader.Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http"), errs
+}
+
+func dial(t *testing.T, url string, header http.Header) *Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	conn, _, err := Dial(ctx, url, header, Config{ReadTimeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestUpgrade(t *testing.T) {
+	url, _ := echoServer(t, Config{})
+
+	header := http.Header{}
+	header.Set("Sec-WebSocket-Protocol", "bearer.secret, echo.v1")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, resp, err := Dial(ctx, url, header, Config{})
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.Subprotocol() != "echo.v1" {
+		t.Errorf("Expected subprotocol echo.v1, got %q", conn.Subprotocol())
+	}
+	if resp.Header.Get("X-Request-ID") != "req-1" {
+		t.Errorf("Expected headers set before upgrade in response, got %v", resp.Header)
+	}
+
+	httpURL := "http" + strings.TrimPrefix(url, "ws")
+	testCases := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{name: "plain request"},
+		{name: "old version", headers: map[string]string{
+			"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "8", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ==",
+		}},
+		{name: "invalid key", headers: map[string]string{
+			"Connection": "keep-alive, Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "short",
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", httpURL, nil)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, resp.StatusCode)
+			}
+		})
+	}
+
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+	}))
+	defer refusing.Close()
+	_, resp, err = Dial(ctx, "ws"+strings.TrimPrefix(refusing.URL, "http"), nil, Config{})
+	if !stdErrors.Is(err, ErrBadHandshake) || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected ErrBadHandshake with server response, got %v", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), "authentication required") {
+		t.Errorf("Expected response body to be readable, got %q", body)
+	}
+}
+
+func TestConn_Messages(t *testing.T) {
+	url, _ := echoServer(t, Config{})
+	conn := dial(t, url, nil)
+
+	testCases := []struct {
+		name        string
+		messageType int
+		data        string
+	}{
+		{name: "text", messageType: TextMessage, data: `{"type":"ping"}`},
+		{name: "binary", messageType: BinaryMessage, data: "\x00\x01\x02"},
+		{name: "empty", messageType: TextMessage, data: ""},
+		{name: "16-bit length", messageType: TextMessage, data: strings.Repeat("a", 300)},
+		{name: "64-bit length", messageType: BinaryMessage, data: strings.Repeat("b", DefaultMaxMessageSize)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := conn.WriteMessage(tc.messageType, []byte(tc.data)); err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("Failed to read: %v", err)
+			}
+			if messageType != tc.messageType || string(data) != tc.data {
+				t.Errorf("Expected echo of %d bytes, got type %d and %d bytes", len(tc.data), messageType, len(data))
+			}
+		})
+	}
+}
+
+func TestConn_Fragments(t *testing.T) {
+	url, _ := echoServer(t, Config{})
+	conn := dial(t, url, nil)
+
+	// Кадры пишутся напрямую: сообщение из двух частей с ping между ними
+	frames := []struct {
+		header byte
+		data   string
+	}{
+		{header: TextMessage, data: "hello, "},
+		{header: 0x80 | PingMessage, data: "keepalive"},
+		{header: 0x80 | continuation, data: "world"},
+	}
+	for _, frame := range frames {
+		payload := []byte(frame.data)
+		mask := [4]byte{1, 2, 3, 4}
+		maskBytes(mask, payload)
+		raw := append([]byte{frame.header, 0x80 | byte(len(payload))}, mask[:]...)
+		if _, err := conn.conn.Write(append(raw, payload...)); err != nil {
+			t.Fatalf("Failed to write frame: %v", err)
+		}
+	}
+
+	// Pong на ping пропускается клиентом так же, как сервером
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(data) != "hello, world" {
+		t.Errorf("Expected assembled message, got %q", data)
+	}
+}
+
+func TestConn_ProtocolErrors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		frame        []byte
+		config       Config
+		expectedCode int
+	}{
+		{name: "unmasked client frame", frame: []byte{0x81, 0x01, 'a'}, expectedCode: CloseProtocolError},
+		{name: "reserved bits", frame: []byte{0xc1, 0x80, 0, 0, 0, 0}, expectedCode: CloseProtocolError},
+		{name: "fragmented ping", frame: []byte{0x09, 0x80, 0, 0, 0, 0}, expectedCode: CloseProtocolError},
+		{name: "too big", frame: []byte{0x81, 0x85, 0, 0, 0, 0, 'h', 'e', 'l', 'l', 'o'}, config: Config{MaxMessageSize: 4}, expectedCode: CloseMessageTooBig},
+		{name: "invalid UTF-8", frame: []byte{0x81, 0x81, 0, 0, 0, 0, 0xff}, expectedCode: CloseInvalidPayload},
+		{name: "unknown opcode", frame: []byte{0x83, 0x80, 0, 0, 0, 0}, expectedCode: CloseProtocolError},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url, errs := echoServer(t, tc.config)
+			conn := dial(t, url, nil)
+
+			if _, err := conn.conn.Write(tc.frame); err != nil {
+				t.Fatalf("Failed to write frame: %v", err)
+			}
+
+			var serverErr *CloseError
+			if err := <-errs; !stdErrors.As(err, &serverErr) || serverErr.Code != tc.expectedCode {
+				t.Errorf("Expected server close %d, got %v", tc.expectedCode, err)
+			}
+			var clientErr *CloseError
+			if _, _, err := conn.ReadMessage(); !stdErrors.As(err, &clientErr) || clientErr.Code != tc.expectedCode {
+				t.Errorf("Expected close %d from server, got %v", tc.expectedCode, err)
+			}
+		})
+	}
+}
+
+func TestConn_Close(t *testing.T) {
+	url, errs := echoServer(t, Config{})
+	conn := dial(t, url, nil)
+
+	if err := conn.WriteClose(CloseGoingAway, "bye"); err != nil {
+		t.Fatalf("Failed to write close: %v", err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("late")); !stdErrors.Is(err, ErrCloseSent) {
+		t.Errorf("Expected ErrCloseSent after close, got %v", err)
+	}
+
+	var serverErr *CloseError
+	if err := <-errs; !stdErrors.As(err, &serverErr) || serverErr.Code != CloseGoingAway || serverErr.Reason != "bye" {
+		t.Errorf("Expected server to receive close 1001 bye, got %v", err)
+	}
+	var confirm *CloseError
+	if _, _, err := conn.ReadMessage(); !stdErrors.As(err, &confirm) || confirm.Code != CloseGoingAway {
+		t.Errorf("Expected close confirmation 1001, got %v", err)
+	}
+}
+
+func TestConn_ReadTimeout(t *testing.T) {
+	url, errs := echoServer(t, Config{ReadTimeout: 100 * time.Millisecond})
+	conn := dial(t, url, nil)
+	defer conn.Close()
+
+	select {
+	case err := <-errs:
+		var netErr interface{ Timeout() bool }
+		if !stdErrors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("Expected read timeout on idle connection, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected idle connection to time out")
+	}
+}
@@ -1,6 +1,9 @@
 #!/bin/bash
 
 BASE_URL="http://localhost:8888"
+# Токен пользователя user-123 из файла AUTH_TOKENS_FILE сервера
+TOKEN="${TOKEN:-dev-token}"
+AUTH_HEADER="Authorization: Bearer $TOKEN"
 
 echo "=== Testing Calendar API with CORS ==="
 echo
@@ -13,17 +16,17 @@ curl -X OPTIONS $BASE_URL/create_event \
   -I && echo
 
 echo "2. Creating events:"
-curl -X POST $BASE_URL/create_event \
+curl -H "$AUTH_HEADER" -X POST $BASE_URL/create_event \
   -H "Content-Type: application/json" \
   -H "Origin: http://localhost:3000" \
   -d '{"id":"event-1","user_id":"user-123","date":"2025-01-15","title":"Встреча с командой"}' && echo
 
-curl -X POST $BASE_URL/create_event \
+curl -H "$AUTH_HEADER" -X POST $BASE_URL/create_event \
   -H "Content-Type: application/json" \
   -H "Origin: http://localhost:3000" \
   -d '{"id":"event-2","user_id":"user-123","date":"2025-01-15","title":"Презентация проекта"}' && echo
 
-curl -X POST $BASE_URL/create_event \
+curl -H "$AUTH_HEADER" -X POST $BASE_URL/create_event \
   -H "Content-Type: application/json" \
   -H "Origin: http://localhost:3000" \
   -d '{"id":"event-3","user_id":"user-123","date":"2025-01-16","title":"Обучение"}' && echo
@@ -31,18 +34,18 @@ curl -X POST $BASE_URL/create_event \
 echo
 
 echo "3. Events for day 2025-01-15:"
-curl "$BASE_URL/events_for_day?user_id=user-123&date=2025-01-15" && echo
+curl -H "$AUTH_HEADER" "$BASE_URL/events_for_day?user_id=user-123&date=2025-01-15" && echo
 
 echo "4. Events for week (starting 2025-01-15):"
-curl "$BASE_URL/events_for_week?user_id=user-123&date=2025-01-15" && echo
+curl -H "$AUTH_HEADER" "$BASE_URL/events_for_week?user_id=user-123&date=2025-01-15" && echo
 
 echo "5. Events for month (January 2025):"
-curl "$BASE_URL/events_for_month?user_id=user-123&date=2025-01-15" && echo
+curl -H "$AUTH_HEADER" "$BASE_URL/events_for_month?user_id=user-123&date=2025-01-15" && echo
 
 echo
 
 echo "6. Updating event event-1:"
-curl -X POST $BASE_URL/update_event \
+curl -H "$AUTH_HEADER" -X POST $BASE_URL/update_event \
   -H "Content-Type: application/json" \
   -H "Origin: http://localhost:3000" \
   -d '{"id":"event-1","user_id":"user-123","date":"2025-01-15","title":"ВСТРЕЧА С КОМАНДОЙ (ОБНОВЛЕННАЯ)"}' && echo
@@ -50,7 +53,7 @@ curl -X POST $BASE_URL/update_event \
 echo
 
 echo "7. Deleting event event-2:"
-curl -X POST $BASE_URL/delete_event \
+curl -H "$AUTH_HEADER" -X POST $BASE_URL/delete_event \
   -H "Content-Type: application/json" \
   -H "Origin: http://localhost:3000" \
   -d '{"id":"event-2"}' && echo
@@ -58,21 +61,27 @@ curl -X POST $BASE_URL/delete_event \
 echo
 
 echo "8. Events after deletion:"
-curl "$BASE_URL/events_for_day?user_id=user-123&date=2025-01-15" && echo
+curl -H "$AUTH_HEADER" "$BASE_URL/events_for_day?user_id=user-123&date=2025-01-15" && echo
 
 echo
 
 echo "9. Error testing:"
 echo "   - Empty ID:"
-curl -X POST $BASE_URL/create_event \
+curl -H "$AUTH_HEADER" -X POST $BASE_URL/create_event \
   -H "Content-Type: application/json" \
   -H "Origin: http://localhost:3000" \
   -d '{"id":"","user_id":"user-123","date":"2025-01-15","title":"Пустой ID"}' && echo
 
 echo "   - Invalid date:"
-curl -X POST $BASE_URL/create_event \
+curl -H "$AUTH_HEADER" -X POST $BASE_URL/create_event \
   -H "Content-Type: application/json" \
   -H "Origin: http://localhost:3000" \
   -d '{"id":"event-err","user_id":"user-123","date":"2025/01/15","title":"Неправильная дата"}' && echo
 
+echo "   - Without token:"
+curl "$BASE_URL/events_for_day?user_id=user-123&date=2025-01-15" && echo
+
+echo "   - Another user's calendar:"
+curl -H "$AUTH_HEADER" "$BASE_URL/events_for_day?user_id=user-999&date=2025-01-15" && echo
+
 echo "=== Testing completed ==="
\ No newline at end of file